@@ -0,0 +1,168 @@
+package avalanche
+
+import (
+	"math"
+	"time"
+)
+
+// QuorumAdaptation is an adaptive quorum decision along with the network
+// conditions it was based on
+type QuorumAdaptation struct {
+	Time time.Time
+
+	// Previous and Quorum are the quorum before and after the decision
+	Previous int
+	Quorum   int
+
+	// Unreliability is the share of the polled nodes' tallied votes that were
+	// on the losing side of finalization
+	Unreliability float64
+
+	// Churn is the share of polled nodes that joined or left since the
+	// previous decision
+	Churn float64
+
+	// Peers is the number of polled nodes
+	Peers int
+}
+
+// GetVoteQuorum returns the number of votes within the window that must agree
+// for a round to be conclusive, as adapted if Config.AdaptiveQuorum is set
+func (p *Processor) GetVoteQuorum() int {
+	return int(p.voteParams().quorum)
+}
+
+// GetQuorumAdaptations returns the most recent adaptive quorum decisions,
+// oldest first, for evaluating how the quorum follows network conditions
+func (p *Processor) GetQuorumAdaptations() []QuorumAdaptation {
+	return append([]QuorumAdaptation{}, p.quorumHistory...)
+}
+
+// voteParams returns the thresholds for new VoteRecords, with the adaptive
+// quorum if there is one
+func (p *Processor) voteParams() voteParams {
+	params := p.config.voteParams()
+	if p.quorum > 0 {
+		params.quorum = p.quorum
+	}
+	return params
+}
+
+// typeVoteParams returns the thresholds for new VoteRecords of the target type,
+// with the adaptive quorum unless the type sets its own
+func (p *Processor) typeVoteParams(targetType string) voteParams {
+	params := p.config.typeVoteParams(targetType)
+	if p.quorum > 0 && p.config.Types[targetType].VoteQuorum <= 0 {
+		params.quorum = p.quorum
+	}
+	return params
+}
+
+// adaptQuorum reconsiders an adaptive quorum once every
+// QuorumAdaptationInterval. Unreliable peers raise it towards the whole vote
+// window, since more agreement is needed before trusting a round. Churn lowers
+// it, since departing peers leave fewer votes to reach a quorum with. It is
+// kept between a majority of the window, below which rounds could be
+// conclusive both ways, or MinVoteQuorum if higher, and the whole window.
+// Target types with their own VoteQuorum keep it.
+func (p *Processor) adaptQuorum() {
+	if !p.config.AdaptiveQuorum {
+		return
+	}
+
+	now := clock.Now()
+	if now.Before(p.nextAdaptation) {
+		return
+	}
+	interval := p.config.QuorumAdaptationInterval
+	if interval <= 0 {
+		interval = AvalancheQuorumAdaptationInterval
+	}
+	p.nextAdaptation = now.Add(interval)
+
+	params := p.config.voteParams()
+	window := countBits8(params.mask)
+	lo := window/2 + 1
+	if p.config.MinVoteQuorum > lo {
+		lo = p.config.MinVoteQuorum
+	}
+	if lo > window {
+		lo = window
+	}
+	base := clampInt(int(params.quorum), lo, window)
+
+	peers := p.getPollPeers()
+	a := QuorumAdaptation{
+		Time:          now,
+		Previous:      p.GetVoteQuorum(),
+		Unreliability: p.unreliability(peers),
+		Churn:         p.churn(peers),
+		Peers:         len(peers),
+	}
+
+	raise := int(math.Ceil(2 * a.Unreliability * float64(window-base)))
+	lower := int(math.Floor(a.Churn * float64(base-lo)))
+	a.Quorum = clampInt(base+raise-lower, lo, window)
+
+	p.quorum = uint8(a.Quorum)
+	for h, vr := range p.voteRecords {
+		vr.params.quorum = p.typeVoteParams(p.targets[h].Type()).quorum
+	}
+
+	p.quorumHistory = append(p.quorumHistory, a)
+	if len(p.quorumHistory) > AvalancheQuorumHistorySize {
+		p.quorumHistory = p.quorumHistory[len(p.quorumHistory)-AvalancheQuorumHistorySize:]
+	}
+}
+
+// unreliability returns the share of the nodes' tallied votes that disagreed
+// with the outcome, or 0 if none have been tallied
+func (p *Processor) unreliability(nodeIDs []NodeID) float64 {
+	agreed, disagreed := int64(0), int64(0)
+	for _, id := range nodeIDs {
+		if s, ok := p.peerStats[id]; ok {
+			agreed += s.votesAgreed
+			disagreed += s.votesDisagreed
+		}
+	}
+	if agreed+disagreed == 0 {
+		return 0
+	}
+	return float64(disagreed) / float64(agreed+disagreed)
+}
+
+// churn returns the share of the nodes that joined or left since the previous
+// call, and remembers them for the next. There is no churn on the first call.
+func (p *Processor) churn(nodeIDs []NodeID) float64 {
+	prev := p.quorumPeers
+	p.quorumPeers = make(map[NodeID]struct{}, len(nodeIDs))
+	for _, id := range nodeIDs {
+		p.quorumPeers[id] = struct{}{}
+	}
+	if prev == nil || len(prev)+len(nodeIDs) == 0 {
+		return 0
+	}
+
+	changed := 0
+	for id := range p.quorumPeers {
+		if _, ok := prev[id]; !ok {
+			changed++
+		}
+	}
+	for id := range prev {
+		if _, ok := p.quorumPeers[id]; !ok {
+			changed++
+		}
+	}
+	return float64(changed) / float64(len(prev)+len(nodeIDs))
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
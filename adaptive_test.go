@@ -0,0 +1,79 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveQuorum(t *testing.T) {
+	now := time.Now()
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	var (
+		connman = NewConnman()
+		config  = Config{AdaptiveQuorum: true, QuorumAdaptationInterval: time.Minute}
+		p       = NewProcessorWithConfig(connman, config)
+	)
+	for i := 0; i < 4; i++ {
+		connman.AddNode(NodeID(i))
+	}
+	assertTrue(t, p.AddTargetToReconcile(blockForHash(Hash(65))))
+
+	// Reliable peers keep the configured quorum
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == AvalancheVoteQuorum)
+
+	// It is only reconsidered once the interval has passed
+	p.stats(NodeID(0)).votesAgreed = 3
+	p.stats(NodeID(1)).votesDisagreed = 1
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == AvalancheVoteQuorum)
+
+	// A quarter of the votes on the losing side raises it, for existing and new
+	// records alike
+	clock = stubClocker{now.Add(time.Minute)}
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == 8)
+	assertTrue(t, p.voteRecords[Hash(65)].params.quorum == 8)
+	assertTrue(t, p.AddTargetToReconcile(blockForHash(Hash(66))))
+	assertTrue(t, p.voteRecords[Hash(66)].params.quorum == 8)
+
+	// Half of the peers being replaced lowers it, but never below a majority
+	// of the window
+	p.peerStats = map[NodeID]*peerStats{}
+	connman.removeNode(NodeID(0))
+	connman.removeNode(NodeID(1))
+	connman.AddNode(NodeID(4))
+	connman.AddNode(NodeID(5))
+	clock = stubClocker{now.Add(2 * time.Minute)}
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == 6)
+
+	connman.removeNode(NodeID(2))
+	connman.removeNode(NodeID(3))
+	connman.removeNode(NodeID(4))
+	connman.removeNode(NodeID(5))
+	for i := 6; i < 10; i++ {
+		connman.AddNode(NodeID(i))
+	}
+	clock = stubClocker{now.Add(3 * time.Minute)}
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == 5)
+
+	// Every decision is recorded with what it was based on
+	history := p.GetQuorumAdaptations()
+	if len(history) != 4 {
+		t.Fatal("Expected 4 adaptations but got", len(history))
+	}
+	a := history[1]
+	assertTrue(t, a.Previous == 7 && a.Quorum == 8 && a.Unreliability == 0.25 && a.Churn == 0 && a.Peers == 4)
+	a = history[2]
+	assertTrue(t, a.Previous == 8 && a.Quorum == 6 && a.Unreliability == 0 && a.Churn == 0.5)
+	assertTrue(t, history[3].Churn == 1 && history[3].Time.Equal(now.Add(3*time.Minute)))
+
+	// The quorum is fixed without the mode
+	p = NewProcessorWithConfig(connman, Config{})
+	p.adaptQuorum()
+	assertTrue(t, p.GetVoteQuorum() == AvalancheVoteQuorum && len(p.GetQuorumAdaptations()) == 0)
+}
@@ -0,0 +1,112 @@
+package avalanche
+
+import (
+	"sort"
+	"time"
+)
+
+// ServiceFlag is a set of services a node offers to avalanche peers
+type ServiceFlag uint64
+
+const (
+	// ServiceVoter means the node answers Polls. Observers don't.
+	ServiceVoter ServiceFlag = 1 << iota
+
+	// ServiceFinalized means the node reports its finalized targets to
+	// bootstrapping peers; see ImportFinalized
+	ServiceFinalized
+)
+
+// NetAddr is an avalanche endpoint relayed between peers
+type NetAddr struct {
+	Addr string
+
+	// Timestamp is when the endpoint was last known to be reachable
+	Timestamp time.Time
+
+	Services ServiceFlag
+}
+
+// AddrMessage relays known avalanche endpoints to a peer, in the spirit of
+// bitcoin's addr message, so nodes find each other without an external
+// registry
+type AddrMessage struct {
+	addrs []NetAddr
+}
+
+// NewAddrMessage creates a new AddrMessage
+func NewAddrMessage(addrs []NetAddr) AddrMessage {
+	return AddrMessage{addrs}
+}
+
+// GetAddrs returns the relayed endpoints
+func (m AddrMessage) GetAddrs() []NetAddr {
+	return m.addrs
+}
+
+// GetServices returns the services this node offers its peers
+func (p *Processor) GetServices() ServiceFlag {
+	if p.config.Observer {
+		return ServiceFinalized
+	}
+	return ServiceVoter | ServiceFinalized
+}
+
+// AddrMessage returns up to max of the most recently seen endpoints in the
+// address book to relay to a peer. At most AvalancheMaxAddrs are returned.
+func (b *AddrBook) AddrMessage(max int) AddrMessage {
+	if max <= 0 || max > AvalancheMaxAddrs {
+		max = AvalancheMaxAddrs
+	}
+
+	b.mu.Lock()
+	addrs := make([]NetAddr, 0, len(b.entries))
+	for _, e := range b.entries {
+		addrs = append(addrs, NetAddr{e.Addr, e.LastSeen, e.Services})
+	}
+	b.mu.Unlock()
+
+	sort.Slice(addrs, func(i, j int) bool {
+		if !addrs[i].Timestamp.Equal(addrs[j].Timestamp) {
+			return addrs[i].Timestamp.After(addrs[j].Timestamp)
+		}
+		return addrs[i].Addr < addrs[j].Addr
+	})
+	if len(addrs) > max {
+		addrs = addrs[:max]
+	}
+	return AddrMessage{addrs}
+}
+
+// AddAddrs records the endpoints a peer relayed, returning how many were new.
+// Known endpoints take the relayed services and are only marked as seen later
+// than before. Timestamps in the future are taken as now, so a peer can't make
+// its endpoints look fresher than they are.
+func (b *AddrBook) AddAddrs(m AddrMessage) int {
+	now := clock.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	added := 0
+	for _, a := range m.GetAddrs() {
+		if a.Addr == "" {
+			continue
+		}
+		if _, ok := b.entries[a.Addr]; !ok {
+			added++
+		}
+
+		seen := a.Timestamp
+		if seen.After(now) {
+			seen = now
+		}
+
+		e := b.entry(a.Addr)
+		e.Services = a.Services
+		if seen.After(e.LastSeen) {
+			e.LastSeen = seen
+		}
+	}
+	return added
+}
@@ -0,0 +1,73 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddrMessageEncoding(t *testing.T) {
+	seen := time.Unix(1500000000, 0)
+	m := NewAddrMessage([]NetAddr{{"10.0.0.1:8333", seen, ServiceVoter | ServiceFinalized}})
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"addrs":[{"addr":"10.0.0.1:8333","time":1500000000,"services":3}]}`
+	if string(data) != expected {
+		t.Fatal("Expected", expected, "but got", string(data))
+	}
+
+	decoded, err := DecodeAddrMessage(strings.NewReader(expected), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, len(decoded.GetAddrs()) == 1 && decoded.GetAddrs()[0] == m.GetAddrs()[0])
+
+	// Messages with too many addresses are refused
+	addrs := make([]string, AvalancheMaxAddrs+1)
+	for i := range addrs {
+		addrs[i] = `{"addr":"a","time":0,"services":0}`
+	}
+	body := `{"version":1,"addrs":[` + strings.Join(addrs, ",") + `]}`
+	_, err = DecodeAddrMessage(strings.NewReader(body), false)
+	if e, ok := err.(*Error); !ok || e.Op != "decode addr" || e.Err != ErrTooManyAddrs {
+		t.Fatal("Expected too many addresses but got", err)
+	}
+}
+
+func TestAddrRelay(t *testing.T) {
+	now := time.Now()
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	book := NewAddrBook()
+	book.Add("known", NodeID(1))
+
+	m := NewAddrMessage([]NetAddr{
+		{"known", now.Add(-time.Hour), ServiceVoter},
+		{"new", now.Add(-time.Minute), ServiceFinalized},
+		{"future", now.Add(time.Hour), ServiceVoter},
+		{"", now, ServiceVoter},
+	})
+	assertTrue(t, book.AddAddrs(m) == 2)
+
+	// Relayed timestamps never make an endpoint look fresher than it is
+	known, _ := book.Get("known")
+	assertTrue(t, known.LastSeen.Equal(now) && known.Services == ServiceVoter && known.NodeID == NodeID(1))
+	future, _ := book.Get("future")
+	assertTrue(t, future.LastSeen.Equal(now) && future.NodeID == NoNode)
+
+	// Endpoints are relayed on most recently seen first
+	addrs := book.AddrMessage(2).GetAddrs()
+	assertTrue(t, len(addrs) == 2 && addrs[0].Addr == "future" && addrs[1].Addr == "known")
+
+	// Relayed endpoints can't be seeded until they have an ID
+	connman := NewConnman()
+	assertTrue(t, book.Seed(connman, 0) == 1)
+
+	// Observers don't advertise voting
+	assertTrue(t, NewProcessor(connman).GetServices() == ServiceVoter|ServiceFinalized)
+	assertTrue(t, NewProcessorWithConfig(connman, Config{Observer: true}).GetServices() == ServiceFinalized)
+}
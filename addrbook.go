@@ -0,0 +1,282 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// addrBookVersion is the version of the address book format written by Save
+const addrBookVersion = 1
+
+// AddrEntry is what the address book remembers about a peer
+type AddrEntry struct {
+	Addr   string `json:"addr"`
+	NodeID NodeID `json:"node_id"`
+
+	// LastSeen is the last time the peer was in the Connman
+	LastSeen time.Time `json:"last_seen"`
+
+	// VotesAgreed and VotesDisagreed count the finalized targets the peer's
+	// votes agreed or disagreed with the outcome of, across every session
+	VotesAgreed    int64 `json:"votes_agreed"`
+	VotesDisagreed int64 `json:"votes_disagreed"`
+
+	// Services are the services the peer advertised, and Capabilities are
+	// what else it supports, such as the target types it votes on
+	Services     ServiceFlag `json:"services,omitempty"`
+	Capabilities []string    `json:"capabilities,omitempty"`
+
+	// sessionAgreed and sessionDisagreed are the peer's counts in this
+	// session when the entry was last updated
+	sessionAgreed    int64
+	sessionDisagreed int64
+}
+
+// Reliability scores the peer's history like GetPeerReliability does for the
+// current session
+func (e AddrEntry) Reliability() float64 {
+	return float64(e.VotesAgreed+1) / float64(e.VotesAgreed+e.VotesDisagreed+2)
+}
+
+// AddrBook remembers known peers and how well they have voted, so a restarted
+// node can reconnect to them and prefer those that have proven reliable, in
+// the spirit of bitcoind's peers.dat. It is safe for concurrent use.
+type AddrBook struct {
+	mu      sync.Mutex
+	entries map[string]*AddrEntry
+}
+
+// addrBookFile is the encoded state of an AddrBook
+type addrBookFile struct {
+	Version int         `json:"version"`
+	Entries []AddrEntry `json:"entries"`
+}
+
+// NewAddrBook creates a new, empty *AddrBook
+func NewAddrBook() *AddrBook {
+	return &AddrBook{entries: map[string]*AddrEntry{}}
+}
+
+// Add records a peer reachable at addr along with its capabilities, replacing
+// any capabilities recorded before. Its history is kept if it is already known.
+func (b *AddrBook) Add(addr string, id NodeID, capabilities ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.entry(addr)
+	e.NodeID = id
+	e.LastSeen = clock.Now()
+	e.Capabilities = append([]string{}, capabilities...)
+}
+
+// Get returns the entry for addr
+func (b *AddrBook) Get(addr string) (AddrEntry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[addr]
+	if !ok {
+		return AddrEntry{}, false
+	}
+	return *e, true
+}
+
+// Entries returns every entry, most reliable first
+func (b *AddrBook) Entries() []AddrEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := make([]AddrEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ri, rj := entries[i].Reliability(), entries[j].Reliability()
+		if ri != rj {
+			return ri > rj
+		}
+		return entries[i].Addr < entries[j].Addr
+	})
+	return entries
+}
+
+// Prune forgets peers not seen since before, returning how many were removed
+func (b *AddrBook) Prune(before time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pruned := 0
+	for addr, e := range b.entries {
+		if e.LastSeen.Before(before) {
+			delete(b.entries, addr)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Seed adds up to max of the most reliable known peers to the Connman, such as
+// after a restart, and returns how many were added. Every peer is tried if max
+// is not positive. Peers only heard of through an AddrMessage have no NodeID
+// until the application connects to them, so they are skipped.
+func (b *AddrBook) Seed(c *Connman, max int) int {
+	added := 0
+	for _, e := range b.Entries() {
+		if max > 0 && added >= max {
+			break
+		}
+		if e.NodeID != NoNode && c.AddNodeWithAddr(e.NodeID, e.Addr) {
+			added++
+		}
+	}
+	return added
+}
+
+// Save writes the address book to w
+func (b *AddrBook) Save(w io.Writer) error {
+	b.mu.Lock()
+	f := addrBookFile{addrBookVersion, make([]AddrEntry, 0, len(b.entries))}
+	for _, e := range b.entries {
+		f.Entries = append(f.Entries, *e)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(f.Entries, func(i, j int) bool { return f.Entries[i].Addr < f.Entries[j].Addr })
+	return wrapError("write address book", json.NewEncoder(w).Encode(f))
+}
+
+// Load reads an address book written by Save, replacing the entries with the
+// same addresses
+func (b *AddrBook) Load(r io.Reader) error {
+	f := addrBookFile{}
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return &Error{"read address book", err}
+	}
+	if f.Version != addrBookVersion {
+		return ErrAddrBookVersion
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range f.Entries {
+		e := f.Entries[i]
+		b.entries[e.Addr] = &e
+	}
+	return nil
+}
+
+// SaveToFile writes the address book to path, replacing it only once the
+// new one has been completely written
+func (b *AddrBook) SaveToFile(path string) error {
+	return saveToFile(path, "write address book", b.Save)
+}
+
+// LoadFromFile loads an address book written by SaveToFile
+func (b *AddrBook) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return &Error{"read address book", err}
+	}
+	defer f.Close()
+
+	return b.Load(f)
+}
+
+// saveToFile writes path with save, replacing it only once the new file has
+// been completely written. Errors from the file are returned as an *Error for
+// op.
+func saveToFile(path string, op string, save func(io.Writer) error) error {
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return &Error{op, err}
+	}
+
+	err = save(f)
+	if err == nil {
+		err = wrapError(op, f.Sync())
+	}
+	if cerr := f.Close(); err == nil {
+		err = wrapError(op, cerr)
+	}
+	if err == nil {
+		err = wrapError(op, os.Rename(path+".tmp", path))
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+	}
+	return err
+}
+
+// reliability returns the reliability of the peer at addr, or that of a peer
+// with no history if it is unknown
+func (b *AddrBook) reliability(addr string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.entries[addr]; ok {
+		return e.Reliability()
+	}
+	return AddrEntry{}.Reliability()
+}
+
+// entry returns the entry for addr, creating it if needed. b.mu must be held.
+func (b *AddrBook) entry(addr string) *AddrEntry {
+	e, ok := b.entries[addr]
+	if !ok {
+		e = &AddrEntry{Addr: addr, NodeID: NoNode}
+		b.entries[addr] = e
+	}
+	return e
+}
+
+// SetAddrBook sets an address book to keep up to date with the nodes in the
+// Connman. Peer rotation then prefers candidates with a history of reliable
+// votes. It must be called before the *Processor is started.
+func (p *Processor) SetAddrBook(b *AddrBook) {
+	p.addrBook = b
+}
+
+// UpdateAddrBook records every node in the Connman that has an address as seen
+// now, along with its votes since the last update. It is called before each
+// peer rotation, and should be called before saving the address book.
+func (p *Processor) UpdateAddrBook() {
+	if p.addrBook == nil {
+		return
+	}
+
+	b := p.addrBook
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := clock.Now()
+	for id, n := range p.connman.nodes {
+		if n.addr == "" {
+			continue
+		}
+
+		e := b.entry(n.addr)
+		e.NodeID = id
+		e.LastSeen = now
+		if policy, ok := p.peerPolicies[id]; ok {
+			e.Capabilities = append(e.Capabilities[:0], policy.TargetTypes...)
+		}
+
+		s, ok := p.peerStats[id]
+		if !ok {
+			continue
+		}
+
+		// Counts below the last update mean a new session for the address
+		if s.votesAgreed < e.sessionAgreed || s.votesDisagreed < e.sessionDisagreed {
+			e.sessionAgreed, e.sessionDisagreed = 0, 0
+		}
+		e.VotesAgreed += s.votesAgreed - e.sessionAgreed
+		e.VotesDisagreed += s.votesDisagreed - e.sessionDisagreed
+		e.sessionAgreed, e.sessionDisagreed = s.votesAgreed, s.votesDisagreed
+	}
+}
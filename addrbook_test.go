@@ -0,0 +1,111 @@
+package avalanche
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddrBook(t *testing.T) {
+	start := time.Now()
+	clock = stubClocker{start}
+	defer func() { clock = realClocker{} }()
+
+	var (
+		connman = NewConnman()
+		p       = NewProcessor(connman)
+		book    = NewAddrBook()
+	)
+	p.SetAddrBook(book)
+	connman.AddNodeWithAddr(NodeID(1), "10.0.0.1:8333")
+	connman.AddNodeWithAddr(NodeID(2), "10.0.0.2:8333")
+	connman.AddNode(NodeID(3))
+	book.Add("10.0.0.1:8333", NodeID(1), "block", "tx")
+
+	// Votes are added to the history once, however often it is updated
+	p.stats(NodeID(1)).votesAgreed = 3
+	p.stats(NodeID(2)).votesDisagreed = 1
+	p.UpdateAddrBook()
+	p.stats(NodeID(1)).votesAgreed = 5
+	p.UpdateAddrBook()
+
+	entries := book.Entries()
+	if len(entries) != 2 {
+		t.Fatal("Expected nodes with addresses to be recorded but got", entries)
+	}
+	assertTrue(t, entries[0].Addr == "10.0.0.1:8333" && entries[0].VotesAgreed == 5 && entries[0].VotesDisagreed == 0)
+	assertTrue(t, len(entries[0].Capabilities) == 2 && entries[0].LastSeen.Equal(start))
+	assertTrue(t, entries[1].Addr == "10.0.0.2:8333" && entries[1].VotesDisagreed == 1)
+	assertTrue(t, entries[0].Reliability() > entries[1].Reliability())
+
+	// The book survives a restart, adding to the history of the new session
+	dir, err := ioutil.TempDir("", "addrbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peers.json")
+	if err = book.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewConnman()
+	p = NewProcessor(restarted)
+	book = NewAddrBook()
+	if err = book.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	p.SetAddrBook(book)
+
+	// The most reliable peers are reconnected first
+	assertTrue(t, book.Seed(restarted, 1) == 1)
+	assertTrue(t, len(restarted.NodesIDs()) == 1 && restarted.NodesIDs()[0] == NodeID(1))
+	assertTrue(t, book.Seed(restarted, 0) == 1)
+
+	p.stats(NodeID(1)).votesAgreed = 1
+	p.UpdateAddrBook()
+	e, ok := book.Get("10.0.0.1:8333")
+	assertTrue(t, ok && e.VotesAgreed == 6)
+
+	// Peers not seen for a while are forgotten
+	clock = stubClocker{start.Add(time.Hour)}
+	restarted.removeNode(NodeID(2))
+	p.UpdateAddrBook()
+	assertTrue(t, book.Prune(start.Add(time.Minute)) == 1)
+	_, ok = book.Get("10.0.0.2:8333")
+	assertFalse(t, ok)
+
+	// Books from other versions are refused
+	err = NewAddrBook().Load(strings.NewReader(`{"version":2,"entries":[]}`))
+	assertTrue(t, err == ErrAddrBookVersion)
+}
+
+func TestAddrBookRotation(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{MaxPollPeers: 1})
+		book    = NewAddrBook()
+	)
+	p.rng = rand.New(rand.NewSource(1))
+	p.SetAddrBook(book)
+	connman.AddNodeWithAddr(NodeID(1), "reliable")
+	connman.AddNodeWithAddr(NodeID(2), "unreliable")
+	p.stats(NodeID(1)).votesAgreed = 100
+	p.stats(NodeID(2)).votesDisagreed = 100
+	p.UpdateAddrBook()
+
+	// Candidates with a history of reliable votes are preferred
+	reliable := 0
+	for i := 0; i < 100; i++ {
+		if id, _ := p.randomCandidate(connman.NodesIDs()); id == NodeID(1) {
+			reliable++
+		}
+	}
+	if reliable < 90 {
+		t.Fatal("Expected the reliable node to be preferred but it was picked", reliable, "times")
+	}
+}
@@ -0,0 +1,105 @@
+package avalanche
+
+import "sort"
+
+// ChainTipSource reports the current tip of the chain that finalizations are
+// anchored to
+type ChainTipSource interface {
+	ChainTip() (hash Hash, height int64)
+}
+
+// Anchor is the chain tip at the time a target was finalized
+type Anchor struct {
+	Hash   Hash
+	Height int64
+}
+
+// finalization is the outcome of a finalized target
+type finalization struct {
+	status Status
+	anchor Anchor
+}
+
+// accepted returns whether the outcome was acceptance, by voting or by
+// inclusion in a block
+func (f finalization) accepted() bool {
+	return f.status == StatusFinalized || f.status == StatusIncluded
+}
+
+// SetChainTipSource sets where the chain tip is read from when anchoring
+// finalizations. Without one finalizations are recorded with a zero Anchor.
+func (p *Processor) SetChainTipSource(src ChainTipSource) {
+	p.chain = src
+}
+
+// GetFinalizationAnchor returns the chain tip at the time the target with the
+// given hash was finalized, for finalizations that haven't been pruned
+func (p *Processor) GetFinalizationAnchor(h Hash) (Anchor, bool) {
+	f, ok := p.finalizations[h]
+	return f.anchor, ok
+}
+
+// recordFinalization remembers the outcome of a target along with the current
+// chain tip
+func (p *Processor) recordFinalization(h Hash, status Status) {
+	var anchor Anchor
+	if p.chain != nil {
+		anchor.Hash, anchor.Height = p.chain.ChainTip()
+	}
+	p.recordAnchoredFinalization(h, status, anchor)
+}
+
+// recordAnchoredFinalization remembers the outcome of a target along with the
+// block it is anchored to
+func (p *Processor) recordAnchoredFinalization(h Hash, status Status, anchor Anchor) {
+	p.finalizations[h] = finalization{status, anchor}
+	p.recent.remove(h)
+}
+
+// pruneFinalizations moves finalizations whose grace period has ended to the
+// bounded cache of recent finalizations, keeping those anchored within
+// Config.MaxReorgDepth blocks of the chain tip so a reorg can still invalidate
+// them
+func (p *Processor) pruneFinalizations() {
+	var tipHeight int64
+	if p.chain != nil {
+		_, tipHeight = p.chain.ChainTip()
+	}
+
+	for h, f := range p.finalizations {
+		if _, ok := p.graceful[h]; ok {
+			continue
+		}
+		if p.chain != nil && tipHeight-f.anchor.Height <= p.config.MaxReorgDepth {
+			continue
+		}
+		delete(p.finalizations, h)
+		p.recent.add(h, f.accepted())
+	}
+}
+
+// HandleReorg tells the Processor the anchoring chain was reorganized from a
+// tip at oldTipHeight onto a new branch forking at forkHeight. Reorgs no deeper
+// than Config.MaxReorgDepth are tolerated. For deeper ones, every finalization
+// anchored above forkHeight is invalidated: it is forgotten and its hash is
+// returned so the caller can re-verify the target, e.g. by adding it for
+// reconciliation again. Finalizations deeper than Config.MaxReorgDepth are
+// pruned once their grace period ends, so only those still remembered are
+// invalidated.
+func (p *Processor) HandleReorg(forkHeight, oldTipHeight int64) []Hash {
+	if oldTipHeight-forkHeight <= p.config.MaxReorgDepth {
+		return nil
+	}
+
+	invalidated := []Hash{}
+	for h, f := range p.finalizations {
+		if f.anchor.Height > forkHeight {
+			invalidated = append(invalidated, h)
+			delete(p.finalizations, h)
+			p.recent.remove(h)
+		}
+	}
+
+	sort.Slice(invalidated, func(i, j int) bool { return invalidated[i] < invalidated[j] })
+	return invalidated
+}
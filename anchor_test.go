@@ -0,0 +1,99 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+type stubChain struct {
+	hash   Hash
+	height int64
+}
+
+func (c *stubChain) ChainTip() (Hash, int64) { return c.hash, c.height }
+
+func TestFinalizationAnchoring(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{MaxReorgDepth: 2})
+		chain   = &stubChain{Hash(1000), 100}
+		a       = &Block{Hash(1), 1, true, true}
+		b       = &Block{Hash(2), 1, true, true}
+		updates = []StatusUpdate{}
+	)
+	p.SetChainTipSource(chain)
+
+	finalize := func(target Target) {
+		assertTrue(t, p.AddTargetToReconcile(target))
+		yesVote := Response{votes: []Vote{NewVote(0, target.Hash())}}
+		for i := 0; i < AvalancheFinalizationScore+7; i++ {
+			registerVotes(p, NodeID(0), yesVote, &updates)
+		}
+	}
+
+	// Each finalization is anchored to the tip at the time
+	finalize(a)
+	chain.hash, chain.height = Hash(1001), 101
+	finalize(b)
+
+	anchor, ok := p.GetFinalizationAnchor(a.Hash())
+	assertTrue(t, ok)
+	if anchor != (Anchor{Hash(1000), 100}) {
+		t.Fatal("Incorrect anchor. Got", anchor)
+	}
+	if anchor, _ = p.GetFinalizationAnchor(b.Hash()); anchor.Height != 101 {
+		t.Fatal("Incorrect anchor height. Got", anchor.Height)
+	}
+
+	// Shallow reorgs are tolerated
+	if invalidated := p.HandleReorg(100, 102); len(invalidated) != 0 {
+		t.Fatal("Expected no invalidated finalizations but got", invalidated)
+	}
+
+	// Deeper ones invalidate finalizations anchored above the fork
+	invalidated := p.HandleReorg(100, 103)
+	if len(invalidated) != 1 || invalidated[0] != b.Hash() {
+		t.Fatal("Expected only B to be invalidated but got", invalidated)
+	}
+	_, ok = p.GetFinalizationAnchor(b.Hash())
+	assertFalse(t, ok)
+	_, ok = p.GetFinalizationAnchor(a.Hash())
+	assertTrue(t, ok)
+}
+
+func TestFinalizationPruning(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{MaxReorgDepth: 2, GracePeriod: time.Minute})
+		chain   = &stubChain{Hash(1000), 100}
+		a       = &Block{Hash(1), 1, true, true}
+		updates = []StatusUpdate{}
+		now     = time.Now()
+	)
+	p.SetChainTipSource(chain)
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	assertTrue(t, p.AddTargetToReconcile(a))
+	yesVote := Response{votes: []Vote{NewVote(0, a.Hash())}}
+	for p.voteRecords[a.Hash()] != nil {
+		registerVotes(p, NodeID(0), yesVote, &updates)
+	}
+
+	// Finalizations are kept through their grace period
+	chain.height = 110
+	p.collectGarbage()
+	_, ok := p.GetFinalizationAnchor(a.Hash())
+	assertTrue(t, ok)
+
+	// And afterwards while a tolerated reorg could reach them
+	clock = stubClocker{now.Add(time.Minute)}
+	chain.height = 102
+	p.collectGarbage()
+	_, ok = p.GetFinalizationAnchor(a.Hash())
+	assertTrue(t, ok)
+
+	// But not once they are buried deeper
+	chain.height = 103
+	p.collectGarbage()
+	_, ok = p.GetFinalizationAnchor(a.Hash())
+	assertFalse(t, ok)
+}
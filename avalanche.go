@@ -9,9 +9,21 @@ const (
 	// AvalancheFinalizationScore is the confidence score we consider to be final
 	AvalancheFinalizationScore = 128
 
+	// AvalancheVoteWindow is the number of most recent votes considered when
+	// deciding whether a round is conclusive
+	AvalancheVoteWindow = 8
+
+	// AvalancheVoteQuorum is the number of votes within the window that must
+	// agree for a round to be conclusive
+	AvalancheVoteQuorum = 7
+
 	// AvalancheTimeStep is the amount of time to wait between event ticks
 	AvalancheTimeStep = 10 * time.Millisecond
 
+	// AvalanchePollDebounce is the amount of time to wait after a new target
+	// is added before triggering an early event tick
+	AvalanchePollDebounce = 1 * time.Millisecond
+
 	// AvalancheMaxElementPoll is the maximum number of invs to send in a single
 	// query
 	AvalancheMaxElementPoll = 4096
@@ -19,6 +31,81 @@ const (
 	// AvalancheRequestTimeout is the amount of time to wait for a response to a
 	// query
 	AvalancheRequestTimeout = 1 * time.Minute
+
+	// AvalancheSubscriberBufferSize is the number of StatusUpdates buffered for
+	// each subscriber
+	AvalancheSubscriberBufferSize = 1024
+
+	// AvalancheUpdateHistorySize is the number of recent StatusUpdates kept for
+	// Cursors to read
+	AvalancheUpdateHistorySize = 4096
+
+	// AvalanchePeerLatencySamples is the number of recent response times kept
+	// for each peer
+	AvalanchePeerLatencySamples = 32
+
+	// AvalancheSlowPeerMinSamples is the number of response times needed before
+	// a peer can be judged slow
+	AvalancheSlowPeerMinSamples = 8
+
+	// AvalancheSlowPeerProbeInterval is how many rounds pass between polls of
+	// demoted peers
+	AvalancheSlowPeerProbeInterval = 16
+
+	// AvalancheGracePeriod is how long finalized and invalid targets linger
+	// before being garbage collected
+	AvalancheGracePeriod = AvalancheRequestTimeout
+
+	// AvalancheAgingWeight is how much a target's polling priority rises for
+	// each poll that leaves it out
+	AvalancheAgingWeight = 1
+
+	// AvalancheQuorumAdaptationInterval is how often an adaptive quorum is
+	// reconsidered
+	AvalancheQuorumAdaptationInterval = 30 * time.Second
+
+	// AvalancheQuorumHistorySize is the number of recent adaptive quorum
+	// decisions kept
+	AvalancheQuorumHistorySize = 256
+
+	// AvalancheMaxAddrs is the maximum number of addresses in a single
+	// AddrMessage
+	AvalancheMaxAddrs = 1000
+
+	// AvalancheMaxClockSkew is how far the median of peers' clocks may be from
+	// ours before the clock is reported as skewed
+	AvalancheMaxClockSkew = 5 * time.Minute
+
+	// AvalancheMinTimeSamples is the number of peers whose clocks must be
+	// sampled before the median offset is trusted
+	AvalancheMinTimeSamples = 5
+
+	// AvalancheMaxTimeSamples is the most peers whose clocks are sampled
+	AvalancheMaxTimeSamples = 200
+
+	// AvalancheValidationConcurrency is the number of raw targets validated at
+	// once
+	AvalancheValidationConcurrency = 4
+
+	// AvalancheEclipseCheckInterval is how often the polled nodes and their
+	// votes are checked for signs of an eclipse attack
+	AvalancheEclipseCheckInterval = 1 * time.Minute
+
+	// AvalancheEclipseMinPeers is the fewest polled nodes that are checked for
+	// sharing a subnet or being replaced
+	AvalancheEclipseMinPeers = 4
+
+	// AvalancheEclipseMaxTurnover is the fraction of polled nodes that may be
+	// replaced within a check interval
+	AvalancheEclipseMaxTurnover = 0.5
+
+	// AvalancheEclipseMinVotes is the fewest votes within a check interval
+	// that are checked for unanimous dissent
+	AvalancheEclipseMinVotes = 64
+
+	// AvalancheFinalizationCacheSize is the number of pruned finalizations
+	// cached for answering polls
+	AvalancheFinalizationCacheSize = 4096
 )
 
 // NodeID is the identifier for an avalanche node
@@ -53,22 +140,79 @@ const (
 
 	// StatusFinalized means the consensus on the target is been finalized
 	StatusFinalized
+
+	// StatusWithdrawn means the target was removed before a decision was
+	// reached; see Processor.RemoveTarget
+	StatusWithdrawn
+
+	// StatusSuspended means the target would have finalized but too few nodes
+	// are being polled to trust the decision; see Config.SuspendBelowQuorum
+	StatusSuspended
+
+	// StatusIncluded means the target was resolved by being included in a
+	// block rather than by voting; see Processor.HandleBlock
+	StatusIncluded
 )
 
+// String returns a lower case name for the Status
+func (s Status) String() string {
+	switch s {
+	case StatusInvalid:
+		return "invalid"
+	case StatusRejected:
+		return "rejected"
+	case StatusAccepted:
+		return "accepted"
+	case StatusFinalized:
+		return "finalized"
+	case StatusWithdrawn:
+		return "withdrawn"
+	case StatusSuspended:
+		return "suspended"
+	case StatusIncluded:
+		return "included"
+	}
+	return "unknown"
+}
+
 // StatusUpdate represents a change in status for a particular Target
 type StatusUpdate struct {
 	Hash
 	Status
+
+	// Metadata is whatever was attached to the Target when it was added
+	Metadata Metadata
+
+	// LikelyFinal is set on the early signal sent when confidence reaches
+	// Config.LikelyFinalFraction of the finalization score. The Status is not
+	// final yet and may still change.
+	LikelyFinal bool
+
+	// Confidence is how far the Status is towards finalization, from 0 to 1
+	Confidence float64
+
+	// Reason is why the target was withdrawn, for StatusWithdrawn
+	Reason WithdrawReason
+
+	// Degraded is set while there are signs of an eclipse attack, so the
+	// Status may reflect an attacker's view rather than the network's; see
+	// Processor.IsDegraded
+	Degraded bool
 }
 
+// Metadata is a set of key/value tags attached to a Target, such as where it
+// came from, so consumers of StatusUpdates can correlate them
+type Metadata map[string]string
+
 // Inv is a poll request for a Target
 type Inv struct {
-	TargetType string
-	TargetHash Hash
+	TargetType string `json:"type"`
+	TargetHash Hash   `json:"hash"`
 }
 
-// Hash is a unique digest that represents a Target
-type Hash int
+// Hash is a unique digest that represents a Target. It is 64 bits on every
+// platform so it holds the same value everywhere; see HashSize.
+type Hash int64
 
 // Target is is something being decided by consensus; e.g. a transaction or block
 type Target interface {
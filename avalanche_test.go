@@ -1,8 +1,11 @@
 package avalanche
 
 import (
+	"bytes"
 	"testing"
 	"time"
+
+	"github.com/tyler-smith/go-avalanche/avalanchetest/golden"
 )
 
 var (
@@ -90,6 +93,289 @@ func TestVoteRecord(t *testing.T) {
 	// The next vote will finalize the decision.
 	registerVoteAndCheck(0, false, true, AvalancheFinalizationScore)
 }
+
+func TestVoteRecordParams(t *testing.T) {
+	config := DefaultConfig
+	config.VoteWindow = 4
+	config.VoteQuorum = 3
+	config.FinalizationScore = 2
+
+	vr := newVoteRecordWithParams(false, config.voteParams())
+
+	// Only 3 of the last 4 votes need to agree
+	assertFalse(t, vr.regsiterVote(0))
+	assertFalse(t, vr.regsiterVote(0))
+	assertTrue(t, vr.regsiterVote(0))
+	assertTrue(t, vr.isAccepted())
+	assertTrue(t, vr.getConfidence() == 0)
+
+	// A dissenting vote still leaves 3 of the last 4 agreeing
+	assertFalse(t, vr.regsiterVote(1))
+	assertTrue(t, vr.getConfidence() == 1)
+
+	// Votes outside the window are ignored, so these are inconclusive
+	assertFalse(t, vr.regsiterVote(negativeOne))
+	assertFalse(t, vr.regsiterVote(0))
+	assertFalse(t, vr.regsiterVote(0))
+	assertTrue(t, vr.getConfidence() == 1)
+
+	assertTrue(t, vr.regsiterVote(0))
+	assertTrue(t, vr.hasFinalized())
+}
+
+func TestTypeVoteParams(t *testing.T) {
+	config := DefaultConfig
+	config.VoteQuorum = 6
+	config.FinalizationScore = 16
+	config.Types = map[string]TypeConfig{"block": {VoteWindow: 4, FinalizationScore: 32}}
+
+	// Types without their own thresholds take the Config's
+	params := config.typeVoteParams("tx")
+	assertTrue(t, params == config.voteParams())
+	assertTrue(t, params.quorum == 6 && params.finalizationScore == 16)
+
+	params = config.typeVoteParams("block")
+	assertTrue(t, params.mask == 0x0f && params.quorum == 6 && params.finalizationScore == 32)
+
+	// Scores too high for a VoteRecord to reach are capped rather than wrapped
+	tooHigh := config
+	tooHigh.Types = map[string]TypeConfig{"block": {FinalizationScore: 1 << 16}}
+	assertTrue(t, tooHigh.typeVoteParams("block").finalizationScore == maxFinalizationScore)
+
+	// New targets take their type's thresholds
+	p := NewProcessorWithConfig(NewConnman(), config)
+	block := blockForHash(Hash(65))
+	assertTrue(t, p.AddTargetToReconcile(block))
+	assertTrue(t, p.voteRecords[block.Hash()].params.finalizationScore == 32)
+
+	// Snapshots are only restored with the same thresholds for every type
+	buf := &bytes.Buffer{}
+	assertTrue(t, p.Snapshot(buf) == nil)
+	config.Types = map[string]TypeConfig{"block": {FinalizationScore: 64}}
+	other := NewProcessorWithConfig(NewConnman(), config)
+	resolve := func(Hash) Target { return block }
+	assertTrue(t, other.Restore(bytes.NewReader(buf.Bytes()), resolve) == ErrSnapshotMismatch)
+	assertTrue(t, p.Restore(bytes.NewReader(buf.Bytes()), resolve) == nil)
+}
+
+func TestUnknownVotes(t *testing.T) {
+	assertTrue(t, VoteUnknown == negativeOne)
+	assertTrue(t, NewVote(VoteUnknown, 0).IsUnknown())
+	assertTrue(t, NewVote(uint32(_negativeOne-1), 0).IsUnknown())
+	assertFalse(t, NewVote(VoteNo, 0).IsUnknown())
+	assertFalse(t, NewVote(2, 0).IsUnknown())
+
+	// An unknown vote among no votes counts for neither side, so the quorum
+	// of 7 is only reached by the 7th no vote
+	vr := NewVoteRecord(true)
+	assertFalse(t, vr.regsiterVote(VoteNo))
+	assertFalse(t, vr.regsiterVote(VoteUnknown))
+	for i := 0; i < 5; i++ {
+		assertFalse(t, vr.regsiterVote(VoteNo))
+		assertTrue(t, vr.isAccepted())
+	}
+	assertTrue(t, vr.regsiterVote(VoteNo))
+	assertFalse(t, vr.isAccepted())
+
+	// Two unknown votes in the window leave too few no votes for a quorum
+	for i := 0; i < 8; i++ {
+		vr = NewVoteRecord(true)
+		for j := 0; j < 8; j++ {
+			vote := VoteNo
+			if j == i || j == (i+3)%8 {
+				vote = VoteUnknown
+			}
+			assertFalse(t, vr.regsiterVote(vote))
+		}
+		assertTrue(t, vr.isAccepted())
+	}
+
+	// Other positive values are no votes
+	vr = NewVoteRecord(true)
+	for i := 0; i < 6; i++ {
+		assertFalse(t, vr.regsiterVote(2))
+	}
+	assertTrue(t, vr.regsiterVote(2))
+	assertFalse(t, vr.isAccepted())
+
+	// A window with too few known votes is inconclusive either way
+	vr = NewVoteRecord(false)
+	for i := 0; i < 16; i++ {
+		vote := VoteYes
+		if i%4 == 0 {
+			vote = VoteUnknown
+		}
+		assertFalse(t, vr.regsiterVote(vote))
+		vote = VoteNo
+		if i%3 == 0 {
+			vote = VoteUnknown
+		}
+		assertFalse(t, vr.regsiterVote(vote))
+	}
+	assertFalse(t, vr.isAccepted())
+	assertTrue(t, vr.getConfidence() == 0)
+
+	// We answer polls for targets we don't know with an unknown vote
+	p := NewProcessor(NewConnman())
+	resp := p.HandlePoll(NodeID(0), NewPoll(0, []Inv{{"block", Hash(65)}}))
+	assertTrue(t, resp.GetVotes()[0].IsUnknown())
+}
+
+func TestLikelyFinal(t *testing.T) {
+	var (
+		connman = NewConnman()
+		config  = Config{FinalizationScore: 8, LikelyFinalFraction: 0.5}
+		p       = NewProcessorWithConfig(connman, config)
+		target  = &Block{Hash(1), 0, true, true}
+		updates = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(target))
+
+	for i := 0; i < 100 && len(p.voteRecords) > 0; i++ {
+		round := p.GetRound()
+		p.eventLoop()
+		vote := NewResponse(round, 0, []Vote{NewVote(VoteYes, target.Hash())})
+		assertTrue(t, registerVotes(p, NodeID(0), vote, &updates))
+	}
+
+	// A single early signal comes halfway to finalization
+	if len(updates) != 2 {
+		t.Fatal("Expected 2 updates but got", updates)
+	}
+	likely, final := updates[0], updates[1]
+	assertTrue(t, likely.LikelyFinal && likely.Status == StatusAccepted && likely.Confidence == 0.5)
+	assertTrue(t, !final.LikelyFinal && final.Status == StatusFinalized && final.Confidence == 1)
+
+	// Fractions outside (0, 1) disable the signal
+	for _, fraction := range []float64{0, 1, 2} {
+		config.LikelyFinalFraction = fraction
+		assertTrue(t, config.voteParams().likelyFinalScore == 0)
+	}
+}
+
+func TestLikelyFinalInconclusive(t *testing.T) {
+	var (
+		connman = NewConnman()
+		config  = Config{VoteQuorum: 8, FinalizationScore: 8, LikelyFinalFraction: 0.5}
+		p       = NewProcessorWithConfig(connman, config)
+		target  = &Block{Hash(1), 0, true, true}
+		updates = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(target))
+
+	vote := func(err uint32) {
+		round := p.GetRound()
+		p.eventLoop()
+		resp := NewResponse(round, 0, []Vote{NewVote(err, target.Hash())})
+		assertTrue(t, registerVotes(p, NodeID(0), resp, &updates))
+	}
+	likelyCount := func() int {
+		n := 0
+		for _, u := range updates {
+			if u.LikelyFinal {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Reach the likely final score
+	for likelyCount() == 0 {
+		vote(VoteYes)
+	}
+	assertTrue(t, p.GetConfidence(target) == 4)
+
+	// Inconclusive votes at the threshold don't signal again. With a quorum
+	// of the whole window, every vote is inconclusive until the unknown ones
+	// leave it.
+	for i := 0; i < 10; i++ {
+		vote(VoteUnknown)
+	}
+	assertTrue(t, p.GetConfidence(target) == 4 && likelyCount() == 1)
+
+	for i := 0; i < 100 && len(p.voteRecords) > 0; i++ {
+		vote(VoteYes)
+	}
+	assertTrue(t, likelyCount() == 1 && updates[len(updates)-1].Status == StatusFinalized)
+}
+
+func TestObserver(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{FinalizationScore: 8, Observer: true})
+		target  = &Block{Hash(1), 0, true, false}
+		updates = []StatusUpdate{}
+		poll    = NewPoll(1, []Inv{{"block", target.Hash()}})
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(target))
+
+	// Inbound polls are refused, and answered neutrally if handled anyway
+	assertTrue(t, p.ValidatePoll(poll) == ErrObserver)
+	assertVotes(t, p.HandlePoll(NodeID(0), poll), []Vote{NewVote(VoteUnknown, target.Hash())})
+
+	// The network's votes still decide the target
+	for i := 0; i < 100 && len(p.voteRecords) > 0; i++ {
+		round := p.GetRound()
+		p.eventLoop()
+		vote := NewResponse(round, 0, []Vote{NewVote(VoteYes, target.Hash())})
+		assertTrue(t, registerVotes(p, NodeID(0), vote, &updates))
+	}
+	if len(updates) != 2 || updates[0].Status != StatusAccepted || updates[1].Status != StatusFinalized {
+		t.Fatal("Expected the target to be accepted then finalized but got", updates)
+	}
+
+	// Even once it is finalized
+	assertVotes(t, p.HandlePoll(NodeID(0), poll), []Vote{NewVote(VoteUnknown, target.Hash())})
+}
+
+func TestGoldenVectors(t *testing.T) {
+	if golden.FinalizationScore != AvalancheFinalizationScore {
+		t.Fatal("Golden vectors expect a finalization score of", golden.FinalizationScore)
+	}
+
+	for _, vector := range golden.Vectors {
+		// Check the vote record directly
+		vr := NewVoteRecord(vector.Accepted)
+		for i, step := range vector.Steps {
+			vr.regsiterVote(step.Vote)
+			if vr.isAccepted() != step.Accepted || vr.hasFinalized() != step.Finalized ||
+				vr.getConfidence() != step.Confidence {
+				t.Fatal(vector.Name, "diverged from golden vector at step", i)
+			}
+		}
+
+		// Check the processor until it finalizes and forgets the target
+		var (
+			p       = NewProcessor(NewConnman())
+			target  = &Block{Hash(1), 1, true, vector.Accepted}
+			updates = []StatusUpdate{}
+		)
+		assertTrue(t, p.AddTargetToReconcile(target))
+		for i, step := range vector.Steps {
+			resp := Response{votes: []Vote{NewVote(step.Vote, target.Hash())}}
+			assertTrue(t, registerVotes(p, NodeID(0), resp, &updates))
+
+			if step.Finalized {
+				expected := StatusInvalid
+				if step.Accepted {
+					expected = StatusFinalized
+				}
+				if updates[len(updates)-1].Status != expected {
+					t.Fatal(vector.Name, "finalized with incorrect status at step", i)
+				}
+				break
+			}
+
+			if p.IsAccepted(target) != step.Accepted || p.GetConfidence(target) != step.Confidence {
+				t.Fatal(vector.Name, "processor diverged from golden vector at step", i)
+			}
+		}
+	}
+}
+
 func TestBlockRegister(t *testing.T) {
 	var (
 		connman = NewConnman()
@@ -126,7 +412,7 @@ func TestBlockRegister(t *testing.T) {
 	// Vote for the block a few times
 	for i := 0; i < 6; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertConfidence(t, p, pindex, 0)
 		assertUpdateCount(0)
@@ -134,14 +420,14 @@ func TestBlockRegister(t *testing.T) {
 
 	// A single neutral vote do not change anything.
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID, neutralVote, &updates))
+	assertTrue(t, registerVotes(p, nodeID, neutralVote, &updates))
 	assertTrue(t, p.IsAccepted(pindex))
 	assertConfidence(t, p, pindex, 0)
 	assertUpdateCount(0)
 
 	for i := uint16(1); i < 7; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertConfidence(t, p, pindex, i)
 		assertUpdateCount(0)
@@ -150,7 +436,7 @@ func TestBlockRegister(t *testing.T) {
 	// Two neutral votes will stall progress.
 	for i := 0; i < 2; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, neutralVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, neutralVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertConfidence(t, p, pindex, 6)
 		assertUpdateCount(0)
@@ -158,7 +444,7 @@ func TestBlockRegister(t *testing.T) {
 
 	for i := 2; i < 8; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertConfidence(t, p, pindex, 6)
 		assertUpdateCount(0)
@@ -167,7 +453,7 @@ func TestBlockRegister(t *testing.T) {
 	// We vote on it numerous times to finalize it
 	for i := uint16(7); i < AvalancheFinalizationScore; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertConfidence(t, p, pindex, i)
 		assertUpdateCount(0)
@@ -179,7 +465,7 @@ func TestBlockRegister(t *testing.T) {
 
 	// Now finalize the decision.
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+	assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 	assertUpdateCount(1)
 	if updates[0].Hash != blockHash {
 		t.Fatal("Update has incorrect hash. Got", updates[0].Hash, "but wanted:", blockHash)
@@ -199,14 +485,14 @@ func TestBlockRegister(t *testing.T) {
 
 	for i := 0; i < 6; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, noVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, noVote, &updates))
 		assertTrue(t, p.IsAccepted(pindex))
 		assertUpdateCount(0)
 	}
 
 	// Now the state will flip.
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID, noVote, &updates))
+	assertTrue(t, registerVotes(p, nodeID, noVote, &updates))
 	assertFalse(t, p.IsAccepted(pindex))
 	assertUpdateCount(1)
 	if updates[0].Hash != blockHash {
@@ -220,7 +506,7 @@ func TestBlockRegister(t *testing.T) {
 	// Now it is rejected, but we can vote for it numerous times.
 	for i := 1; i < AvalancheFinalizationScore; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID, noVote, &updates))
+		assertTrue(t, registerVotes(p, nodeID, noVote, &updates))
 		assertFalse(t, p.IsAccepted(pindex))
 		assertUpdateCount(0)
 	}
@@ -231,7 +517,7 @@ func TestBlockRegister(t *testing.T) {
 
 	// Now finalize the decision.
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID, yesVote, &updates))
+	assertTrue(t, registerVotes(p, nodeID, yesVote, &updates))
 	assertFalse(t, p.IsAccepted(pindex))
 	assertUpdateCount(1)
 	if updates[0].Hash != blockHash {
@@ -266,9 +552,9 @@ func TestMultiBlockRegister(t *testing.T) {
 		pindexB    = blockForHash(blockHashB)
 
 		round          = p.GetRound()
-		yesVoteForA    = Response{round, 0, []Vote{NewVote(0, blockHashA)}}
-		yesVoteForB    = Response{round + 1, 0, []Vote{NewVote(0, blockHashB)}}
-		yesVoteForBoth = Response{round + 1, 0, []Vote{NewVote(0, blockHashB), NewVote(0, blockHashA)}}
+		yesVoteForA    = NewResponse(round, 0, []Vote{NewVote(0, blockHashA)})
+		yesVoteForB    = NewResponse(round+1, 0, []Vote{NewVote(0, blockHashB)})
+		yesVoteForBoth = NewResponse(round+1, 0, []Vote{NewVote(0, blockHashB), NewVote(0, blockHashA)})
 	)
 	connman.AddNode(nodeID0)
 	connman.AddNode(nodeID1)
@@ -295,7 +581,7 @@ func TestMultiBlockRegister(t *testing.T) {
 	assertBlockPollCount(t, p, 1)
 	assertPollExistsForBlock(t, p, pindexA)
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID0, yesVoteForA, &updates))
+	assertTrue(t, registerVotes(p, nodeID0, yesVoteForA, &updates))
 	assertUpdateCount(0)
 
 	// Start voting on block B after one vote
@@ -315,14 +601,14 @@ func TestMultiBlockRegister(t *testing.T) {
 	// Let's vote for these blocks a few times
 	for i := 0; i < 4; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID0, yesVoteForBoth, &updates))
+		assertTrue(t, registerVotes(p, nodeID0, yesVoteForBoth, &updates))
 		assertUpdateCount(0)
 	}
 
 	// Now it is accepted, but we can vote for it numerous times.
 	for i := 0; i < AvalancheFinalizationScore; i++ {
 		p.eventLoop()
-		assertTrue(t, p.RegisterVotes(nodeID0, yesVoteForBoth, &updates))
+		assertTrue(t, registerVotes(p, nodeID0, yesVoteForBoth, &updates))
 		assertUpdateCount(0)
 	}
 
@@ -332,7 +618,7 @@ func TestMultiBlockRegister(t *testing.T) {
 
 	// Next vote will finalize block A
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID0, yesVoteForBoth, &updates))
+	assertTrue(t, registerVotes(p, nodeID0, yesVoteForBoth, &updates))
 	assertUpdateCount(1)
 	if updates[0].Hash != blockHashA {
 		t.Fatal("Update has incorrect hash. Got", updates[0].Hash, "but wanted:", blockHashA)
@@ -348,7 +634,7 @@ func TestMultiBlockRegister(t *testing.T) {
 
 	// Next vote will finalize block B
 	p.eventLoop()
-	assertTrue(t, p.RegisterVotes(nodeID0, yesVoteForB, &updates))
+	assertTrue(t, registerVotes(p, nodeID0, yesVoteForB, &updates))
 	assertUpdateCount(1)
 	if updates[0].Hash != blockHashB {
 		t.Fatal("Update has incorrect hash. Got", updates[0].Hash, "but wanted:", blockHashB)
@@ -366,20 +652,166 @@ func TestProcessorEventLoop(t *testing.T) {
 	p := NewProcessor(NewConnman())
 
 	// Start loop
-	assertTrue(t, p.start())
+	assertTrue(t, p.Start())
 
 	// Can't start it twice
-	assertFalse(t, p.start())
+	assertFalse(t, p.Start())
 
 	// Stop loop
-	assertTrue(t, p.stop())
+	assertTrue(t, p.Stop())
 
 	// Can't stop twice
-	assertFalse(t, p.stop())
+	assertFalse(t, p.Stop())
 
 	// You can restart it and stop it again
-	assertTrue(t, p.start())
-	assertTrue(t, p.stop())
+	assertTrue(t, p.Start())
+	assertTrue(t, p.Stop())
+}
+
+func TestProcessorEventLoopTrigger(t *testing.T) {
+	var (
+		connman = NewConnman()
+		config  = Config{PollInterval: time.Hour, PollDebounce: time.Millisecond}
+		p       = NewProcessorWithConfig(connman, config)
+	)
+	connman.AddNode(NodeID(0))
+
+	// Adding a target polls without waiting for the next tick
+	assertTrue(t, p.AddTargetToReconcile(blockForHash(Hash(65))))
+	assertTrue(t, p.Start())
+	time.Sleep(50 * time.Millisecond)
+	assertTrue(t, p.Stop())
+
+	if len(p.queries) != 1 {
+		t.Fatal("Expected 1 query but got", len(p.queries))
+	}
+}
+
+func TestPollWindow(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollWindow: 2})
+		pindex  = blockForHash(Hash(65))
+		updates = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+
+	// Two queries can be outstanding to the node at once
+	round := p.GetRound()
+	p.eventLoop()
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+	p.eventLoop()
+	assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
+	if p.GetRound() != round+2 {
+		t.Fatal("Each query should have its own round")
+	}
+
+	// No query is made while the window is full
+	p.eventLoop()
+	if len(p.queries) != 2 {
+		t.Fatal("Expected 2 outstanding queries but got", len(p.queries))
+	}
+
+	// Answering either query reopens the window
+	vote := NewResponse(round+1, 0, []Vote{NewVote(0, pindex.Hash())})
+	assertTrue(t, p.RegisterVotes(NodeID(0), vote, &updates))
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+
+	// Unanswered queries expire and reopen the window too
+	p.eventLoop()
+	assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
+	clock = stubClocker{time.Now().Add(2 * AvalancheRequestTimeout)}
+	defer func() { clock = realClocker{} }()
+	p.eventLoop()
+	if len(p.queries) != 1 {
+		t.Fatal("Expected expired queries to be replaced by 1 new query but got", len(p.queries))
+	}
+
+	// A zero window allows one query
+	p = NewProcessorWithConfig(connman, Config{})
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+	p.eventLoop()
+	assertTrue(t, len(p.queries) == 1 && p.getSuitableNodeToQuery() == NoNode)
+}
+
+func TestPollFairness(t *testing.T) {
+	p := NewProcessor(NewConnman())
+	for h := Hash(1); h <= 5; h++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{h, 0, true, true}))
+	}
+
+	// Polls that can't fit every target take turns through the backlog
+	expected := [][]Hash{{1, 2, 3}, {4, 5, 1}, {2, 3, 4}, {5, 1, 2}}
+	for i, hashes := range expected {
+		invs := p.appendInvsForNextPoll(NoNode, nil, 3)
+		if len(invs) != len(hashes) {
+			t.Fatal("Poll", i, "expected", len(hashes), "invs but got", len(invs))
+		}
+		for j, h := range hashes {
+			if invs[j].TargetHash != h {
+				t.Fatal("Poll", i, "expected", hashes, "but got", invs)
+			}
+		}
+	}
+
+	// Polls with room for every target include them all
+	assertTrue(t, len(p.appendInvsForNextPoll(NoNode, nil, 5)) == 5)
+
+	// A low scoring target is still polled while higher scoring targets keep
+	// arriving, sooner with a greater aging weight
+	pollsUntilLow := func(weight int64) int {
+		p := NewProcessorWithConfig(NewConnman(), Config{AgingWeight: weight})
+		assertTrue(t, p.AddTargetToReconcile(&Block{1, 0, true, true}))
+		for i := 1; i < 100; i++ {
+			high := &Block{Hash(100 + i), 10, true, true}
+			assertTrue(t, p.AddTargetToReconcile(high))
+			invs := p.appendInvsForNextPoll(NoNode, nil, 1)
+			if invs[0].TargetHash == 1 {
+				return i
+			}
+			p.forgetTarget(high.Hash())
+		}
+		t.Fatal("Low scoring target was starved with aging weight", weight)
+		return 0
+	}
+	assertTrue(t, pollsUntilLow(5) < pollsUntilLow(1))
+}
+
+func TestProcessorSubscribe(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		pindex  = blockForHash(Hash(65))
+		noVote  = Response{votes: []Vote{NewVote(1, pindex.Hash())}}
+		updates = []StatusUpdate{}
+
+		sub1 = p.Subscribe()
+		sub2 = p.Subscribe()
+	)
+
+	md := Metadata{"source": "test"}
+	assertTrue(t, p.AddTargetToReconcileWithMetadata(pindex, md))
+
+	// Changing the caller's copy doesn't affect the target's metadata
+	md["source"] = "changed"
+	assertTrue(t, p.GetMetadata(pindex)["source"] == "test")
+
+	// Flip the block to rejected
+	for i := 0; i < 7; i++ {
+		assertTrue(t, registerVotes(p, NodeID(0), noVote, &updates))
+	}
+
+	// Every subscriber gets the update, with the metadata attached
+	for _, sub := range []<-chan StatusUpdate{sub1, sub2} {
+		select {
+		case update := <-sub:
+			if update.Hash != pindex.Hash() || update.Status != StatusRejected || update.Metadata["source"] != "test" {
+				t.Fatal("Incorrect update. Got", update)
+			}
+		default:
+			t.Fatal("Expected subscriber to receive an update")
+		}
+	}
 }
 
 func assertTrue(t *testing.T, actual bool) {
@@ -394,6 +826,25 @@ func assertFalse(t *testing.T, actual bool) {
 	}
 }
 
+// registerVotes registers resp as the node's answer to a query about exactly
+// the targets it votes on, as if that query had been sent
+func registerVotes(p *Processor, id NodeID, resp Response, updates *[]StatusUpdate) bool {
+	key := queryKey{resp.GetRound(), id}
+	if _, ok := p.queries[key]; !ok {
+		invs := make([]Inv, len(resp.GetVotes()))
+		for i, v := range resp.GetVotes() {
+			invs[i] = Inv{TargetHash: v.GetHash()}
+			if t, ok := p.targets[v.GetHash()]; ok {
+				invs[i].TargetType = t.Type()
+			}
+		}
+		r := NewRequestRecord(clock.Now().Unix(), invs)
+		r.sent = clock.Now()
+		p.addQuery(key, r)
+	}
+	return p.RegisterVotes(id, resp, updates)
+}
+
 func assertBlockPollCount(t *testing.T, p *Processor, count int) {
 	invs := p.GetInvsForNextPoll()
 	if len(invs) != count {
@@ -450,11 +901,10 @@ func TestPollAndResponse(t *testing.T) {
 	// Trigger a poll on avanode
 	round := p.GetRound()
 	p.eventLoop()
-	// TODO: We should put nodes on a request timer and make this assertion pass
-	// assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
+	assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
 
 	// Response to the request
-	vote := Response{round, 0, []Vote{NewVote(0, blockHash)}}
+	vote := NewResponse(round, 0, []Vote{NewVote(0, blockHash)})
 	assertTrue(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
@@ -469,46 +919,48 @@ func TestPollAndResponse(t *testing.T) {
 	// Trigger a poll on avanode
 	round = p.GetRound()
 	p.eventLoop()
-	// TODO: We should put nodes on a request timer and make this assertion pass
-	// assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
+	assertTrue(t, p.getSuitableNodeToQuery() == NoNode)
 
 	// Sending responses that do not match the request also fails.
 	// 1. Too many results.
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{NewVote(0, blockHash), NewVote(0, blockHash)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash), NewVote(0, blockHash)})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
 	// 2. Not enough results.
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{}}
+	vote = NewResponse(round, 0, []Vote{})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
 	// 3. Do not match the poll
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{{}}}
+	vote = NewResponse(round, 0, []Vote{{}})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
 	// 4.Invalid round count. Request is not discarded
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round + 1, 0, []Vote{NewVote(0, blockHash)}}
+	vote = NewResponse(round+1, 0, []Vote{NewVote(0, blockHash)})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
-	vote = Response{round - 1, 0, []Vote{NewVote(0, blockHash)}}
+	vote = NewResponse(round-1, 0, []Vote{NewVote(0, blockHash)})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
 	// 5. Making request for invalid nodes do not work. Request is not discarded
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{NewVote(0, blockHash)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash)})
 	assertFalse(t, p.RegisterVotes(NodeID(1234), vote, &updates))
 	assertUpdateCount(0)
 
 	// Proper response gets processed and avanode is available again.
-	vote = Response{round, 0, []Vote{NewVote(0, blockHash)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash)})
 	assertTrue(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 
@@ -517,30 +969,114 @@ func TestPollAndResponse(t *testing.T) {
 	pindexB := blockForHash(blockHashB)
 	assertTrue(t, p.AddTargetToReconcile(pindexB))
 
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{NewVote(0, blockHash), NewVote(0, blockHashB)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash), NewVote(0, blockHashB)})
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 	assertTrue(t, p.getSuitableNodeToQuery() == avanode)
 
 	// But they are accepted in order
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{NewVote(0, blockHashB), NewVote(0, blockHash)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHashB), NewVote(0, blockHash)})
 	assertTrue(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 	assertTrue(t, p.getSuitableNodeToQuery() == avanode)
 
 	// When a block is marked invalid, stop polling.
 	pindexB.valid = false
+	defer func() { pindexB.valid = true }()
+	round = p.GetRound()
 	p.eventLoop()
-	vote = Response{round, 0, []Vote{NewVote(0, blockHash)}}
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash)})
 	assertTrue(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 	assertTrue(t, p.getSuitableNodeToQuery() == avanode)
 
 	// Expire requests after some time.
+	round = p.GetRound()
 	p.eventLoop()
+	vote = NewResponse(round, 0, []Vote{NewVote(0, blockHash)})
 	clock = stubClocker{time.Now().Add(1 * time.Minute)}
+	defer func() { clock = realClocker{} }()
 	assertFalse(t, p.RegisterVotes(avanode, vote, &updates))
 	assertUpdateCount(0)
 }
+
+func TestPollNode(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		pindex  = &Block{Hash(1), 1, true, true}
+		updates = []StatusUpdate{}
+	)
+
+	// There is nothing to ask about yet
+	_, ok := p.PollNode(NodeID(3))
+	assertFalse(t, ok)
+
+	// Nodes polled by hand needn't be in the Connman, and answering the Poll
+	// makes room in their window for the next
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+	poll, ok := p.PollNode(NodeID(3))
+	assertTrue(t, ok && len(poll.GetInvs()) == 1 && poll.GetInvs()[0].TargetHash == pindex.Hash())
+	_, ok = p.PollNode(NodeID(3))
+	assertFalse(t, ok)
+
+	assertTrue(t, p.RegisterVotes(NodeID(3), NewResponse(poll.GetRound(), 0, []Vote{NewVote(VoteYes, pindex.Hash())}), &updates))
+	next, ok := p.PollNode(NodeID(3))
+	assertTrue(t, ok && next.GetRound() == poll.GetRound()+1)
+}
+
+func TestResponseValidation(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessor(connman)
+		a       = &Block{Hash(1), 2, true, true}
+		b       = &Block{Hash(2), 1, true, true}
+		updates = []StatusUpdate{}
+		reports = []error{}
+	)
+	connman.AddNode(NodeID(0))
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		assertTrue(t, tags["node"] == "0")
+		reports = append(reports, err)
+	}))
+	assertTrue(t, p.AddTargetToReconcile(a))
+	assertTrue(t, p.AddTargetToReconcile(b))
+
+	code := func(resp Response) ResponseErrorCode {
+		err := p.CheckResponse(NodeID(0), resp)
+		if err == nil {
+			return 0
+		}
+		return err.(*ResponseError).Code
+	}
+
+	// Unsolicited responses are refused without a penalty
+	yesA, yesB := NewVote(VoteYes, a.Hash()), NewVote(VoteYes, b.Hash())
+	assertTrue(t, code(NewResponse(0, 0, []Vote{yesA, yesB})) == ResponseErrorUnsolicited)
+	assertFalse(t, p.RegisterVotes(NodeID(0), NewResponse(0, 0, []Vote{yesA, yesB}), &updates))
+	assertTrue(t, len(reports) == 0 && p.GetPeerReliability(NodeID(0)) == 0.5)
+
+	// The votes must match the Invs one for one and in order
+	round := p.GetRound()
+	p.eventLoop()
+	assertTrue(t, code(NewResponse(round, 0, []Vote{yesA, yesB})) == 0)
+	assertTrue(t, code(NewResponse(round, 0, []Vote{yesA, yesB, yesB})) == ResponseErrorTooManyVotes)
+	assertTrue(t, code(NewResponse(round, 0, []Vote{yesA})) == ResponseErrorTooFewVotes)
+	assertTrue(t, code(NewTruncatedResponse(round, 0, []Vote{yesA})) == 0)
+	assertTrue(t, code(NewResponse(round, 0, []Vote{yesB, yesA})) == ResponseErrorMismatch)
+
+	// A reordered response is refused, reported and counts against the node
+	assertFalse(t, p.RegisterVotes(NodeID(0), NewResponse(round, 0, []Vote{yesB, yesA}), &updates))
+	assertTrue(t, len(reports) == 1)
+	e, ok := reports[0].(*ResponseError)
+	assertTrue(t, ok && e.Code == ResponseErrorMismatch && e.Index == 0 && e.Unwrap() == ErrInvalidResponse)
+	assertTrue(t, p.PeerInfo()[0].InvalidResponses == 1)
+	assertTrue(t, p.GetPeerReliability(NodeID(0)) == 1.0/3)
+
+	// Its query is answered all the same, and none of its votes count
+	assertTrue(t, len(p.queries) == 0)
+	assertTrue(t, p.voteRecords[a.Hash()].votes == 0)
+}
@@ -0,0 +1,192 @@
+// Package avalanchetest provides test doubles and fixtures for code built on
+// top of the avalanche package.
+package avalanchetest
+
+import (
+	"sync"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// RegisteredResponse is a Response passed to Consensus.RegisterVotes along
+// with the node it came from
+type RegisteredResponse struct {
+	NodeID   avalanche.NodeID
+	Response avalanche.Response
+}
+
+// Consensus is a mock avalanche.Consensus. It records the calls made to it and
+// only produces the StatusUpdates it is told to, so tests are deterministic.
+type Consensus struct {
+	mu sync.Mutex
+
+	targets    []avalanche.Target
+	invs       []avalanche.Inv
+	responses  []RegisteredResponse
+	pending    []avalanche.StatusUpdate
+	rejectVote bool
+	stopped    bool
+
+	subscribers []chan avalanche.StatusUpdate
+}
+
+var _ avalanche.Consensus = (*Consensus)(nil)
+
+// NewConsensus creates a new *Consensus
+func NewConsensus() *Consensus {
+	return &Consensus{}
+}
+
+// AddTargetToReconcile records the target. It returns false if a target with
+// the same hash was already added, matching the *Processor.
+func (c *Consensus) AddTargetToReconcile(t avalanche.Target) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.targets {
+		if existing.Hash() == t.Hash() {
+			return false
+		}
+	}
+
+	c.targets = append(c.targets, t)
+	return true
+}
+
+// RemoveTarget forgets the target and publishes a StatusUpdate with
+// StatusWithdrawn. It returns false if no target with the hash was added,
+// matching the *Processor.
+func (c *Consensus) RemoveTarget(h avalanche.Hash, reason avalanche.WithdrawReason) bool {
+	c.mu.Lock()
+	removed := false
+	for i, t := range c.targets {
+		if t.Hash() == h {
+			c.targets = append(c.targets[:i], c.targets[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if removed {
+		c.Publish(avalanche.StatusUpdate{Hash: h, Status: avalanche.StatusWithdrawn, Reason: reason})
+	}
+	return removed
+}
+
+// GetInvsForNextPoll returns the Invs set by SetInvs, or an Inv for each added
+// target if none have been set
+func (c *Consensus) GetInvsForNextPoll() []avalanche.Inv {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.invs != nil {
+		return append([]avalanche.Inv{}, c.invs...)
+	}
+
+	invs := make([]avalanche.Inv, 0, len(c.targets))
+	for _, t := range c.targets {
+		invs = append(invs, avalanche.Inv{TargetType: t.Type(), TargetHash: t.Hash()})
+	}
+	return invs
+}
+
+// RegisterVotes records the response and appends any updates queued with
+// QueueUpdates. It returns false if SetRejectVotes(true) was called.
+func (c *Consensus) RegisterVotes(id avalanche.NodeID, resp avalanche.Response, updates *[]avalanche.StatusUpdate) bool {
+	c.mu.Lock()
+	c.responses = append(c.responses, RegisteredResponse{id, resp})
+	if c.rejectVote {
+		c.mu.Unlock()
+		return false
+	}
+
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	*updates = append(*updates, pending...)
+	for _, update := range pending {
+		c.Publish(update)
+	}
+
+	return true
+}
+
+// Subscribe returns a channel that receives every StatusUpdate returned from
+// RegisterVotes or sent with Publish
+func (c *Consensus) Subscribe() <-chan avalanche.StatusUpdate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan avalanche.StatusUpdate, avalanche.AvalancheSubscriberBufferSize)
+	c.subscribers = append(c.subscribers, ch)
+	return ch
+}
+
+// Stop records that Stop was called. It returns false if already stopped.
+func (c *Consensus) Stop() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return false
+	}
+	c.stopped = true
+	return true
+}
+
+// SetInvs overrides the Invs returned by GetInvsForNextPoll
+func (c *Consensus) SetInvs(invs []avalanche.Inv) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invs = invs
+}
+
+// SetRejectVotes sets whether RegisterVotes should reject responses
+func (c *Consensus) SetRejectVotes(reject bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejectVote = reject
+}
+
+// QueueUpdates sets updates to be returned by the next call to RegisterVotes
+func (c *Consensus) QueueUpdates(updates ...avalanche.StatusUpdate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, updates...)
+}
+
+// Publish sends the update to all subscribers without blocking
+func (c *Consensus) Publish(update avalanche.StatusUpdate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// Targets returns the targets added with AddTargetToReconcile
+func (c *Consensus) Targets() []avalanche.Target {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]avalanche.Target{}, c.targets...)
+}
+
+// Responses returns the responses passed to RegisterVotes
+func (c *Consensus) Responses() []RegisteredResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]RegisteredResponse{}, c.responses...)
+}
+
+// IsStopped returns whether Stop has been called
+func (c *Consensus) IsStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
@@ -0,0 +1,79 @@
+package avalanchetest
+
+import (
+	"reflect"
+	"testing"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+type testTarget avalanche.Hash
+
+func (t testTarget) Hash() avalanche.Hash { return avalanche.Hash(t) }
+func (testTarget) Type() string           { return "test" }
+func (testTarget) IsAccepted() bool       { return true }
+func (testTarget) Score() int64           { return 1 }
+func (testTarget) IsValid() bool          { return true }
+
+func TestConsensus(t *testing.T) {
+	c := NewConsensus()
+	updatesCh := c.Subscribe()
+
+	if !c.AddTargetToReconcile(testTarget(1)) {
+		t.Fatal("Expected target to be added")
+	}
+	if c.AddTargetToReconcile(testTarget(1)) {
+		t.Fatal("Expected duplicate target to be ignored")
+	}
+
+	invs := c.GetInvsForNextPoll()
+	if len(invs) != 1 || invs[0].TargetHash != 1 || invs[0].TargetType != "test" {
+		t.Fatal("Unexpected invs:", invs)
+	}
+
+	// Queued updates are returned and published
+	update := avalanche.StatusUpdate{Hash: 1, Status: avalanche.StatusFinalized}
+	c.QueueUpdates(update)
+
+	updates := []avalanche.StatusUpdate{}
+	resp := avalanche.NewResponse(0, 0, []avalanche.Vote{avalanche.NewVote(0, 1)})
+	if !c.RegisterVotes(2, resp, &updates) {
+		t.Fatal("Expected votes to be registered")
+	}
+	if len(updates) != 1 || !reflect.DeepEqual(updates[0], update) {
+		t.Fatal("Unexpected updates:", updates)
+	}
+	if got := <-updatesCh; !reflect.DeepEqual(got, update) {
+		t.Fatal("Unexpected published update:", got)
+	}
+
+	// Updates are only returned once
+	updates = []avalanche.StatusUpdate{}
+	c.RegisterVotes(2, resp, &updates)
+	if len(updates) != 0 {
+		t.Fatal("Expected no updates but got", len(updates))
+	}
+
+	c.SetRejectVotes(true)
+	if c.RegisterVotes(2, resp, &updates) {
+		t.Fatal("Expected votes to be rejected")
+	}
+	if len(c.Responses()) != 3 || c.Responses()[0].NodeID != 2 {
+		t.Fatal("Unexpected responses:", c.Responses())
+	}
+
+	// Withdrawn targets are forgotten and published
+	if !c.RemoveTarget(1, avalanche.WithdrawReplaced) || c.RemoveTarget(1, avalanche.WithdrawReplaced) {
+		t.Fatal("Expected target to be removed once")
+	}
+	if len(c.Targets()) != 0 {
+		t.Fatal("Expected no targets but got", c.Targets())
+	}
+	if got := <-updatesCh; got.Status != avalanche.StatusWithdrawn || got.Reason != avalanche.WithdrawReplaced {
+		t.Fatal("Unexpected published update:", got)
+	}
+
+	if !c.Stop() || c.Stop() || !c.IsStopped() {
+		t.Fatal("Expected Stop to succeed exactly once")
+	}
+}
@@ -0,0 +1,173 @@
+// Package golden provides vote sequences along with the state a vote record is
+// expected to be in after each vote. The sequences are transcribed from Bitcoin
+// ABC's avalanche unit tests so compatibility with the reference implementation
+// can be verified continuously.
+//
+// The package only depends on the standard library so it can be used from the
+// avalanche package's own tests.
+//
+// More vectors can be imported straight from ABC's test sources with
+// cmd/abcvectors, which writes a file here adding them to Vectors:
+//
+//	go run ./cmd/abcvectors -o avalanchetest/golden/abc.go $ABC/src/avalanche/test/*.cpp
+package golden
+
+const (
+	// FinalizationScore is the confidence at which the vectors expect a
+	// decision to finalize
+	FinalizationScore = 128
+
+	// VoteYes is a vote for the target
+	VoteYes uint32 = 0
+
+	// VoteNo is a vote against the target
+	VoteNo uint32 = 1
+
+	// VoteNeutral is a vote that is not considered
+	VoteNeutral = ^uint32(0)
+)
+
+// Step is a single vote and the state expected after registering it
+type Step struct {
+	Vote       uint32
+	Accepted   bool
+	Finalized  bool
+	Confidence uint16
+}
+
+// Vector is a named sequence of votes applied to a new vote record
+type Vector struct {
+	Name string
+
+	// Accepted is the initial state of the vote record
+	Accepted bool
+
+	Steps []Step
+}
+
+// Vectors are the golden vectors transcribed from ABC
+var Vectors = []Vector{
+	voteRecordVector(),
+	blockRegisterAcceptVector(),
+	blockRegisterRejectVector(),
+}
+
+// voteRecordVector is ABC's vote_record test
+func voteRecordVector() Vector {
+	b := newBuilder("vote_record", false)
+
+	// We need to register 6 positive votes before we start counting.
+	b.repeat(6, VoteYes, false, false, 0)
+
+	// Next vote will flip state, and confidence will increase as long as we
+	// vote yes.
+	b.add(VoteYes, true, false, 0)
+
+	// A single neutral vote do not change anything.
+	b.add(VoteNeutral, true, false, 1)
+	b.increasing(2, 8, VoteYes, true, false)
+
+	// Two neutral votes will stall progress.
+	b.repeat(2, VoteNeutral, true, false, 7)
+	b.repeat(6, VoteYes, true, false, 7)
+
+	// Now confidence will increase as long as we vote yes.
+	b.increasing(8, FinalizationScore, VoteYes, true, false)
+
+	// The next vote will finalize the decision.
+	b.add(VoteNo, true, true, FinalizationScore)
+
+	// Now that we have two no votes, confidence stop increasing.
+	b.repeat(5, VoteNo, true, true, FinalizationScore)
+
+	// Next vote will flip state, and confidence will increase as long as we
+	// vote no.
+	b.add(VoteNo, false, false, 0)
+
+	// A single neutral vote do not change anything.
+	b.add(VoteNeutral, false, false, 1)
+	b.increasing(2, 8, VoteNo, false, false)
+
+	// Two neutral votes will stall progress.
+	b.repeat(2, VoteNeutral, false, false, 7)
+	b.repeat(6, VoteNo, false, false, 7)
+
+	// Now confidence will increase as long as we vote no.
+	b.increasing(8, FinalizationScore, VoteNo, false, false)
+
+	// The next vote will finalize the decision.
+	b.add(VoteYes, false, true, FinalizationScore)
+
+	return b.vector
+}
+
+// blockRegisterAcceptVector is the acceptance half of ABC's block_register
+// processor test
+func blockRegisterAcceptVector() Vector {
+	b := newBuilder("block_register_accept", true)
+
+	// Vote for the block a few times
+	b.repeat(6, VoteYes, true, false, 0)
+
+	// A single neutral vote do not change anything.
+	b.add(VoteNeutral, true, false, 0)
+	b.increasing(1, 7, VoteYes, true, false)
+
+	// Two neutral votes will stall progress.
+	b.repeat(2, VoteNeutral, true, false, 6)
+	b.repeat(6, VoteYes, true, false, 6)
+
+	// We vote on it numerous times to finalize it
+	b.increasing(7, FinalizationScore, VoteYes, true, false)
+
+	// Now finalize the decision.
+	b.add(VoteYes, true, true, FinalizationScore)
+
+	return b.vector
+}
+
+// blockRegisterRejectVector is the rejection half of ABC's block_register
+// processor test
+func blockRegisterRejectVector() Vector {
+	b := newBuilder("block_register_reject", true)
+
+	b.repeat(6, VoteNo, true, false, 0)
+
+	// Now the state will flip.
+	b.add(VoteNo, false, false, 0)
+
+	// Now it is rejected, but we can vote for it numerous times.
+	b.increasing(1, FinalizationScore, VoteNo, false, false)
+
+	// Now finalize the decision.
+	b.add(VoteYes, false, true, FinalizationScore)
+
+	return b.vector
+}
+
+type builder struct {
+	vector Vector
+}
+
+func newBuilder(name string, accepted bool) *builder {
+	return &builder{Vector{Name: name, Accepted: accepted}}
+}
+
+// add appends a single step
+func (b *builder) add(vote uint32, accepted, finalized bool, confidence uint16) {
+	b.vector.Steps = append(b.vector.Steps, Step{vote, accepted, finalized, confidence})
+}
+
+// repeat appends n identical steps
+func (b *builder) repeat(n int, vote uint32, accepted, finalized bool, confidence uint16) {
+	for i := 0; i < n; i++ {
+		b.add(vote, accepted, finalized, confidence)
+	}
+}
+
+// increasing appends a step for each confidence in [from, to)
+func (b *builder) increasing(from, to uint16, vote uint32, accepted, finalized bool) {
+	for c := from; c < to; c++ {
+		b.add(vote, accepted, finalized, c)
+	}
+}
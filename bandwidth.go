@@ -0,0 +1,134 @@
+package avalanche
+
+import "time"
+
+// Polls and responses are metered at the size of their encoding in ABC's
+// avapoll and avaresponse messages
+const (
+	// invWireSize is an inv's type and hash
+	invWireSize = 4 + 32
+
+	// voteWireSize is a vote's error and hash
+	voteWireSize = 4 + 32
+
+	// pollWireOverhead is a poll's round and the largest inv count prefix
+	pollWireOverhead = 8 + 9
+
+	// responseWireOverhead is a response's round, cooldown and the largest vote
+	// count prefix
+	responseWireOverhead = 8 + 4 + 9
+)
+
+// BandwidthStats is the poll and response traffic exchanged with nodes
+type BandwidthStats struct {
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// bandwidthMeter counts traffic and holds a token bucket for capping it
+type bandwidthMeter struct {
+	BandwidthStats
+
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill at rate bytes per
+// second. The bucket holds at most a second's worth.
+func (m *bandwidthMeter) refill(rate int, now time.Time) {
+	if m.last.IsZero() {
+		m.tokens = float64(rate)
+	} else {
+		m.tokens += now.Sub(m.last).Seconds() * float64(rate)
+	}
+	m.last = now
+
+	if m.tokens > float64(rate) {
+		m.tokens = float64(rate)
+	}
+}
+
+// GetPeerBandwidth returns the traffic exchanged with a node
+func (p *Processor) GetPeerBandwidth(id NodeID) BandwidthStats {
+	if m, ok := p.bandwidth[id]; ok {
+		return m.BandwidthStats
+	}
+	return BandwidthStats{}
+}
+
+// GetBandwidth returns the traffic exchanged with all nodes
+func (p *Processor) GetBandwidth() BandwidthStats {
+	return p.totalBW.BandwidthStats
+}
+
+// pollInvLimit returns how many Invs a poll to the node may hold within the
+// bandwidth caps. Room is left for the response, as a poll commits the node to
+// sending one.
+func (p *Processor) pollInvLimit(id NodeID) int {
+	limit := AvalancheMaxElementPoll
+	now := clock.Now()
+
+	caps := []struct {
+		m    *bandwidthMeter
+		rate int
+	}{
+		{p.peerMeter(id), p.config.MaxPeerBytesPerSecond},
+		{p.totalBW, p.config.MaxBytesPerSecond},
+	}
+	for _, c := range caps {
+		if c.rate <= 0 {
+			continue
+		}
+		c.m.refill(c.rate, now)
+
+		budget := c.m.tokens - pollWireOverhead - responseWireOverhead
+		if n := int(budget) / (invWireSize + voteWireSize); n < limit {
+			limit = n
+		}
+	}
+
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// meterSent records a poll of the given number of Invs sent to a node. The
+// bytes its response will take are spent from the budget now too.
+func (p *Processor) meterSent(id NodeID, invs int) {
+	size := pollWireSize(invs)
+	spend := float64(size + responseWireSize(invs))
+
+	for _, m := range []*bandwidthMeter{p.peerMeter(id), p.totalBW} {
+		m.BytesSent += uint64(size)
+		m.tokens -= spend
+	}
+}
+
+// meterReceived records a response of the given number of votes from a node
+func (p *Processor) meterReceived(id NodeID, votes int) {
+	size := uint64(responseWireSize(votes))
+	p.peerMeter(id).BytesReceived += size
+	p.totalBW.BytesReceived += size
+}
+
+func (p *Processor) peerMeter(id NodeID) *bandwidthMeter {
+	m, ok := p.bandwidth[id]
+	if !ok {
+		m = &bandwidthMeter{}
+		p.bandwidth[id] = m
+	}
+	return m
+}
+
+// pollWireSize returns the encoded size of a poll with the given number of
+// Invs
+func pollWireSize(invs int) int {
+	return pollWireOverhead + invs*invWireSize
+}
+
+// responseWireSize returns the encoded size of a response with the given
+// number of votes
+func responseWireSize(votes int) int {
+	return responseWireOverhead + votes*voteWireSize
+}
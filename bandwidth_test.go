@@ -0,0 +1,51 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBandwidthCaps(t *testing.T) {
+	var (
+		connman = NewConnman()
+		config  = Config{PollWindow: 3, MaxPeerBytesPerSecond: pollWireSize(3) + responseWireSize(3)}
+		p       = NewProcessorWithConfig(connman, config)
+		updates = []StatusUpdate{}
+		now     = time.Now()
+	)
+	connman.AddNode(NodeID(0))
+	for i := 0; i < 5; i++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{Hash(i), 1, true, true}))
+	}
+
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	// The poll shrinks to fit the budget
+	round := p.GetRound()
+	p.eventLoop()
+	invs := p.queries[queryKey{round, NodeID(0)}].GetInvs()
+	assertTrue(t, len(invs) == 3)
+	assertTrue(t, p.GetPeerBandwidth(NodeID(0)).BytesSent == uint64(pollWireSize(3)))
+
+	// The budget is spent until it refills
+	p.eventLoop()
+	assertTrue(t, len(p.queries) == 1)
+	clock = stubClocker{now.Add(time.Second)}
+	p.eventLoop()
+	assertTrue(t, len(p.queries) == 2)
+
+	votes := make([]Vote, len(invs))
+	for i, inv := range invs {
+		votes[i] = NewVote(0, inv.TargetHash)
+	}
+	assertTrue(t, p.RegisterVotes(NodeID(0), NewResponse(round, 0, votes), &updates))
+
+	stats := p.GetPeerBandwidth(NodeID(0))
+	if stats.BytesSent != uint64(2*pollWireSize(3)) || stats.BytesReceived != uint64(responseWireSize(3)) {
+		t.Fatal("Unexpected bandwidth stats:", stats)
+	}
+	if p.GetBandwidth() != stats {
+		t.Fatal("Expected the totals to match the only node but got", p.GetBandwidth())
+	}
+}
@@ -0,0 +1,69 @@
+package avalanche
+
+import "testing"
+
+// newBenchProcessor returns a Processor reconciling n blocks with one node to
+// query, and neutral votes for each block so they never finalize
+func newBenchProcessor(n int) (*Processor, []Vote) {
+	connman := NewConnman()
+	connman.AddNode(NodeID(0))
+
+	p := NewProcessor(connman)
+	votes := make([]Vote, n)
+	for i := 0; i < n; i++ {
+		p.AddTargetToReconcile(&Block{Hash(i), int64(i), true, true})
+		votes[i] = NewVote(negativeOne, Hash(i))
+	}
+	return p, votes
+}
+
+func BenchmarkEventLoop(b *testing.B) {
+	p, _ := newBenchProcessor(1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := queryKey{p.GetRound(), NodeID(0)}
+		p.eventLoop()
+
+		// Complete the query so the node can be queried again
+		r := p.queries[key]
+		p.removeQuery(key)
+		r.release()
+	}
+}
+
+func BenchmarkPollAndResponse(b *testing.B) {
+	p, votes := newBenchProcessor(1024)
+	updates := make([]StatusUpdate, 0, len(votes))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := queryKey{p.GetRound(), NodeID(0)}
+		p.eventLoop()
+
+		// Answer the Invs in the order they were asked
+		invs := p.queries[key].GetInvs()
+		for j, inv := range invs {
+			votes[j] = NewVote(negativeOne, inv.TargetHash)
+		}
+		p.RegisterVotes(NodeID(0), NewResponse(key.round, 0, votes[:len(invs)]), &updates)
+		updates = updates[:0]
+	}
+}
+
+func BenchmarkHandlePoll(b *testing.B) {
+	p, _ := newBenchProcessor(1024)
+	invs := make([]Inv, 1024)
+	for i := range invs {
+		invs[i] = Inv{"block", Hash(i)}
+	}
+	poll := NewPoll(0, invs)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.HandlePoll(NodeID(1), poll).Release()
+	}
+}
@@ -0,0 +1,113 @@
+// Package bitcoinrpc is a minimal client for the JSON-RPC interface of BCHD,
+// bitcoind and other nodes compatible with it, for bridging avalanche
+// decisions back to the node
+package bitcoinrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Error codes returned by nodes that callers may want to treat specially
+const (
+	// ErrCodeVerifyAlreadyInChain is returned when sending a transaction that
+	// is already in the chain
+	ErrCodeVerifyAlreadyInChain = -27
+)
+
+// Config configures a Client
+type Config struct {
+	// URL is the node's JSON-RPC endpoint, such as http://127.0.0.1:8334
+	URL string
+
+	// User and Password authenticate with the node if User is not empty
+	User     string
+	Password string
+
+	// Client sends the requests. A client with a ten second timeout is used
+	// if it is nil.
+	Client *http.Client
+}
+
+// Client makes JSON-RPC calls to a node. It is safe for concurrent use.
+type Client struct {
+	// id is used atomically so it comes first to be 64-bit aligned on 32-bit
+	// platforms
+	id     int64
+	config Config
+}
+
+// NewClient creates a new *Client
+func NewClient(config Config) *Client {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{config: config}
+}
+
+// RPCError is an error returned by the node for a call
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("bitcoinrpc: rpc error %d: %s", e.Code, e.Message)
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int64         `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Call calls method with params and returns the raw result. Errors reported by
+// the node are returned as an *RPCError.
+func (c *Client) Call(method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(request{"1.0", atomic.AddInt64(&c.id, 1), method, params})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.User != "" {
+		req.SetBasicAuth(c.config.User, c.config.Password)
+	}
+
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Nodes answer RPC errors with a non-2xx status and the error in the body
+	r := response{}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("bitcoinrpc: %s returned %s", method, resp.Status)
+		}
+		return nil, err
+	}
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return r.Result, nil
+}
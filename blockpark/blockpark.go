@@ -0,0 +1,33 @@
+// Package blockpark applies avalanche's decisions on blocks to the node that
+// owns the chain: blocks finalized as invalid are parked so the node won't
+// build on them, and finalized blocks are unparked in case they were parked
+// before.
+package blockpark
+
+import avalanche "github.com/tyler-smith/go-avalanche"
+
+// Parker parks and unparks blocks on a node
+type Parker interface {
+	// Park stops the node from considering the block part of its chain
+	Park(avalanche.Target) error
+
+	// Unpark lets the node consider the block part of its chain again
+	Unpark(avalanche.Target) error
+}
+
+// Callback returns a FinalizationCallback parking blocks finalized as invalid
+// and unparking finalized ones. Other statuses, such as StatusIncluded which
+// only transactions reach, are ignored. Register it for block targets:
+//
+//	p.OnFinalized("block", blockpark.Callback(parker))
+func Callback(parker Parker) avalanche.FinalizationCallback {
+	return func(t avalanche.Target, status avalanche.Status) error {
+		switch status {
+		case avalanche.StatusInvalid:
+			return parker.Park(t)
+		case avalanche.StatusFinalized:
+			return parker.Unpark(t)
+		}
+		return nil
+	}
+}
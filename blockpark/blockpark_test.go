@@ -0,0 +1,84 @@
+package blockpark
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+type block avalanche.Hash
+
+func (b block) Hash() avalanche.Hash { return avalanche.Hash(b) }
+func (block) IsAccepted() bool       { return true }
+func (block) IsValid() bool          { return true }
+func (block) Type() string           { return "block" }
+func (block) Score() int64           { return 1 }
+
+type rpcRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+func TestRPCParker(t *testing.T) {
+	calls := []rpcRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Error("Expected basic auth but got", user, pass)
+		}
+
+		req := rpcRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, req)
+
+		if req.Params[0] == "bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Block not found"},"id":3}`))
+			return
+		}
+		w.Write([]byte(`{"result":null,"error":null,"id":1}`))
+	}))
+	defer srv.Close()
+
+	parker := NewRPCParker(RPCConfig{URL: srv.URL, User: "user", Password: "pass"})
+	cb := Callback(parker)
+
+	// Invalid blocks are parked, finalized ones unparked and others left alone
+	if err := cb(block(0xab), avalanche.StatusInvalid); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb(block(0xcd), avalanche.StatusFinalized); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb(block(0xef), avalanche.StatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatal("Expected 2 calls but got", calls)
+	}
+	expected := []struct{ method, hash string }{
+		{"invalidateblock", "00000000000000000000000000000000000000000000000000000000000000ab"},
+		{"reconsiderblock", "00000000000000000000000000000000000000000000000000000000000000cd"},
+	}
+	for i, e := range expected {
+		if calls[i].Method != e.method || len(calls[i].Params) != 1 || calls[i].Params[0] != e.hash {
+			t.Fatal("Expected", e.method, e.hash, "but got", calls[i])
+		}
+	}
+
+	// Errors from the node are returned
+	parker = NewRPCParker(RPCConfig{
+		URL:       srv.URL,
+		User:      "user",
+		Password:  "pass",
+		BlockHash: func(avalanche.Target) string { return "bad" },
+	})
+	err := parker.Park(block(1))
+	if e, ok := err.(*bitcoinrpc.RPCError); !ok || e.Code != -5 || e.Message != "Block not found" {
+		t.Fatal("Expected an RPCError but got", err)
+	}
+}
@@ -0,0 +1,62 @@
+package blockpark
+
+import (
+	"net/http"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+// RPCConfig configures an RPCParker
+type RPCConfig struct {
+	// URL is the node's JSON-RPC endpoint, such as http://127.0.0.1:8334
+	URL string
+
+	// User and Password authenticate with the node if User is not empty
+	User     string
+	Password string
+
+	// BlockHash returns the hex block hash the node knows a target by. The
+	// target's Hash.DisplayHex is used if it is nil.
+	BlockHash func(avalanche.Target) string
+
+	// Client sends the requests. A client with a ten second timeout is used
+	// if it is nil, since the callbacks run on the goroutine driving the
+	// Processor.
+	Client *http.Client
+}
+
+// RPCParker is a Parker for BCHD and other nodes with bitcoind's JSON-RPC
+// interface. It parks blocks with invalidateblock and unparks them with
+// reconsiderblock. Errors from the node are returned as a
+// *bitcoinrpc.RPCError.
+type RPCParker struct {
+	client    *bitcoinrpc.Client
+	blockHash func(avalanche.Target) string
+}
+
+// NewRPCParker creates a new *RPCParker
+func NewRPCParker(config RPCConfig) *RPCParker {
+	if config.BlockHash == nil {
+		config.BlockHash = func(t avalanche.Target) string { return t.Hash().DisplayHex() }
+	}
+	client := bitcoinrpc.NewClient(bitcoinrpc.Config{
+		URL:      config.URL,
+		User:     config.User,
+		Password: config.Password,
+		Client:   config.Client,
+	})
+	return &RPCParker{client, config.BlockHash}
+}
+
+// Park calls invalidateblock
+func (p *RPCParker) Park(t avalanche.Target) error {
+	_, err := p.client.Call("invalidateblock", p.blockHash(t))
+	return err
+}
+
+// Unpark calls reconsiderblock
+func (p *RPCParker) Unpark(t avalanche.Target) error {
+	_, err := p.client.Call("reconsiderblock", p.blockHash(t))
+	return err
+}
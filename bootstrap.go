@@ -0,0 +1,95 @@
+package avalanche
+
+import "sort"
+
+// PeerFinalized is a set of finalized targets reported by a peer, such as its
+// FinalizedInBucket results for every bucket
+type PeerFinalized struct {
+	NodeID  NodeID
+	Targets []FinalizedTarget
+}
+
+// BootstrapResult describes what ImportFinalized did with each reported target.
+// Each list is ordered by hash.
+type BootstrapResult struct {
+	// Imported are the targets recorded as finalized
+	Imported []FinalizedTarget
+
+	// Unconfirmed are targets too few peers reported to import
+	Unconfirmed []Hash
+
+	// Conflicting are targets peers reported different outcomes for
+	Conflicting []Hash
+
+	// Known are targets we were already voting on or had finalized, which are
+	// left as they are
+	Known []Hash
+}
+
+// ImportFinalized bulk-imports the finalized targets reported by several peers,
+// so a node that has just started, such as an observer, knows recent outcomes
+// without having voted on them. Reports are unsigned, so agreement between
+// peers is the only check: a target is imported once at least quorum distinct
+// peers report the same outcome and none reports another. A majority of the
+// peers is required if quorum is not positive. Each import is published as a
+// StatusUpdate.
+func (p *Processor) ImportFinalized(reports []PeerFinalized, quorum int) BootstrapResult {
+	// outcomes counts the distinct peers reporting each outcome of each target
+	outcomes := map[Hash]map[Status]map[NodeID]struct{}{}
+	peers := map[NodeID]struct{}{}
+	for _, r := range reports {
+		peers[r.NodeID] = struct{}{}
+		for _, t := range r.Targets {
+			if outcomes[t.Hash] == nil {
+				outcomes[t.Hash] = map[Status]map[NodeID]struct{}{}
+			}
+			if outcomes[t.Hash][t.Status] == nil {
+				outcomes[t.Hash][t.Status] = map[NodeID]struct{}{}
+			}
+			outcomes[t.Hash][t.Status][r.NodeID] = struct{}{}
+		}
+	}
+
+	if quorum < 1 {
+		quorum = len(peers)/2 + 1
+	}
+
+	hashes := make([]Hash, 0, len(outcomes))
+	for h := range outcomes {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	result := BootstrapResult{}
+	for _, h := range hashes {
+		_, voting := p.voteRecords[h]
+		_, cold := p.cold[h]
+		_, finalized := p.finalizations[h]
+		switch {
+		case voting || cold || finalized:
+			result.Known = append(result.Known, h)
+			continue
+		case len(outcomes[h]) > 1:
+			result.Conflicting = append(result.Conflicting, h)
+			continue
+		}
+
+		for status, reporters := range outcomes[h] {
+			if len(reporters) < quorum {
+				result.Unconfirmed = append(result.Unconfirmed, h)
+				break
+			}
+
+			p.recordFinalization(h, status)
+			p.publish(StatusUpdate{
+				Hash:       h,
+				Status:     status,
+				Confidence: 1,
+				Degraded:   p.IsDegraded(),
+			})
+			result.Imported = append(result.Imported, FinalizedTarget{h, status})
+		}
+	}
+
+	return result
+}
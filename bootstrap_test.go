@@ -0,0 +1,43 @@
+package avalanche
+
+import "testing"
+
+func TestImportFinalized(t *testing.T) {
+	p := NewProcessor(NewConnman())
+	sub := p.Subscribe()
+
+	assertTrue(t, p.AddTargetToReconcile(blockForHash(Hash(65))))
+	p.recordFinalization(Hash(66), StatusFinalized)
+
+	reports := []PeerFinalized{
+		{NodeID(1), []FinalizedTarget{{Hash(1), StatusFinalized}, {Hash(2), StatusFinalized}, {Hash(3), StatusInvalid}, {Hash(65), StatusInvalid}}},
+		{NodeID(2), []FinalizedTarget{{Hash(1), StatusFinalized}, {Hash(2), StatusInvalid}, {Hash(3), StatusInvalid}, {Hash(66), StatusFinalized}}},
+		{NodeID(3), []FinalizedTarget{{Hash(1), StatusFinalized}, {Hash(4), StatusFinalized}}},
+
+		// A peer reporting twice only counts once
+		{NodeID(3), []FinalizedTarget{{Hash(4), StatusFinalized}}},
+	}
+
+	// A majority of the three peers must agree by default
+	result := p.ImportFinalized(reports, 0)
+	expected := []FinalizedTarget{{Hash(1), StatusFinalized}, {Hash(3), StatusInvalid}}
+	if len(result.Imported) != len(expected) || result.Imported[0] != expected[0] || result.Imported[1] != expected[1] {
+		t.Fatal("Expected", expected, "to be imported but got", result.Imported)
+	}
+	assertTrue(t, len(result.Conflicting) == 1 && result.Conflicting[0] == Hash(2))
+	assertTrue(t, len(result.Unconfirmed) == 1 && result.Unconfirmed[0] == Hash(4))
+	assertTrue(t, len(result.Known) == 2 && result.Known[0] == Hash(65) && result.Known[1] == Hash(66))
+
+	// Imports are recorded and published, and known targets are left alone
+	assertTrue(t, p.finalizations[Hash(1)].status == StatusFinalized)
+	assertTrue(t, p.finalizations[Hash(3)].status == StatusInvalid)
+	assertTrue(t, p.voteRecords[Hash(65)] != nil)
+	for _, e := range expected {
+		update := <-sub
+		assertTrue(t, update.Hash == e.Hash && update.Status == e.Status && update.Confidence == 1)
+	}
+
+	// A lower quorum accepts a single peer's word
+	result = p.ImportFinalized(reports, 1)
+	assertTrue(t, len(result.Imported) == 1 && result.Imported[0].Hash == Hash(4))
+}
@@ -0,0 +1,26 @@
+package avalanche
+
+// FinalizationCallback is called when a target reaches a final status:
+// StatusFinalized or StatusInvalid by voting, or StatusIncluded when a block
+// includes a transaction still being voted on; see Processor.HandleBlock. It is
+// called by the goroutine driving the *Processor, so it should be quick. A
+// returned error is sent to the ErrorReporter.
+type FinalizationCallback func(t Target, status Status) error
+
+// OnFinalized registers a callback for targets of the given type reaching a
+// final status, such as to park invalid blocks on the node that owns the chain
+func (p *Processor) OnFinalized(targetType string, cb FinalizationCallback) {
+	p.finalizationCallbacks[targetType] = append(p.finalizationCallbacks[targetType], cb)
+}
+
+// runFinalizationCallbacks calls the callbacks registered for the target's type
+func (p *Processor) runFinalizationCallbacks(t Target, status Status) {
+	for _, cb := range p.finalizationCallbacks[t.Type()] {
+		if err := cb(t, status); err != nil {
+			reportError(p.reporter, &Error{"finalization callback", err}, map[string]string{
+				"target_type": t.Type(),
+				"status":      status.String(),
+			})
+		}
+	}
+}
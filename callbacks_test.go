@@ -0,0 +1,59 @@
+package avalanche
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFinalizationCallbacks(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{GracePeriod: time.Minute})
+		a       = &Block{Hash(1), 1, true, true}
+		b       = &Block{Hash(2), 1, true, true}
+		updates = []StatusUpdate{}
+		now     = time.Now()
+	)
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	type call struct {
+		hash   Hash
+		status Status
+	}
+	calls := []call{}
+	p.OnFinalized("block", func(t Target, status Status) error {
+		calls = append(calls, call{t.Hash(), status})
+		return errors.New("node unreachable")
+	})
+	p.OnFinalized("tx", func(Target, Status) error {
+		t.Fatal("Expected only block callbacks to be called")
+		return nil
+	})
+
+	var reported error
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		reported = err
+	}))
+
+	assertTrue(t, p.AddTargetToReconcile(a))
+	assertTrue(t, p.AddTargetToReconcile(b))
+
+	// Finalizing by vote calls back once
+	yes := NewResponse(0, 0, []Vote{NewVote(VoteYes, a.Hash())})
+	for p.voteRecords[a.Hash()] != nil {
+		registerVotes(p, NodeID(0), yes, &updates)
+	}
+	assertTrue(t, len(calls) == 1 && calls[0] == call{a.Hash(), StatusFinalized})
+
+	// Callback errors are reported
+	e, ok := reported.(*Error)
+	assertTrue(t, ok && e.Op == "finalization callback" && e.Err.Error() == "node unreachable")
+
+	// So does being removed as invalid
+	b.valid = false
+	p.collectGarbage()
+	clock = stubClocker{now.Add(time.Minute)}
+	p.collectGarbage()
+	assertTrue(t, len(calls) == 2 && calls[1] == call{b.Hash(), StatusInvalid})
+}
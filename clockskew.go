@@ -0,0 +1,63 @@
+package avalanche
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RecordPeerTime samples a peer's clock, such as from the timestamp it sends
+// while connecting. Once enough peers are sampled, ErrClockSkew is reported
+// to the ErrorReporter if the median offset exceeds Config.MaxClockSkew, since
+// a skewed clock misjudges the age of journaled queries and relayed
+// addresses. It is reported again only after the clock comes back in line.
+// Peers beyond AvalancheMaxTimeSamples are not sampled.
+func (p *Processor) RecordPeerTime(id NodeID, peerTime time.Time) {
+	if peerTime.IsZero() {
+		return
+	}
+	if _, ok := p.timeOffsets[id]; !ok && len(p.timeOffsets) >= AvalancheMaxTimeSamples {
+		return
+	}
+	p.timeOffsets[id] = peerTime.Sub(clock.Now())
+
+	offset, ok := p.GetTimeOffset()
+	if !ok {
+		return
+	}
+
+	maxSkew := p.config.MaxClockSkew
+	if maxSkew <= 0 {
+		maxSkew = AvalancheMaxClockSkew
+	}
+	skewed := offset > maxSkew || offset < -maxSkew
+	if skewed && !p.clockSkewed {
+		reportError(p.reporter, &Error{"check clock", ErrClockSkew}, map[string]string{
+			"offset":  offset.String(),
+			"samples": strconv.Itoa(len(p.timeOffsets)),
+		})
+	}
+	p.clockSkewed = skewed
+}
+
+// GetTimeOffset returns the median of how far sampled peers' clocks are ahead
+// of ours, and false until AvalancheMinTimeSamples peers have been sampled
+func (p *Processor) GetTimeOffset() (time.Duration, bool) {
+	if len(p.timeOffsets) < AvalancheMinTimeSamples {
+		return 0, false
+	}
+
+	offsets := make([]time.Duration, 0, len(p.timeOffsets))
+	for _, offset := range p.timeOffsets {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets[len(offsets)/2], true
+}
+
+// GetPeerTimeOffset returns how far a peer's clock was ahead of ours when it
+// was sampled
+func (p *Processor) GetPeerTimeOffset(id NodeID) (time.Duration, bool) {
+	offset, ok := p.timeOffsets[id]
+	return offset, ok
+}
@@ -0,0 +1,94 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	now := time.Now()
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	var (
+		p        = NewProcessorWithConfig(NewConnman(), Config{MaxClockSkew: time.Minute})
+		reported = []error{}
+	)
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		reported = append(reported, err)
+	}))
+
+	// The median isn't trusted until enough peers are sampled
+	for id := NodeID(0); id < AvalancheMinTimeSamples-1; id++ {
+		p.RecordPeerTime(id, now.Add(time.Hour))
+	}
+	_, ok := p.GetTimeOffset()
+	assertFalse(t, ok)
+	assertTrue(t, len(reported) == 0)
+
+	// A skewed clock is reported once
+	p.RecordPeerTime(NodeID(10), now.Add(-time.Hour))
+	offset, ok := p.GetTimeOffset()
+	assertTrue(t, ok && offset == time.Hour)
+	p.RecordPeerTime(NodeID(11), now.Add(time.Hour))
+	if len(reported) != 1 {
+		t.Fatal("Expected the skew to be reported once but got", reported)
+	}
+	if e, ok := reported[0].(*Error); !ok || e.Err != ErrClockSkew {
+		t.Fatal("Expected ErrClockSkew but got", reported[0])
+	}
+
+	// Once the clock is back in line a new skew is reported again
+	for id := NodeID(0); id < AvalancheMinTimeSamples-1; id++ {
+		p.RecordPeerTime(id, now.Add(time.Second))
+	}
+	offset, _ = p.GetTimeOffset()
+	assertTrue(t, offset == time.Second)
+	for id := NodeID(0); id < AvalancheMinTimeSamples-1; id++ {
+		p.RecordPeerTime(id, now.Add(-time.Hour))
+	}
+	assertTrue(t, len(reported) == 2)
+
+	peerOffset, ok := p.GetPeerTimeOffset(NodeID(11))
+	assertTrue(t, ok && peerOffset == time.Hour)
+}
+
+// loadedJournal is a QueryJournal holding queries from a previous run
+type loadedJournal []JournaledQuery
+
+func (loadedJournal) Record(JournaledQuery) error { return nil }
+
+func (loadedJournal) Remove(int64, NodeID) error { return nil }
+
+func (j loadedJournal) Load() ([]JournaledQuery, int64, error) { return j, 0, nil }
+
+func TestRecoveredQueryTiming(t *testing.T) {
+	now := time.Now()
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	// Recovered queries keep the age they had from the wall clock, and are
+	// timed with the monotonic clock from then on
+	p := NewProcessor(NewConnman())
+	sent := now.Add(-AvalancheRequestTimeout / 2).Unix()
+	err := p.RecoverQueries(loadedJournal{{0, NodeID(0), sent, []Inv{{"block", Hash(65)}}}})
+	assertTrue(t, err == nil)
+
+	p.expireQueries()
+	assertTrue(t, len(p.queries) == 1)
+
+	clock = stubClocker{now.Add(AvalancheRequestTimeout / 4)}
+	updates := []StatusUpdate{}
+	assertTrue(t, p.RegisterVotes(NodeID(0), NewResponse(0, 0, []Vote{NewVote(VoteYes, Hash(65))}), &updates))
+	latency, _ := p.GetPeerLatency(NodeID(0))
+	if latency < AvalancheRequestTimeout*3/4-time.Second || latency > AvalancheRequestTimeout*3/4+time.Second {
+		t.Fatal("Expected the latency to include the time before the restart but got", latency)
+	}
+
+	p = NewProcessor(NewConnman())
+	clock = stubClocker{now}
+	assertTrue(t, p.RecoverQueries(loadedJournal{{0, NodeID(0), sent, nil}}) == nil)
+	clock = stubClocker{now.Add(AvalancheRequestTimeout/2 + time.Second)}
+	p.expireQueries()
+	assertTrue(t, len(p.queries) == 0)
+}
@@ -0,0 +1,116 @@
+// Command abcvectors imports the vote sequences in Bitcoin ABC's avalanche
+// unit tests as golden vectors, so parity with the reference implementation
+// can be rechecked whenever upstream changes.
+//
+// Usage:
+//
+//	abcvectors [-o avalanchetest/golden/abc.go] [-score 128] src/avalanche/test/*.cpp
+//
+// Point it at ABC's processor_tests.cpp and voterecord_tests.cpp (or the
+// older avalanche_tests.cpp). The output is a Go file in package golden that
+// appends the imported vectors to golden.Vectors, so the avalanche package's
+// tests check them with no further changes.
+//
+// The C++ is not compiled, only pattern matched. Two kinds of test case are
+// understood:
+//
+//   - vote record tests: a VoteRecord declaration followed by
+//     REGISTER_VOTE_AND_CHECK(vr, vote, accepted, finalized, confidence)
+//     calls
+//   - single target processor tests: Responses with one Vote passed to
+//     registerVotes, followed by checks of isAccepted, getConfidence and the
+//     status of the resulting updates
+//
+// Both may use counted for loops and integer constants. Test cases using
+// anything else, such as responses voting on several targets, are skipped
+// with a warning rather than imported wrongly.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	out := flag.String("o", "", "Write the generated Go file here instead of stdout")
+	score := flag.Int("score", 128, "Value of AVALANCHE_FINALIZATION_SCORE")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: abcvectors [-o out.go] [-score n] tests.cpp...")
+		os.Exit(2)
+	}
+
+	p := newParser(map[string]int{"AVALANCHE_FINALIZATION_SCORE": *score})
+	for _, path := range flag.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			fatal(err)
+		}
+
+		vectors, skipped := p.parseFile(string(src))
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "%s: skipped %s\n", filepath.Base(path), s)
+		}
+		p.vectors = append(p.vectors, vectors...)
+	}
+
+	code, err := generate(p.vectors)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(code)
+		return
+	}
+	if err = ioutil.WriteFile(*out, code, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// generate returns the Go source appending vectors to golden.Vectors
+func generate(vectors []vector) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "// Code generated by abcvectors. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "package golden")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "func init() {")
+	fmt.Fprintln(buf, "Vectors = append(Vectors,")
+	for _, v := range vectors {
+		fmt.Fprintf(buf, "Vector{Name: %q, Accepted: %t, Steps: []Step{\n", "abc/"+v.name, v.accepted)
+		for _, s := range v.steps {
+			fmt.Fprintf(buf, "{%s, %t, %t, %d},\n", voteName(s.vote), s.accepted, s.finalized, s.confidence)
+		}
+		fmt.Fprintln(buf, "}},")
+	}
+	fmt.Fprintln(buf, ")")
+	fmt.Fprintln(buf, "}")
+
+	return format.Source(buf.Bytes())
+}
+
+// voteName returns the golden constant for a vote, or the number if there
+// isn't one
+func voteName(vote int) string {
+	switch vote {
+	case 0:
+		return "VoteYes"
+	case 1:
+		return "VoteNo"
+	case -1:
+		return "VoteNeutral"
+	}
+	return fmt.Sprintf("%d", uint32(int32(vote)))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/tyler-smith/go-avalanche/avalanchetest/golden"
+)
+
+func TestImport(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/avalanche_tests.cpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newParser(map[string]int{"AVALANCHE_FINALIZATION_SCORE": golden.FinalizationScore})
+	vectors, skipped := p.parseFile(string(src))
+
+	// Responses voting on several targets aren't understood
+	if len(skipped) != 1 || skipped[0] != "multi_block_register: response resp votes on several targets" {
+		t.Fatal("Unexpected skipped test cases:", skipped)
+	}
+
+	// The rest match the hand transcribed vectors
+	if len(vectors) != len(golden.Vectors) {
+		t.Fatal("Expected", len(golden.Vectors), "vectors but got", len(vectors))
+	}
+	for i, v := range vectors {
+		expected := golden.Vectors[i]
+		steps := make([]golden.Step, len(v.steps))
+		for j, s := range v.steps {
+			steps[j] = golden.Step{Vote: uint32(int32(s.vote)), Accepted: s.accepted, Finalized: s.finalized, Confidence: uint16(s.confidence)}
+		}
+		if v.accepted != expected.Accepted || !reflect.DeepEqual(steps, expected.Steps) {
+			t.Fatal("Imported", v.name, "does not match", expected.Name)
+		}
+	}
+
+	code, err := generate(vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) == 0 {
+		t.Fatal("Expected generated code")
+	}
+}
+
+func TestEval(t *testing.T) {
+	p := newParser(map[string]int{"SCORE": 128})
+	env := map[string]int{"i": 3}
+	for expr, expected := range map[string]int{
+		"-1": -1, "0x10": 16, "true": 1, "SCORE": 128, "i + 1": 4, "SCORE - i": 125, "(i - 1)": 2, "7u": 7,
+	} {
+		if v, err := p.eval(expr, env); err != nil || v != expected {
+			t.Fatal("Evaluating", expr, "expected", expected, "but got", v, err)
+		}
+	}
+	if _, err := p.eval("i * 2", env); err == nil {
+		t.Fatal("Expected an error for an unsupported expression")
+	}
+}
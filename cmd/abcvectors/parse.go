@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// step is a vote and the state expected after it
+type step struct {
+	vote       int
+	accepted   bool
+	finalized  bool
+	confidence int
+}
+
+// vector is a named vote sequence
+type vector struct {
+	name     string
+	accepted bool
+	steps    []step
+}
+
+// parser extracts vectors from C++ test sources
+type parser struct {
+	consts  map[string]int
+	vectors []vector
+}
+
+func newParser(consts map[string]int) *parser {
+	return &parser{consts: consts}
+}
+
+var testCaseRE = regexp.MustCompile(`BOOST_(?:AUTO|FIXTURE)_TEST_CASE\s*\(\s*(\w+)[^)]*\)\s*\{`)
+
+// parseFile returns the vectors of every test case it understands, and a
+// description of each test case skipped
+func (p *parser) parseFile(src string) ([]vector, []string) {
+	src = stripComments(src)
+
+	var (
+		vectors []vector
+		skipped []string
+	)
+	for _, m := range testCaseRE.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		end := matching(src, m[1]-1)
+		if end < 0 {
+			skipped = append(skipped, name+": unbalanced braces")
+			continue
+		}
+
+		stmts, err := parseStatements(src[m[1] : end-1])
+		if err == nil {
+			var found []vector
+			found, err = p.run(name, stmts)
+			vectors = append(vectors, found...)
+		}
+		if err != nil {
+			skipped = append(skipped, name+": "+err.Error())
+		}
+	}
+	return vectors, skipped
+}
+
+// stripComments removes // and /* */ comments, leaving string literals alone
+func stripComments(src string) string {
+	out := &strings.Builder{}
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '"' || src[i] == '\'':
+			j := skipLiteral(src, i)
+			out.WriteString(src[i:j])
+			i = j - 1
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return out.String()
+			}
+			i += end + 3
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(src[i])
+		}
+	}
+	return out.String()
+}
+
+// skipLiteral returns the index after the string or char literal at i
+func skipLiteral(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// matching returns the index after the bracket closing the one at i, or -1
+func matching(src string, i int) int {
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '"', '\'':
+			j = skipLiteral(src, j) - 1
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
+
+// stmt is a simple statement, a counted for loop, or a block
+type stmt struct {
+	text string
+	loop *loop
+	body []stmt
+}
+
+// loop is for (int v = from; v < to; v++)
+type loop struct {
+	v        string
+	from, to string
+}
+
+var (
+	loopRE    = regexp.MustCompile(`^\s*(?:int|size_t|uint\w*|auto)\s+(\w+)\s*=\s*(.+?);\s*(\w+)\s*<\s*(.+?);\s*(?:\+\+(\w+)|(\w+)\+\+)\s*$`)
+	controlRE = regexp.MustCompile(`^(for|if|while|switch)\b`)
+)
+
+// parseStatements splits a block's contents into statements
+func parseStatements(src string) ([]stmt, error) {
+	var stmts []stmt
+	for {
+		src = strings.TrimSpace(src)
+		if src == "" {
+			return stmts, nil
+		}
+
+		switch m := controlRE.FindStringSubmatch(src); {
+		case src[0] == '{':
+			end := matching(src, 0)
+			if end < 0 {
+				return nil, fmt.Errorf("unbalanced braces")
+			}
+			body, err := parseStatements(src[1 : end-1])
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt{body: body})
+			src = src[end:]
+
+		case m != nil:
+			open := strings.IndexByte(src, '(')
+			end := matching(src, open)
+			if open < 0 || end < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+			header := src[open+1 : end-1]
+
+			body, rest, err := parseBody(src[end:])
+			if err != nil {
+				return nil, err
+			}
+			src = rest
+
+			if m[1] != "for" {
+				stmts = append(stmts, stmt{text: m[1] + " (" + header + ") " + flatten(body)})
+				continue
+			}
+
+			l := loopRE.FindStringSubmatch(header)
+			if l == nil || l[1] != l[3] || (l[5] != l[1] && l[6] != l[1]) {
+				stmts = append(stmts, stmt{text: "for (" + header + ") " + flatten(body)})
+				continue
+			}
+			stmts = append(stmts, stmt{loop: &loop{l[1], l[2], l[4]}, body: body})
+
+		default:
+			end := statementEnd(src)
+			stmts = append(stmts, stmt{text: strings.Join(strings.Fields(src[:end]), " ")})
+			src = src[end:]
+			if src != "" {
+				src = src[1:]
+			}
+		}
+	}
+}
+
+// parseBody parses the block or single statement following a control
+// statement's header
+func parseBody(src string) ([]stmt, string, error) {
+	src = strings.TrimSpace(src)
+	if strings.HasPrefix(src, "{") {
+		end := matching(src, 0)
+		if end < 0 {
+			return nil, "", fmt.Errorf("unbalanced braces")
+		}
+		body, err := parseStatements(src[1 : end-1])
+		return body, src[end:], err
+	}
+
+	end := statementEnd(src)
+	if end < len(src) {
+		end++
+	}
+	body, err := parseStatements(src[:end])
+	return body, src[end:], err
+}
+
+// statementEnd returns the index of the semicolon ending the first statement
+func statementEnd(src string) int {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'':
+			i = skipLiteral(src, i) - 1
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		case ';':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+// flatten joins the text of statements so unsupported constructs can still be
+// recognised as mentioning votes
+func flatten(stmts []stmt) string {
+	parts := make([]string, 0, len(stmts))
+	for _, s := range stmts {
+		parts = append(parts, s.text+flatten(s.body))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// splitArgs splits a call's arguments at top level commas
+func splitArgs(src string) []string {
+	var (
+		args  []string
+		depth int
+		start int
+	)
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'':
+			i = skipLiteral(src, i) - 1
+		case '(', '{', '[', '<':
+			depth++
+		case ')', '}', ']', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(src[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(src[start:]))
+}
+
+// eval evaluates integer literals, booleans, names and sums of them
+func (p *parser) eval(expr string, env map[string]int) (int, error) {
+	expr = strings.TrimSpace(expr)
+	for strings.HasPrefix(expr, "(") && matching(expr, 0) == len(expr) {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+
+	// Split at the last top level + or - that isn't a sign
+	depth := 0
+	for i := len(expr) - 1; i > 0; i-- {
+		switch expr[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+		case '+', '-':
+			if depth != 0 || strings.ContainsAny(expr[i-1:i], "+-*/(") {
+				continue
+			}
+			a, err := p.eval(expr[:i], env)
+			if err != nil {
+				return 0, err
+			}
+			b, err := p.eval(expr[i+1:], env)
+			if err != nil {
+				return 0, err
+			}
+			if expr[i] == '-' {
+				return a - b, nil
+			}
+			return a + b, nil
+		}
+	}
+
+	switch expr {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	if strings.HasPrefix(expr, "-") {
+		v, err := p.eval(expr[1:], env)
+		return -v, err
+	}
+	if v, ok := env[expr]; ok {
+		return v, nil
+	}
+	if v, ok := p.consts[expr]; ok {
+		return v, nil
+	}
+	if v, err := strconv.ParseInt(strings.TrimRight(expr, "uUlL"), 0, 64); err == nil {
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("can't evaluate %q", expr)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	voteRecordRE    = regexp.MustCompile(`^VoteRecord\s+(\w+)\s*[({]\s*(true|false)\s*[)}]$`)
+	registerCheckRE = regexp.MustCompile(`^REGISTER_VOTE_AND_CHECK\s*\((.*)\)$`)
+	responseRE      = regexp.MustCompile(`^(?:(?:\w+::)*(?:Avalanche)?Response\s+(\w+)\s*=?\s*|(\w+)\s*=\s*(?:(?:\w+::)*(?:Avalanche)?Response\s*)?)[({](.*)[)}]$`)
+	voteRE          = regexp.MustCompile(`Vote\s*[({]\s*([^,]+),`)
+	registerVotesRE = regexp.MustCompile(`registerVotes\s*\((.*)\)`)
+	addTargetRE     = regexp.MustCompile(`\badd\w*ToReconcile\s*\(`)
+	isAcceptedRE    = regexp.MustCompile(`^BOOST_CHECK\s*\(\s*(!?)\s*[\w.>-]*isAccepted\s*\(.*\)\s*\)$`)
+	isAcceptedEqRE  = regexp.MustCompile(`^BOOST_CHECK_EQUAL\s*\(\s*[\w.>-]*isAccepted\s*\(.*\)\s*,\s*(true|false)\s*\)$`)
+	confidenceRE    = regexp.MustCompile(`^BOOST_CHECK_EQUAL\s*\(\s*[\w.>-]*getConfidence\s*\(.*\)\s*,\s*(.+)\)$`)
+	statusRE        = regexp.MustCompile(`getStatus\s*\(\s*\)\s*(?:,|==)\s*[\w:]*::(\w+)\s*\)`)
+	identRE         = regexp.MustCompile(`\w+`)
+)
+
+// caseRunner interprets the statements of a single test case
+type caseRunner struct {
+	p    *parser
+	name string
+
+	// records are the vectors of VoteRecords declared in the case
+	records map[string]*vector
+	order   []string
+
+	// responses are the votes of single vote Responses by variable name.
+	// Responses voting on several targets map to nil.
+	responses map[string]*int
+
+	// targets are the vectors of targets added to the processor in turn, and
+	// pending is the step for the last registered vote until it is checked
+	targets        []*vector
+	initialChecked bool
+	finalized      bool
+	pending        *pendingStep
+}
+
+// pendingStep is a processor step whose outcome is still being checked
+type pendingStep struct {
+	vote       int
+	accepted   *bool
+	confidence *int
+	finalized  bool
+}
+
+// run interprets a test case, returning its vectors
+func (p *parser) run(name string, stmts []stmt) ([]vector, error) {
+	r := &caseRunner{
+		p:         p,
+		name:      name,
+		records:   map[string]*vector{},
+		responses: map[string]*int{},
+	}
+	if err := r.exec(stmts, map[string]int{}); err != nil {
+		return nil, err
+	}
+	if err := r.flush(); err != nil {
+		return nil, err
+	}
+
+	var vectors []vector
+	for _, v := range r.order {
+		if len(r.records[v].steps) > 0 {
+			vectors = append(vectors, *r.records[v])
+		}
+	}
+	for _, v := range r.targets {
+		if len(v.steps) > 0 {
+			vectors = append(vectors, *v)
+		}
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no votes found")
+	}
+	return vectors, nil
+}
+
+// exec runs statements with loop variables bound by env
+func (r *caseRunner) exec(stmts []stmt, env map[string]int) error {
+	for _, s := range stmts {
+		var err error
+		switch {
+		case s.loop != nil:
+			err = r.execLoop(s, env)
+		case s.text == "":
+			err = r.exec(s.body, env)
+		default:
+			err = r.execStatement(s.text, env)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *caseRunner) execLoop(s stmt, env map[string]int) error {
+	from, err := r.p.eval(s.loop.from, env)
+	if err != nil {
+		return err
+	}
+	to, err := r.p.eval(s.loop.to, env)
+	if err != nil {
+		return err
+	}
+
+	inner := make(map[string]int, len(env)+1)
+	for k, v := range env {
+		inner[k] = v
+	}
+	for i := from; i < to; i++ {
+		inner[s.loop.v] = i
+		if err = r.exec(s.body, inner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execStatement handles a single statement. Statements that have nothing to
+// do with voting are ignored.
+func (r *caseRunner) execStatement(text string, env map[string]int) error {
+	if m := voteRecordRE.FindStringSubmatch(text); m != nil {
+		name := r.name
+		if len(r.order) > 0 {
+			name += "_" + m[1]
+		}
+		r.records[m[1]] = &vector{name: name, accepted: m[2] == "true"}
+		r.order = append(r.order, m[1])
+		return nil
+	}
+
+	if m := registerCheckRE.FindStringSubmatch(text); m != nil {
+		return r.registerAndCheck(splitArgs(m[1]), env)
+	}
+
+	if m := responseRE.FindStringSubmatch(text); m != nil {
+		name := m[1] + m[2]
+		votes := voteRE.FindAllStringSubmatch(m[3], -1)
+		switch {
+		case len(votes) == 0:
+			// Not a Response, such as updates being cleared
+			return nil
+		case len(votes) > 1:
+			r.responses[name] = nil
+			return nil
+		}
+
+		vote, err := r.p.eval(votes[0][1], env)
+		if err != nil {
+			return err
+		}
+		r.responses[name] = &vote
+		return nil
+	}
+
+	if addTargetRE.MatchString(text) {
+		return r.addTarget()
+	}
+
+	if m := registerVotesRE.FindStringSubmatch(text); m != nil {
+		return r.registerVotes(m[1])
+	}
+
+	if strings.Contains(text, "registerVote") || strings.Contains(text, "REGISTER_VOTE") {
+		return fmt.Errorf("unsupported statement %q", text)
+	}
+
+	return r.check(text, env)
+}
+
+// registerAndCheck appends a step to a VoteRecord's vector
+func (r *caseRunner) registerAndCheck(args []string, env map[string]int) error {
+	if len(args) != 5 {
+		return fmt.Errorf("REGISTER_VOTE_AND_CHECK with %d arguments", len(args))
+	}
+	v, ok := r.records[args[0]]
+	if !ok {
+		return fmt.Errorf("vote on undeclared VoteRecord %s", args[0])
+	}
+
+	values := make([]int, 4)
+	for i, arg := range args[1:] {
+		var err error
+		if values[i], err = r.p.eval(arg, env); err != nil {
+			return err
+		}
+	}
+	v.steps = append(v.steps, step{values[0], values[1] != 0, values[2] != 0, values[3]})
+	return nil
+}
+
+// addTarget starts the vector for a target added to the processor. Targets
+// must be added one at a time, each after the last has finalized.
+func (r *caseRunner) addTarget() error {
+	if err := r.flush(); err != nil {
+		return err
+	}
+	if len(r.targets) > 0 && !r.finalized {
+		return fmt.Errorf("several targets voted on at once")
+	}
+
+	name := r.name
+	if len(r.targets) > 0 {
+		name += "_" + strconv.Itoa(len(r.targets)+1)
+	}
+	r.targets = append(r.targets, &vector{name: name})
+	r.initialChecked, r.finalized = false, false
+	return nil
+}
+
+// registerVotes starts a step for the vote of the Response passed
+func (r *caseRunner) registerVotes(args string) error {
+	if len(r.targets) == 0 {
+		return fmt.Errorf("votes registered before a target was added")
+	}
+	if err := r.flush(); err != nil {
+		return err
+	}
+
+	for _, name := range identRE.FindAllString(args, -1) {
+		vote, ok := r.responses[name]
+		if !ok {
+			continue
+		}
+		if vote == nil {
+			return fmt.Errorf("response %s votes on several targets", name)
+		}
+		r.pending = &pendingStep{vote: *vote}
+		return nil
+	}
+	return fmt.Errorf("votes registered from an unknown response: %s", args)
+}
+
+// check records the outcome of a processor step from an assertion
+func (r *caseRunner) check(text string, env map[string]int) error {
+	if len(r.targets) == 0 {
+		return nil
+	}
+	target := r.targets[len(r.targets)-1]
+
+	accepted := (*bool)(nil)
+	if m := isAcceptedRE.FindStringSubmatch(text); m != nil {
+		b := m[1] == ""
+		accepted = &b
+	} else if m := isAcceptedEqRE.FindStringSubmatch(text); m != nil {
+		b := m[1] == "true"
+		accepted = &b
+	}
+	if accepted != nil {
+		if r.pending == nil {
+			if len(target.steps) == 0 && !r.initialChecked {
+				target.accepted, r.initialChecked = *accepted, true
+			}
+			return nil
+		}
+		r.pending.accepted = accepted
+		return nil
+	}
+
+	if r.pending == nil {
+		return nil
+	}
+
+	if m := confidenceRE.FindStringSubmatch(text); m != nil {
+		c, err := r.p.eval(m[1], env)
+		if err != nil {
+			return err
+		}
+		r.pending.confidence = &c
+		return nil
+	}
+
+	if m := statusRE.FindStringSubmatch(text); m != nil {
+		b := m[1] == "Finalized" || m[1] == "Accepted"
+		r.pending.accepted = &b
+		r.pending.finalized = m[1] == "Finalized" || m[1] == "Invalid"
+	}
+	return nil
+}
+
+// flush appends the pending processor step to the current target's vector
+func (r *caseRunner) flush() error {
+	s := r.pending
+	if s == nil {
+		return nil
+	}
+	r.pending = nil
+
+	target := r.targets[len(r.targets)-1]
+	if r.finalized {
+		return fmt.Errorf("votes registered after %s finalized", target.name)
+	}
+
+	confidence := r.p.consts["AVALANCHE_FINALIZATION_SCORE"]
+	if !s.finalized {
+		if s.confidence == nil {
+			return fmt.Errorf("confidence not checked after vote %d", len(target.steps))
+		}
+		confidence = *s.confidence
+	}
+	if s.accepted == nil {
+		return fmt.Errorf("acceptance not checked after vote %d", len(target.steps))
+	}
+
+	// A single vote can't flip the state, so without an explicit check the
+	// initial state is that after the first vote
+	if len(target.steps) == 0 && !r.initialChecked {
+		target.accepted = *s.accepted
+	}
+
+	target.steps = append(target.steps, step{s.vote, *s.accepted, s.finalized, confidence})
+	r.finalized = s.finalized
+	return nil
+}
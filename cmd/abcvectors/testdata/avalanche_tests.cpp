@@ -0,0 +1,238 @@
+// Excerpt in the style of Bitcoin ABC's src/test/avalanche_tests.cpp, trimmed
+// to the test cases the importer reads. Used to check the importer reproduces
+// the hand transcribed golden vectors.
+
+#include <avalanche.h>
+
+#include <test/test_bitcoin.h>
+
+#include <boost/test/unit_test.hpp>
+
+BOOST_FIXTURE_TEST_SUITE(avalanche_tests, TestChain100Setup)
+
+#define REGISTER_VOTE_AND_CHECK(vr, vote, state, finalized, confidence)        \
+    vr.registerVote(vote);                                                     \
+    BOOST_CHECK_EQUAL(vr.isAccepted(), state);                                 \
+    BOOST_CHECK_EQUAL(vr.hasFinalized(), finalized);                           \
+    BOOST_CHECK_EQUAL(vr.getConfidence(), confidence);
+
+BOOST_AUTO_TEST_CASE(vote_record) {
+    VoteRecord vr(false);
+
+    // Check initial state.
+    BOOST_CHECK_EQUAL(vr.isAccepted(), false);
+    BOOST_CHECK_EQUAL(vr.hasFinalized(), false);
+    BOOST_CHECK_EQUAL(vr.getConfidence(), 0);
+
+    // We need to register 6 positive votes before we start counting.
+    for (int i = 0; i < 6; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 0, false, false, 0);
+    }
+
+    // Next vote will flip state, and confidence will increase as long as we
+    // vote yes.
+    REGISTER_VOTE_AND_CHECK(vr, 0, true, false, 0);
+
+    // A single neutral vote do not change anything.
+    REGISTER_VOTE_AND_CHECK(vr, -1, true, false, 1);
+    for (int i = 2; i < 8; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 0, true, false, i);
+    }
+
+    // Two neutral votes will stall progress.
+    REGISTER_VOTE_AND_CHECK(vr, -1, true, false, 7);
+    REGISTER_VOTE_AND_CHECK(vr, -1, true, false, 7);
+    for (int i = 2; i < 8; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 0, true, false, 7);
+    }
+
+    // Now confidence will increase as long as we vote yes.
+    for (int i = 8; i < AVALANCHE_FINALIZATION_SCORE; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 0, true, false, i);
+    }
+
+    // The next vote will finalize the decision.
+    REGISTER_VOTE_AND_CHECK(vr, 1, true, true, AVALANCHE_FINALIZATION_SCORE);
+
+    // Now that we have two no votes, confidence stop increasing.
+    for (int i = 0; i < 5; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 1, true, true,
+                                AVALANCHE_FINALIZATION_SCORE);
+    }
+
+    // Next vote will flip state, and confidence will increase as long as we
+    // vote no.
+    REGISTER_VOTE_AND_CHECK(vr, 1, false, false, 0);
+
+    // A single neutral vote do not change anything.
+    REGISTER_VOTE_AND_CHECK(vr, -1, false, false, 1);
+    for (int i = 2; i < 8; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 1, false, false, i);
+    }
+
+    // Two neutral votes will stall progress.
+    REGISTER_VOTE_AND_CHECK(vr, -1, false, false, 7);
+    REGISTER_VOTE_AND_CHECK(vr, -1, false, false, 7);
+    for (int i = 2; i < 8; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 1, false, false, 7);
+    }
+
+    // Now confidence will increase as long as we vote no.
+    for (int i = 8; i < AVALANCHE_FINALIZATION_SCORE; i++) {
+        REGISTER_VOTE_AND_CHECK(vr, 1, false, false, i);
+    }
+
+    // The next vote will finalize the decision.
+    REGISTER_VOTE_AND_CHECK(vr, 0, false, true, AVALANCHE_FINALIZATION_SCORE);
+}
+
+BOOST_AUTO_TEST_CASE(block_register) {
+    AvalancheProcessor p(g_connman.get());
+    std::vector<AvalancheBlockUpdate> updates;
+
+    CBlock block = CreateAndProcessBlock({}, CScript());
+    const uint256 blockHash = block.GetHash();
+    const CBlockIndex *pindex = mapBlockIndex[blockHash];
+
+    // Create a node that supports avalanche.
+    auto avanode = ConnectNode(config, NODE_AVALANCHE, *peerLogic);
+    NodeId nodeid = avanode->GetId();
+
+    // Querying for random block returns false.
+    BOOST_CHECK(!p.isAccepted(pindex));
+
+    // Add a new block. Check it is added to the polls.
+    BOOST_CHECK(p.addBlockToReconcile(pindex));
+    auto invs = AvalancheTest::getInvsForNextPoll(p);
+    BOOST_CHECK_EQUAL(invs.size(), 1);
+    BOOST_CHECK_EQUAL(invs[0].type, MSG_BLOCK);
+    BOOST_CHECK(invs[0].hash == blockHash);
+
+    // Newly added blocks' state reflect the blockchain.
+    BOOST_CHECK(p.isAccepted(pindex));
+
+    // Let's vote for this block a few times.
+    AvalancheResponse resp{0, 0, {AvalancheVote(0, blockHash)}};
+    for (int i = 0; i < 6; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), 0);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // A single neutral vote do not change anything.
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(-1, blockHash)}};
+    AvalancheTest::runEventLoop(p);
+    BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+    BOOST_CHECK(p.isAccepted(pindex));
+    BOOST_CHECK_EQUAL(p.getConfidence(pindex), 0);
+    BOOST_CHECK_EQUAL(updates.size(), 0);
+
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(0, blockHash)}};
+    for (int i = 1; i < 7; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), i);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // Two neutral votes will stall progress.
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(-1, blockHash)}};
+    for (int i = 0; i < 2; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), 6);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(0, blockHash)}};
+    for (int i = 2; i < 8; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), 6);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // We vote for it numerous times to finalize it.
+    for (int i = 7; i < AVALANCHE_FINALIZATION_SCORE; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), i);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // Now finalize the decision.
+    AvalancheTest::runEventLoop(p);
+    BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+    BOOST_CHECK_EQUAL(updates.size(), 1);
+    BOOST_CHECK(updates[0].getBlockIndex() == pindex);
+    BOOST_CHECK_EQUAL(updates[0].getStatus(),
+                      AvalancheBlockUpdate::Status::Finalized);
+    updates = {};
+
+    // Once the decision is finalized, there is no poll for it.
+    invs = AvalancheTest::getInvsForNextPoll(p);
+    BOOST_CHECK_EQUAL(invs.size(), 0);
+
+    // Now let's undo this and finalize rejection.
+    BOOST_CHECK(p.addBlockToReconcile(pindex));
+    invs = AvalancheTest::getInvsForNextPoll(p);
+    BOOST_CHECK_EQUAL(invs.size(), 1);
+
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(1, blockHash)}};
+    for (int i = 0; i < 6; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), 0);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // Now the state will flip.
+    AvalancheTest::runEventLoop(p);
+    BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+    BOOST_CHECK(!p.isAccepted(pindex));
+    BOOST_CHECK_EQUAL(p.getConfidence(pindex), 0);
+    BOOST_CHECK_EQUAL(updates.size(), 1);
+    BOOST_CHECK_EQUAL(updates[0].getStatus(),
+                      AvalancheBlockUpdate::Status::Rejected);
+    updates = {};
+
+    // Now it is rejected, but we can vote for it numerous times.
+    for (int i = 1; i < AVALANCHE_FINALIZATION_SCORE; i++) {
+        AvalancheTest::runEventLoop(p);
+        BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+        BOOST_CHECK(!p.isAccepted(pindex));
+        BOOST_CHECK_EQUAL(p.getConfidence(pindex), i);
+        BOOST_CHECK_EQUAL(updates.size(), 0);
+    }
+
+    // Now finalize the decision.
+    resp = {AvalancheTest::getRound(p), 0, {AvalancheVote(0, blockHash)}};
+    AvalancheTest::runEventLoop(p);
+    BOOST_CHECK(p.registerVotes(nodeid, resp, updates));
+    BOOST_CHECK_EQUAL(updates.size(), 1);
+    BOOST_CHECK_EQUAL(updates[0].getStatus(),
+                      AvalancheBlockUpdate::Status::Invalid);
+    updates = {};
+}
+
+BOOST_AUTO_TEST_CASE(multi_block_register) {
+    AvalancheProcessor p(g_connman.get());
+    std::vector<AvalancheBlockUpdate> updates;
+
+    // Start voting on block A.
+    BOOST_CHECK(p.addBlockToReconcile(pindexA));
+
+    AvalancheResponse resp{
+        0, 0, {AvalancheVote(0, blockHashB), AvalancheVote(0, blockHashA)}};
+    AvalancheTest::runEventLoop(p);
+    BOOST_CHECK(p.registerVotes(avanodes[0]->GetId(), resp, updates));
+}
+
+BOOST_AUTO_TEST_SUITE_END()
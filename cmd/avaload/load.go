@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+var (
+	// errInvalid is returned for a Poll the node rejected as malformed
+	errInvalid = errors.New("poll rejected as invalid")
+
+	// errRefused is returned for a Poll the node had no room for
+	errRefused = errors.New("poll refused")
+
+	// errTimeout is returned for a Poll not answered within the timeout
+	errTimeout = errors.New("poll timed out")
+)
+
+// loadConfig describes a load test
+type loadConfig struct {
+	url         string
+	rate        float64
+	invs        int
+	targets     int
+	malformed   float64
+	concurrency int
+	duration    time.Duration
+	timeout     time.Duration
+	workers     int
+}
+
+// poller sends an encoded Poll from a node and waits for the Response
+type poller interface {
+	poll(id avalanche.NodeID, body []byte) error
+	close()
+}
+
+// httpPoller POSTs Polls to a node's poll endpoint
+type httpPoller struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPPoller(url string, timeout time.Duration) *httpPoller {
+	return &httpPoller{url, &http.Client{Timeout: timeout}}
+}
+
+func (p *httpPoller) poll(id avalanche.NodeID, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", avalanche.JSONContentType)
+	req.Header.Set(avalanche.NodeIDHeader, fmt.Sprint(id))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if e, ok := err.(interface{ Timeout() bool }); ok && e.Timeout() {
+			return errTimeout
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		return errInvalid
+	case http.StatusServiceUnavailable, http.StatusTooManyRequests:
+		return errRefused
+	default:
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	_, err = avalanche.DecodeResponse(resp.Body, true)
+	return err
+}
+
+func (*httpPoller) close() {}
+
+// localPoller answers Polls in process with a *PollServer in front of a
+// *Processor reconciling every target the Polls are drawn from
+type localPoller struct {
+	server  *avalanche.PollServer
+	timeout time.Duration
+}
+
+func newLocalPoller(c loadConfig) *localPoller {
+	p := avalanche.NewProcessor(avalanche.NewConnman())
+	for i := 0; i < c.targets; i++ {
+		p.AddTargetToReconcile(&tx{avalanche.Hash(i)})
+	}
+
+	server := avalanche.NewPollServer(avalanche.LockedPollHandler(&sync.Mutex{}, p), c.workers, c.concurrency)
+	server.SetValidator(p)
+	server.Start()
+	return &localPoller{server, c.timeout}
+}
+
+func (p *localPoller) poll(id avalanche.NodeID, body []byte) error {
+	poll, err := avalanche.DecodePoll(bytes.NewReader(body), true)
+	if err != nil {
+		return errInvalid
+	}
+
+	respCh, err := p.server.SubmitPoll(id, poll)
+	switch {
+	case err == avalanche.ErrPollRefused:
+		return errRefused
+	case err != nil:
+		return errInvalid
+	}
+
+	select {
+	case _, ok := <-respCh:
+		if !ok {
+			return errors.New("handler panicked")
+		}
+		return nil
+	case <-time.After(p.timeout):
+		return errTimeout
+	}
+}
+
+func (p *localPoller) close() { p.server.Stop() }
+
+// tx is a transaction Target
+type tx struct {
+	hash avalanche.Hash
+}
+
+func (t *tx) Hash() avalanche.Hash { return t.hash }
+
+func (*tx) IsAccepted() bool { return true }
+
+func (*tx) IsValid() bool { return true }
+
+func (*tx) Type() string { return "tx" }
+
+func (*tx) Score() int64 { return 1 }
+
+// outcome is the result of sending a single Poll
+type outcome struct {
+	malformed bool
+	latency   time.Duration
+	err       error
+}
+
+// runLoad sends Polls at the configured rate for the configured duration and
+// reports on how they were answered. Polls that would exceed the concurrency
+// limit are skipped rather than queued, so a saturated node shows up as a
+// shortfall from the target rate instead of as ever growing latency.
+func runLoad(c loadConfig) (report, error) {
+	var p poller
+	if c.url != "" {
+		p = newHTTPPoller(c.url, c.timeout)
+	} else {
+		p = newLocalPoller(c)
+	}
+	defer p.close()
+
+	rng := rand.New(rand.NewSource(1))
+	interval := time.Duration(float64(time.Second) / c.rate)
+	sem := make(chan struct{}, c.concurrency)
+	outcomes := make(chan outcome, c.concurrency)
+
+	r := report{URL: c.url, Rate: c.rate, Invs: c.invs, MalformedShare: c.malformed, Errors: map[string]int64{}}
+	latencies := []time.Duration{}
+	done := make(chan struct{})
+	go func() {
+		for o := range outcomes {
+			r.record(o)
+			if o.err == nil && !o.malformed {
+				latencies = append(latencies, o.latency)
+			}
+		}
+		close(done)
+	}()
+
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	for i := 0; ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		if next.Sub(start) >= c.duration {
+			break
+		}
+		time.Sleep(time.Until(next))
+
+		malformed := rng.Float64() < c.malformed
+		body, err := encodePoll(rng, c, malformed)
+		if err != nil {
+			return report{}, err
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			r.Skipped++
+			continue
+		}
+
+		r.Sent++
+		wg.Add(1)
+		go func(id avalanche.NodeID) {
+			defer func() { <-sem; wg.Done() }()
+
+			sent := time.Now()
+			err := p.poll(id, body)
+			outcomes <- outcome{malformed, time.Since(sent), err}
+		}(avalanche.NodeID(i % c.concurrency))
+	}
+
+	wg.Wait()
+	close(outcomes)
+	<-done
+
+	r.finish(time.Since(start), latencies)
+	return r, nil
+}
+
+// encodePoll returns a JSON Poll of random invs. Malformed Polls are either
+// cut short or repeat an inv, alternating at random, so both decoding and
+// validation are exercised.
+func encodePoll(rng *rand.Rand, c loadConfig, malformed bool) ([]byte, error) {
+	invs := make([]avalanche.Inv, 0, c.invs)
+	for _, i := range rng.Perm(c.targets)[:c.invs] {
+		invs = append(invs, avalanche.Inv{TargetType: "tx", TargetHash: avalanche.Hash(i)})
+	}
+
+	truncate := false
+	if malformed {
+		truncate = rng.Intn(2) == 0
+		if !truncate {
+			invs = append(invs, invs[0])
+		}
+	}
+
+	body, err := json.Marshal(avalanche.NewPoll(0, invs))
+	if err != nil {
+		return nil, err
+	}
+	if truncate {
+		body = body[:len(body)/2]
+	}
+	return body, nil
+}
+
+// percentile returns the nearest rank percentile of sorted latencies
+func percentile(sorted []time.Duration, pct float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(pct/100*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func sortDurations(ds []time.Duration) {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+}
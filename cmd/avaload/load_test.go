@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+func testConfig() loadConfig {
+	return loadConfig{
+		rate:        500,
+		invs:        8,
+		targets:     100,
+		malformed:   0.2,
+		concurrency: 16,
+		duration:    300 * time.Millisecond,
+		timeout:     time.Second,
+		workers:     2,
+	}
+}
+
+func TestLoadInProcess(t *testing.T) {
+	r, err := runLoad(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Succeeded == 0 || r.Malformed == 0 {
+		t.Fatal("Expected well formed and malformed polls but got", r.Succeeded, r.Malformed)
+	}
+	if r.Sent != r.Succeeded+r.Failed+r.Malformed {
+		t.Fatal("Expected every sent poll to be recorded but got", r)
+	}
+	if r.MalformedRejected != r.Malformed {
+		t.Fatal("Expected every malformed poll to be rejected but got", r.MalformedRejected, "of", r.Malformed)
+	}
+	if r.ErrorRate != 0 || r.Throughput <= 0 {
+		t.Fatal("Expected no errors and some throughput but got", r.ErrorRate, r.Throughput)
+	}
+	if r.Latency.P50Ms > r.Latency.P99Ms || r.Latency.P99Ms > r.Latency.MaxMs {
+		t.Fatal("Expected ordered percentiles but got", r.Latency)
+	}
+}
+
+func TestLoadHTTP(t *testing.T) {
+	mu := sync.Mutex{}
+	p := avalanche.NewProcessor(avalanche.NewConnman())
+	busy := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		poll, err := avalanche.DecodePoll(req.Body, true)
+		if err == nil {
+			err = p.ValidatePoll(poll)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Refuse every tenth poll as if overloaded
+		mu.Lock()
+		busy++
+		if busy%10 == 0 {
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		resp := p.HandlePoll(0, poll)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	c := testConfig()
+	c.url = server.URL
+	r, err := runLoad(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Succeeded == 0 || r.MalformedRejected != r.Malformed {
+		t.Fatal("Expected successes and every malformed poll rejected but got", r)
+	}
+	if r.Errors["refused"] == 0 || r.Errors["refused"] != r.Failed || r.ErrorRate <= 0 {
+		t.Fatal("Expected only refusals to fail but got", r.Errors)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	ds := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+	sortDurations(ds)
+
+	for _, c := range []struct {
+		pct  float64
+		want time.Duration
+	}{{50, 5}, {90, 9}, {99, 10}, {100, 10}, {0, 1}} {
+		if got := percentile(ds, c.pct); got != c.want {
+			t.Fatal("Expected p", c.pct, "to be", c.want, "but got", got)
+		}
+	}
+
+	if percentile(nil, 50) != 0 {
+		t.Fatal("Expected no latency without samples")
+	}
+}
@@ -0,0 +1,81 @@
+// Command avaload generates synthetic poll traffic against a node to size
+// deployments. It sends Polls at a steady rate, a share of them deliberately
+// malformed, and reports throughput, error rates and latency percentiles.
+//
+// Usage:
+//
+//	avaload [-url http://node/poll] [-rate 500] [-invs 16] [-malformed 0.01] [-duration 1m] [-o report.json]
+//
+// With -url each Poll is POSTed as JSON, with the polling node in the
+// X-Avalanche-Node-Id header, and a JSON Response is expected back.
+// Without it the Polls are answered in process by a *PollServer in front of a
+// *Processor, which measures the library on its own.
+//
+// A JSON report is written to stdout, or to -o. The exit status is 1 if more
+// than -max-error-rate of the well formed Polls failed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+func main() {
+	c := loadConfig{}
+	flag.StringVar(&c.url, "url", "", "Poll endpoint to load; polls are answered in process if empty")
+	flag.Float64Var(&c.rate, "rate", 500, "Polls sent per second")
+	flag.IntVar(&c.invs, "invs", 16, "Invs in each poll")
+	flag.IntVar(&c.targets, "targets", 1000, "Distinct targets the invs are drawn from")
+	flag.Float64Var(&c.malformed, "malformed", 0, "Share of polls that are malformed, from 0 to 1")
+	flag.IntVar(&c.concurrency, "concurrency", 64, "Most polls in flight at once")
+	flag.DurationVar(&c.duration, "duration", time.Minute, "How long to send polls")
+	flag.DurationVar(&c.timeout, "timeout", avalanche.AvalancheRequestTimeout, "Time to wait for each response")
+	flag.IntVar(&c.workers, "workers", 4, "Poll server workers when answering in process")
+	maxErrorRate := flag.Float64("max-error-rate", 0, "Share of well formed polls allowed to fail")
+	out := flag.String("o", "", "Write the report to this file instead of stdout")
+	flag.Parse()
+
+	if flag.NArg() != 0 || c.rate <= 0 || c.invs <= 0 || c.targets < c.invs || c.malformed < 0 || c.malformed > 1 ||
+		c.concurrency <= 0 || c.duration <= 0 || c.timeout <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: avaload [-url u] [-rate r] [-invs n] [-targets n] [-malformed f] [-concurrency n] [-duration d] [-o report.json]")
+		os.Exit(2)
+	}
+
+	r, err := runLoad(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "avaload:", err)
+		os.Exit(1)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "avaload:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeReport(w, r); err != nil {
+		fmt.Fprintln(os.Stderr, "avaload:", err)
+		os.Exit(1)
+	}
+
+	if r.ErrorRate > *maxErrorRate {
+		os.Exit(1)
+	}
+}
+
+func writeReport(w io.Writer, r report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
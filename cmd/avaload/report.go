@@ -0,0 +1,101 @@
+package main
+
+import "time"
+
+// latency summarizes the response times of well formed Polls that succeeded
+type latency struct {
+	P50Ms float64 `json:"p50_ms"`
+	P90Ms float64 `json:"p90_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// report is the machine-readable outcome of a load test
+type report struct {
+	URL             string  `json:"url,omitempty"`
+	Rate            float64 `json:"rate"`
+	Invs            int     `json:"invs"`
+	MalformedShare  float64 `json:"malformed_share"`
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// Sent is the number of Polls sent. Skipped is the number not sent
+	// because the concurrency limit was reached.
+	Sent    int64 `json:"sent"`
+	Skipped int64 `json:"skipped"`
+
+	// Succeeded and Failed count the well formed Polls by outcome
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+
+	// Malformed counts the malformed Polls sent, and MalformedRejected those
+	// the node rightly rejected
+	Malformed         int64 `json:"malformed"`
+	MalformedRejected int64 `json:"malformed_rejected"`
+
+	// Throughput is successful Polls per second
+	Throughput float64 `json:"throughput"`
+
+	// ErrorRate is the share of well formed Polls that failed
+	ErrorRate float64 `json:"error_rate"`
+
+	// Errors counts the failures of well formed Polls by cause
+	Errors map[string]int64 `json:"errors"`
+
+	Latency latency `json:"latency"`
+}
+
+// record tallies the outcome of a Poll
+func (r *report) record(o outcome) {
+	if o.malformed {
+		r.Malformed++
+		if o.err == errInvalid {
+			r.MalformedRejected++
+		}
+		return
+	}
+
+	if o.err != nil {
+		r.Failed++
+		r.Errors[errorClass(o.err)]++
+		return
+	}
+	r.Succeeded++
+}
+
+// finish works out the rates and latency percentiles once every Poll has been
+// recorded
+func (r *report) finish(elapsed time.Duration, latencies []time.Duration) {
+	r.DurationSeconds = elapsed.Seconds()
+	if r.DurationSeconds > 0 {
+		r.Throughput = float64(r.Succeeded) / r.DurationSeconds
+	}
+	if wellFormed := r.Succeeded + r.Failed; wellFormed > 0 {
+		r.ErrorRate = float64(r.Failed) / float64(wellFormed)
+	}
+
+	sortDurations(latencies)
+	r.Latency = latency{
+		P50Ms: millis(percentile(latencies, 50)),
+		P90Ms: millis(percentile(latencies, 90)),
+		P99Ms: millis(percentile(latencies, 99)),
+		MaxMs: millis(percentile(latencies, 100)),
+	}
+}
+
+// errorClass groups errors so that ones naming addresses or ports don't each
+// get their own count
+func errorClass(err error) string {
+	switch err {
+	case errInvalid:
+		return "invalid"
+	case errRefused:
+		return "refused"
+	case errTimeout:
+		return "timeout"
+	}
+	return "other"
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
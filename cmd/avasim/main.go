@@ -0,0 +1,243 @@
+// Command avasim simulates a network of avalanche nodes described by a YAML
+// scenario file and reports how quickly and safely it converges.
+//
+// Usage:
+//
+//	avasim [-csv decisions.csv] scenario.yaml
+//	avasim -sweep [-seeds 20] [-workers 8] [-csv sweep.csv] scenario.yaml
+//
+// A scenario is a flat set of keys; any that are left out take the defaults
+// shown here:
+//
+//	name: default
+//	seed: 1
+//	nodes: 50
+//	adversary_fraction: 0     # share of nodes voting against everything
+//	observer_fraction: 0      # share of nodes polling but never answering
+//	latency: constant         # constant, uniform or exponential
+//	latency_mean_ms: 50       # one-way message delay
+//	tx_count: 100
+//	tx_rate: 20               # transactions arriving per second
+//	poll_interval_ms: 10
+//	max_duration_ms: 600000
+//	vote_window: 8            # k, recent votes considered, at most 8
+//	vote_quorum: 7            # alpha, votes in the window that must agree
+//	finalization_score: 128   # beta, confidence at which a decision is final
+//
+// Nodes that are neither adversaries nor observers are honest voters.
+// Observers decide on transactions like voters but never answer polls, so a
+// poll sent to one goes unanswered.
+//
+// The CSV has a row per transaction per node, ready for plotting.
+//
+// In sweep mode the vote parameters may also be lists such as "32,64,128" or
+// inclusive ranges such as "5..8". Every valid combination is run with -seeds
+// consecutive seeds starting from the scenario's, and the safety-violation and
+// liveness-failure rates of each are reported. The CSV then has a row per
+// combination instead.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+func main() {
+	csvPath := flag.String("csv", "", "Write each node's decision on each transaction to this CSV file")
+	sweep := flag.Bool("sweep", false, "Run every combination of the swept vote parameters")
+	seeds := flag.Int("seeds", 20, "Number of seeds to run each combination with in sweep mode")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of simulations to run in parallel in sweep mode")
+	flag.Parse()
+
+	if flag.NArg() != 1 || *seeds < 1 || *workers < 1 {
+		fmt.Fprintln(os.Stderr, "usage: avasim [-sweep] [-seeds n] [-workers n] [-csv out.csv] scenario.yaml")
+		os.Exit(2)
+	}
+
+	s, err := loadScenario(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "avasim:", err)
+		os.Exit(1)
+	}
+
+	if *sweep {
+		runSweepMode(s, *seeds, *workers, *csvPath)
+		return
+	}
+
+	if len(s.sweeps) > 0 {
+		fmt.Fprintf(os.Stderr, "avasim: %s has several values; run with -sweep\n", s.sweeps[0].key)
+		os.Exit(1)
+	}
+
+	sim := newSimulation(s)
+	sim.run()
+
+	printStats(os.Stdout, s, sim.stats())
+
+	if *csvPath != "" {
+		if err = writeCSV(*csvPath, sim); err != nil {
+			fmt.Fprintln(os.Stderr, "avasim:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func runSweepMode(s scenario, seeds, workers int, csvPath string) {
+	results := runSweep(s, seeds, workers)
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "avasim: no valid combination of vote parameters")
+		os.Exit(1)
+	}
+
+	printSweep(os.Stdout, s, results)
+
+	if csvPath != "" {
+		if err := writeSweepCSV(csvPath, results); err != nil {
+			fmt.Fprintln(os.Stderr, "avasim:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func loadScenario(path string) (scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return scenario{}, err
+	}
+	defer f.Close()
+
+	return parseScenario(f)
+}
+
+// stats summarizes the honest nodes' decisions
+type stats struct {
+	adversaries int
+	observers   int
+	decisions   int
+	final       int
+
+	// safetyViolations are transactions honest nodes finalized differently
+	safetyViolations int
+
+	// livenessFailures are transactions some honest node never finalized
+	livenessFailures int
+
+	// latencies are the times from arrival to finalization in milliseconds
+	latencies []int64
+
+	durationMS int64
+}
+
+func (sim *simulation) stats() stats {
+	st := stats{durationMS: sim.now}
+	for _, n := range sim.nodes {
+		switch n.role {
+		case roleAdversary:
+			st.adversaries++
+		case roleObserver:
+			st.observers++
+		}
+	}
+
+	for _, decisions := range sim.decisions {
+		outcomes := map[avalanche.Status]bool{}
+		allFinal := true
+
+		for _, d := range decisions {
+			if d.role == roleAdversary {
+				continue
+			}
+
+			st.decisions++
+			if !d.final {
+				allFinal = false
+				continue
+			}
+
+			st.final++
+			outcomes[d.status] = true
+			st.latencies = append(st.latencies, d.finalMS-d.arrivalMS)
+		}
+
+		if len(outcomes) > 1 {
+			st.safetyViolations++
+		}
+		if !allFinal {
+			st.livenessFailures++
+		}
+	}
+
+	sort.Slice(st.latencies, func(i, j int) bool { return st.latencies[i] < st.latencies[j] })
+	return st
+}
+
+// percentile returns the pth percentile of the sorted latencies
+func (st stats) percentile(p float64) int64 {
+	if len(st.latencies) == 0 {
+		return 0
+	}
+	return st.latencies[int(p*float64(len(st.latencies)-1))]
+}
+
+func printStats(w io.Writer, s scenario, st stats) {
+	fmt.Fprintf(w, "scenario:            %s\n", s.name)
+	fmt.Fprintf(w, "nodes:               %d (%d adversarial, %d observers)\n", s.nodes, st.adversaries, st.observers)
+	fmt.Fprintf(w, "transactions:        %d\n", s.txCount)
+	fmt.Fprintf(w, "simulated time:      %dms\n", st.durationMS)
+	fmt.Fprintf(w, "honest decisions:    %d/%d final (%.1f%%)\n",
+		st.final, st.decisions, 100*float64(st.final)/float64(st.decisions))
+	fmt.Fprintf(w, "safety violations:   %d\n", st.safetyViolations)
+	fmt.Fprintf(w, "liveness failures:   %d\n", st.livenessFailures)
+	fmt.Fprintf(w, "finalization ms:     p50=%d p95=%d max=%d\n",
+		st.percentile(0.5), st.percentile(0.95), st.percentile(1))
+}
+
+func writeCSV(path string, sim *simulation) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{"tx", "node", "role", "arrival_ms", "learned_ms", "final_ms", "latency_ms", "status"}}
+	for _, decisions := range sim.decisions {
+		for _, d := range decisions {
+			latency := ""
+			if d.final {
+				latency = strconv.FormatInt(d.finalMS-d.arrivalMS, 10)
+			}
+
+			rows = append(rows, []string{
+				strconv.Itoa(d.tx),
+				strconv.Itoa(d.node),
+				d.role.String(),
+				strconv.FormatInt(d.arrivalMS, 10),
+				strconv.FormatInt(d.learnedMS, 10),
+				strconv.FormatInt(d.finalMS, 10),
+				latency,
+				statusName(d),
+			})
+		}
+	}
+
+	if err = csv.NewWriter(f).WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func statusName(d decision) string {
+	if d.learnedMS < 0 {
+		return "unknown"
+	}
+	return d.status.String()
+}
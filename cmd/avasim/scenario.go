@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// scenario describes a simulation run
+type scenario struct {
+	name string
+	seed int64
+
+	// nodes is the number of nodes, adversaryFraction the share of them that
+	// vote against every transaction and observerFraction the share that poll
+	// but never answer polls
+	nodes             int
+	adversaryFraction float64
+	observerFraction  float64
+
+	// latency is the distribution of message delays; one of constant, uniform
+	// or exponential
+	latency       string
+	latencyMeanMS float64
+
+	// txCount transactions arrive at txRate per second
+	txCount int
+	txRate  float64
+
+	// pollIntervalMS is how often each node polls a peer
+	pollIntervalMS int64
+
+	// maxDurationMS bounds the simulated time
+	maxDurationMS int64
+
+	// voteWindow (k) is how many recent votes each record considers,
+	// voteQuorum (alpha) how many of them must agree and finalizationScore
+	// (beta) the confidence at which a decision is final
+	voteWindow        int
+	voteQuorum        int
+	finalizationScore int
+
+	// sweeps are the vote parameters given as a list or range of values
+	sweeps []sweepParam
+}
+
+// sweepParam is a scenario key taking several values in sweep mode
+type sweepParam struct {
+	key    string
+	values []int
+}
+
+func defaultScenario() scenario {
+	return scenario{
+		name:              "default",
+		seed:              1,
+		nodes:             50,
+		adversaryFraction: 0,
+		latency:           "constant",
+		latencyMeanMS:     50,
+		txCount:           100,
+		txRate:            20,
+		pollIntervalMS:    10,
+		maxDurationMS:     10 * 60 * 1000,
+		voteWindow:        avalanche.AvalancheVoteWindow,
+		voteQuorum:        avalanche.AvalancheVoteQuorum,
+		finalizationScore: avalanche.AvalancheFinalizationScore,
+	}
+}
+
+// parseScenario reads a scenario from YAML. Only the flat "key: value" subset
+// of YAML used by scenario files is supported; unset keys keep their defaults.
+func parseScenario(r io.Reader) (scenario, error) {
+	s := defaultScenario()
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return s, fmt.Errorf("line %d: expected key: value", lineNum)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		if err := s.set(key, value); err != nil {
+			return s, fmt.Errorf("line %d: %s", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+
+	return s, s.validate()
+}
+
+// set assigns a single scenario key
+func (s *scenario) set(key, value string) (err error) {
+	switch key {
+	case "name":
+		s.name = value
+	case "seed":
+		s.seed, err = strconv.ParseInt(value, 10, 64)
+	case "nodes":
+		s.nodes, err = strconv.Atoi(value)
+	case "adversary_fraction":
+		s.adversaryFraction, err = strconv.ParseFloat(value, 64)
+	case "observer_fraction":
+		s.observerFraction, err = strconv.ParseFloat(value, 64)
+	case "latency":
+		s.latency = value
+	case "latency_mean_ms":
+		s.latencyMeanMS, err = strconv.ParseFloat(value, 64)
+	case "tx_count":
+		s.txCount, err = strconv.Atoi(value)
+	case "tx_rate":
+		s.txRate, err = strconv.ParseFloat(value, 64)
+	case "poll_interval_ms":
+		s.pollIntervalMS, err = strconv.ParseInt(value, 10, 64)
+	case "max_duration_ms":
+		s.maxDurationMS, err = strconv.ParseInt(value, 10, 64)
+	case "vote_window", "vote_quorum", "finalization_score":
+		return s.setVoteParam(key, value)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", key, err)
+	}
+	return nil
+}
+
+// setVoteParam assigns a vote parameter, which may be a single value, a list
+// such as "32,64,128" or an inclusive range such as "5..8"
+func (s *scenario) setVoteParam(key, value string) error {
+	values, err := parseValues(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", key, err)
+	}
+
+	switch key {
+	case "vote_window":
+		s.voteWindow = values[0]
+	case "vote_quorum":
+		s.voteQuorum = values[0]
+	case "finalization_score":
+		s.finalizationScore = values[0]
+	}
+
+	for i, p := range s.sweeps {
+		if p.key == key {
+			s.sweeps = append(s.sweeps[:i], s.sweeps[i+1:]...)
+			break
+		}
+	}
+	if len(values) > 1 {
+		s.sweeps = append(s.sweeps, sweepParam{key, values})
+	}
+	return nil
+}
+
+// parseValues parses a single value, a comma separated list or a range
+func parseValues(value string) ([]int, error) {
+	if parts := strings.SplitN(value, "..", 2); len(parts) == 2 {
+		lo, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		hi, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("empty range %s", value)
+		}
+
+		values := make([]int, 0, hi-lo+1)
+		for v := lo; v <= hi; v++ {
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	values := []int{}
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// expand returns a scenario for every combination of the swept vote
+// parameters. Combinations that are not valid, such as a quorum larger than
+// the window, are left out.
+func (s scenario) expand() []scenario {
+	base := s
+	base.sweeps = nil
+	scenarios := []scenario{base}
+
+	for _, p := range s.sweeps {
+		next := []scenario{}
+		for _, sc := range scenarios {
+			for _, v := range p.values {
+				sc.setVoteParam(p.key, strconv.Itoa(v))
+				next = append(next, sc)
+			}
+		}
+		scenarios = next
+	}
+
+	valid := scenarios[:0]
+	for _, sc := range scenarios {
+		if sc.validate() == nil {
+			valid = append(valid, sc)
+		}
+	}
+	return valid
+}
+
+// roleCounts returns how many nodes are adversaries and how many observers
+func (s scenario) roleCounts() (adversaries, observers int) {
+	adversaries = int(math.Round(float64(s.nodes) * s.adversaryFraction))
+	observers = int(math.Round(float64(s.nodes) * s.observerFraction))
+	return adversaries, observers
+}
+
+// voters returns how many nodes answer polls honestly
+func (s scenario) voters() int {
+	adversaries, observers := s.roleCounts()
+	return s.nodes - adversaries - observers
+}
+
+func (s scenario) validate() error {
+	switch {
+	case s.nodes < 2:
+		return fmt.Errorf("nodes must be at least 2")
+	case s.adversaryFraction < 0 || s.adversaryFraction >= 1:
+		return fmt.Errorf("adversary_fraction must be in [0, 1)")
+	case s.observerFraction < 0 || s.observerFraction >= 1:
+		return fmt.Errorf("observer_fraction must be in [0, 1)")
+	case s.voters() < 1:
+		return fmt.Errorf("adversary_fraction and observer_fraction leave no voting nodes")
+	case s.latency != "constant" && s.latency != "uniform" && s.latency != "exponential":
+		return fmt.Errorf("latency must be constant, uniform or exponential")
+	case s.latencyMeanMS < 0:
+		return fmt.Errorf("latency_mean_ms must not be negative")
+	case s.txCount < 1:
+		return fmt.Errorf("tx_count must be at least 1")
+	case s.txRate <= 0:
+		return fmt.Errorf("tx_rate must be positive")
+	case s.pollIntervalMS < 1:
+		return fmt.Errorf("poll_interval_ms must be at least 1")
+	case s.maxDurationMS < 1:
+		return fmt.Errorf("max_duration_ms must be at least 1")
+	case len(s.sweeps) > 0:
+		// Swept parameters are checked per combination by expand
+		return nil
+	case s.voteWindow < 1 || s.voteWindow > avalanche.AvalancheVoteWindow:
+		return fmt.Errorf("vote_window must be in [1, %d]", avalanche.AvalancheVoteWindow)
+	case s.voteQuorum < 1 || s.voteQuorum > s.voteWindow:
+		return fmt.Errorf("vote_quorum must be in [1, vote_window]")
+	case s.finalizationScore < 1 || s.finalizationScore > math.MaxInt16:
+		return fmt.Errorf("finalization_score must be in [1, %d]", math.MaxInt16)
+	}
+	return nil
+}
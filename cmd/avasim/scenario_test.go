@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScenario(t *testing.T) {
+	s, err := parseScenario(strings.NewReader(`
+# comment
+name: "small"
+nodes: 10  # trailing comment
+adversary_fraction: 0.1
+latency: uniform
+tx_count: 5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.name != "small" || s.nodes != 10 || s.adversaryFraction != 0.1 || s.latency != "uniform" || s.txCount != 5 {
+		t.Fatal("Scenario parsed incorrectly:", s)
+	}
+
+	// Unset keys keep their defaults
+	if s.pollIntervalMS != defaultScenario().pollIntervalMS {
+		t.Fatal("Expected default poll interval but got", s.pollIntervalMS)
+	}
+
+	for _, bad := range []string{"nodes: ten", "unknown: 1", "latency: normal", "nodes: 1", "no value"} {
+		if _, err = parseScenario(strings.NewReader(bad)); err == nil {
+			t.Fatal("Expected an error parsing", bad)
+		}
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	s := defaultScenario()
+	s.nodes = 10
+	s.txCount = 5
+	s.adversaryFraction = 0.1
+
+	sim := newSimulation(s)
+	sim.run()
+	st := sim.stats()
+
+	if st.adversaries != 1 || st.decisions != 45 {
+		t.Fatal("Expected 1 adversary and 45 honest decisions but got", st.adversaries, st.decisions)
+	}
+	if st.final != st.decisions || st.safetyViolations != 0 || st.livenessFailures != 0 {
+		t.Fatal("Expected every honest node to finalize every transaction:", st)
+	}
+
+	// Runs are deterministic for a seed
+	again := newSimulation(s)
+	again.run()
+	if again.now != sim.now {
+		t.Fatal("Expected identical runs but they took", sim.now, "and", again.now)
+	}
+}
+
+func TestObservers(t *testing.T) {
+	s := defaultScenario()
+	s.nodes = 10
+	s.txCount = 5
+	s.adversaryFraction = 0.1
+	s.observerFraction = 0.3
+
+	sim := newSimulation(s)
+	sim.run()
+	st := sim.stats()
+
+	// Observers decide like voters even though their peers hear nothing back
+	// from them
+	if st.adversaries != 1 || st.observers != 3 || st.decisions != 45 {
+		t.Fatal("Expected 1 adversary, 3 observers and 45 honest decisions but got", st.adversaries, st.observers, st.decisions)
+	}
+	if st.final != st.decisions || st.safetyViolations != 0 || st.livenessFailures != 0 {
+		t.Fatal("Expected every honest node to finalize every transaction:", st)
+	}
+
+	if _, err := parseScenario(strings.NewReader("adversary_fraction: 0.5\nobserver_fraction: 0.5")); err == nil {
+		t.Fatal("Expected an error for a network without voters")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	s, err := parseScenario(strings.NewReader(`
+nodes: 10
+tx_count: 5
+vote_window: 7..8
+vote_quorum: 7,8
+finalization_score: 16
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A quorum of 8 can't be reached in a window of 7
+	configs := s.expand()
+	if len(configs) != 3 {
+		t.Fatal("Expected 3 valid combinations but got", len(configs))
+	}
+	for _, c := range configs {
+		if len(c.sweeps) != 0 || c.finalizationScore != 16 || c.validate() != nil {
+			t.Fatal("Expected a single valid configuration but got", c)
+		}
+	}
+
+	results := runSweep(s, 3, 2)
+	if len(results) != 3 {
+		t.Fatal("Expected 3 results but got", len(results))
+	}
+	for _, r := range results {
+		if r.runs != 3 || r.txs != 15 {
+			t.Fatal("Expected 3 runs of 5 transactions but got", r.runs, r.txs)
+		}
+		if r.safetyRate() != 0 || r.livenessRate() != 0 {
+			t.Fatal("Expected every run to be safe and live but got", r.safetyRate(), r.livenessRate())
+		}
+	}
+
+	for _, bad := range []string{"vote_window: 9", "vote_quorum: 1..x", "vote_window: 8..7", "finalization_score: 0"} {
+		if _, err = parseScenario(strings.NewReader(bad)); err == nil {
+			t.Fatal("Expected an error parsing", bad)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"container/heap"
+	"math/rand"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+type eventKind int
+
+const (
+	// eventLearn is a node hearing about a transaction
+	eventLearn eventKind = iota
+
+	// eventPoll is a node polling a random peer
+	eventPoll
+
+	// eventResponse is a response arriving back at the node that polled
+	eventResponse
+)
+
+// event is something happening at a point in simulated time
+type event struct {
+	at   int64
+	seq  int64
+	kind eventKind
+	node int
+
+	tx   int
+	peer int
+	resp avalanche.Response
+}
+
+// eventQueue orders events by time, then by when they were scheduled so runs
+// are deterministic
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// tx is a transaction Target. Honest nodes accept every transaction.
+type tx struct {
+	hash avalanche.Hash
+}
+
+func (t *tx) Hash() avalanche.Hash { return t.hash }
+
+func (*tx) IsAccepted() bool { return true }
+
+func (*tx) IsValid() bool { return true }
+
+func (*tx) Type() string { return "tx" }
+
+func (*tx) Score() int64 { return 1 }
+
+// role is the part a node plays in the network
+type role int
+
+const (
+	// roleVoter follows transactions, polls its peers and answers their polls
+	roleVoter role = iota
+
+	// roleAdversary votes against everything and never polls
+	roleAdversary
+
+	// roleObserver follows transactions and polls its peers but never answers
+	// their polls, like a wallet or block explorer
+	roleObserver
+)
+
+func (r role) String() string {
+	switch r {
+	case roleAdversary:
+		return "adversary"
+	case roleObserver:
+		return "observer"
+	}
+	return "voter"
+}
+
+// simNode is a single node in the simulation
+type simNode struct {
+	processor *avalanche.Processor
+	role      role
+}
+
+// respond answers a poll. Adversaries vote against everything.
+func (n *simNode) respond(id avalanche.NodeID, poll avalanche.Poll) avalanche.Response {
+	if n.role != roleAdversary {
+		return n.processor.HandlePoll(id, poll)
+	}
+
+	invs := poll.GetInvs()
+	votes := make([]avalanche.Vote, len(invs))
+	for i, inv := range invs {
+		votes[i] = avalanche.NewVote(avalanche.VoteNo, inv.TargetHash)
+	}
+	return avalanche.NewResponse(poll.GetRound(), 0, votes)
+}
+
+// decision is the outcome of a transaction on a single node
+type decision struct {
+	tx        int
+	node      int
+	role      role
+	arrivalMS int64
+	learnedMS int64
+	finalMS   int64
+	status    avalanche.Status
+	final     bool
+}
+
+// simulation runs a scenario
+type simulation struct {
+	scenario scenario
+	rng      *rand.Rand
+	nodes    []*simNode
+	queue    eventQueue
+	seq      int64
+	now      int64
+
+	arrivals  []int64
+	decisions [][]decision
+
+	// pending is the number of honest decisions not yet final
+	pending int
+}
+
+func newSimulation(s scenario) *simulation {
+	sim := &simulation{
+		scenario:  s,
+		rng:       rand.New(rand.NewSource(s.seed)),
+		nodes:     make([]*simNode, s.nodes),
+		arrivals:  make([]int64, s.txCount),
+		decisions: make([][]decision, s.txCount),
+	}
+
+	config := avalanche.DefaultConfig
+	config.VoteWindow = s.voteWindow
+	config.VoteQuorum = s.voteQuorum
+	config.FinalizationScore = s.finalizationScore
+
+	adversaries, observers := s.roleCounts()
+	for i := range sim.nodes {
+		r := roleVoter
+		switch {
+		case i < adversaries:
+			r = roleAdversary
+		case i < adversaries+observers:
+			r = roleObserver
+		}
+
+		sim.nodes[i] = &simNode{
+			processor: avalanche.NewProcessorWithConfig(avalanche.NewConnman(), config),
+			role:      r,
+		}
+	}
+	sim.pending = s.txCount * (s.nodes - adversaries)
+
+	// Transactions arrive as a Poisson process and propagate to every node
+	at := 0.0
+	for t := 0; t < s.txCount; t++ {
+		at += sim.rng.ExpFloat64() * 1000 / s.txRate
+		sim.arrivals[t] = int64(at)
+		sim.decisions[t] = make([]decision, s.nodes)
+
+		for n := range sim.nodes {
+			sim.decisions[t][n] = decision{
+				tx:        t,
+				node:      n,
+				role:      sim.nodes[n].role,
+				arrivalMS: sim.arrivals[t],
+				learnedMS: -1,
+				finalMS:   -1,
+			}
+			sim.schedule(&event{at: sim.arrivals[t] + sim.latency(), kind: eventLearn, node: n, tx: t})
+		}
+	}
+
+	// Stagger the nodes' first polls
+	for n := range sim.nodes {
+		if sim.nodes[n].role != roleAdversary {
+			sim.schedule(&event{at: sim.rng.Int63n(s.pollIntervalMS), kind: eventPoll, node: n})
+		}
+	}
+
+	return sim
+}
+
+// run processes events until every honest node has finalized every
+// transaction or the time limit is reached
+func (sim *simulation) run() {
+	for sim.queue.Len() > 0 && sim.pending > 0 {
+		e := heap.Pop(&sim.queue).(*event)
+		if e.at > sim.scenario.maxDurationMS {
+			break
+		}
+		sim.now = e.at
+
+		switch e.kind {
+		case eventLearn:
+			sim.learn(e)
+		case eventPoll:
+			sim.poll(e)
+		case eventResponse:
+			sim.receive(e)
+		}
+	}
+}
+
+func (sim *simulation) learn(e *event) {
+	sim.decisions[e.tx][e.node].learnedMS = sim.now
+	sim.decisions[e.tx][e.node].status = avalanche.StatusAccepted
+	sim.nodes[e.node].processor.AddTargetToReconcile(&tx{txHash(e.tx)})
+}
+
+func (sim *simulation) poll(e *event) {
+	defer sim.schedule(&event{at: sim.now + sim.scenario.pollIntervalMS, kind: eventPoll, node: e.node})
+
+	// Pick a random peer other than ourself
+	peer := sim.rng.Intn(len(sim.nodes) - 1)
+	if peer >= e.node {
+		peer++
+	}
+
+	poll, ok := sim.nodes[e.node].processor.PollNode(avalanche.NodeID(peer))
+	if !ok {
+		return
+	}
+
+	// Observers never answer. Other peers answer when the poll reaches them and
+	// the response takes as long again to come back.
+	if sim.nodes[peer].role == roleObserver {
+		return
+	}
+	resp := sim.nodes[peer].respond(avalanche.NodeID(e.node), poll)
+	sim.schedule(&event{
+		at:   sim.now + sim.latency() + sim.latency(),
+		kind: eventResponse,
+		node: e.node,
+		peer: peer,
+		resp: resp,
+	})
+}
+
+func (sim *simulation) receive(e *event) {
+	updates := []avalanche.StatusUpdate{}
+	sim.nodes[e.node].processor.RegisterVotes(avalanche.NodeID(e.peer), e.resp, &updates)
+
+	for _, update := range updates {
+		d := &sim.decisions[txIndex(update.Hash)][e.node]
+		d.status = update.Status
+
+		if update.Status == avalanche.StatusFinalized || update.Status == avalanche.StatusInvalid {
+			d.final = true
+			d.finalMS = sim.now
+			sim.pending--
+		}
+	}
+}
+
+func (sim *simulation) schedule(e *event) {
+	sim.seq++
+	e.seq = sim.seq
+	heap.Push(&sim.queue, e)
+}
+
+// latency samples a one-way message delay in milliseconds
+func (sim *simulation) latency() int64 {
+	mean := sim.scenario.latencyMeanMS
+	switch sim.scenario.latency {
+	case "uniform":
+		return int64(sim.rng.Float64() * 2 * mean)
+	case "exponential":
+		return int64(sim.rng.ExpFloat64() * mean)
+	}
+	return int64(mean)
+}
+
+func txHash(t int) avalanche.Hash { return avalanche.Hash(t + 1) }
+
+func txIndex(h avalanche.Hash) int { return int(h) - 1 }
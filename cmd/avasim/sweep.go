@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+)
+
+// sweepResult aggregates the runs of a single configuration across seeds
+type sweepResult struct {
+	scenario scenario
+	runs     int
+	txs      int
+
+	// safetyViolations and livenessFailures are summed over every run
+	safetyViolations int
+	livenessFailures int
+
+	// unsafeRuns and unliveRuns are the runs with at least one of each
+	unsafeRuns int
+	unliveRuns int
+
+	latencies []int64
+}
+
+func (r *sweepResult) add(st stats) {
+	r.runs++
+	r.txs += r.scenario.txCount
+	r.safetyViolations += st.safetyViolations
+	r.livenessFailures += st.livenessFailures
+	if st.safetyViolations > 0 {
+		r.unsafeRuns++
+	}
+	if st.livenessFailures > 0 {
+		r.unliveRuns++
+	}
+	r.latencies = append(r.latencies, st.latencies...)
+}
+
+// safetyRate is the share of transactions honest nodes finalized differently
+func (r *sweepResult) safetyRate() float64 {
+	return float64(r.safetyViolations) / float64(r.txs)
+}
+
+// livenessRate is the share of transactions some honest node never finalized
+func (r *sweepResult) livenessRate() float64 {
+	return float64(r.livenessFailures) / float64(r.txs)
+}
+
+// medianLatency is the median finalization latency over every run
+func (r *sweepResult) medianLatency() int64 {
+	return stats{latencies: r.latencies}.percentile(0.5)
+}
+
+// runSweep simulates every configuration of the scenario's swept parameters
+// with seeds consecutive seeds each, spread across workers goroutines
+func runSweep(s scenario, seeds, workers int) []*sweepResult {
+	configs := s.expand()
+	results := make([]*sweepResult, len(configs))
+	for i, c := range configs {
+		results[i] = &sweepResult{scenario: c}
+	}
+
+	type job struct {
+		result   int
+		scenario scenario
+	}
+	jobs := make(chan job)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				sim := newSimulation(j.scenario)
+				sim.run()
+				st := sim.stats()
+
+				mu.Lock()
+				results[j.result].add(st)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i, c := range configs {
+		for seed := int64(0); seed < int64(seeds); seed++ {
+			c.seed = s.seed + seed
+			jobs <- job{i, c}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range results {
+		sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	}
+	return results
+}
+
+func printSweep(w io.Writer, s scenario, results []*sweepResult) {
+	fmt.Fprintf(w, "scenario: %s\n\n", s.name)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "k\talpha\tbeta\truns\tsafety\tunsafe runs\tliveness\tunlive runs\tp50 ms\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%.4f\t%d\t%.4f\t%d\t%d\t\n",
+			r.scenario.voteWindow, r.scenario.voteQuorum, r.scenario.finalizationScore, r.runs,
+			r.safetyRate(), r.unsafeRuns, r.livenessRate(), r.unliveRuns, r.medianLatency())
+	}
+	tw.Flush()
+}
+
+func writeSweepCSV(path string, results []*sweepResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{{"vote_window", "vote_quorum", "finalization_score", "runs",
+		"safety_violation_rate", "unsafe_runs", "liveness_failure_rate", "unlive_runs", "p50_latency_ms"}}
+	for _, r := range results {
+		rows = append(rows, []string{
+			strconv.Itoa(r.scenario.voteWindow),
+			strconv.Itoa(r.scenario.voteQuorum),
+			strconv.Itoa(r.scenario.finalizationScore),
+			strconv.Itoa(r.runs),
+			strconv.FormatFloat(r.safetyRate(), 'f', -1, 64),
+			strconv.Itoa(r.unsafeRuns),
+			strconv.FormatFloat(r.livenessRate(), 'f', -1, 64),
+			strconv.Itoa(r.unliveRuns),
+			strconv.FormatInt(r.medianLatency(), 10),
+		})
+	}
+
+	if err = csv.NewWriter(f).WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
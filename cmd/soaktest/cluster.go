@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// tx is a transaction Target. Every node accepts every transaction.
+type tx struct {
+	hash avalanche.Hash
+}
+
+func (t *tx) Hash() avalanche.Hash { return t.hash }
+
+func (*tx) IsAccepted() bool { return true }
+
+func (*tx) IsValid() bool { return true }
+
+func (*tx) Type() string { return "tx" }
+
+func (*tx) Score() int64 { return 1 }
+
+// soakNode is a Processor with the lock that serializes access to it
+type soakNode struct {
+	mu        sync.Mutex
+	processor *avalanche.Processor
+}
+
+// cluster is a set of nodes polling each other in real time under a steady
+// load of transactions
+type cluster struct {
+	// added counts the transactions sent to every node and finalized counts
+	// the finalizations across all nodes. They are used atomically so they
+	// come first to be 64-bit aligned on 32-bit platforms.
+	added     int64
+	finalized int64
+
+	nodes        []*soakNode
+	pollInterval time.Duration
+	txRate       float64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newCluster(nodes int, pollInterval time.Duration, txRate float64) *cluster {
+	c := &cluster{
+		nodes:        make([]*soakNode, nodes),
+		pollInterval: pollInterval,
+		txRate:       txRate,
+		quit:         make(chan struct{}),
+	}
+	for i := range c.nodes {
+		c.nodes[i] = &soakNode{processor: avalanche.NewProcessor(avalanche.NewConnman())}
+	}
+	return c
+}
+
+// start runs the load generator and each node's poll loop
+func (c *cluster) start() {
+	c.wg.Add(len(c.nodes) + 1)
+	go c.generateLoad()
+	for i := range c.nodes {
+		go c.poll(i, rand.New(rand.NewSource(int64(i))))
+	}
+}
+
+// stop ends every goroutine started by start and waits for them to exit
+func (c *cluster) stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// getAdded returns the number of transactions sent to every node
+func (c *cluster) getAdded() int64 { return atomic.LoadInt64(&c.added) }
+
+// getFinalized returns the number of finalizations across all nodes
+func (c *cluster) getFinalized() int64 { return atomic.LoadInt64(&c.finalized) }
+
+// generateLoad adds transactions to every node at txRate per second
+func (c *cluster) generateLoad() {
+	defer c.wg.Done()
+
+	const step = 10 * time.Millisecond
+	t := time.NewTicker(step)
+	defer t.Stop()
+
+	owed := 0.0
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-t.C:
+		}
+
+		owed += c.txRate * step.Seconds()
+		for ; owed >= 1; owed-- {
+			h := avalanche.Hash(atomic.AddInt64(&c.added, 1))
+			for _, n := range c.nodes {
+				n.mu.Lock()
+				n.processor.AddTargetToReconcile(&tx{h})
+				n.mu.Unlock()
+			}
+		}
+	}
+}
+
+// poll has node i poll a random peer every pollInterval
+func (c *cluster) poll(i int, rng *rand.Rand) {
+	defer c.wg.Done()
+
+	t := time.NewTicker(c.pollInterval)
+	defer t.Stop()
+
+	n := c.nodes[i]
+	updates := []avalanche.StatusUpdate{}
+	for {
+		select {
+		case <-c.quit:
+			return
+		case <-t.C:
+		}
+
+		// Pick a random peer other than ourself
+		j := rng.Intn(len(c.nodes) - 1)
+		if j >= i {
+			j++
+		}
+		peer := c.nodes[j]
+
+		n.mu.Lock()
+		poll, ok := n.processor.PollNode(avalanche.NodeID(j))
+		n.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		peer.mu.Lock()
+		resp := peer.processor.HandlePoll(avalanche.NodeID(i), poll)
+		peer.mu.Unlock()
+
+		updates = updates[:0]
+		n.mu.Lock()
+		n.processor.RegisterVotes(avalanche.NodeID(j), resp, &updates)
+		n.mu.Unlock()
+
+		for _, update := range updates {
+			if update.Status == avalanche.StatusFinalized {
+				atomic.AddInt64(&c.finalized, 1)
+			}
+		}
+	}
+}
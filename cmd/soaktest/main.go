@@ -0,0 +1,140 @@
+// Command soaktest runs a small cluster of avalanche nodes for a long time
+// under a steady load of synthetic transactions, checking for goroutine leaks,
+// unbounded heap growth and stalls in finalization.
+//
+// Usage:
+//
+//	soaktest [-nodes 5] [-duration 4h] [-tx-rate 20] [-o report.json]
+//
+// The process is sampled every -sample-interval. Once the -warm-up has passed
+// every sample must have no more than -max-goroutine-growth goroutines more
+// than the first sample after warm-up, a live heap within -max-heap-growth of
+// it plus -decision-bytes per finalization since, and at least
+// -min-throughput finalizations per second across the cluster. After the
+// cluster stops no more goroutines than before it started may remain.
+//
+// A JSON report of the samples and any failures is written to stdout, or to
+// -o. The exit status is 1 if any check failed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// soakConfig describes a soak
+type soakConfig struct {
+	nodes          int
+	duration       time.Duration
+	txRate         float64
+	pollInterval   time.Duration
+	sampleInterval time.Duration
+	limits         limits
+}
+
+func main() {
+	c := soakConfig{}
+	flag.IntVar(&c.nodes, "nodes", 5, "Number of nodes in the cluster")
+	flag.DurationVar(&c.duration, "duration", 4*time.Hour, "How long to run the cluster")
+	flag.Float64Var(&c.txRate, "tx-rate", 20, "Transactions sent to the cluster per second")
+	flag.DurationVar(&c.pollInterval, "poll-interval", avalanche.AvalancheTimeStep, "Time between each node's polls")
+	flag.DurationVar(&c.sampleInterval, "sample-interval", time.Minute, "Time between samples")
+	flag.DurationVar(&c.limits.warmUp, "warm-up", 5*time.Minute, "Time before samples are checked")
+	flag.IntVar(&c.limits.maxGoroutineGrowth, "max-goroutine-growth", 0, "Goroutines allowed beyond those running after warm-up")
+	flag.Uint64Var(&c.limits.maxHeapGrowth, "max-heap-growth", 64<<20, "Bytes the live heap may grow after warm-up, besides -decision-bytes")
+	flag.Uint64Var(&c.limits.decisionBytes, "decision-bytes", 1024, "Bytes the live heap may grow for each finalization after warm-up")
+	flag.Float64Var(&c.limits.minThroughput, "min-throughput", 1, "Fewest finalizations per second allowed in a sample after warm-up")
+	out := flag.String("o", "", "Write the report to this file instead of stdout")
+	flag.Parse()
+
+	if flag.NArg() != 0 || c.nodes < 2 || c.duration <= 0 || c.txRate <= 0 || c.pollInterval <= 0 || c.sampleInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: soaktest [-nodes n] [-duration d] [-tx-rate r] [-poll-interval d] [-sample-interval d] [-o report.json]")
+		os.Exit(2)
+	}
+
+	r := runSoak(c)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "soaktest:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeReport(w, r); err != nil {
+		fmt.Fprintln(os.Stderr, "soaktest:", err)
+		os.Exit(1)
+	}
+
+	if !r.Pass {
+		for _, failure := range r.Failures {
+			fmt.Fprintln(os.Stderr, "soaktest:", failure)
+		}
+		os.Exit(1)
+	}
+}
+
+// runSoak runs the cluster for the configured duration, sampling it as it goes,
+// and checks the samples
+func runSoak(c soakConfig) report {
+	r := report{
+		Nodes:              c.nodes,
+		TxRate:             c.txRate,
+		DurationSeconds:    c.duration.Seconds(),
+		BaselineGoroutines: runtime.NumGoroutine(),
+		Samples:            []sample{},
+		Failures:           []string{},
+	}
+
+	cl := newCluster(c.nodes, c.pollInterval, c.txRate)
+	start := time.Now()
+	cl.start()
+
+	// The soak runs until the first sample at or past the duration so every
+	// sample covers a whole interval
+	t := time.NewTicker(c.sampleInterval)
+	var prev *sample
+	for time.Since(start) < c.duration {
+		<-t.C
+		r.Samples = append(r.Samples, takeSample(cl, start, prev))
+		prev = &r.Samples[len(r.Samples)-1]
+	}
+	t.Stop()
+
+	cl.stop()
+	r.FinalGoroutines = settledGoroutines(r.BaselineGoroutines, time.Second)
+
+	r.check(c.limits)
+	return r
+}
+
+// settledGoroutines returns the number of goroutines once it has fallen to the
+// baseline, or after the timeout if it never does, giving goroutines that are
+// exiting time to finish
+func settledGoroutines(baseline int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func writeReport(w io.Writer, r report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
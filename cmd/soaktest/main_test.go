@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoak(t *testing.T) {
+	r := runSoak(soakConfig{
+		nodes:          3,
+		duration:       2 * time.Second,
+		txRate:         50,
+		pollInterval:   time.Millisecond,
+		sampleInterval: 250 * time.Millisecond,
+		limits: limits{
+			warmUp:        500 * time.Millisecond,
+			maxHeapGrowth: 16 << 20,
+			decisionBytes: 1024,
+			minThroughput: 1,
+		},
+	})
+
+	if !r.Pass {
+		t.Fatal("Expected soak to pass but got", r.Failures)
+	}
+	if r.Samples[len(r.Samples)-1].Finalized == 0 {
+		t.Fatal("Expected finalizations")
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	l := limits{warmUp: time.Second, maxGoroutineGrowth: 1, maxHeapGrowth: 100, decisionBytes: 10, minThroughput: 5}
+
+	r := report{BaselineGoroutines: 5, FinalGoroutines: 5, Samples: []sample{
+		{ElapsedSeconds: 0, Goroutines: 2, HeapBytes: 0},
+		{ElapsedSeconds: 1, Goroutines: 10, HeapBytes: 1000, Finalized: 10, Throughput: 10},
+		{ElapsedSeconds: 2, Goroutines: 11, HeapBytes: 1200, Finalized: 20, Throughput: 10},
+	}}
+	r.check(l)
+	if !r.Pass {
+		t.Fatal("Expected report to pass but got", r.Failures)
+	}
+
+	// A leaked goroutine, heap beyond the budget, a stall and goroutines left
+	// running are each a failure
+	r = report{BaselineGoroutines: 5, FinalGoroutines: 7, Samples: []sample{
+		{ElapsedSeconds: 1, Goroutines: 10, HeapBytes: 1000, Finalized: 10, Throughput: 10},
+		{ElapsedSeconds: 2, Goroutines: 12, HeapBytes: 1201, Finalized: 10, Throughput: 0},
+	}}
+	r.check(l)
+	if r.Pass || len(r.Failures) != 4 {
+		t.Fatal("Expected 4 failures but got", r.Failures)
+	}
+
+	// Without samples after warm-up nothing was checked
+	r = report{Samples: []sample{{ElapsedSeconds: 0}}}
+	r.check(l)
+	if r.Pass {
+		t.Fatal("Expected report without samples after warm-up to fail")
+	}
+}
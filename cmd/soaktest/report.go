@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// limits are the thresholds a soak must stay within to pass
+type limits struct {
+	// warmUp is how long the cluster runs before samples are held to the
+	// limits below
+	warmUp time.Duration
+
+	// maxGoroutineGrowth is how many more goroutines than after warm-up may be
+	// running at any later sample, and than before the cluster started once
+	// it has stopped
+	maxGoroutineGrowth int
+
+	// maxHeapGrowth is how far the live heap may grow past its size after
+	// warm-up, on top of decisionBytes for each finalization since then.
+	// Processors keep a small record of every decision for anchoring and
+	// answering late polls, so some growth with the number of decisions is
+	// expected.
+	maxHeapGrowth uint64
+	decisionBytes uint64
+
+	// minThroughput is the fewest finalizations per second across the cluster
+	// allowed in any interval after warm-up
+	minThroughput float64
+}
+
+// sample is the state of the process at a point in the soak
+type sample struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Goroutines     int     `json:"goroutines"`
+	HeapBytes      uint64  `json:"heap_bytes"`
+	Added          int64   `json:"added"`
+	Finalized      int64   `json:"finalized"`
+
+	// Throughput is finalizations per second since the previous sample
+	Throughput float64 `json:"throughput"`
+}
+
+// report is the machine-readable outcome of a soak
+type report struct {
+	Nodes           int     `json:"nodes"`
+	TxRate          float64 `json:"tx_rate"`
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	BaselineGoroutines int      `json:"baseline_goroutines"`
+	FinalGoroutines    int      `json:"final_goroutines"`
+	Samples            []sample `json:"samples"`
+
+	Pass     bool     `json:"pass"`
+	Failures []string `json:"failures"`
+}
+
+// takeSample measures the process and cluster. It forces a collection so the
+// heap size is the live heap.
+func takeSample(c *cluster, start time.Time, prev *sample) sample {
+	runtime.GC()
+	ms := runtime.MemStats{}
+	runtime.ReadMemStats(&ms)
+
+	s := sample{
+		ElapsedSeconds: time.Since(start).Seconds(),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapBytes:      ms.HeapAlloc,
+		Added:          c.getAdded(),
+		Finalized:      c.getFinalized(),
+	}
+	if prev != nil && s.ElapsedSeconds > prev.ElapsedSeconds {
+		s.Throughput = float64(s.Finalized-prev.Finalized) / (s.ElapsedSeconds - prev.ElapsedSeconds)
+	}
+	return s
+}
+
+// check holds the samples taken after warm-up to the limits, recording any
+// failures. The first such sample is the reference for growth.
+func (r *report) check(l limits) {
+	var ref *sample
+	for i := range r.Samples {
+		s := &r.Samples[i]
+		if s.ElapsedSeconds < l.warmUp.Seconds() {
+			continue
+		}
+		if ref == nil {
+			ref = s
+			continue
+		}
+
+		if s.Goroutines > ref.Goroutines+l.maxGoroutineGrowth {
+			r.fail("%d goroutines at %.0fs, up from %d after warm-up", s.Goroutines, s.ElapsedSeconds, ref.Goroutines)
+		}
+
+		budget := ref.HeapBytes + l.maxHeapGrowth + l.decisionBytes*uint64(s.Finalized-ref.Finalized)
+		if s.HeapBytes > budget {
+			r.fail("heap of %d bytes at %.0fs exceeds budget of %d bytes", s.HeapBytes, s.ElapsedSeconds, budget)
+		}
+
+		if s.Throughput < l.minThroughput {
+			r.fail("%.1f finalizations per second at %.0fs, below %.1f", s.Throughput, s.ElapsedSeconds, l.minThroughput)
+		}
+	}
+
+	if ref == nil {
+		r.fail("no samples taken after the %s warm-up", l.warmUp)
+	}
+
+	if r.FinalGoroutines > r.BaselineGoroutines+l.maxGoroutineGrowth {
+		r.fail("%d goroutines still running after stopping, up from %d", r.FinalGoroutines, r.BaselineGoroutines)
+	}
+
+	r.Pass = len(r.Failures) == 0
+}
+
+func (r *report) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,155 @@
+package avalanche
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+// FinalizedTarget is the outcome of a finalized target
+type FinalizedTarget struct {
+	Hash   Hash
+	Status Status
+}
+
+// Commitment is a Merkle root over the set of finalized targets. The set is
+// split into buckets by hash, each with its own root, so two peers whose roots
+// differ can find which buckets diverge and only compare those.
+type Commitment struct {
+	Root    [32]byte
+	Buckets [][32]byte
+}
+
+// DivergentBuckets returns the indexes of the buckets whose roots differ from
+// other's. Every bucket is divergent if the commitments use different bucket
+// counts.
+func (c Commitment) DivergentBuckets(other Commitment) []int {
+	divergent := []int{}
+	if c.Root == other.Root && len(c.Buckets) == len(other.Buckets) {
+		return divergent
+	}
+
+	for i := range c.Buckets {
+		if len(c.Buckets) != len(other.Buckets) || c.Buckets[i] != other.Buckets[i] {
+			divergent = append(divergent, i)
+		}
+	}
+	return divergent
+}
+
+// FinalizedCommitment computes a Commitment over the finalized targets using
+// the given number of buckets
+func (p *Processor) FinalizedCommitment(buckets int) Commitment {
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	c := Commitment{Buckets: make([][32]byte, buckets)}
+	for i := range c.Buckets {
+		c.Buckets[i] = merkleRoot(finalizedLeaves(p.FinalizedInBucket(i, buckets)))
+	}
+
+	c.Root = merkleRoot(c.Buckets)
+	return c
+}
+
+// FinalizedInBucket returns the finalized targets in the given bucket, ordered
+// by hash
+func (p *Processor) FinalizedInBucket(bucket, buckets int) []FinalizedTarget {
+	targets := []FinalizedTarget{}
+	for h, f := range p.finalizations {
+		if bucketForHash(h, buckets) == bucket {
+			targets = append(targets, FinalizedTarget{h, f.status})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Hash < targets[j].Hash })
+	return targets
+}
+
+// FinalizedDiff describes how a peer's finalized targets differ from ours
+type FinalizedDiff struct {
+	// Missing are targets the peer finalized that we have not
+	Missing []FinalizedTarget
+
+	// Extra are targets we finalized that the peer has not
+	Extra []FinalizedTarget
+
+	// Conflicting are targets we both finalized with different outcomes; the
+	// peer's outcome is given
+	Conflicting []FinalizedTarget
+}
+
+// DiffFinalized compares a peer's finalized targets for a bucket, as returned
+// by their FinalizedInBucket, with ours
+func (p *Processor) DiffFinalized(bucket, buckets int, theirs []FinalizedTarget) FinalizedDiff {
+	diff := FinalizedDiff{}
+
+	seen := make(map[Hash]struct{}, len(theirs))
+	for _, t := range theirs {
+		seen[t.Hash] = struct{}{}
+
+		f, ok := p.finalizations[t.Hash]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, t)
+		case f.status != t.Status:
+			diff.Conflicting = append(diff.Conflicting, t)
+		}
+	}
+
+	for _, t := range p.FinalizedInBucket(bucket, buckets) {
+		if _, ok := seen[t.Hash]; !ok {
+			diff.Extra = append(diff.Extra, t)
+		}
+	}
+
+	return diff
+}
+
+// bucketForHash returns which of the buckets the hash falls into
+func bucketForHash(h Hash, buckets int) int {
+	b := int(h) % buckets
+	if b < 0 {
+		b += buckets
+	}
+	return b
+}
+
+// finalizedLeaves returns the Merkle leaves committing to each target
+func finalizedLeaves(targets []FinalizedTarget) [][32]byte {
+	leaves := make([][32]byte, len(targets))
+	for i, t := range targets {
+		var buf [9]byte
+		binary.BigEndian.PutUint64(buf[:8], uint64(t.Hash))
+		buf[8] = byte(t.Status)
+		leaves[i] = sha256.Sum256(buf[:])
+	}
+	return leaves
+}
+
+// merkleRoot computes a Merkle root in the style of Bitcoin's, duplicating the
+// last node of odd levels. The root of no leaves is all zeros.
+func merkleRoot(leaves [][32]byte) [32]byte {
+	if len(leaves) == 0 {
+		return [32]byte{}
+	}
+
+	level := append([][32]byte{}, leaves...)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			var buf [64]byte
+			copy(buf[:32], level[i][:])
+			copy(buf[32:], level[i+1][:])
+			next = append(next, sha256.Sum256(buf[:]))
+		}
+		level = next
+	}
+
+	return level[0]
+}
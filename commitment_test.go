@@ -0,0 +1,58 @@
+package avalanche
+
+import "testing"
+
+func TestFinalizedCommitment(t *testing.T) {
+	var (
+		ours   = NewProcessor(NewConnman())
+		theirs = NewProcessor(NewConnman())
+	)
+
+	// Nothing finalized commits to the same root
+	if ours.FinalizedCommitment(4).Root != theirs.FinalizedCommitment(4).Root {
+		t.Fatal("Empty sets should have the same root")
+	}
+
+	for h := Hash(0); h < 10; h++ {
+		ours.recordFinalization(h, StatusFinalized)
+		theirs.recordFinalization(h, StatusFinalized)
+	}
+
+	c1, c2 := ours.FinalizedCommitment(4), theirs.FinalizedCommitment(4)
+	if c1.Root != c2.Root || len(c1.DivergentBuckets(c2)) != 0 {
+		t.Fatal("Identical sets should have identical commitments")
+	}
+
+	// They are missing one of ours, have one we don't, and disagree on another
+	delete(theirs.finalizations, Hash(1))
+	theirs.recordFinalization(Hash(13), StatusFinalized)
+	theirs.recordFinalization(Hash(6), StatusInvalid)
+
+	c2 = theirs.FinalizedCommitment(4)
+	if c1.Root == c2.Root {
+		t.Fatal("Different sets should have different roots")
+	}
+
+	divergent := c1.DivergentBuckets(c2)
+	if len(divergent) != 2 || divergent[0] != 1 || divergent[1] != 2 {
+		t.Fatal("Expected buckets 1 and 2 to diverge but got", divergent)
+	}
+
+	diff := ours.DiffFinalized(1, 4, theirs.FinalizedInBucket(1, 4))
+	if len(diff.Missing) != 1 || diff.Missing[0].Hash != Hash(13) {
+		t.Fatal("Expected 13 to be missing but got", diff.Missing)
+	}
+	if len(diff.Extra) != 1 || diff.Extra[0].Hash != Hash(1) {
+		t.Fatal("Expected 1 to be extra but got", diff.Extra)
+	}
+
+	diff = ours.DiffFinalized(2, 4, theirs.FinalizedInBucket(2, 4))
+	if len(diff.Conflicting) != 1 || diff.Conflicting[0] != (FinalizedTarget{Hash(6), StatusInvalid}) {
+		t.Fatal("Expected 6 to conflict but got", diff.Conflicting)
+	}
+
+	// Commitments with different bucket counts diverge everywhere
+	if len(c1.DivergentBuckets(ours.FinalizedCommitment(2))) != 4 {
+		t.Fatal("Expected every bucket to diverge")
+	}
+}
@@ -0,0 +1,270 @@
+package avalanche
+
+import (
+	"math"
+	"time"
+)
+
+// Config holds the tunable parameters of a Processor
+type Config struct {
+	// PollWindow is the maximum number of queries that may be outstanding to a
+	// single node at once. Responses are matched to queries by their round, so
+	// raising it lets several queries to a high-latency node be in flight. One
+	// query is allowed if it is not positive.
+	PollWindow int
+
+	// PollInterval is how often the running Processor polls for outstanding
+	// targets. AvalancheTimeStep is used if it is not positive.
+	PollInterval time.Duration
+
+	// PollDebounce is how long to wait after a target is added before polling
+	// early rather than waiting for the next PollInterval. Targets added during
+	// the wait are included in the same poll.
+	PollDebounce time.Duration
+
+	// MaxReorgDepth is the deepest reorg of the anchoring chain that leaves
+	// existing finalizations standing; see Processor.HandleReorg
+	MaxReorgDepth int64
+
+	// VoteWindow is how many of a target's most recent votes are considered
+	// when deciding whether a round is conclusive, at most 8.
+	// AvalancheVoteWindow is used if it is not positive.
+	VoteWindow int
+
+	// VoteQuorum is how many votes within the window must agree for a round
+	// to be conclusive. AvalancheVoteQuorum is used if it is not positive.
+	VoteQuorum int
+
+	// FinalizationScore is the confidence at which a decision is final.
+	// AvalancheFinalizationScore is used if it is not positive.
+	FinalizationScore int
+
+	// Types overrides VoteWindow, VoteQuorum and FinalizationScore for
+	// targets of particular types, such as to finalize blocks at a different
+	// score than transactions
+	Types map[string]TypeConfig
+
+	// UpdateHistorySize is how many recent StatusUpdates are kept for Cursors.
+	// AvalancheUpdateHistorySize is used if it is not positive.
+	UpdateHistorySize int
+
+	// TargetTypes are the target types accepted in inbound Polls. Any type is
+	// accepted if it is empty. They are sent to peers in the handshake; see
+	// Processor.GetPolicy.
+	TargetTypes []string
+
+	// MinFeeRate is the lowest fee rate, in satoshis per byte, of the
+	// transactions relayed. It is sent to peers in the handshake so they don't
+	// poll about transactions below it.
+	MinFeeRate float64
+
+	// SlowPeerThreshold is the p95 response time above which a peer is demoted
+	// to the probe pool. Peers are never demoted if it is not positive.
+	SlowPeerThreshold time.Duration
+
+	// SlowPeerProbeInterval is how many rounds pass between polls of demoted
+	// peers. AvalancheSlowPeerProbeInterval is used if it is not positive.
+	SlowPeerProbeInterval int
+
+	// MaxPollPeers is the most nodes that are polled. When the Connman has more,
+	// a random subset is polled. Every node is polled if it is not positive.
+	MaxPollPeers int
+
+	// MinPollSubnets is the fewest /16 subnets, or /32 for IPv6, the polled
+	// nodes should span when MaxPollPeers limits them to a subset, so an
+	// attacker with many addresses in one range can't dominate sampling.
+	// Candidates in new subnets are preferred until it is met. It is not
+	// enforced if it is not positive.
+	MinPollSubnets int
+
+	// MinPollASNs is the fewest autonomous systems the polled nodes should
+	// span, in the same way as MinPollSubnets. It needs an ASNProvider; see
+	// Processor.SetASNProvider.
+	MinPollASNs int
+
+	// PollRedundancy is the most nodes that may have outstanding queries about
+	// the same target at once, so a round spreads its polls across targets
+	// rather than over-sampling a few. There is no limit if it is not
+	// positive.
+	PollRedundancy int
+
+	// PeerRotationInterval is how often the slowest polled node is swapped for
+	// a random unpolled one, so sampling covers the network over time. Nodes
+	// are not rotated if it is not positive.
+	PeerRotationInterval time.Duration
+
+	// MaxPeerBytesPerSecond caps the poll and response traffic exchanged with
+	// each node. Polls shrink to fit the remaining budget rather than failing.
+	// There is no cap if it is not positive.
+	MaxPeerBytesPerSecond int
+
+	// MaxBytesPerSecond caps the poll and response traffic exchanged with all
+	// nodes together, in the same way. There is no cap if it is not positive.
+	MaxBytesPerSecond int
+
+	// GracePeriod is how long late votes on finalized targets are still
+	// counted, and how long a target may stay invalid before it is removed
+	// from polling and recorded as StatusInvalid. AvalancheGracePeriod is used
+	// if it is not positive.
+	GracePeriod time.Duration
+
+	// AgingWeight is how much a target's polling priority rises, in units of
+	// Target.Score, for each poll that leaves it out. It lets low scoring
+	// targets get polled even while higher scoring ones keep arriving.
+	// AvalancheAgingWeight is used if it is not positive.
+	AgingWeight int64
+
+	// MaxResponseVotes is the most Invs answered when handling a Poll. Polls
+	// with more get a truncated Response and the querier asks again for the
+	// rest. Every Inv is answered if it is not positive.
+	MaxResponseVotes int
+
+	// LikelyFinalFraction is the fraction of the finalization score at which a
+	// StatusUpdate with LikelyFinal set is sent, so consumers that can accept
+	// some risk may act before finalization. None are sent unless it is
+	// between 0 and 1.
+	LikelyFinalFraction float64
+
+	// Observer makes the Processor follow the network's decisions without
+	// influencing them, for explorers and monitoring services. It polls peers
+	// and reports finalization as usual, but ValidatePoll refuses inbound
+	// Polls with ErrObserver and HandlePoll votes VoteUnknown on everything.
+	Observer bool
+
+	// MaxVoteFlips is how many times a node may change its vote on a target
+	// before its votes on that target are ignored. Rapidly flipping votes can
+	// be used to stall a decision. Votes are never ignored if it is not
+	// positive.
+	MaxVoteFlips int
+
+	// AdaptiveQuorum is an experimental mode where the vote quorum is adjusted
+	// to network conditions every QuorumAdaptationInterval; see
+	// Processor.GetQuorumAdaptations. VoteQuorum is the starting point.
+	AdaptiveQuorum bool
+
+	// MinVoteQuorum is the lowest an adaptive quorum may go. It is never lower
+	// than a majority of the vote window, whatever this is set to.
+	MinVoteQuorum int
+
+	// MaxClockSkew is how far the median of peers' clocks may be from ours
+	// before ErrClockSkew is reported; see Processor.RecordPeerTime.
+	// AvalancheMaxClockSkew is used if it is not positive.
+	MaxClockSkew time.Duration
+
+	// SuspendBelowQuorum suspends finalization while fewer nodes are being
+	// polled than the vote quorum, since reaching it would take several votes
+	// from the same node. Targets that would have finalized are published with
+	// StatusSuspended and finalize once enough nodes return.
+	SuspendBelowQuorum bool
+
+	// QuorumAdaptationInterval is how often an adaptive quorum is reconsidered.
+	// AvalancheQuorumAdaptationInterval is used if it is not positive.
+	QuorumAdaptationInterval time.Duration
+
+	// ValidationConcurrency is how many raw targets submitted with
+	// Processor.SubmitRawTarget are validated at once.
+	// AvalancheValidationConcurrency is used if it is not positive.
+	ValidationConcurrency int
+
+	// MaxPendingValidations is the most raw targets that may wait for or
+	// undergo validation. There is no limit if it is not positive.
+	MaxPendingValidations int
+
+	// MaxHotRecords is the most vote records kept in memory when the
+	// Processor has a ColdStore. The rest are spilled to it; see
+	// Processor.SetColdStore. Every record is kept in memory if it is not
+	// positive.
+	MaxHotRecords int
+
+	// FinalizationCacheSize is how many finalized targets are remembered for
+	// answering polls once their grace period has ended and a reorg can no
+	// longer reach them; see Processor.HandleReorg. While remembered, a target
+	// is ignored by SubmitRawTarget, so polls from laggard peers don't get it
+	// fetched and reconciled again. AvalancheFinalizationCacheSize is used if
+	// it is not positive.
+	FinalizationCacheSize int
+
+	// DetectEclipse checks the polled nodes and their votes for signs of an
+	// eclipse attack, such as all of them sharing a subnet. Each sign found
+	// is reported as ErrEclipseSuspected, and StatusUpdates are Degraded
+	// until none remain; see Processor.GetEclipseConditions.
+	DetectEclipse bool
+
+	// Network is the network the Processor votes on. Polls and Responses from
+	// other networks are refused. MainNet is used if it is zero.
+	Network Network
+}
+
+// TypeConfig holds the voting thresholds for targets of one type. Those that
+// are not positive are taken from the Config.
+type TypeConfig struct {
+	VoteWindow        int
+	VoteQuorum        int
+	FinalizationScore int
+}
+
+// pollWindow returns the most queries that may be outstanding to a node
+func (c Config) pollWindow() int {
+	if c.PollWindow <= 0 {
+		return 1
+	}
+	return c.PollWindow
+}
+
+// voteParams returns the thresholds for new VoteRecords
+func (c Config) voteParams() voteParams {
+	return c.voteParamsFor(TypeConfig{c.VoteWindow, c.VoteQuorum, c.FinalizationScore})
+}
+
+// typeVoteParams returns the thresholds for new VoteRecords of the target type
+func (c Config) typeVoteParams(targetType string) voteParams {
+	tc, ok := c.Types[targetType]
+	if !ok {
+		return c.voteParams()
+	}
+	if tc.VoteWindow <= 0 {
+		tc.VoteWindow = c.VoteWindow
+	}
+	if tc.VoteQuorum <= 0 {
+		tc.VoteQuorum = c.VoteQuorum
+	}
+	if tc.FinalizationScore <= 0 {
+		tc.FinalizationScore = c.FinalizationScore
+	}
+	return c.voteParamsFor(tc)
+}
+
+// maxFinalizationScore is the highest confidence a VoteRecord can count to, as
+// it shares a uint16 with the accepted bit
+const maxFinalizationScore = math.MaxUint16 >> 1
+
+// voteParamsFor returns the thresholds for new VoteRecords using those of tc.
+// A FinalizationScore too high to be reached is capped.
+func (c Config) voteParamsFor(tc TypeConfig) voteParams {
+	params := defaultVoteParams
+	if tc.VoteWindow > 0 && tc.VoteWindow <= AvalancheVoteWindow {
+		params.mask = uint8(0xff >> uint(AvalancheVoteWindow-tc.VoteWindow))
+	}
+	if tc.VoteQuorum > 0 {
+		params.quorum = uint8(tc.VoteQuorum)
+	}
+	if tc.FinalizationScore > maxFinalizationScore {
+		params.finalizationScore = maxFinalizationScore
+	} else if tc.FinalizationScore > 0 {
+		params.finalizationScore = uint16(tc.FinalizationScore)
+	}
+	if c.LikelyFinalFraction > 0 && c.LikelyFinalFraction < 1 {
+		score := uint16(math.Ceil(c.LikelyFinalFraction * float64(params.finalizationScore)))
+		if score > 0 && score < params.finalizationScore {
+			params.likelyFinalScore = score
+		}
+	}
+	return params
+}
+
+// DefaultConfig is the Config used by NewProcessor
+var DefaultConfig = Config{
+	PollWindow:   1,
+	PollInterval: AvalancheTimeStep,
+	PollDebounce: AvalanchePollDebounce,
+}
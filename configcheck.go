@@ -0,0 +1,154 @@
+package avalanche
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigProblem is a single problem with a Config. Field names the offending
+// field, such as "VoteQuorum" or "Types[tx].VoteWindow".
+type ConfigProblem struct {
+	Field   string
+	Message string
+}
+
+// ConfigError lists every problem found in a Config by Validate
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+// Error implements error, reporting every problem on its own line
+func (e *ConfigError) Error() string {
+	lines := []string{"avalanche: invalid config:"}
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.Field+": "+p.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns ErrInvalidConfig
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate checks the Config for values out of range and settings that can't
+// work together, such as a vote quorum larger than the vote window. Rather
+// than stopping at the first, it returns a *ConfigError listing every
+// problem, so they can all be fixed before the next start. Zero values, which
+// take the defaults, are always valid.
+func (c Config) Validate() error {
+	e := &ConfigError{}
+	add := func(field, format string, args ...interface{}) {
+		e.Problems = append(e.Problems, ConfigProblem{field, fmt.Sprintf(format, args...)})
+	}
+
+	window, quorum, score := c.VoteWindow, c.VoteQuorum, c.FinalizationScore
+	c.checkVoteParams(add, "", window, quorum, score)
+
+	types := make([]string, 0, len(c.Types))
+	for targetType := range c.Types {
+		types = append(types, targetType)
+	}
+	sort.Strings(types)
+	for _, targetType := range types {
+		tc := c.Types[targetType]
+		prefix := "Types[" + targetType + "]."
+		if targetType == "" {
+			add("Types", "has an empty target type")
+		}
+
+		// Fields the type leaves unset are inherited, but only checked once
+		w, q, s := tc.VoteWindow, tc.VoteQuorum, tc.FinalizationScore
+		if w <= 0 && q <= 0 && s <= 0 {
+			continue
+		}
+		if w <= 0 {
+			w = window
+		}
+		if q <= 0 {
+			q = quorum
+		}
+		if s <= 0 {
+			s = score
+		}
+		c.checkVoteParams(add, prefix, w, q, s)
+	}
+
+	if c.AdaptiveQuorum && c.MinVoteQuorum > effectiveInt(quorum, AvalancheVoteQuorum) {
+		add("MinVoteQuorum", "is %d, above the VoteQuorum of %d the adaptive quorum starts from",
+			c.MinVoteQuorum, effectiveInt(quorum, AvalancheVoteQuorum))
+	}
+	if c.MinVoteQuorum > 0 && !c.AdaptiveQuorum {
+		add("MinVoteQuorum", "is set but has no effect without AdaptiveQuorum")
+	}
+	if c.SuspendBelowQuorum && c.MaxPollPeers > 0 && c.MaxPollPeers < effectiveInt(quorum, AvalancheVoteQuorum) {
+		add("MaxPollPeers", "is %d, below the vote quorum of %d, so SuspendBelowQuorum would suspend every finalization",
+			c.MaxPollPeers, effectiveInt(quorum, AvalancheVoteQuorum))
+	}
+
+	if c.MaxPollPeers > 0 && c.MinPollSubnets > c.MaxPollPeers {
+		add("MinPollSubnets", "is %d, more than the %d nodes polled by MaxPollPeers", c.MinPollSubnets, c.MaxPollPeers)
+	}
+	if c.MaxPollPeers > 0 && c.MinPollASNs > c.MaxPollPeers {
+		add("MinPollASNs", "is %d, more than the %d nodes polled by MaxPollPeers", c.MinPollASNs, c.MaxPollPeers)
+	}
+
+	if c.LikelyFinalFraction < 0 || c.LikelyFinalFraction >= 1 {
+		add("LikelyFinalFraction", "is %g; it must be from 0 to 1, with 0 disabling likely final updates", c.LikelyFinalFraction)
+	}
+	if c.MinFeeRate < 0 {
+		add("MinFeeRate", "is %g; it can't be negative", c.MinFeeRate)
+	}
+
+	minBytes := pollWireOverhead + responseWireOverhead + invWireSize + voteWireSize
+	if c.MaxPeerBytesPerSecond > 0 && c.MaxPeerBytesPerSecond < minBytes {
+		add("MaxPeerBytesPerSecond", "is %d, too few for a poll of a single Inv, which needs %d", c.MaxPeerBytesPerSecond, minBytes)
+	}
+	if c.MaxBytesPerSecond > 0 && c.MaxBytesPerSecond < minBytes {
+		add("MaxBytesPerSecond", "is %d, too few for a poll of a single Inv, which needs %d", c.MaxBytesPerSecond, minBytes)
+	}
+
+	seen := map[string]struct{}{}
+	for _, targetType := range c.TargetTypes {
+		if _, ok := seen[targetType]; ok || targetType == "" {
+			add("TargetTypes", "has an empty or repeated type %q", targetType)
+		}
+		seen[targetType] = struct{}{}
+	}
+
+	if len(e.Problems) > 0 {
+		return e
+	}
+	return nil
+}
+
+// checkVoteParams adds the problems with a vote window, quorum and
+// finalization score, with defaults taken for those that are not positive
+func (c Config) checkVoteParams(add func(string, string, ...interface{}), prefix string, window, quorum, score int) {
+	if window > AvalancheVoteWindow {
+		add(prefix+"VoteWindow", "is %d; it can be at most %d", window, AvalancheVoteWindow)
+		return
+	}
+
+	w := effectiveInt(window, AvalancheVoteWindow)
+	q := effectiveInt(quorum, AvalancheVoteQuorum)
+	switch {
+	case q > w:
+		add(prefix+"VoteQuorum", "is %d, more votes than the window of %d holds, so nothing would finalize", q, w)
+	case q*2 <= w:
+		add(prefix+"VoteQuorum", "is %d, not a majority of the window of %d, so both outcomes could reach it", q, w)
+	}
+
+	if score > maxFinalizationScore {
+		add(prefix+"FinalizationScore", "is %d; it can be at most %d", score, maxFinalizationScore)
+	}
+}
+
+// effectiveInt returns v, or def if v is not positive
+func effectiveInt(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package avalanche
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	assertTrue(t, DefaultConfig.Validate() == nil)
+	for _, name := range PresetNames() {
+		config, _ := GetPreset(name)
+		if err := config.Validate(); err != nil {
+			t.Fatal("Expected preset", name, "to be valid but got", err)
+		}
+	}
+
+	// Every problem is reported, not just the first
+	config := DefaultConfig
+	config.VoteWindow = 4
+	config.VoteQuorum = 5
+	config.FinalizationScore = maxFinalizationScore + 1
+	config.Types = map[string]TypeConfig{"block": {VoteWindow: 9}, "tx": {VoteQuorum: 2, FinalizationScore: 1 << 15}}
+	config.MinVoteQuorum = 3
+	config.SuspendBelowQuorum = true
+	config.MaxPollPeers = 2
+	config.MinPollSubnets = 3
+	config.MaxPeerBytesPerSecond = 10
+	config.TargetTypes = []string{"tx", "tx"}
+
+	err := config.Validate()
+	e, ok := err.(*ConfigError)
+	if !ok || e.Unwrap() != ErrInvalidConfig {
+		t.Fatal("Expected a *ConfigError but got", err)
+	}
+
+	fields := []string{}
+	for _, p := range e.Problems {
+		fields = append(fields, p.Field)
+	}
+	expected := []string{"VoteQuorum", "FinalizationScore", "Types[block].VoteWindow", "Types[tx].VoteQuorum",
+		"Types[tx].FinalizationScore", "MinVoteQuorum", "MaxPollPeers", "MinPollSubnets", "MaxPeerBytesPerSecond",
+		"TargetTypes"}
+	if strings.Join(fields, ",") != strings.Join(expected, ",") {
+		t.Fatal("Expected problems with", expected, "but got", fields)
+	}
+	assertTrue(t, strings.Count(err.Error(), "\n") == len(expected))
+}
@@ -0,0 +1,63 @@
+package avalanche
+
+// ConflictSource finds the targets that conflict with a target, such as the
+// transactions spending any of the same outputs as a transaction
+type ConflictSource interface {
+	Conflicts(Hash) []Hash
+}
+
+// ConflictHandler is called when a node rejects a target we are voting on
+// because it conflicts with another it has accepted, so the conflicting target
+// can be fetched and evaluated
+type ConflictHandler func(id NodeID, target Hash, conflict Hash)
+
+// SetConflictSource sets where HandlePoll looks up conflicts. Polled targets we
+// don't accept that conflict with one we have accepted or finalized are voted
+// VoteConflict, naming that target.
+func (p *Processor) SetConflictSource(src ConflictSource) {
+	p.conflicts = src
+}
+
+// OnConflict sets the handler for conflicts reported in responses. It is
+// called once for each conflict reported for a target, from the goroutine
+// calling RegisterVotes, so it should be quick.
+func (p *Processor) OnConflict(handler ConflictHandler) {
+	p.conflictHandler = handler
+}
+
+// getConflictVote returns our vote on h, as VoteConflict if we don't accept it
+// but have accepted a target it conflicts with
+func (p *Processor) getConflictVote(h Hash) Vote {
+	vote := p.getVote(h)
+	if vote == VoteYes || p.conflicts == nil {
+		return NewVote(vote, h)
+	}
+
+	for _, c := range p.conflicts.Conflicts(h) {
+		if c != h && p.getVote(c) == VoteYes {
+			return NewConflictVote(h, c)
+		}
+	}
+	return NewVote(vote, h)
+}
+
+// reportConflict passes a conflict named in a vote to the ConflictHandler the
+// first time it is reported for the target
+func (p *Processor) reportConflict(id NodeID, v Vote) {
+	conflict, ok := v.GetConflict()
+	if !ok || p.conflictHandler == nil {
+		return
+	}
+
+	reported := p.reportedConflicts[v.GetHash()]
+	if _, ok := reported[conflict]; ok {
+		return
+	}
+	if reported == nil {
+		reported = map[Hash]struct{}{}
+		p.reportedConflicts[v.GetHash()] = reported
+	}
+	reported[conflict] = struct{}{}
+
+	p.conflictHandler(id, v.GetHash(), conflict)
+}
@@ -0,0 +1,74 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type stubConflicts map[Hash][]Hash
+
+func (c stubConflicts) Conflicts(h Hash) []Hash { return c[h] }
+
+func TestConflictVotes(t *testing.T) {
+	var (
+		p        = NewProcessor(NewConnman())
+		accepted = &Block{Hash(1), 0, true, true}
+		rejected = &Block{Hash(2), 0, true, false}
+	)
+	assertTrue(t, p.AddTargetToReconcile(accepted))
+	assertTrue(t, p.AddTargetToReconcile(rejected))
+	p.recordFinalization(Hash(3), StatusFinalized)
+
+	// Hash(4) conflicts with a target we accepted, Hash(5) with one we
+	// finalized, and Hash(6) only with ones we reject or don't know
+	p.SetConflictSource(stubConflicts{
+		Hash(4): {Hash(7), Hash(1)},
+		Hash(5): {Hash(3)},
+		Hash(6): {Hash(2), Hash(8)},
+		Hash(1): {Hash(4)},
+	})
+
+	resp := p.HandlePoll(NodeID(0), NewPoll(0, []Inv{
+		{"block", Hash(4)}, {"block", Hash(5)}, {"block", Hash(6)}, {"block", Hash(1)}, {"block", Hash(2)},
+	}))
+	assertVotes(t, resp, []Vote{
+		NewConflictVote(Hash(4), Hash(1)),
+		NewConflictVote(Hash(5), Hash(3)),
+		NewVote(VoteUnknown, Hash(6)),
+		NewVote(VoteYes, Hash(1)),
+		NewVote(VoteNo, Hash(2)),
+	})
+
+	// The conflict survives the wire
+	data, err := json.Marshal(resp.GetVotes()[0])
+	assertTrue(t, err == nil && string(data) == `{"error":2,"hash":4,"conflict":1}`)
+	decoded := Vote{}
+	assertTrue(t, json.Unmarshal(data, &decoded) == nil)
+	conflict, ok := decoded.GetConflict()
+	assertTrue(t, ok && conflict == Hash(1))
+	_, ok = NewVote(VoteNo, Hash(1)).GetConflict()
+	assertFalse(t, ok)
+}
+
+func TestConflictReports(t *testing.T) {
+	var (
+		p        = NewProcessor(NewConnman())
+		target   = &Block{Hash(1), 0, true, true}
+		updates  = []StatusUpdate{}
+		reported = [][3]int{}
+	)
+	p.OnConflict(func(id NodeID, h Hash, conflict Hash) {
+		reported = append(reported, [3]int{int(id), int(h), int(conflict)})
+	})
+	assertTrue(t, p.AddTargetToReconcile(target))
+
+	// Conflict votes count as no, and each conflict is reported once
+	resp := NewResponse(0, 0, []Vote{NewConflictVote(Hash(1), Hash(9))})
+	for i := 0; i < AvalancheVoteQuorum; i++ {
+		registerVotes(p, NodeID(i), resp, &updates)
+	}
+	assertFalse(t, p.IsAccepted(target))
+	if len(reported) != 1 || reported[0] != [3]int{0, 1, 9} {
+		t.Fatal("Expected the conflict to be reported once but got", reported)
+	}
+}
@@ -0,0 +1,27 @@
+package avalanche
+
+// Consensus is the set of operations used to drive the Avalanche process. It
+// is implemented by *Processor, and by a mock in the avalanchetest package so
+// integrators can test their code without running real voting.
+type Consensus interface {
+	// AddTargetToReconcile begins the voting process for a given target
+	AddTargetToReconcile(Target) bool
+
+	// RemoveTarget withdraws a target from the voting process
+	RemoveTarget(Hash, WithdrawReason) bool
+
+	// GetInvsForNextPoll returns Invs for outstanding items that need to be
+	// resolved by further queries
+	GetInvsForNextPoll() []Inv
+
+	// RegisterVotes processes responses to queries
+	RegisterVotes(NodeID, Response, *[]StatusUpdate) bool
+
+	// Subscribe returns a channel that receives StatusUpdates
+	Subscribe() <-chan StatusUpdate
+
+	// Stop ends the poll/response cycle
+	Stop() bool
+}
+
+var _ Consensus = (*Processor)(nil)
@@ -0,0 +1,127 @@
+package avalanche
+
+import "sync"
+
+// Cursor reads StatusUpdates from a Processor's bounded history of recent
+// updates. Each Cursor moves independently, so a slow consumer never holds up
+// voting or other consumers; if it falls more than the history size behind it
+// skips ahead and the skipped updates are counted as dropped. A reconnecting
+// consumer can resume from a saved position with CursorAt.
+type Cursor struct {
+	ring    *updateRing
+	pos     uint64
+	dropped uint64
+}
+
+// NewCursor returns a Cursor that reads updates published from now on
+func (p *Processor) NewCursor() *Cursor {
+	return p.CursorAt(p.history.head())
+}
+
+// CursorAt returns a Cursor that reads updates starting at pos, as returned by
+// Cursor.GetPosition. Updates no longer in the history are counted as dropped.
+func (p *Processor) CursorAt(pos uint64) *Cursor {
+	return &Cursor{ring: p.history, pos: pos}
+}
+
+// Next returns the next update and advances the cursor. It returns false if
+// there are no updates past the cursor yet.
+func (c *Cursor) Next() (StatusUpdate, bool) {
+	update, pos, skipped, ok := c.ring.read(c.pos)
+	c.dropped += skipped
+	c.pos = pos
+	if ok {
+		c.pos++
+	}
+	return update, ok
+}
+
+// Wait returns a channel that is closed once there is an update past the
+// cursor. It is meant to be used with select when Next returns false.
+func (c *Cursor) Wait() <-chan struct{} {
+	return c.ring.wait(c.pos)
+}
+
+// GetPosition returns the position of the next update the cursor will read
+func (c *Cursor) GetPosition() uint64 {
+	return c.pos
+}
+
+// GetDropped returns how many updates the cursor skipped because they left the
+// history before being read
+func (c *Cursor) GetDropped() uint64 {
+	return c.dropped
+}
+
+// updateRing is a fixed size ring buffer of StatusUpdates. Each update has a
+// position that increases by one with every update appended.
+type updateRing struct {
+	mu     sync.Mutex
+	buf    []StatusUpdate
+	next   uint64
+	notify chan struct{}
+}
+
+func newUpdateRing(size int) *updateRing {
+	return &updateRing{
+		buf:    make([]StatusUpdate, size),
+		notify: make(chan struct{}),
+	}
+}
+
+// append adds an update, overwriting the oldest if the ring is full, and wakes
+// any waiters
+func (r *updateRing) append(update StatusUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf[r.next%uint64(len(r.buf))] = update
+	r.next++
+
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// head returns the position the next update will be appended at
+func (r *updateRing) head() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.next
+}
+
+// read returns the first update still held at or after pos, its position, and
+// how many updates before it were overwritten
+func (r *updateRing) read(pos uint64) (update StatusUpdate, at uint64, skipped uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if oldest := r.oldest(); pos < oldest {
+		skipped = oldest - pos
+		pos = oldest
+	}
+	if pos >= r.next {
+		return StatusUpdate{}, pos, skipped, false
+	}
+	return r.buf[pos%uint64(len(r.buf))], pos, skipped, true
+}
+
+// wait returns a channel that is closed once an update exists at pos
+func (r *updateRing) wait(pos uint64) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pos < r.next {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
+	}
+	return r.notify
+}
+
+// oldest returns the position of the oldest update held
+func (r *updateRing) oldest() uint64 {
+	if r.next < uint64(len(r.buf)) {
+		return 0
+	}
+	return r.next - uint64(len(r.buf))
+}
@@ -0,0 +1,64 @@
+package avalanche
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	config := DefaultConfig
+	config.UpdateHistorySize = 4
+	p := NewProcessorWithConfig(NewConnman(), config)
+
+	early := p.NewCursor()
+	_, ok := early.Next()
+	assertFalse(t, ok)
+
+	wait := early.Wait()
+	select {
+	case <-wait:
+		t.Fatal("Expected Wait to block with no updates")
+	default:
+	}
+
+	for i := 1; i <= 3; i++ {
+		p.publish(StatusUpdate{Hash: Hash(i), Status: StatusAccepted})
+	}
+
+	// Waiters are woken by new updates
+	select {
+	case <-wait:
+	default:
+		t.Fatal("Expected Wait to be woken")
+	}
+
+	// A cursor created now only sees later updates
+	late := p.NewCursor()
+	assertTrue(t, late.GetPosition() == 3)
+	_, ok = late.Next()
+	assertFalse(t, ok)
+
+	// Cursors move independently
+	u, ok := early.Next()
+	assertTrue(t, ok && u.Hash == 1)
+	pos := early.GetPosition()
+
+	for i := 4; i <= 6; i++ {
+		p.publish(StatusUpdate{Hash: Hash(i), Status: StatusAccepted})
+	}
+
+	u, ok = late.Next()
+	assertTrue(t, ok && u.Hash == 4)
+
+	// A reconnecting consumer resumes from its saved position, skipping
+	// updates that have left the history
+	resumed := p.CursorAt(pos)
+	u, ok = resumed.Next()
+	assertTrue(t, ok && u.Hash == 3)
+	assertTrue(t, resumed.GetDropped() == 1)
+
+	for i := 4; i <= 6; i++ {
+		u, ok = resumed.Next()
+		assertTrue(t, ok && u.Hash == Hash(i))
+	}
+	_, ok = resumed.Next()
+	assertFalse(t, ok)
+	assertTrue(t, resumed.GetDropped() == 1)
+}
@@ -0,0 +1,191 @@
+package avalanche
+
+import (
+	"net"
+	"sort"
+)
+
+// ASNProvider looks up the autonomous system an IP address is announced by,
+// such as from a GeoIP/ASN database. It returns false if the address is not
+// in the database.
+type ASNProvider interface {
+	LookupASN(ip net.IP) (uint32, bool)
+}
+
+// ASNProviderFunc allows a plain function to be used as an ASNProvider
+type ASNProviderFunc func(net.IP) (uint32, bool)
+
+// LookupASN calls f(ip)
+func (f ASNProviderFunc) LookupASN(ip net.IP) (uint32, bool) {
+	return f(ip)
+}
+
+// SetASNProvider sets the provider used to find the ASNs of nodes for
+// Config.MinPollASNs. It must be called before the *Processor is started.
+func (p *Processor) SetASNProvider(provider ASNProvider) {
+	p.asnProvider = provider
+}
+
+// GetPollPeerDiversity returns how many distinct subnets and ASNs the nodes
+// being polled span. Subnets are /16 for IPv4 and /32 for IPv6. Nodes without
+// an IP address are not counted, and neither are ASNs without an
+// ASNProvider.
+func (p *Processor) GetPollPeerDiversity() (subnets int, asns int) {
+	s, a := p.pollPeerGroups(p.getPollPeers())
+	return len(s), len(a)
+}
+
+// needsDiversity returns whether the nodes being polled span fewer subnets or
+// ASNs than the Config asks for
+func (p *Processor) needsDiversity(subnets map[string]int, asns map[uint32]int) (bool, bool) {
+	needSubnets := p.config.MinPollSubnets > 0 && len(subnets) < p.config.MinPollSubnets
+	needASNs := p.config.MinPollASNs > 0 && p.asnProvider != nil && len(asns) < p.config.MinPollASNs
+	return needSubnets, needASNs
+}
+
+// pollPeerGroups counts the nodes in each subnet and ASN
+func (p *Processor) pollPeerGroups(nodeIDs []NodeID) (map[string]int, map[uint32]int) {
+	subnets, asns := map[string]int{}, map[uint32]int{}
+	for _, id := range nodeIDs {
+		ip := p.nodeIP(id)
+		if subnet, ok := subnetOf(ip); ok {
+			subnets[subnet]++
+		}
+		if asn, ok := p.asnOf(ip); ok {
+			asns[asn]++
+		}
+	}
+	return subnets, asns
+}
+
+// preferDiverse narrows the unpolled candidates down to those in a subnet or
+// ASN not yet polled, while the nodes being polled span too few of them. All
+// of the candidates are returned if none would help.
+func (p *Processor) preferDiverse(unpolled []NodeID) []NodeID {
+	if p.config.MinPollSubnets <= 0 && p.config.MinPollASNs <= 0 {
+		return unpolled
+	}
+
+	subnets, asns := p.pollPeerGroups(p.pollPeerIDs())
+	needSubnets, needASNs := p.needsDiversity(subnets, asns)
+	if !needSubnets && !needASNs {
+		return unpolled
+	}
+
+	diverse := make([]NodeID, 0, len(unpolled))
+	for _, id := range unpolled {
+		if p.addsDiversity(id, subnets, asns, needSubnets, needASNs) {
+			diverse = append(diverse, id)
+		}
+	}
+	if len(diverse) == 0 {
+		return unpolled
+	}
+	return diverse
+}
+
+// addsDiversity returns whether the node is in a subnet or ASN that is needed
+// and not yet polled
+func (p *Processor) addsDiversity(id NodeID, subnets map[string]int, asns map[uint32]int, needSubnets, needASNs bool) bool {
+	ip := p.nodeIP(id)
+	if subnet, ok := subnetOf(ip); ok && needSubnets && subnets[subnet] == 0 {
+		return true
+	}
+	asn, ok := p.asnOf(ip)
+	return ok && needASNs && asns[asn] == 0
+}
+
+// diversifyPollPeers swaps a polled node for an unpolled one in a new subnet
+// or ASN while the nodes being polled span fewer than the Config asks for.
+// Only unpinned nodes whose subnet and ASN are shared with another polled
+// node are swapped out, so each swap adds to the diversity without losing
+// any. One node is swapped per call.
+func (p *Processor) diversifyPollPeers() {
+	if p.config.MaxPollPeers <= 0 || (p.config.MinPollSubnets <= 0 && p.config.MinPollASNs <= 0) {
+		return
+	}
+
+	nodeIDs := p.getPollPeers()
+	if len(nodeIDs) < p.config.MaxPollPeers {
+		// getPollPeers already prefers diverse candidates to fill the set
+		return
+	}
+	subnets, asns := p.pollPeerGroups(nodeIDs)
+	needSubnets, needASNs := p.needsDiversity(subnets, asns)
+	if !needSubnets && !needASNs {
+		return
+	}
+
+	var replacement NodeID = NoNode
+	for _, id := range p.connman.NodesIDs() {
+		if _, ok := p.pollPeers[id]; ok {
+			continue
+		}
+		if p.addsDiversity(id, subnets, asns, needSubnets, needASNs) && (replacement == NoNode || id < replacement) {
+			replacement = id
+		}
+	}
+	if replacement == NoNode {
+		return
+	}
+
+	sort.Sort(nodesInRequestOrder(nodeIDs))
+	for _, id := range nodeIDs {
+		if p.isPinned(id) {
+			continue
+		}
+		ip := p.nodeIP(id)
+		if subnet, ok := subnetOf(ip); ok && subnets[subnet] == 1 {
+			continue
+		}
+		if asn, ok := p.asnOf(ip); ok && asns[asn] == 1 {
+			continue
+		}
+
+		delete(p.pollPeers, id)
+		p.pollPeers[replacement] = struct{}{}
+		return
+	}
+}
+
+// pollPeerIDs returns the nodes in the poll set without refreshing it
+func (p *Processor) pollPeerIDs() []NodeID {
+	nodeIDs := make([]NodeID, 0, len(p.pollPeers))
+	for id := range p.pollPeers {
+		nodeIDs = append(nodeIDs, id)
+	}
+	return nodeIDs
+}
+
+// nodeIP returns the IP address of the node, or nil if it has none
+func (p *Processor) nodeIP(id NodeID) net.IP {
+	n, ok := p.connman.nodes[id]
+	if !ok {
+		return nil
+	}
+	host := n.addr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return net.ParseIP(host)
+}
+
+// asnOf returns the ASN of the IP address, if there is an ASNProvider that
+// knows it
+func (p *Processor) asnOf(ip net.IP) (uint32, bool) {
+	if ip == nil || p.asnProvider == nil {
+		return 0, false
+	}
+	return p.asnProvider.LookupASN(ip)
+}
+
+// subnetOf returns the /16 of an IPv4 address or the /32 of an IPv6 one
+func subnetOf(ip net.IP) (string, bool) {
+	if ip == nil {
+		return "", false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String() + "/16", true
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String() + "/32", true
+}
@@ -0,0 +1,64 @@
+package avalanche
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPollPeerDiversity(t *testing.T) {
+	connman := NewConnman()
+	for i, addr := range []string{"10.0.0.1:8333", "10.0.0.2:8333", "10.0.1.1:8333", "10.0.2.1:8333", "10.1.0.1:8333", "[2001:db8::1]:8333"} {
+		assertTrue(t, connman.AddNodeWithAddr(NodeID(i), addr))
+	}
+
+	// Candidates in new subnets are preferred while too few are polled
+	p := NewProcessorWithConfig(connman, Config{MaxPollPeers: 3, MinPollSubnets: 3})
+	assertTrue(t, len(p.GetPollPeers()) == 3)
+	subnets, asns := p.GetPollPeerDiversity()
+	assertTrue(t, subnets == 3 && asns == 0)
+
+	// Without the constraint the set can stay in one subnet
+	p = NewProcessorWithConfig(connman, Config{MaxPollPeers: 3})
+	p.pollPeers = map[NodeID]struct{}{0: {}, 1: {}, 2: {}}
+	p.diversifyPollPeers()
+	subnets, _ = p.GetPollPeerDiversity()
+	assertTrue(t, subnets == 1)
+
+	// With it, polled nodes sharing a subnet are swapped out one at a time,
+	// except for pinned ones
+	p.config.MinPollSubnets = 3
+	p.PinPeer(NodeID(0))
+	p.diversifyPollPeers()
+	subnets, _ = p.GetPollPeerDiversity()
+	assertTrue(t, subnets == 2)
+	p.diversifyPollPeers()
+	subnets, _ = p.GetPollPeerDiversity()
+	assertTrue(t, subnets == 3)
+	_, ok := p.pollPeers[NodeID(0)]
+	assertTrue(t, ok)
+	p.diversifyPollPeers()
+	subnets, _ = p.GetPollPeerDiversity()
+	assertTrue(t, subnets == 3)
+}
+
+func TestPollPeerASNDiversity(t *testing.T) {
+	connman := NewConnman()
+	for i, addr := range []string{"10.0.0.1:8333", "10.1.0.1:8333", "10.2.0.1:8333", "11.0.0.1:8333"} {
+		assertTrue(t, connman.AddNodeWithAddr(NodeID(i), addr))
+	}
+
+	// ASNs are only counted with a provider
+	p := NewProcessorWithConfig(connman, Config{MaxPollPeers: 2, MinPollASNs: 2})
+	_, asns := p.GetPollPeerDiversity()
+	assertTrue(t, asns == 0)
+
+	for seed := int64(0); seed < 8; seed++ {
+		p = NewProcessorWithConfig(connman, Config{MaxPollPeers: 2, MinPollASNs: 2})
+		p.rng.Seed(seed)
+		p.SetASNProvider(ASNProviderFunc(func(ip net.IP) (uint32, bool) {
+			return uint32(ip.To4()[0]), true
+		}))
+		_, asns = p.GetPollPeerDiversity()
+		assertTrue(t, asns == 2)
+	}
+}
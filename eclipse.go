@@ -0,0 +1,154 @@
+package avalanche
+
+import (
+	"strconv"
+	"time"
+)
+
+// EclipseCondition is a sign that the nodes we poll may all be controlled by
+// an attacker, so that our view of the network is theirs
+type EclipseCondition int
+
+const (
+	// EclipseOneSubnet is set when every polled node with an address is in
+	// one subnet, or one ASN with an ASNProvider
+	EclipseOneSubnet EclipseCondition = iota + 1
+
+	// EclipsePeerTurnover is set when more than
+	// AvalancheEclipseMaxTurnover of the polled nodes were replaced within a
+	// check interval
+	EclipsePeerTurnover
+
+	// EclipseUnanimousDissent is set when every yes or no vote within a check
+	// interval, at least AvalancheEclipseMinVotes of them, disagreed with our
+	// own initial view of the target
+	EclipseUnanimousDissent
+)
+
+// String returns the condition's name as used in error tags
+func (c EclipseCondition) String() string {
+	switch c {
+	case EclipseOneSubnet:
+		return "one_subnet"
+	case EclipsePeerTurnover:
+		return "peer_turnover"
+	case EclipseUnanimousDissent:
+		return "unanimous_dissent"
+	}
+	return "unknown"
+}
+
+// eclipseState is what checkEclipse compares against from one check to the
+// next
+type eclipseState struct {
+	next       time.Time
+	conditions map[EclipseCondition]struct{}
+
+	// peers are the nodes polled at the last check
+	peers map[NodeID]struct{}
+
+	// votes are the yes or no votes since the last check and dissent those
+	// that disagreed with our initial view
+	votes   int
+	dissent int
+}
+
+// GetEclipseConditions returns the conditions found by the last check; see
+// Config.DetectEclipse
+func (p *Processor) GetEclipseConditions() []EclipseCondition {
+	conditions := []EclipseCondition{}
+	for c := EclipseOneSubnet; c <= EclipseUnanimousDissent; c++ {
+		if _, ok := p.eclipse.conditions[c]; ok {
+			conditions = append(conditions, c)
+		}
+	}
+	return conditions
+}
+
+// IsDegraded returns whether confidence in our results is degraded because
+// the last check found signs of an eclipse attack. StatusUpdates sent
+// meanwhile have Degraded set.
+func (p *Processor) IsDegraded() bool {
+	return len(p.eclipse.conditions) > 0
+}
+
+// countEclipseVote counts a vote towards EclipseUnanimousDissent
+func (p *Processor) countEclipseVote(err uint32, t Target) {
+	if !p.config.DetectEclipse || isUnknownVote(err) {
+		return
+	}
+	p.eclipse.votes++
+	if (err == VoteYes) != t.IsAccepted() {
+		p.eclipse.dissent++
+	}
+}
+
+// checkEclipse looks for signs of an eclipse attack once every
+// AvalancheEclipseCheckInterval. Each condition is reported as
+// ErrEclipseSuspected when it starts, and the results are degraded until a
+// check finds none of them.
+func (p *Processor) checkEclipse() {
+	if !p.config.DetectEclipse {
+		return
+	}
+
+	now := clock.Now()
+	if now.Before(p.eclipse.next) {
+		return
+	}
+	p.eclipse.next = now.Add(AvalancheEclipseCheckInterval)
+
+	nodeIDs := p.getPollPeers()
+	conditions := map[EclipseCondition]struct{}{}
+	tags := map[EclipseCondition]map[string]string{}
+
+	subnets, asns := p.pollPeerGroups(nodeIDs)
+	addressed, withASN := 0, 0
+	for _, n := range subnets {
+		addressed += n
+	}
+	for _, n := range asns {
+		withASN += n
+	}
+	oneASN := len(asns) == 1 && withASN == addressed
+	if addressed >= AvalancheEclipseMinPeers && (len(subnets) == 1 || oneASN) {
+		conditions[EclipseOneSubnet] = struct{}{}
+		tags[EclipseOneSubnet] = map[string]string{"peers": strconv.Itoa(addressed)}
+	}
+
+	peers := make(map[NodeID]struct{}, len(nodeIDs))
+	for _, id := range nodeIDs {
+		peers[id] = struct{}{}
+	}
+	if len(p.eclipse.peers) >= AvalancheEclipseMinPeers {
+		replaced := 0
+		for id := range p.eclipse.peers {
+			if _, ok := peers[id]; !ok {
+				replaced++
+			}
+		}
+		if float64(replaced) > AvalancheEclipseMaxTurnover*float64(len(p.eclipse.peers)) {
+			conditions[EclipsePeerTurnover] = struct{}{}
+			tags[EclipsePeerTurnover] = map[string]string{
+				"replaced": strconv.Itoa(replaced),
+				"peers":    strconv.Itoa(len(p.eclipse.peers)),
+			}
+		}
+	}
+	p.eclipse.peers = peers
+
+	if p.eclipse.votes >= AvalancheEclipseMinVotes && p.eclipse.dissent == p.eclipse.votes {
+		conditions[EclipseUnanimousDissent] = struct{}{}
+		tags[EclipseUnanimousDissent] = map[string]string{"votes": strconv.Itoa(p.eclipse.votes)}
+	}
+	p.eclipse.votes, p.eclipse.dissent = 0, 0
+
+	for c := EclipseOneSubnet; c <= EclipseUnanimousDissent; c++ {
+		_, found := conditions[c]
+		if _, ok := p.eclipse.conditions[c]; found && !ok {
+			tags[c]["condition"] = c.String()
+			reportError(p.reporter, &Error{"check eclipse", ErrEclipseSuspected}, tags[c])
+		}
+	}
+	p.eclipse.conditions = conditions
+}
@@ -0,0 +1,69 @@
+package avalanche
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEclipseDetection(t *testing.T) {
+	now := time.Now()
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	connman := NewConnman()
+	for i := 0; i < 4; i++ {
+		assertTrue(t, connman.AddNodeWithAddr(NodeID(i), fmt.Sprintf("10.0.%d.1:8333", i)))
+	}
+	p := NewProcessorWithConfig(connman, Config{DetectEclipse: true})
+	reported := []string{}
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		if e, ok := err.(*Error); ok && e.Err == ErrEclipseSuspected {
+			reported = append(reported, tags["condition"])
+		}
+	}))
+
+	// Every node in one subnet
+	p.checkEclipse()
+	conditions := p.GetEclipseConditions()
+	assertTrue(t, len(conditions) == 1 && conditions[0] == EclipseOneSubnet && p.IsDegraded())
+	assertTrue(t, len(reported) == 1 && reported[0] == "one_subnet")
+
+	// Updates sent meanwhile are degraded
+	updates := p.Subscribe()
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(1), 1, true, true}))
+	assertTrue(t, p.RemoveTarget(Hash(1), WithdrawReason("test")))
+	assertTrue(t, (<-updates).Degraded)
+
+	// Conditions are only checked once per interval
+	for i := 0; i < 3; i++ {
+		connman.RemoveNode(NodeID(i))
+	}
+	p.checkEclipse()
+	assertTrue(t, len(p.GetEclipseConditions()) == 1)
+
+	// Most nodes replaced and every vote against our view
+	for i := 4; i < 8; i++ {
+		assertTrue(t, connman.AddNodeWithAddr(NodeID(i), fmt.Sprintf("10.%d.0.1:8333", i)))
+	}
+	target := &Block{Hash(2), 1, true, true}
+	for i := 0; i < AvalancheEclipseMinVotes; i++ {
+		p.countEclipseVote(VoteNo, target)
+	}
+	clock = stubClocker{now.Add(AvalancheEclipseCheckInterval)}
+	p.checkEclipse()
+	conditions = p.GetEclipseConditions()
+	assertTrue(t, len(conditions) == 2 && conditions[0] == EclipsePeerTurnover && conditions[1] == EclipseUnanimousDissent)
+	assertTrue(t, len(reported) == 3)
+
+	// A single agreeing vote breaks unanimity, and conditions clear once
+	// they no longer hold
+	for i := 0; i < AvalancheEclipseMinVotes; i++ {
+		p.countEclipseVote(VoteNo, target)
+	}
+	p.countEclipseVote(VoteYes, target)
+	clock = stubClocker{now.Add(2 * AvalancheEclipseCheckInterval)}
+	p.checkEclipse()
+	assertTrue(t, len(p.GetEclipseConditions()) == 0 && !p.IsDegraded())
+	assertTrue(t, len(reported) == 3)
+}
@@ -0,0 +1,120 @@
+package avalanche
+
+import "errors"
+
+// Sentinel errors returned by the package. They are returned as is, or as the
+// cause of an *Error or *PollError, so callers can check for them with ==
+// after unwrapping or with errors.Is.
+var (
+	// ErrPollRefused is returned for a Poll that a *PollServer has no room for
+	ErrPollRefused = errors.New("avalanche: poll refused")
+
+	// ErrInvalidPoll is the cause of every *PollError
+	ErrInvalidPoll = errors.New("avalanche: invalid poll")
+
+	// ErrInvalidResponse is the cause of every *ResponseError
+	ErrInvalidResponse = errors.New("avalanche: invalid response")
+
+	// ErrSnapshotMismatch is returned when restoring a snapshot taken with
+	// different voting parameters than the running config
+	ErrSnapshotMismatch = errors.New("avalanche: snapshot parameters do not match config")
+
+	// ErrSnapshotVersion is returned when restoring a snapshot written in a
+	// format this version does not understand
+	ErrSnapshotVersion = errors.New("avalanche: unsupported snapshot version")
+
+	// ErrInvalidSketch is returned when decoding a malformed TargetSketch
+	ErrInvalidSketch = errors.New("avalanche: invalid sketch length")
+
+	// ErrObserver is returned by ValidatePoll on a Processor in observer mode,
+	// which doesn't answer Polls
+	ErrObserver = errors.New("avalanche: observers do not answer polls")
+
+	// ErrProtocolVersion is returned when decoding a Poll or Response encoded
+	// with a different ProtocolVersion
+	ErrProtocolVersion = errors.New("avalanche: unsupported protocol version")
+
+	// ErrTooManyAddrs is returned when decoding an AddrMessage with more than
+	// AvalancheMaxAddrs addresses
+	ErrTooManyAddrs = errors.New("avalanche: too many addresses")
+
+	// ErrClockSkew is reported when the local clock is too far from the
+	// median of peers' clocks
+	ErrClockSkew = errors.New("avalanche: local clock is skewed from peers")
+
+	// ErrInvalidHash is returned when decoding a hash that is not HashSize
+	// bytes
+	ErrInvalidHash = errors.New("avalanche: invalid hash")
+
+	// ErrQuorumLost is reported when finalization is suspended because fewer
+	// nodes are being polled than the vote quorum
+	ErrQuorumLost = errors.New("avalanche: too few peers for the vote quorum")
+
+	// ErrAddrBookVersion is returned when loading an address book written in a
+	// format this version does not understand
+	ErrAddrBookVersion = errors.New("avalanche: unsupported address book version")
+
+	// ErrInvalidVoteRecord is returned when decoding a VoteRecord that is not
+	// ABCVoteRecordSize bytes
+	ErrInvalidVoteRecord = errors.New("avalanche: invalid vote record")
+
+	// ErrPeerOverridesVersion is returned when loading peer overrides written
+	// in a format this version does not understand
+	ErrPeerOverridesVersion = errors.New("avalanche: unsupported peer overrides version")
+
+	// ErrUnknownPreset is returned when selecting a Config preset by a name
+	// that isn't one of PresetNames
+	ErrUnknownPreset = errors.New("avalanche: unknown config preset")
+
+	// ErrHashMismatch is returned when a target's bytes don't hash to the Hash
+	// they were delivered for
+	ErrHashMismatch = errors.New("avalanche: target bytes do not match hash")
+
+	// ErrNoValidator is returned when submitting a raw target to a Processor
+	// without a TargetValidator
+	ErrNoValidator = errors.New("avalanche: no target validator")
+
+	// ErrValidationBusy is returned when submitting a raw target while
+	// Config.MaxPendingValidations targets are already being validated
+	ErrValidationBusy = errors.New("avalanche: too many pending validations")
+
+	// ErrInvalidBinary is returned when decoding a malformed binary encoded
+	// Poll or Response
+	ErrInvalidBinary = errors.New("avalanche: invalid binary encoding")
+
+	// ErrInvalidFilter is returned when decoding a malformed FinalizedFilter
+	ErrInvalidFilter = errors.New("avalanche: invalid finalized filter")
+
+	// ErrInvalidConfig is the cause of every *ConfigError
+	ErrInvalidConfig = errors.New("avalanche: invalid config")
+
+	// ErrEclipseSuspected is reported when a sign of an eclipse attack is
+	// found; see Config.DetectEclipse
+	ErrEclipseSuspected = errors.New("avalanche: eclipse attack suspected")
+)
+
+// Error is returned when an operation fails because of an underlying error,
+// such as one from a file, a QueryJournal or a MempoolSource. Op names the
+// operation and Err is the underlying error.
+type Error struct {
+	Op  string
+	Err error
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return "avalanche: " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// wrapError returns err as an *Error for op, or nil if err is nil
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{op, err}
+}
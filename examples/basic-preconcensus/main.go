@@ -120,20 +120,17 @@ func (n node) run(wg *sync.WaitGroup) {
 
 		// Query node
 		n.snowballMu.Lock()
-		invs := n.snowball.GetInvsForNextPoll()
+		poll, ok := n.snowball.PollNode(avalanche.NodeID(nodeID))
 		n.snowballMu.Unlock()
+		if !ok {
+			continue
+		}
 
-		// All done
-		// if len(invs) == 0 {
-		// 	fmt.Println("Out of invs:", n.id)
-		// 	return
-		// }
-
-		resp := networkNodes[nodeID].query(invs)
+		resp := networkNodes[nodeID].query(poll)
 
 		// Register query response
 		n.snowballMu.Lock()
-		n.snowball.RegisterVotes(n.id, resp, &updates)
+		n.snowball.RegisterVotes(avalanche.NodeID(nodeID), resp, &updates)
 		n.snowballMu.Unlock()
 
 		if len(updates) == 0 {
@@ -165,10 +162,11 @@ func (n node) run(wg *sync.WaitGroup) {
 	log("Limit exceeded")
 }
 
-func (n node) query(invs []avalanche.Inv) avalanche.Response {
+func (n node) query(poll avalanche.Poll) avalanche.Response {
 	n.snowballMu.Lock()
 	defer n.snowballMu.Unlock()
 
+	invs := poll.GetInvs()
 	votes := make([]avalanche.Vote, len(invs))
 
 	for i := 0; i < len(invs); i++ {
@@ -176,9 +174,9 @@ func (n node) query(invs []avalanche.Inv) avalanche.Response {
 
 		n.snowball.AddTargetToReconcile(t)
 
-		var vote uint32 = 0
+		vote := avalanche.VoteYes
 		if !n.snowball.IsAccepted(t) {
-			vote = 1
+			vote = avalanche.VoteNo
 		}
 
 		// Randomly flip votes to prolong convergence
@@ -189,7 +187,7 @@ func (n node) query(invs []avalanche.Inv) avalanche.Response {
 		votes[i] = avalanche.NewVote(vote, invs[i].TargetHash)
 	}
 
-	return avalanche.NewResponse(0, 0, votes)
+	return avalanche.NewResponse(poll.GetRound(), 0, votes)
 }
 
 // tx
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+const (
+	nodeCount     = 20
+	proposalCount = 5
+
+	// maxQueries bounds how many queries a node makes before we give up on it
+	maxQueries = 1e6
+)
+
+var loggingEnabled = false
+
+func main() {
+	logging := flag.Bool("logging", false, "Enable logging")
+	seed := flag.Int64("seed", 1, "Seed for deciding each node's preferences")
+	flag.Parse()
+
+	loggingEnabled = *logging
+	rand.Seed(*seed)
+
+	// Create the proposals being voted on. Each one changes a single network
+	// parameter.
+	proposals := make([]*proposal, proposalCount)
+	for i := range proposals {
+		proposals[i] = &proposal{
+			hash:  avalanche.Hash(1000 + i),
+			param: fmt.Sprintf("param-%d", i),
+			value: int64(rand.Intn(100)),
+		}
+	}
+
+	// Create nodes; each node supports each proposal with a probability that
+	// varies by proposal so we get a mix of outcomes.
+	nodes := make([]*node, nodeCount)
+	for i := range nodes {
+		nodes[i] = newNode(avalanche.NodeID(i))
+		for j, p := range proposals {
+			support := rand.Float64() < float64(j+1)/float64(proposalCount+1)
+			nodes[i].addProposal(p, support)
+		}
+	}
+
+	// Every node runs its own processor so preferences can converge
+	wg := &sync.WaitGroup{}
+	wg.Add(len(nodes))
+	for _, n := range nodes {
+		go n.run(nodes, wg)
+	}
+	wg.Wait()
+
+	// Node 0 is the one whose view we report on
+	printReport(nodes[0].tallies())
+}
+
+func log(str string, args ...interface{}) {
+	if loggingEnabled {
+		fmt.Println(fmt.Sprintf(str, args...))
+	}
+}
+
+// proposal is a vote to change a network parameter. Together with a node's
+// preference it forms a custom Target type; see localProposal.
+type proposal struct {
+	hash  avalanche.Hash
+	param string
+	value int64
+}
+
+// Hash returns the proposal hash, which identifies the proposal
+func (p *proposal) Hash() avalanche.Hash { return p.hash }
+
+// Type returns the Target type; in this case a proposal
+func (*proposal) Type() string { return "proposal" }
+
+// IsValid returns true; proposals are always valid to vote on
+func (*proposal) IsValid() bool { return true }
+
+// Score returns a constant as all proposals carry equal weight
+func (*proposal) Score() int64 { return 1 }
+
+// localProposal wraps a proposal with this node's own preference for it
+type localProposal struct {
+	*proposal
+	support bool
+}
+
+// IsAccepted returns the node's own preference for the proposal
+func (p *localProposal) IsAccepted() bool { return p.support }
+
+// tally counts the votes received for a proposal and its current result
+type tally struct {
+	proposal *proposal
+	yes      int
+	no       int
+	neutral  int
+	status   avalanche.Status
+	final    bool
+}
+
+type node struct {
+	id        avalanche.NodeID
+	mu        sync.Mutex
+	processor *avalanche.Processor
+	proposals map[avalanche.Hash]*localProposal
+	tally     map[avalanche.Hash]*tally
+}
+
+func newNode(id avalanche.NodeID) *node {
+	return &node{
+		id:        id,
+		processor: avalanche.NewProcessor(avalanche.NewConnman()),
+		proposals: map[avalanche.Hash]*localProposal{},
+		tally:     map[avalanche.Hash]*tally{},
+	}
+}
+
+func (n *node) addProposal(p *proposal, support bool) {
+	lp := &localProposal{p, support}
+	n.proposals[p.Hash()] = lp
+	n.tally[p.Hash()] = &tally{proposal: p, status: avalanche.StatusRejected}
+	if support {
+		n.tally[p.Hash()].status = avalanche.StatusAccepted
+	}
+	n.processor.AddTargetToReconcile(lp)
+}
+
+// run queries the other nodes until every proposal has been finalized
+func (n *node) run(nodes []*node, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	finalized := 0
+	for i := 0; i < maxQueries && finalized < len(n.proposals); i++ {
+		// Sample peers at random; polling them in a fixed order can leave an
+		// evenly split network stuck
+		peer := nodes[rand.Intn(len(nodes))]
+
+		// Don't query ourself
+		if peer.id == n.id {
+			continue
+		}
+
+		n.mu.Lock()
+		poll, ok := n.processor.PollNode(peer.id)
+		n.mu.Unlock()
+		if !ok {
+			continue
+		}
+		resp := peer.query(poll)
+
+		n.mu.Lock()
+		n.count(resp)
+
+		updates := []avalanche.StatusUpdate{}
+		n.processor.RegisterVotes(peer.id, resp, &updates)
+
+		for _, update := range updates {
+			t := n.tally[update.Hash]
+			t.status = update.Status
+
+			switch update.Status {
+			case avalanche.StatusFinalized, avalanche.StatusInvalid:
+				t.final = true
+				finalized++
+			}
+
+			log("Proposal %d is now %s on node %d after %d queries", update.Hash, statusString(update.Status), n.id, i)
+		}
+		n.mu.Unlock()
+	}
+}
+
+// count records each vote in the response against the proposal's tally
+func (n *node) count(resp avalanche.Response) {
+	for _, v := range resp.GetVotes() {
+		t, ok := n.tally[v.GetHash()]
+		if !ok {
+			continue
+		}
+
+		switch v.GetError() {
+		case avalanche.VoteYes:
+			t.yes++
+		case avalanche.VoteNo:
+			t.no++
+		default:
+			t.neutral++
+		}
+	}
+}
+
+// query answers a poll using the node's current view of each proposal
+func (n *node) query(poll avalanche.Poll) avalanche.Response {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	invs := poll.GetInvs()
+	votes := make([]avalanche.Vote, len(invs))
+
+	for i, inv := range invs {
+		p, ok := n.proposals[inv.TargetHash]
+
+		// We don't know about this proposal so we can't vote on it
+		if !ok {
+			votes[i] = avalanche.NewVote(avalanche.VoteUnknown, inv.TargetHash)
+			continue
+		}
+
+		vote := avalanche.VoteYes
+		if !n.prefers(p) {
+			vote = avalanche.VoteNo
+		}
+		votes[i] = avalanche.NewVote(vote, inv.TargetHash)
+	}
+
+	return avalanche.NewResponse(poll.GetRound(), 0, votes)
+}
+
+// prefers returns whether the node currently favors the proposal; once a
+// proposal is final the processor forgets it so we fall back to the tally
+func (n *node) prefers(p *localProposal) bool {
+	if t := n.tally[p.Hash()]; t.final {
+		return t.status == avalanche.StatusFinalized
+	}
+	return n.processor.IsAccepted(p)
+}
+
+// tallies returns the node's tallies ordered by proposal hash
+func (n *node) tallies() []*tally {
+	tallies := make([]*tally, 0, len(n.tally))
+	for _, t := range n.tally {
+		tallies = append(tallies, t)
+	}
+
+	sort.Slice(tallies, func(i, j int) bool {
+		return tallies[i].proposal.Hash() < tallies[j].proposal.Hash()
+	})
+
+	return tallies
+}
+
+func printReport(tallies []*tally) {
+	fmt.Printf("%-10s %-10s %6s %6s %6s %8s  %s\n", "proposal", "param", "value", "yes", "no", "neutral", "result")
+	for _, t := range tallies {
+		result := statusString(t.status)
+		if !t.final {
+			result += " (not final)"
+		}
+
+		fmt.Printf("%-10d %-10s %6d %6d %6d %8d  %s\n",
+			t.proposal.Hash(), t.proposal.param, t.proposal.value, t.yes, t.no, t.neutral, result)
+	}
+}
+
+func statusString(s avalanche.Status) string {
+	switch s {
+	case avalanche.StatusAccepted:
+		return "accepted"
+	case avalanche.StatusRejected:
+		return "rejected"
+	case avalanche.StatusFinalized:
+		return "finalized (passed)"
+	case avalanche.StatusInvalid:
+		return "finalized (failed)"
+	}
+	return "unknown"
+}
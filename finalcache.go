@@ -0,0 +1,107 @@
+package avalanche
+
+import "container/list"
+
+// FinalizationCacheStats describes the cache of pruned finalizations; see
+// Config.FinalizationCacheSize
+type FinalizationCacheStats struct {
+	// Size is how many finalizations are cached
+	Size int
+
+	// Hits are the votes on polled targets answered from the cache, and
+	// Refused the raw targets finalizations kept from being validated and
+	// reconciled again; see Processor.SubmitRawTarget
+	Hits    int64
+	Refused int64
+}
+
+// finalizationCache is an LRU cache of the outcomes of finalized targets that
+// have been pruned from the Processor's finalizations. It bounds how many old
+// outcomes are remembered. Answering a poll about a target keeps it cached, so
+// targets that laggard peers still ask about are the last to be evicted.
+type finalizationCache struct {
+	size    int
+	order   *list.List
+	entries map[Hash]*list.Element
+
+	hits    int64
+	refused int64
+}
+
+// cachedFinalization is the value of a finalizationCache element
+type cachedFinalization struct {
+	hash     Hash
+	accepted bool
+}
+
+// newFinalizationCache creates a finalizationCache holding up to size
+// finalizations
+func newFinalizationCache(size int) *finalizationCache {
+	return &finalizationCache{size: size, order: list.New(), entries: map[Hash]*list.Element{}}
+}
+
+// add caches the outcome of a target, evicting the least recently used one if
+// the cache is full
+func (c *finalizationCache) add(h Hash, accepted bool) {
+	if e, ok := c.entries[h]; ok {
+		e.Value = cachedFinalization{h, accepted}
+		c.order.MoveToFront(e)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(cachedFinalization).hash)
+		c.order.Remove(oldest)
+	}
+	c.entries[h] = c.order.PushFront(cachedFinalization{h, accepted})
+}
+
+// get returns the cached outcome of a target, marking it as recently used
+func (c *finalizationCache) get(h Hash) (bool, bool) {
+	e, ok := c.entries[h]
+	if !ok {
+		return false, false
+	}
+	c.order.MoveToFront(e)
+	c.hits++
+	return e.Value.(cachedFinalization).accepted, true
+}
+
+// contains returns whether a target is cached without marking it as used
+func (c *finalizationCache) contains(h Hash) bool {
+	_, ok := c.entries[h]
+	return ok
+}
+
+// remove drops a target from the cache
+func (c *finalizationCache) remove(h Hash) {
+	if e, ok := c.entries[h]; ok {
+		delete(c.entries, h)
+		c.order.Remove(e)
+	}
+}
+
+// GetFinalizationCacheStats returns statistics on the cache of pruned
+// finalizations
+func (p *Processor) GetFinalizationCacheStats() FinalizationCacheStats {
+	return FinalizationCacheStats{p.recent.order.Len(), p.recent.hits, p.recent.refused}
+}
+
+// isRecentlyFinalized returns whether a target was finalized recently enough
+// to still be remembered, counting it as refused if so
+func (p *Processor) isRecentlyFinalized(h Hash) bool {
+	if _, ok := p.finalizations[h]; !ok && !p.recent.contains(h) {
+		return false
+	}
+	p.recent.refused++
+	return true
+}
+
+// finalizationCacheSize returns the most finalizations that are cached
+func (c Config) finalizationCacheSize() int {
+	if c.FinalizationCacheSize <= 0 {
+		return AvalancheFinalizationCacheSize
+	}
+	return c.FinalizationCacheSize
+}
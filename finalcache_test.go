@@ -0,0 +1,62 @@
+package avalanche
+
+import "testing"
+
+func TestFinalizationCache(t *testing.T) {
+	c := newFinalizationCache(2)
+	c.add(Hash(1), true)
+	c.add(Hash(2), false)
+
+	// Using a target keeps it cached over older ones
+	accepted, ok := c.get(Hash(1))
+	assertTrue(t, ok && accepted)
+	c.add(Hash(3), true)
+	assertTrue(t, c.contains(Hash(1)) && c.contains(Hash(3)))
+	assertFalse(t, c.contains(Hash(2)))
+
+	c.remove(Hash(1))
+	assertFalse(t, c.contains(Hash(1)))
+	assertTrue(t, c.order.Len() == 1 && len(c.entries) == 1)
+}
+
+func TestFinalizationCachePolls(t *testing.T) {
+	p := NewProcessorWithConfig(NewConnman(), Config{FinalizationCacheSize: 2})
+	p.SetTargetValidator(TargetValidatorFunc(func(targetType string, h Hash, raw []byte) (Target, error) {
+		return &Block{h, 1, true, true}, nil
+	}))
+	sum := DoubleSHA256.Sum([]byte("tx"))
+	h, _ := HashFromWireBytes(sum[:])
+
+	p.recordFinalization(h, StatusRejected)
+	p.recordFinalization(Hash(2), StatusFinalized)
+
+	// Finalizations aren't cached until they are pruned, but still aren't
+	// fetched and reconciled again
+	assertTrue(t, p.GetFinalizationCacheStats().Size == 0)
+	assertTrue(t, p.SubmitRawTarget("tx", h, []byte("tx")) == nil)
+	assertTrue(t, p.GetPendingValidations() == 0)
+
+	// Once pruned, polls about them are answered from the cache
+	p.collectGarbage()
+	assertTrue(t, len(p.finalizations) == 0)
+	resp := p.HandlePoll(NodeID(0), NewPoll(0, []Inv{{"tx", h}, {"block", Hash(2)}, {"block", Hash(3)}}))
+	votes := resp.GetVotes()
+	assertTrue(t, votes[0].GetError() == VoteNo && votes[1].GetError() == VoteYes && votes[2].GetError() == VoteUnknown)
+
+	assertTrue(t, p.SubmitRawTarget("tx", h, []byte("tx")) == nil)
+	assertTrue(t, p.GetPendingValidations() == 0)
+	stats := p.GetFinalizationCacheStats()
+	assertTrue(t, stats.Size == 2 && stats.Hits == 2 && stats.Refused == 2)
+
+	// Once evicted the finalization is forgotten
+	p.recordFinalization(Hash(4), StatusFinalized)
+	p.recordFinalization(Hash(5), StatusFinalized)
+	p.collectGarbage()
+	assertFalse(t, p.recent.contains(h))
+	resp = p.HandlePoll(NodeID(0), NewPoll(1, []Inv{{"tx", h}}))
+	assertTrue(t, resp.GetVotes()[0].GetError() == VoteUnknown)
+
+	// Adding a target by hand takes it out of the cache
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(5), 1, true, true}))
+	assertFalse(t, p.recent.contains(Hash(5)))
+}
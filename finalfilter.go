@@ -0,0 +1,172 @@
+package avalanche
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const (
+	// finalFilterSeed separates the bit indexes of the filter's hash
+	// functions
+	finalFilterSeed = 0x2545f4914f6cdd1d
+
+	// finalFilterRejectSeed separates the keys of rejected targets from those
+	// of accepted ones
+	finalFilterRejectSeed = 0xd6e8feb86659fd93
+
+	// finalFilterMaxHashes is the most hash functions a filter may use
+	finalFilterMaxHashes = 32
+
+	// finalFilterMaxSize is the largest encoded filter accepted from a peer
+	finalFilterMaxSize = 1 << 20
+)
+
+// FinalizedFilter is a Bloom filter over the targets a node has finalized,
+// keyed by Hash and outcome. Peers exchange them so a querier can skip asking
+// a node about a target it has already finalized the way the querier leans,
+// which saves traffic late in convergence. Like any Bloom filter it may claim
+// a target that was never added, at the false positive rate it was sized for,
+// but never misses one that was.
+type FinalizedFilter struct {
+	bits   []uint64
+	hashes int
+}
+
+// NewFinalizedFilter creates an empty filter sized to hold n targets with the
+// given false positive rate
+func NewFinalizedFilter(n int, fpRate float64) *FinalizedFilter {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	words := int(math.Ceil(m / 64))
+	if words > finalFilterMaxSize/8 {
+		words = finalFilterMaxSize / 8
+	}
+
+	k := int(math.Round(float64(words*64) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > finalFilterMaxHashes {
+		k = finalFilterMaxHashes
+	}
+	return &FinalizedFilter{make([]uint64, words), k}
+}
+
+// FinalizedFilter returns a filter over the targets we have finalized and not
+// yet forgotten, to send to peers
+func (p *Processor) FinalizedFilter(fpRate float64) *FinalizedFilter {
+	f := NewFinalizedFilter(len(p.finalizations), fpRate)
+	for h, fin := range p.finalizations {
+		f.Add(h, fin.accepted())
+	}
+	return f
+}
+
+// SetPeerFinalizedFilter records the filter a node sent of the targets it has
+// finalized. The node is then not polled about targets the filter says it
+// finalized the way we lean, as long as another node may still be asked about
+// them. A nil filter forgets the node's filter.
+func (p *Processor) SetPeerFinalizedFilter(id NodeID, f *FinalizedFilter) {
+	if f == nil {
+		delete(p.peerFilters, id)
+		return
+	}
+	p.peerFilters[id] = f
+}
+
+// Add adds a target finalized with the given outcome
+func (f *FinalizedFilter) Add(h Hash, accepted bool) {
+	key := finalFilterKey(h, accepted)
+	for i := 0; i < f.hashes; i++ {
+		bit := f.bit(key, i)
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains returns whether the target may have been added with the given
+// outcome
+func (f *FinalizedFilter) Contains(h Hash, accepted bool) bool {
+	key := finalFilterKey(h, accepted)
+	for i := 0; i < f.hashes; i++ {
+		bit := f.bit(key, i)
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary encodes the filter for sending to a peer, as the number of
+// hash functions followed by the bits
+func (f *FinalizedFilter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 1+len(f.bits)*8)
+	buf[0] = byte(f.hashes)
+	for i, w := range f.bits {
+		binary.LittleEndian.PutUint64(buf[1+i*8:], w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a filter encoded with MarshalBinary
+func (f *FinalizedFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 || len(data) > finalFilterMaxSize+1 || (len(data)-1)%8 != 0 {
+		return ErrInvalidFilter
+	}
+	if data[0] < 1 || data[0] > finalFilterMaxHashes {
+		return ErrInvalidFilter
+	}
+
+	f.hashes = int(data[0])
+	f.bits = make([]uint64, (len(data)-1)/8)
+	for i := range f.bits {
+		f.bits[i] = binary.LittleEndian.Uint64(data[1+i*8:])
+	}
+	return nil
+}
+
+// bit returns the i-th bit index of a key, by double hashing
+func (f *FinalizedFilter) bit(key uint64, i int) uint64 {
+	h2 := mix64(key^finalFilterSeed) | 1
+	return (key + uint64(i)*h2) % uint64(len(f.bits)*64)
+}
+
+// finalFilterKey returns the key of a target with the given outcome
+func finalFilterKey(h Hash, accepted bool) uint64 {
+	if accepted {
+		return mix64(uint64(h))
+	}
+	return mix64(uint64(h) ^ finalFilterRejectSeed)
+}
+
+// isFinalizedByPeer returns whether a node's filter says it finalized a target
+// the way vr leans. It returns false if every connected node's filter says so,
+// so the target can still gather votes from someone.
+func (p *Processor) isFinalizedByPeer(id NodeID, h Hash, vr *VoteRecord) bool {
+	f, ok := p.peerFilters[id]
+	if !ok {
+		return false
+	}
+	accepted := vr.isAccepted()
+	if !f.Contains(h, accepted) {
+		return false
+	}
+
+	finalized := 0
+	for other, f := range p.peerFilters {
+		if _, ok := p.connman.nodes[other]; ok && f.Contains(h, accepted) {
+			finalized++
+		}
+	}
+	if finalized >= len(p.connman.nodes) {
+		return false
+	}
+
+	p.stats(id).invsSkipped++
+	return true
+}
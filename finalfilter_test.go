@@ -0,0 +1,73 @@
+package avalanche
+
+import "testing"
+
+func TestFinalizedFilter(t *testing.T) {
+	f := NewFinalizedFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(Hash(i), i%2 == 0)
+	}
+	for i := 0; i < 1000; i++ {
+		assertTrue(t, f.Contains(Hash(i), i%2 == 0))
+	}
+
+	// False positives stay near the rate the filter was sized for
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if f.Contains(Hash(i), true) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Fatal("Expected about 100 false positives but got", falsePositives)
+	}
+
+	data, err := f.MarshalBinary()
+	assertTrue(t, err == nil)
+	decoded := &FinalizedFilter{}
+	assertTrue(t, decoded.UnmarshalBinary(data) == nil)
+	for i := 0; i < 1000; i++ {
+		assertTrue(t, decoded.Contains(Hash(i), i%2 == 0))
+	}
+	assertTrue(t, decoded.UnmarshalBinary(data[:len(data)-1]) == ErrInvalidFilter)
+	data[0] = 0
+	assertTrue(t, decoded.UnmarshalBinary(data) == ErrInvalidFilter)
+}
+
+func TestPeerFinalizedFilter(t *testing.T) {
+	connman := NewConnman()
+	connman.AddNode(NodeID(0))
+	connman.AddNode(NodeID(1))
+	p := NewProcessor(connman)
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(1), 1, true, true}))
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(2), 1, true, true}))
+
+	polled := func(id NodeID) map[Hash]bool {
+		hashes := map[Hash]bool{}
+		for _, inv := range p.appendInvsForNextPoll(id, nil, AvalancheMaxElementPoll) {
+			hashes[inv.TargetHash] = true
+		}
+		return hashes
+	}
+
+	// A node that finalized a target the way we lean isn't asked about it
+	peer := NewProcessor(NewConnman())
+	peer.recordFinalization(Hash(1), StatusFinalized)
+	peer.recordFinalization(Hash(2), StatusInvalid)
+	f := peer.FinalizedFilter(0.001)
+	p.SetPeerFinalizedFilter(NodeID(0), f)
+
+	hashes := polled(NodeID(0))
+	assertTrue(t, !hashes[Hash(1)] && hashes[Hash(2)])
+	assertTrue(t, p.PeerInfo()[0].InvsSkipped == 1)
+	assertTrue(t, polled(NodeID(1))[Hash(1)])
+
+	// Once every node has finalized it they are asked anyway, so we can
+	// finalize too
+	p.SetPeerFinalizedFilter(NodeID(1), f)
+	assertTrue(t, polled(NodeID(0))[Hash(1)])
+
+	p.SetPeerFinalizedFilter(NodeID(1), nil)
+	p.SetPeerFinalizedFilter(NodeID(0), nil)
+	assertTrue(t, polled(NodeID(0))[Hash(1)])
+}
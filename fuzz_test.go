@@ -0,0 +1,213 @@
+//go:build go1.18
+// +build go1.18
+
+package avalanche
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// FuzzVoteRecord registers arbitrary vote sequences and checks the record
+// stays internally consistent
+func FuzzVoteRecord(f *testing.F) {
+	f.Add(true, []byte{0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(false, []byte{1, 1, 1, 1, 1, 1, 1, 0xff, 1})
+
+	f.Fuzz(func(t *testing.T, accepted bool, data []byte) {
+		vr := NewVoteRecord(accepted)
+		for _, b := range data {
+			// Map bytes onto yes, no, and neutral votes
+			err := uint32(b % 3)
+			if err == 2 {
+				err = negativeOne
+			}
+
+			wasAccepted, wasFinalized := vr.isAccepted(), vr.hasFinalized()
+			changed := vr.regsiterVote(err)
+
+			if !changed && wasAccepted != vr.isAccepted() {
+				t.Fatal("Acceptance changed without being reported")
+			}
+			if !changed && !wasFinalized && vr.hasFinalized() {
+				t.Fatal("Finalization happened without being reported")
+			}
+			if vr.getConfidence() > 1<<15-1 {
+				t.Fatal("Confidence overflowed:", vr.getConfidence())
+			}
+		}
+	})
+}
+
+// FuzzRegisterVotes polls nodes and feeds arbitrary answers to the polls
+// into a Processor. Each 4 byte chunk of input is a node ID, a seed for the
+// votes on the polled Invs, how to tamper with the response, and an argument
+// for the tampering. Untampered and validly truncated responses must be
+// accepted and the others refused.
+func FuzzRegisterVotes(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 0, 1, 0, 0, 0})
+	f.Add([]byte{1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			connman = NewConnman()
+			p       = NewProcessor(connman)
+			updates = []StatusUpdate{}
+		)
+		for id := NodeID(0); id < 4; id++ {
+			connman.AddNode(id)
+		}
+		for h := range staticTestBlockMap {
+			p.AddTargetToReconcile(&Block{h, 1, true, true})
+		}
+
+		for ; len(data) >= 4; data = data[4:] {
+			nodeID := NodeID(data[0] % 4)
+			poll, ok := p.PollNode(nodeID)
+			if !ok {
+				continue
+			}
+
+			invs := poll.GetInvs()
+			votes := make([]Vote, len(invs))
+			for i, inv := range invs {
+				// Map bytes onto yes, no, and neutral votes
+				err := uint32((data[1] ^ byte(i)) % 3)
+				if err == 2 {
+					err = negativeOne
+				}
+				votes[i] = NewVote(err, inv.TargetHash)
+			}
+
+			resp, valid := NewResponse(poll.GetRound(), 0, votes), true
+			switch data[2] % 4 {
+			case 1:
+				resp = NewTruncatedResponse(poll.GetRound(), 0, votes[:int(data[3])%len(votes)])
+			case 2:
+				resp, valid = NewResponse(poll.GetRound(), 0, append(votes, NewVote(VoteYes, Hash(data[3])))), false
+			case 3:
+				i := int(data[3]) % len(votes)
+				votes[i] = NewVote(votes[i].GetError(), votes[i].GetHash()+1)
+				valid = false
+			}
+
+			before := len(updates)
+			if p.RegisterVotes(nodeID, resp, &updates) != valid {
+				t.Fatal("Expected the response to be accepted:", valid)
+			}
+
+			for _, update := range updates[before:] {
+				if _, ok := staticTestBlockMap[update.Hash]; !ok {
+					t.Fatal("Got update for unknown target", update.Hash)
+				}
+			}
+		}
+
+		// Finalized targets are never polled again
+		for _, inv := range p.GetInvsForNextPoll() {
+			for _, update := range updates {
+				if update.Hash == inv.TargetHash && (update.Status == StatusFinalized || update.Status == StatusInvalid) {
+					t.Fatal("Finalized target", inv.TargetHash, "is still being polled")
+				}
+			}
+		}
+	})
+}
+
+// roundTrip re-encodes a decoded message and checks that decoding and
+// encoding it again gives the same bytes
+func roundTrip(t *testing.T, encode func() ([]byte, error), decode func([]byte) error) {
+	first, err := encode()
+	if err != nil {
+		t.Fatal("Failed to encode a decoded message:", err)
+	}
+	if err := decode(first); err != nil {
+		t.Fatal("Failed to decode an encoded message:", err)
+	}
+	second, err := encode()
+	if err != nil {
+		t.Fatal("Failed to encode a decoded message:", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("Round trip changed the message from", first, "to", second)
+	}
+}
+
+// FuzzDecodePoll decodes arbitrary JSON Polls, as received from peers, and
+// checks that those accepted round trip
+func FuzzDecodePoll(f *testing.F) {
+	f.Add([]byte(`{"version":1,"round":1,"invs":[{"type":"block","hash":"` + Hash(1).DisplayHex() + `"}]}`))
+	f.Add([]byte(`{"version":1,"magic":4109624820,"round":-1,"invs":[]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, strict := range []bool{false, true} {
+			poll, err := DecodePoll(bytes.NewReader(data), strict)
+			if err != nil {
+				continue
+			}
+			roundTrip(t, func() ([]byte, error) { return json.Marshal(poll) }, func(b []byte) (err error) {
+				poll, err = DecodePoll(bytes.NewReader(b), true)
+				return err
+			})
+		}
+	})
+}
+
+// FuzzDecodeResponse decodes arbitrary JSON Responses and checks that those
+// accepted round trip
+func FuzzDecodeResponse(f *testing.F) {
+	f.Add([]byte(`{"version":1,"round":1,"cooldown":0,"votes":[{"error":0,"hash":"` + Hash(1).DisplayHex() + `"}]}`))
+	f.Add([]byte(`{"version":1,"round":2,"cooldown":5,"votes":[{"error":2,"hash":"` + Hash(1).DisplayHex() +
+		`","conflict":"` + Hash(2).DisplayHex() + `"}],"truncated":true}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, strict := range []bool{false, true} {
+			resp, err := DecodeResponse(bytes.NewReader(data), strict)
+			if err != nil {
+				continue
+			}
+			roundTrip(t, func() ([]byte, error) { return json.Marshal(resp) }, func(b []byte) (err error) {
+				resp, err = DecodeResponse(bytes.NewReader(b), true)
+				return err
+			})
+		}
+	})
+}
+
+// FuzzPollBinary decodes arbitrary binary Polls and checks that those
+// accepted round trip
+func FuzzPollBinary(f *testing.F) {
+	for _, poll := range []Poll{NewPoll(1, []Inv{{"block", Hash(1)}}), NewPoll(-1, nil).WithNetwork(TestNet)} {
+		data, _ := poll.MarshalBinary()
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		poll := Poll{}
+		if poll.UnmarshalBinary(data) != nil {
+			return
+		}
+		roundTrip(t, func() ([]byte, error) { return poll.MarshalBinary() }, poll.UnmarshalBinary)
+	})
+}
+
+// FuzzResponseBinary decodes arbitrary binary Responses and checks that those
+// accepted round trip
+func FuzzResponseBinary(f *testing.F) {
+	for _, resp := range []Response{
+		NewResponse(1, 0, []Vote{NewVote(VoteYes, Hash(1))}),
+		NewTruncatedResponse(2, 5, []Vote{NewConflictVote(Hash(1), Hash(2))}).WithNetwork(RegTest),
+	} {
+		data, _ := resp.MarshalBinary()
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		resp := Response{}
+		if resp.UnmarshalBinary(data) != nil {
+			return
+		}
+		roundTrip(t, func() ([]byte, error) { return resp.MarshalBinary() }, resp.UnmarshalBinary)
+	})
+}
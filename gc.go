@@ -0,0 +1,110 @@
+package avalanche
+
+import "time"
+
+// gracePeriod tracks a target that has left active polling but whose late
+// votes are still counted towards peer statistics
+type gracePeriod struct {
+	since time.Time
+
+	// tallied are the nodes whose votes have already been compared with the
+	// outcome
+	tallied map[NodeID]struct{}
+}
+
+// getGracePeriod returns how long finalized and invalid targets linger
+func (p *Processor) getGracePeriod() time.Duration {
+	if p.config.GracePeriod <= 0 {
+		return AvalancheGracePeriod
+	}
+	return p.config.GracePeriod
+}
+
+// beginGracePeriod starts the grace period of a finalized target, noting the
+// nodes whose votes were tallied at finalization
+func (p *Processor) beginGracePeriod(h Hash) {
+	g := gracePeriod{since: clock.Now(), tallied: map[NodeID]struct{}{}}
+	for id := range p.peerVotes[h] {
+		g.tallied[id] = struct{}{}
+	}
+	p.graceful[h] = g
+}
+
+// registerLateVote compares a late vote on a target in its grace period with
+// the target's outcome, once per node
+func (p *Processor) registerLateVote(id NodeID, h Hash, err uint32) {
+	g, ok := p.graceful[h]
+	if !ok || isUnknownVote(err) {
+		return
+	}
+	if _, ok = g.tallied[id]; ok {
+		return
+	}
+	g.tallied[id] = struct{}{}
+
+	if (err == VoteYes) == p.finalizations[h].accepted() {
+		p.stats(id).votesAgreed++
+	} else {
+		p.stats(id).votesDisagreed++
+	}
+}
+
+// collectGarbage ends expired grace periods, pruning the finalizations they
+// kept, and removes targets that have been invalid for longer than the grace
+// period from active polling, moving them to the finalizations as
+// StatusInvalid
+func (p *Processor) collectGarbage() {
+	now := clock.Now()
+	grace := p.getGracePeriod()
+
+	for h, g := range p.graceful {
+		if now.Sub(g.since) >= grace {
+			delete(p.graceful, h)
+		}
+	}
+	p.pruneFinalizations()
+
+	for h := range p.voteRecords {
+		if p.isWorthyPolling(p.targets[h]) {
+			delete(p.invalidSince, h)
+			continue
+		}
+
+		since, ok := p.invalidSince[h]
+		if !ok {
+			p.invalidSince[h] = now
+			continue
+		}
+		if now.Sub(since) < grace {
+			continue
+		}
+
+		update := StatusUpdate{
+			Hash:       h,
+			Status:     StatusInvalid,
+			Metadata:   p.metadata[h],
+			Confidence: 1,
+			Degraded:   p.IsDegraded(),
+		}
+		p.publish(update)
+
+		p.recordFinalization(h, StatusInvalid)
+		p.runFinalizationCallbacks(p.targets[h], StatusInvalid)
+		p.forgetTarget(h)
+		p.graceful[h] = gracePeriod{since: now, tallied: map[NodeID]struct{}{}}
+	}
+}
+
+// forgetTarget removes a target from active polling
+func (p *Processor) forgetTarget(h Hash) {
+	delete(p.voteRecords, h)
+	delete(p.targets, h)
+	delete(p.metadata, h)
+	delete(p.peerVotes, h)
+	delete(p.invalidSince, h)
+	delete(p.lastPolled, h)
+	delete(p.asked, h)
+	delete(p.reportedConflicts, h)
+	delete(p.held, h)
+	delete(p.missing, h)
+}
@@ -0,0 +1,63 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGarbageCollection(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{GracePeriod: time.Minute})
+		a       = &Block{Hash(1), 1, true, true}
+		b       = &Block{Hash(2), 1, true, true}
+		updates = []StatusUpdate{}
+		now     = time.Now()
+		sub     = p.Subscribe()
+	)
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	assertTrue(t, p.AddTargetToReconcile(a))
+	assertTrue(t, p.AddTargetToReconcile(b))
+
+	// Node 0 votes on a until it finalizes
+	yes := NewResponse(0, 0, []Vote{NewVote(0, a.Hash())})
+	for p.voteRecords[a.Hash()] != nil {
+		registerVotes(p, NodeID(0), yes, &updates)
+	}
+
+	// Late votes from other nodes are still counted during the grace period,
+	// once per node
+	no := NewResponse(0, 0, []Vote{NewVote(1, a.Hash())})
+	registerVotes(p, NodeID(1), no, &updates)
+	registerVotes(p, NodeID(1), no, &updates)
+	registerVotes(p, NodeID(0), no, &updates)
+	assertTrue(t, p.peerStats[NodeID(0)].votesAgreed == 1 && p.peerStats[NodeID(0)].votesDisagreed == 0)
+	assertTrue(t, p.peerStats[NodeID(1)].votesDisagreed == 1)
+
+	// But not after it
+	now = now.Add(time.Minute)
+	clock = stubClocker{now}
+	p.collectGarbage()
+	registerVotes(p, NodeID(2), no, &updates)
+	_, ok := p.peerStats[NodeID(2)]
+	assertTrue(t, !ok || p.peerStats[NodeID(2)].votesDisagreed == 0)
+
+	// Targets that stay invalid for the grace period are removed and recorded
+	// as invalid
+	b.valid = false
+	p.collectGarbage()
+	assertTrue(t, p.voteRecords[b.Hash()] != nil)
+	now = now.Add(time.Minute)
+	clock = stubClocker{now}
+	p.collectGarbage()
+	assertTrue(t, p.voteRecords[b.Hash()] == nil && p.targets[b.Hash()] == nil)
+	assertTrue(t, p.finalizations[b.Hash()].status == StatusInvalid)
+
+	// Subscribers hear about it
+	var last StatusUpdate
+	for len(sub) > 0 {
+		last = <-sub
+	}
+	assertTrue(t, last.Hash == b.Hash() && last.Status == StatusInvalid)
+}
@@ -0,0 +1,22 @@
+package avalanche
+
+// HandleGoodbye handles a node announcing that it is shutting down. It is
+// removed from the Connman so it is no longer polled, and its outstanding
+// queries are dropped so their targets can be asked of other nodes at once
+// rather than after they expire. Unlike DisconnectPeer its address is not
+// blocked, so it can be added again once it is back. It returns false if the
+// node was not in the Connman.
+func (p *Processor) HandleGoodbye(id NodeID) bool {
+	if !p.connman.RemoveNode(id) {
+		return false
+	}
+	delete(p.pollPeers, id)
+
+	for key, r := range p.queries {
+		if key.nodeID == id {
+			p.removeQuery(key)
+			r.release()
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package avalanche
+
+import "testing"
+
+func TestHandleGoodbye(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollRedundancy: 1})
+		block   = &Block{Hash(1), 0, true, true}
+	)
+	assertTrue(t, connman.AddNodeWithAddr(NodeID(0), "a"))
+	assertTrue(t, connman.AddNodeWithAddr(NodeID(1), "b"))
+	assertTrue(t, p.AddTargetToReconcile(block))
+
+	p.eventLoop()
+	asked := p.GetAskedPeers(block.Hash())
+	assertTrue(t, len(asked) == 1)
+
+	// The departing node's query is dropped so another node can be asked
+	departed := asked[0]
+	assertTrue(t, p.HandleGoodbye(departed))
+	assertFalse(t, p.HandleGoodbye(departed))
+	assertTrue(t, len(p.queries) == 0 && len(p.GetAskedPeers(block.Hash())) == 0)
+	assertTrue(t, len(connman.NodesIDs()) == 1)
+
+	p.eventLoop()
+	asked = p.GetAskedPeers(block.Hash())
+	assertTrue(t, len(asked) == 1 && asked[0] == connman.NodesIDs()[0])
+
+	// Its address is not blocked so it can come back
+	assertTrue(t, connman.AddNodeWithAddr(departed, []string{"a", "b"}[departed]))
+}
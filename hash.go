@@ -0,0 +1,61 @@
+package avalanche
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// HashSize is the size in bytes of the hashes Bitcoin uses to identify blocks
+// and transactions. They are 256 bit numbers: on the wire, in ABC's messages
+// and in BCHD's notifications they are little endian bytes, while RPCs and
+// explorers display them as big endian hex, so the bytes appear reversed.
+//
+// A Hash holds the low 64 bits, which are wire bytes 0-7 and the last 16
+// digits of the display form. That is plenty to tell targets apart, but the
+// mapping is one way, so keep the full hash, e.g. in Metadata, if it is needed
+// again.
+const HashSize = 32
+
+// HashFromWireBytes returns the Hash for a hash in wire byte order
+func HashFromWireBytes(b []byte) (Hash, error) {
+	if len(b) != HashSize {
+		return 0, ErrInvalidHash
+	}
+	return Hash(binary.LittleEndian.Uint64(b)), nil
+}
+
+// HashFromDisplayHex returns the Hash for a hash in the reversed hex form
+// displayed by RPCs and explorers
+func HashFromDisplayHex(s string) (Hash, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != HashSize {
+		return 0, ErrInvalidHash
+	}
+	reverseBytes(b)
+	return HashFromWireBytes(b)
+}
+
+// WireBytes returns the Hash as a 256 bit hash in wire byte order, with the
+// bits a Hash doesn't hold set to zero. A negative Hash is its two's
+// complement bits, so it round trips through HashFromWireBytes.
+func (h Hash) WireBytes() [HashSize]byte {
+	b := [HashSize]byte{}
+	binary.LittleEndian.PutUint64(b[:], uint64(h))
+	return b
+}
+
+// DisplayHex returns the Hash as a 256 bit hash in the reversed hex form
+// displayed by RPCs and explorers, with the bits a Hash doesn't hold set to
+// zero
+func (h Hash) DisplayHex() string {
+	b := h.WireBytes()
+	reverseBytes(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// reverseBytes reverses b in place
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -0,0 +1,39 @@
+package avalanche
+
+import "testing"
+
+func TestHashEncoding(t *testing.T) {
+	// The genesis block, as displayed and in wire order
+	display := "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	wire := []byte{
+		0x6f, 0xe2, 0x8c, 0x0a, 0xb6, 0xf1, 0xb3, 0x72, 0xc1, 0xa6, 0xa2, 0x46, 0xae, 0x63, 0xf7, 0x4f,
+		0x93, 0x1e, 0x83, 0x65, 0xe1, 0x5a, 0x08, 0x9c, 0x68, 0xd6, 0x19, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	fromDisplay, err := HashFromDisplayHex(display)
+	assertTrue(t, err == nil)
+	fromWire, err := HashFromWireBytes(wire)
+	assertTrue(t, err == nil)
+	assertTrue(t, fromDisplay == fromWire && uint64(fromWire) == 0x72b3f1b60a8ce26f)
+
+	// Only the low 64 bits survive the round trip
+	assertTrue(t, fromWire.DisplayHex() == "00000000000000000000000000000000000000000000000072b3f1b60a8ce26f")
+	b := fromWire.WireBytes()
+	assertTrue(t, string(b[:8]) == string(wire[:8]) && b[8] == 0)
+	roundTrip, _ := HashFromDisplayHex(fromWire.DisplayHex())
+	assertTrue(t, roundTrip == fromWire)
+
+	// Hashes with the top bit set are negative but keep all 64 bits
+	high := Hash(-0x7ed3d5e8b9a63b01)
+	b = high.WireBytes()
+	assertTrue(t, b[7] == 0x81 && b[8] == 0)
+	roundTrip, _ = HashFromWireBytes(b[:])
+	assertTrue(t, roundTrip == high)
+
+	for _, s := range []string{"", "00", display + "00", "zz" + display[2:]} {
+		_, err = HashFromDisplayHex(s)
+		assertTrue(t, err == ErrInvalidHash)
+	}
+	_, err = HashFromWireBytes(wire[1:])
+	assertTrue(t, err == ErrInvalidHash)
+}
@@ -0,0 +1,62 @@
+package avalanche
+
+import "crypto/sha256"
+
+// Hasher computes the hash identifying a target from its serialized bytes,
+// returned in wire byte order
+type Hasher interface {
+	Sum(data []byte) [HashSize]byte
+}
+
+// HasherFunc is a function used as a Hasher
+type HasherFunc func(data []byte) [HashSize]byte
+
+// Sum implements Hasher
+func (f HasherFunc) Sum(data []byte) [HashSize]byte {
+	return f(data)
+}
+
+// DoubleSHA256 is the Hasher Bitcoin uses for transaction ids and block
+// hashes. It is used for targets of every type unless SetHasher says
+// otherwise.
+var DoubleSHA256 Hasher = HasherFunc(func(data []byte) [HashSize]byte {
+	sum := sha256.Sum256(data)
+	return sha256.Sum256(sum[:])
+})
+
+// SHA256 is a Hasher using a single round of SHA-256, for custom targets that
+// don't follow Bitcoin's scheme
+var SHA256 Hasher = HasherFunc(sha256.Sum256)
+
+// VerifyHash returns ErrHashMismatch unless data hashes to h under hasher
+func VerifyHash(hasher Hasher, h Hash, data []byte) error {
+	sum := hasher.Sum(data)
+	if got, _ := HashFromWireBytes(sum[:]); got != h {
+		return ErrHashMismatch
+	}
+	return nil
+}
+
+// SetHasher sets the Hasher used to check the bytes delivered for targets of
+// the given type. A nil hasher restores DoubleSHA256.
+func (p *Processor) SetHasher(targetType string, hasher Hasher) {
+	if hasher == nil {
+		delete(p.hashers, targetType)
+		return
+	}
+	p.hashers[targetType] = hasher
+}
+
+// VerifyTargetBytes checks that the serialized bytes delivered for a target of
+// the given type hash to the Hash it was claimed under, such as before
+// deserializing a transaction a peer sent in answer to an Inv
+func (p *Processor) VerifyTargetBytes(targetType string, h Hash, data []byte) error {
+	hasher, ok := p.hashers[targetType]
+	if !ok {
+		hasher = DoubleSHA256
+	}
+	if err := VerifyHash(hasher, h, data); err != nil {
+		return &Error{"verify " + targetType, err}
+	}
+	return nil
+}
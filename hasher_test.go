@@ -0,0 +1,30 @@
+package avalanche
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyTargetBytes(t *testing.T) {
+	// The double SHA-256 of "hello", as bitcoind would display it
+	h, err := HashFromDisplayHex("503d8319a48348cdc610a582f7bf754b5833df65038606eb48510790dfc99595")
+	assertTrue(t, err == nil)
+
+	p := NewProcessor(NewConnman())
+	assertTrue(t, p.VerifyTargetBytes("tx", h, []byte("hello")) == nil)
+	err = p.VerifyTargetBytes("tx", h, []byte("hellO"))
+	if e, ok := err.(*Error); !ok || e.Op != "verify tx" || e.Err != ErrHashMismatch {
+		t.Fatal("Expected ErrHashMismatch but got", err)
+	}
+
+	// Custom targets can use another scheme
+	b, _ := hex.DecodeString("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	custom, _ := HashFromWireBytes(b)
+	assertTrue(t, p.VerifyTargetBytes("custom", custom, []byte("hello")) != nil)
+	p.SetHasher("custom", SHA256)
+	assertTrue(t, p.VerifyTargetBytes("custom", custom, []byte("hello")) == nil)
+	assertTrue(t, p.VerifyTargetBytes("tx", custom, []byte("hello")) != nil)
+
+	p.SetHasher("custom", nil)
+	assertTrue(t, p.VerifyTargetBytes("custom", h, []byte("hello")) == nil)
+}
@@ -0,0 +1,44 @@
+package avalanche
+
+// BlockNotification announces a new block and the transactions it includes
+type BlockNotification struct {
+	Hash   Hash
+	Height int64
+	Txs    []Hash
+}
+
+// HandleBlock resolves every target still being voted on that the block
+// includes. Inclusion settles a transaction however the vote was going, so
+// each is removed from polling and published with StatusIncluded, and the
+// block is recorded as its finalization anchor; see GetFinalizationAnchor.
+// Finalization callbacks are run with StatusIncluded. A
+// reorg past the block invalidates the inclusions like any other finalization
+// anchored to it. Targets already finalized by voting are left as they are.
+// It returns the number of targets resolved.
+func (p *Processor) HandleBlock(b BlockNotification) int {
+	resolved := 0
+	for _, h := range b.Txs {
+		p.warmRecord(h)
+		vr, ok := p.voteRecords[h]
+		if !ok {
+			continue
+		}
+
+		update := StatusUpdate{
+			Hash:       h,
+			Status:     StatusIncluded,
+			Metadata:   p.metadata[h],
+			Confidence: vr.confidenceFraction(),
+			Degraded:   p.IsDegraded(),
+		}
+		p.publish(update)
+
+		p.recordAnchoredFinalization(h, StatusIncluded, Anchor{b.Hash, b.Height})
+		p.beginGracePeriod(h)
+		p.tallyPeerVotes(h, true)
+		p.runFinalizationCallbacks(p.targets[h], StatusIncluded)
+		p.forgetTarget(h)
+		resolved++
+	}
+	return resolved
+}
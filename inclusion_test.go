@@ -0,0 +1,64 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandleBlock(t *testing.T) {
+	provider := stubTargetProvider{
+		Hash(1): {Fee: 1000, Size: 250, Valid: true, InMempool: true},
+		Hash(2): {Fee: 1000, Size: 250, Valid: true, InMempool: false},
+		Hash(3): {Fee: 1000, Size: 250, Valid: true, InMempool: true},
+	}
+	p := NewProcessor(NewConnman())
+	txs := []*Tx{}
+	for h := Hash(1); h <= 3; h++ {
+		tx, err := NewTx(h, provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+		assertTrue(t, p.AddTargetToReconcile(tx))
+	}
+	included, rejected, pending := txs[0], txs[1], txs[2]
+
+	statuses := []Status{}
+	p.OnFinalized("tx", func(t Target, status Status) error {
+		statuses = append(statuses, status)
+		return nil
+	})
+	updates := p.Subscribe()
+
+	// Included targets are resolved however the vote was going, and unknown
+	// ones are ignored
+	block := BlockNotification{Hash(100), 500, []Hash{included.Hash(), rejected.Hash(), Hash(4)}}
+	assertTrue(t, p.HandleBlock(block) == 2)
+	assertTrue(t, len(statuses) == 2 && statuses[0] == StatusIncluded)
+	for i := 0; i < 2; i++ {
+		if u := <-updates; u.Status != StatusIncluded {
+			t.Fatal("Expected StatusIncluded but got", u.Status)
+		}
+	}
+
+	invs := p.GetInvsForNextPoll()
+	assertTrue(t, len(invs) == 1 && invs[0].TargetHash == pending.Hash())
+
+	// The block is recorded as the anchor and peers are told it is accepted
+	anchor, ok := p.GetFinalizationAnchor(rejected.Hash())
+	assertTrue(t, ok && anchor == Anchor{Hash(100), 500})
+	resp := p.HandlePoll(NodeID(0), NewPoll(0, []Inv{{"tx", rejected.Hash()}}))
+	assertTrue(t, resp.GetVotes()[0].GetError() == VoteYes)
+
+	// Blocks including nothing being voted on resolve nothing
+	assertTrue(t, p.HandleBlock(block) == 0)
+
+	// Inclusions are pruned to the finalization cache like other finalizations
+	clock = stubClocker{time.Now().Add(AvalancheGracePeriod)}
+	defer func() { clock = realClocker{} }()
+	p.collectGarbage()
+	_, ok = p.GetFinalizationAnchor(rejected.Hash())
+	assertTrue(t, !ok && p.recent.contains(rejected.Hash()))
+	resp = p.HandlePoll(NodeID(0), NewPoll(1, []Inv{{"tx", rejected.Hash()}}))
+	assertTrue(t, resp.GetVotes()[0].GetError() == VoteYes)
+}
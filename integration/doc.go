@@ -0,0 +1,11 @@
+// Package integration holds end to end tests that run avalanche against a
+// regtest bitcoind compatible node. They are behind the integration build tag
+// and need a node to talk to, such as one started with the docker-compose.yml
+// in this directory:
+//
+//	REGTEST_IMAGE=<bitcoind compatible image> docker-compose up -d
+//	REGTEST_RPC_URL=http://127.0.0.1:18443 go test -tags integration ./integration
+//
+// REGTEST_RPC_USER and REGTEST_RPC_PASSWORD default to those in the compose
+// file. The tests are skipped if REGTEST_RPC_URL is not set.
+package integration
@@ -0,0 +1,255 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+// nodes is the number of avalanche nodes voting on the regtest transactions
+const nodes = 8
+
+// rpcErrWalletNotFound is returned by nodes with multiwallet support until a
+// wallet is created
+const rpcErrWalletNotFound = -18
+
+// regtest is a regtest node and the wallet used to make transactions with it
+type regtest struct {
+	t      *testing.T
+	client *bitcoinrpc.Client
+	addr   string
+}
+
+func newRegtest(t *testing.T) *regtest {
+	url := os.Getenv("REGTEST_RPC_URL")
+	if url == "" {
+		t.Skip("REGTEST_RPC_URL is not set")
+	}
+	user, password := os.Getenv("REGTEST_RPC_USER"), os.Getenv("REGTEST_RPC_PASSWORD")
+	if user == "" {
+		user, password = "avalanche", "avalanche"
+	}
+
+	r := &regtest{t: t, client: bitcoinrpc.NewClient(bitcoinrpc.Config{URL: url, User: user, Password: password})}
+
+	// The node may still be starting up
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		_, err := r.client.Call("getblockchaininfo")
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Node did not come up:", err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	_, err := r.client.Call("getnewaddress")
+	if e, ok := err.(*bitcoinrpc.RPCError); ok && e.Code == rpcErrWalletNotFound {
+		r.call(nil, "createwallet", "integration")
+	}
+	r.call(&r.addr, "getnewaddress")
+
+	// Mature enough coinbase outputs to spend
+	r.call(nil, "generatetoaddress", 101, r.addr)
+	return r
+}
+
+// call makes an RPC, decoding the result into result if it is not nil
+func (r *regtest) call(result interface{}, method string, params ...interface{}) {
+	raw, err := r.client.Call(method, params...)
+	if err != nil {
+		r.t.Fatal(method, "failed:", err)
+	}
+	if result != nil {
+		if err = json.Unmarshal(raw, result); err != nil {
+			r.t.Fatal(method, "returned", string(raw), err)
+		}
+	}
+}
+
+// conflictingTxs builds two signed transactions spending the same output and
+// sends the first, which the node accepts into its mempool. It returns the
+// txids of both.
+func (r *regtest) conflictingTxs() (sent, conflict string) {
+	utxos := []struct {
+		TxID   string  `json:"txid"`
+		Vout   int     `json:"vout"`
+		Amount float64 `json:"amount"`
+	}{}
+	r.call(&utxos, "listunspent")
+	if len(utxos) == 0 {
+		r.t.Fatal("No spendable outputs")
+	}
+	utxo := utxos[0]
+
+	txids := [2]string{}
+	for i, fee := range []float64{0.001, 0.002} {
+		inputs := []map[string]interface{}{{"txid": utxo.TxID, "vout": utxo.Vout}}
+		outputs := map[string]float64{r.newAddress(): utxo.Amount - fee}
+
+		raw, signed := "", struct {
+			Hex      string `json:"hex"`
+			Complete bool   `json:"complete"`
+		}{}
+		r.call(&raw, "createrawtransaction", inputs, outputs)
+		r.call(&signed, "signrawtransactionwithwallet", raw)
+		if !signed.Complete {
+			r.t.Fatal("Could not sign", raw)
+		}
+
+		decoded := struct {
+			TxID string `json:"txid"`
+		}{}
+		r.call(&decoded, "decoderawtransaction", signed.Hex)
+		txids[i] = decoded.TxID
+
+		if i == 0 {
+			r.call(nil, "sendrawtransaction", signed.Hex)
+		}
+	}
+	return txids[0], txids[1]
+}
+
+func (r *regtest) newAddress() string {
+	addr := ""
+	r.call(&addr, "getnewaddress")
+	return addr
+}
+
+// mempoolProvider is a TargetProvider looking transactions up in the node's
+// mempool. Transactions it doesn't have, such as ones conflicting with its
+// mempool, are valid but not accepted.
+type mempoolProvider struct {
+	r     *regtest
+	txids map[avalanche.Hash]string
+}
+
+func (p *mempoolProvider) TxInfo(h avalanche.Hash) (avalanche.TxInfo, error) {
+	// Nodes differ in whether they report fee and size or fees.base and vsize
+	entry := struct {
+		Fee  float64 `json:"fee"`
+		Fees struct {
+			Base float64 `json:"base"`
+		} `json:"fees"`
+		Size  int64 `json:"size"`
+		VSize int64 `json:"vsize"`
+	}{}
+	raw, err := p.r.client.Call("getmempoolentry", p.txids[h])
+	if _, ok := err.(*bitcoinrpc.RPCError); ok {
+		return avalanche.TxInfo{Valid: true}, nil
+	}
+	if err != nil {
+		return avalanche.TxInfo{}, err
+	}
+	if err = json.Unmarshal(raw, &entry); err != nil {
+		return avalanche.TxInfo{}, err
+	}
+
+	fee, size := entry.Fee, entry.Size
+	if fee == 0 {
+		fee = entry.Fees.Base
+	}
+	if size == 0 {
+		size = entry.VSize
+	}
+	return avalanche.TxInfo{Fee: int64(fee * 1e8), Size: size, Valid: true, InMempool: true}, nil
+}
+
+// conflicts makes the transactions conflict with each other
+type conflicts map[avalanche.Hash][]avalanche.Hash
+
+func (c conflicts) Conflicts(h avalanche.Hash) []avalanche.Hash { return c[h] }
+
+func TestRegtestConflict(t *testing.T) {
+	r := newRegtest(t)
+	sent, conflict := r.conflictingTxs()
+
+	provider := &mempoolProvider{r, map[avalanche.Hash]string{}}
+	hashes := map[string]avalanche.Hash{}
+	for _, txid := range []string{sent, conflict} {
+		h, err := avalanche.HashFromDisplayHex(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider.txids[h] = txid
+		hashes[txid] = h
+	}
+
+	// Every avalanche node sees the node's mempool, so all accept the sent
+	// transaction and reject the one conflicting with it
+	processors := make([]*avalanche.Processor, nodes)
+	outcomes := make([]map[avalanche.Hash]avalanche.Status, nodes)
+	for i := range processors {
+		p := avalanche.NewProcessor(avalanche.NewConnman())
+		p.SetConflictSource(conflicts{
+			hashes[sent]:     {hashes[conflict]},
+			hashes[conflict]: {hashes[sent]},
+		})
+		for _, txid := range []string{sent, conflict} {
+			tx, err := avalanche.NewTx(hashes[txid], provider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !p.AddTargetToReconcile(tx) {
+				t.Fatal("Could not add", txid)
+			}
+		}
+		processors[i] = p
+		outcomes[i] = map[avalanche.Hash]avalanche.Status{}
+	}
+
+	// Each node polls the next until every node has decided both
+	deadline := time.Now().Add(30 * time.Second)
+	for decided := 0; decided < nodes*2; {
+		if time.Now().After(deadline) {
+			t.Fatal("Only", decided, "decisions were made in time")
+		}
+
+		for i, p := range processors {
+			peer := (i + 1) % nodes
+			poll, ok := p.PollNode(avalanche.NodeID(peer))
+			if !ok {
+				continue
+			}
+
+			resp := processors[peer].HandlePoll(avalanche.NodeID(i), poll)
+			updates := []avalanche.StatusUpdate{}
+			p.RegisterVotes(avalanche.NodeID(peer), resp, &updates)
+
+			for _, u := range updates {
+				if u.Status == avalanche.StatusFinalized || u.Status == avalanche.StatusInvalid {
+					outcomes[i][u.Hash] = u.Status
+					decided++
+				}
+			}
+		}
+	}
+
+	for i, outcome := range outcomes {
+		if outcome[hashes[sent]] != avalanche.StatusFinalized {
+			t.Fatal("Node", i, "did not finalize", sent)
+		}
+		if outcome[hashes[conflict]] != avalanche.StatusInvalid {
+			t.Fatal("Node", i, "did not reject", conflict)
+		}
+	}
+
+	// The node still agrees once a block confirms the finalized transaction
+	r.call(nil, "generatetoaddress", 1, r.addr)
+	confirmed := struct {
+		Confirmations int `json:"confirmations"`
+	}{}
+	r.call(&confirmed, "getrawtransaction", sent, true)
+	if confirmed.Confirmations < 1 {
+		t.Fatal("Expected", sent, "to be mined")
+	}
+}
@@ -0,0 +1,91 @@
+package avalanche
+
+import "strconv"
+
+// PollSender sends a Poll to a node and waits for its Response. It is the
+// client side of a transport, as PollHandler is the server side.
+type PollSender interface {
+	SendPoll(NodeID, Poll) (Response, error)
+}
+
+// PollSenderFunc allows a plain function to be used as a PollSender
+type PollSenderFunc func(NodeID, Poll) (Response, error)
+
+// SendPoll calls f(id, poll)
+func (f PollSenderFunc) SendPoll(id NodeID, poll Poll) (Response, error) {
+	return f(id, poll)
+}
+
+// SetPollSender sets how the running Processor sends the Polls its event loop
+// builds for nodes that aren't loopback peers, passing each one through the
+// interceptors in order first. Every Poll is sent on its own goroutine and its
+// Response is registered by the event loop as a loopback peer's would be. A
+// send error is reported to the ErrorReporter and the query expires as usual.
+// It must be called before the *Processor is started.
+func (p *Processor) SetPollSender(sender PollSender, interceptors ...SendInterceptor) {
+	p.sender = ChainSendInterceptors(sender, interceptors...)
+}
+
+// sendPoll sends a poll through the PollSender, if the Processor is running
+func (p *Processor) sendPoll(id NodeID, poll Poll) {
+	quit := p.quitCh
+	if quit == nil {
+		return
+	}
+
+	sender, reporter := p.sender, p.reporter
+	go func() {
+		resp, err := sender.SendPoll(id, poll)
+		if err != nil {
+			reportError(reporter, &Error{"send poll", err}, map[string]string{
+				"node": strconv.FormatInt(int64(id), 10),
+			})
+			return
+		}
+		select {
+		case p.responseCh <- polledResponse{id, resp}:
+		case <-quit:
+		}
+	}()
+}
+
+// PollInterceptor wraps the handling of inbound Polls, in the way of a gRPC
+// server interceptor, for concerns such as logging, metrics, auth and fault
+// injection. It may change the Poll before calling next, change the Response
+// next returns, or answer the Poll without calling next at all.
+type PollInterceptor func(id NodeID, poll Poll, next PollHandler) Response
+
+// SendInterceptor wraps the sending of outbound Polls in the same way
+type SendInterceptor func(id NodeID, poll Poll, next PollSender) (Response, error)
+
+// ChainPollInterceptors returns a PollHandler that passes each Poll through the
+// interceptors in order before h handles it, so the first interceptor is the
+// outermost
+func ChainPollInterceptors(h PollHandler, interceptors ...PollInterceptor) PollHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		h = interceptPoll(interceptors[i], h)
+	}
+	return h
+}
+
+// ChainSendInterceptors returns a PollSender that passes each Poll through the
+// interceptors in order before s sends it, so the first interceptor is the
+// outermost
+func ChainSendInterceptors(s PollSender, interceptors ...SendInterceptor) PollSender {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		s = interceptSend(interceptors[i], s)
+	}
+	return s
+}
+
+func interceptPoll(interceptor PollInterceptor, next PollHandler) PollHandler {
+	return PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		return interceptor(id, poll, next)
+	})
+}
+
+func interceptSend(interceptor SendInterceptor, next PollSender) PollSender {
+	return PollSenderFunc(func(id NodeID, poll Poll) (Response, error) {
+		return interceptor(id, poll, next)
+	})
+}
@@ -0,0 +1,128 @@
+package avalanche
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChainPollInterceptors(t *testing.T) {
+	calls := []string{}
+	handler := PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		calls = append(calls, "handler")
+		return NewResponse(poll.GetRound(), 0, []Vote{NewVote(VoteYes, poll.GetInvs()[0].TargetHash)})
+	})
+	logging := func(id NodeID, poll Poll, next PollHandler) Response {
+		calls = append(calls, "logging")
+		return next.HandlePoll(id, poll)
+	}
+
+	// Only node 1 may poll us, and everyone else is told nothing
+	auth := func(id NodeID, poll Poll, next PollHandler) Response {
+		calls = append(calls, "auth")
+		if id != NodeID(1) {
+			return NewResponse(poll.GetRound(), 0, nil)
+		}
+		return next.HandlePoll(id, poll)
+	}
+
+	h := ChainPollInterceptors(handler, logging, auth)
+	poll := NewPoll(3, []Inv{{"block", Hash(1)}})
+
+	resp := h.HandlePoll(NodeID(1), poll)
+	assertTrue(t, resp.GetRound() == 3 && len(resp.GetVotes()) == 1)
+	assertTrue(t, len(calls) == 3 && calls[0] == "logging" && calls[1] == "auth" && calls[2] == "handler")
+
+	calls = calls[:0]
+	resp = h.HandlePoll(NodeID(2), poll)
+	assertTrue(t, len(resp.GetVotes()) == 0)
+	assertTrue(t, len(calls) == 2)
+
+	// Without interceptors the handler is used as is
+	assertTrue(t, len(ChainPollInterceptors(handler).HandlePoll(NodeID(2), poll).GetVotes()) == 1)
+}
+
+func TestChainSendInterceptors(t *testing.T) {
+	errDropped := errors.New("dropped")
+	sent := 0
+	sender := PollSenderFunc(func(id NodeID, poll Poll) (Response, error) {
+		sent++
+		return NewResponse(poll.GetRound(), 0, nil), nil
+	})
+
+	// Fault injection drops every other poll before it is sent
+	polls := 0
+	faults := func(id NodeID, poll Poll, next PollSender) (Response, error) {
+		polls++
+		if polls%2 == 0 {
+			return Response{}, errDropped
+		}
+		return next.SendPoll(id, poll)
+	}
+
+	// Metrics count the errors the rest of the chain returns
+	failed := 0
+	metrics := func(id NodeID, poll Poll, next PollSender) (Response, error) {
+		resp, err := next.SendPoll(id, poll)
+		if err != nil {
+			failed++
+		}
+		return resp, err
+	}
+
+	s := ChainSendInterceptors(sender, metrics, faults)
+	for i := 0; i < 4; i++ {
+		resp, err := s.SendPoll(NodeID(1), NewPoll(int64(i), nil))
+		assertTrue(t, (err == errDropped) == (i%2 == 1))
+		assertTrue(t, err != nil || resp.GetRound() == int64(i))
+	}
+	assertTrue(t, sent == 2 && failed == 2)
+}
+
+func TestSetPollSender(t *testing.T) {
+	config := DefaultConfig
+	config.PollInterval = time.Millisecond
+	config.FinalizationScore = 4
+	p := NewProcessorWithConfig(NewConnman(), config)
+	assertTrue(t, p.connman.AddNode(NodeID(1)))
+	assertTrue(t, p.connman.AddNode(NodeID(2)))
+
+	// Polls to node 2 fail to send and are reported; node 1 answers Yes
+	errDropped := errors.New("dropped")
+	var reported int32
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		if e, ok := err.(*Error); ok && e.Err == errDropped && tags["node"] == "2" {
+			atomic.AddInt32(&reported, 1)
+		}
+	}))
+	sender := PollSenderFunc(func(id NodeID, poll Poll) (Response, error) {
+		votes := []Vote{}
+		for _, inv := range poll.GetInvs() {
+			votes = append(votes, NewVote(VoteYes, inv.TargetHash))
+		}
+		return NewResponse(poll.GetRound(), 0, votes), nil
+	})
+	faults := func(id NodeID, poll Poll, next PollSender) (Response, error) {
+		if id == NodeID(2) {
+			return Response{}, errDropped
+		}
+		return next.SendPoll(id, poll)
+	}
+	p.SetPollSender(sender, faults)
+
+	updates := p.Subscribe()
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(65), 1, true, true}))
+	assertTrue(t, p.Start())
+	timeout := time.After(5 * time.Second)
+	for finalized := false; !finalized; {
+		select {
+		case u := <-updates:
+			finalized = u.Status == StatusFinalized
+		case <-timeout:
+			t.Fatal("Timed out waiting for finalization")
+		}
+	}
+	assertTrue(t, p.Stop())
+	assertTrue(t, atomic.LoadInt32(&reported) > 0)
+}
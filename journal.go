@@ -0,0 +1,268 @@
+package avalanche
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JournaledQuery is an outstanding query as recorded in a QueryJournal
+type JournaledQuery struct {
+	Round     int64  `json:"round"`
+	NodeID    NodeID `json:"node"`
+	Timestamp int64  `json:"timestamp"`
+	Invs      []Inv  `json:"invs"`
+}
+
+// QueryJournal durably records outstanding queries so they survive a crash.
+// After a restart the Processor recovers them with RecoverQueries, so late
+// responses are matched to the queries they answer rather than to new queries
+// that happen to reuse their round. It also remembers the highest round
+// recorded, so rounds aren't reused even when no queries are outstanding.
+type QueryJournal interface {
+	// Record adds an outstanding query. It must be durable when it returns.
+	Record(JournaledQuery) error
+
+	// Remove marks a query as answered or expired
+	Remove(round int64, nodeID NodeID) error
+
+	// Load returns the queries still outstanding, ordered by round, and the
+	// round after the highest one ever recorded
+	Load() (queries []JournaledQuery, nextRound int64, err error)
+}
+
+// RecoverQueries restores the outstanding queries in j and journals every query
+// from then on. Rounds continue after the highest round journaled, whether or
+// not its query is still outstanding. Recovered queries that have since expired
+// are cleaned up on the next tick as usual.
+func (p *Processor) RecoverQueries(j QueryJournal) error {
+	queries, nextRound, err := j.Load()
+	if err != nil {
+		return err
+	}
+	if nextRound > p.round {
+		p.round = nextRound
+	}
+
+	for _, q := range queries {
+		key := queryKey{q.Round, q.NodeID}
+		if _, ok := p.queries[key]; ok {
+			continue
+		}
+
+		// The wall clock is all there is to go on across a restart, but the
+		// rest of the query's life is timed with the monotonic clock
+		r := NewRequestRecord(q.Timestamp, q.Invs)
+		now := clock.Now()
+		r.sent = now.Add(-now.Sub(time.Unix(q.Timestamp, 0)))
+		p.addQuery(key, r)
+		if q.Round >= p.round {
+			p.round = q.Round + 1
+		}
+	}
+
+	p.journal = j
+	return nil
+}
+
+// journalQuery records a new query, returning false if it could not be
+// recorded. Queries that aren't journaled must not be sent, or a crash could
+// leave responses to them unaccounted for.
+func (p *Processor) journalQuery(key queryKey, r RequestRecord) bool {
+	if p.journal == nil {
+		return true
+	}
+	err := p.journal.Record(JournaledQuery{key.round, key.nodeID, r.GetTimestamp(), r.GetInvs()})
+	if err != nil {
+		reportError(p.reporter, err, map[string]string{"loop": "event"})
+		return false
+	}
+	return true
+}
+
+// unjournalQuery removes a finished query from the journal. A failure leaves a
+// stale entry that expires after recovery, so it is not treated as fatal.
+func (p *Processor) unjournalQuery(key queryKey) {
+	if p.journal != nil {
+		p.journal.Remove(key.round, key.nodeID)
+	}
+}
+
+// journalOp is a line in a FileQueryJournal. Removals only set Round and
+// NodeID, and the "round" lines written by compaction only set Round, the
+// highest round recorded before it.
+type journalOp struct {
+	Op string `json:"op"`
+	JournaledQuery
+}
+
+// FileQueryJournal is a QueryJournal stored as a log of JSON lines. The log is
+// compacted down to the outstanding queries and the highest round each time it
+// is loaded.
+type FileQueryJournal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// OpenFileQueryJournal opens the journal at path, creating it if needed
+func OpenFileQueryJournal(path string) (*FileQueryJournal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, &Error{"open journal", err}
+	}
+	return &FileQueryJournal{path: path, f: f}, nil
+}
+
+// Record appends the query and syncs the file
+func (j *FileQueryJournal) Record(q JournaledQuery) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.write(journalOp{"add", q}); err != nil {
+		return &Error{"record query", err}
+	}
+	return wrapError("record query", j.f.Sync())
+}
+
+// Remove appends the removal. It is not synced; losing it only means the query
+// is recovered and then expires.
+func (j *FileQueryJournal) Remove(round int64, nodeID NodeID) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return wrapError("remove query", j.write(journalOp{"del", JournaledQuery{Round: round, NodeID: nodeID}}))
+}
+
+// Load replays the log and rewrites it with only the outstanding queries and
+// the highest round
+func (j *FileQueryJournal) Load() ([]JournaledQuery, int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	queries, nextRound, err := j.replay()
+	if err != nil {
+		return nil, 0, &Error{"load journal", err}
+	}
+
+	if err = j.compact(queries, nextRound); err != nil {
+		return nil, 0, &Error{"compact journal", err}
+	}
+	return queries, nextRound, nil
+}
+
+// Close closes the journal file
+func (j *FileQueryJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return wrapError("close journal", j.f.Close())
+}
+
+func (j *FileQueryJournal) write(op journalOp) error {
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	_, err = j.f.Write(append(line, '\n'))
+	return err
+}
+
+// replay reads the log and returns the queries added but not removed, and the
+// round after the highest one seen. A torn final line from a crash mid-write
+// is ignored.
+func (j *FileQueryJournal) replay() ([]JournaledQuery, int64, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var nextRound int64
+	outstanding := map[queryKey]JournaledQuery{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<26)
+	for scanner.Scan() {
+		op := journalOp{}
+		if json.Unmarshal(scanner.Bytes(), &op) != nil {
+			continue
+		}
+
+		key := queryKey{op.Round, op.NodeID}
+		switch op.Op {
+		case "add":
+			outstanding[key] = op.JournaledQuery
+		case "del":
+			delete(outstanding, key)
+		}
+
+		// Every round in the log was recorded at some point, including those
+		// of removed queries and the one left by compaction
+		if op.Round >= nextRound {
+			nextRound = op.Round + 1
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	queries := make([]JournaledQuery, 0, len(outstanding))
+	for _, q := range outstanding {
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, k int) bool {
+		if queries[i].Round != queries[k].Round {
+			return queries[i].Round < queries[k].Round
+		}
+		return queries[i].NodeID < queries[k].NodeID
+	})
+	return queries, nextRound, nil
+}
+
+// compact replaces the log with one holding only the given queries and the
+// round before nextRound
+func (j *FileQueryJournal) compact(queries []JournaledQuery, nextRound int64) error {
+	tmp, err := os.OpenFile(j.path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	old := j.f
+	j.f = tmp
+	if nextRound > 0 {
+		err = j.write(journalOp{"round", JournaledQuery{Round: nextRound - 1}})
+	}
+	for i := 0; i < len(queries) && err == nil; i++ {
+		err = j.write(journalOp{"add", queries[i]})
+	}
+	j.f = old
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(j.path + ".tmp")
+		return err
+	}
+
+	// Windows won't rename over an open file, so close the log first and
+	// reopen whichever one is in place afterwards
+	j.f.Close()
+	err = os.Rename(j.path+".tmp", j.path)
+	if err != nil {
+		os.Remove(j.path + ".tmp")
+	}
+
+	f, openErr := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if openErr != nil {
+		return openErr
+	}
+	j.f = f
+	return err
+}
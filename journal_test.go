@@ -0,0 +1,89 @@
+package avalanche
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryJournalRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "avalanche-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "queries.log")
+
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollWindow: 2})
+		pindex  = blockForHash(Hash(65))
+		updates = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+
+	j, err := OpenFileQueryJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, p.RecoverQueries(j) == nil)
+
+	// Two queries go out and one is answered before we crash
+	p.eventLoop()
+	p.eventLoop()
+	resp := NewResponse(0, 0, []Vote{NewVote(0, pindex.Hash())})
+	assertTrue(t, p.RegisterVotes(NodeID(0), resp, &updates))
+	assertTrue(t, j.Close() == nil)
+
+	// A restarted Processor picks up the unanswered query and carries on from
+	// the next round
+	j, err = OpenFileQueryJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	restarted := NewProcessorWithConfig(connman, Config{PollWindow: 2})
+	assertTrue(t, restarted.AddTargetToReconcile(pindex))
+	assertTrue(t, restarted.RecoverQueries(j) == nil)
+
+	assertTrue(t, len(restarted.queries) == 1)
+	assertTrue(t, restarted.GetRound() == 2)
+	r, ok := restarted.queries[queryKey{1, NodeID(0)}]
+	assertTrue(t, ok)
+	assertTrue(t, len(r.GetInvs()) == 1 && r.GetInvs()[0].TargetHash == pindex.Hash())
+
+	// The window accounts for it, and new queries don't reuse its round
+	restarted.eventLoop()
+	assertTrue(t, restarted.getSuitableNodeToQuery() == NoNode)
+	_, ok = restarted.queries[queryKey{2, NodeID(0)}]
+	assertTrue(t, ok)
+
+	// The journal was compacted and keeps being appended to
+	queries, nextRound, err := j.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 2 || queries[0].Round != 1 || queries[1].Round != 2 || nextRound != 3 {
+		t.Fatal("Unexpected journaled queries:", queries, nextRound)
+	}
+
+	// Once every query is answered, a restart still doesn't reuse their rounds
+	for _, q := range queries {
+		resp := NewResponse(q.Round, 0, []Vote{NewVote(0, pindex.Hash())})
+		assertTrue(t, restarted.RegisterVotes(NodeID(0), resp, &updates))
+	}
+	assertTrue(t, j.Close() == nil)
+	for i := 0; i < 2; i++ {
+		j, err = OpenFileQueryJournal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again := NewProcessorWithConfig(connman, Config{PollWindow: 2})
+		assertTrue(t, again.RecoverQueries(j) == nil)
+		assertTrue(t, len(again.queries) == 0 && again.GetRound() == 3)
+		assertTrue(t, j.Close() == nil)
+	}
+}
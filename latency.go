@@ -0,0 +1,78 @@
+package avalanche
+
+import (
+	"sort"
+	"time"
+)
+
+// peerLatency holds a peer's most recent response times
+type peerLatency struct {
+	samples []time.Duration
+	next    int
+}
+
+// add records a response time, replacing the oldest once full
+func (l *peerLatency) add(d time.Duration) {
+	if len(l.samples) < AvalanchePeerLatencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+// percentile returns the pth percentile of the recorded response times
+func (l *peerLatency) percentile(p float64) time.Duration {
+	if len(l.samples) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// recordLatency records how long a node took to respond to a query
+func (p *Processor) recordLatency(id NodeID, d time.Duration) {
+	l, ok := p.latencies[id]
+	if !ok {
+		l = &peerLatency{}
+		p.latencies[id] = l
+	}
+	l.add(d)
+}
+
+// GetPeerLatency returns the p95 of a node's recent response times, and false
+// if no responses have been timed yet. Unanswered queries count as taking
+// AvalancheRequestTimeout.
+func (p *Processor) GetPeerLatency(id NodeID) (time.Duration, bool) {
+	l, ok := p.latencies[id]
+	if !ok {
+		return 0, false
+	}
+	return l.percentile(0.95), true
+}
+
+// IsDemoted returns whether a node is slow enough to have been moved to the
+// probe pool. A node is promoted again once its p95 response time recovers,
+// which probes give it the chance to do.
+func (p *Processor) IsDemoted(id NodeID) bool {
+	if p.config.SlowPeerThreshold <= 0 {
+		return false
+	}
+
+	l, ok := p.latencies[id]
+	if !ok || len(l.samples) < AvalancheSlowPeerMinSamples {
+		return false
+	}
+	return l.percentile(0.95) > p.config.SlowPeerThreshold
+}
+
+// slowPeerProbeInterval returns how many rounds pass between polls of demoted
+// nodes
+func (p *Processor) slowPeerProbeInterval() int {
+	if p.config.SlowPeerProbeInterval <= 0 {
+		return AvalancheSlowPeerProbeInterval
+	}
+	return p.config.SlowPeerProbeInterval
+}
@@ -0,0 +1,109 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowPeerDemotion(t *testing.T) {
+	var (
+		connman = NewConnman()
+		config  = Config{SlowPeerThreshold: 100 * time.Millisecond, SlowPeerProbeInterval: 4}
+		p       = NewProcessorWithConfig(connman, config)
+		pindex  = blockForHash(Hash(65))
+		updates = []StatusUpdate{}
+		now     = time.Now()
+	)
+	connman.AddNode(NodeID(0))
+	connman.AddNode(NodeID(1))
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	// respond answers the outstanding query to the node after d
+	respond := func(id NodeID, d time.Duration) {
+		for key := range p.queries {
+			if key.nodeID == id {
+				now = now.Add(d)
+				clock = stubClocker{now}
+				resp := NewResponse(key.round, 0, []Vote{NewVote(negativeOne, pindex.Hash())})
+				assertTrue(t, p.RegisterVotes(id, resp, &updates))
+				return
+			}
+		}
+		t.Fatal("No query outstanding to node", id)
+	}
+
+	// Node 0 is queried first, until it has shown itself to be slow
+	for i := 0; i < AvalancheSlowPeerMinSamples; i++ {
+		assertFalse(t, p.IsDemoted(NodeID(0)))
+		p.round = 1
+		assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+		p.eventLoop()
+		respond(NodeID(0), time.Second)
+	}
+	assertTrue(t, p.IsDemoted(NodeID(0)))
+	latency, ok := p.GetPeerLatency(NodeID(0))
+	assertTrue(t, ok && latency == time.Second)
+
+	// Node 1 is preferred now, and node 0 is only probed every 4 rounds
+	p.round = 1
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(1))
+	p.round = 4
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+
+	// Demoted nodes are still used when nothing else is available
+	p.round = 1
+	p.eventLoop()
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+	respond(NodeID(1), time.Millisecond)
+	latency, ok = p.GetPeerLatency(NodeID(1))
+	assertTrue(t, ok && latency == time.Millisecond)
+
+	// Fast probe responses promote the node again
+	for p.IsDemoted(NodeID(0)) {
+		p.round = 4
+		p.eventLoop()
+		respond(NodeID(0), time.Millisecond)
+	}
+	p.round = 1
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(0))
+
+	// Unanswered queries count against a node
+	for i := 0; i < AvalancheSlowPeerMinSamples; i++ {
+		p.eventLoop()
+		now = now.Add(2 * AvalancheRequestTimeout)
+		clock = stubClocker{now}
+		p.expireQueries()
+	}
+	assertTrue(t, p.IsDemoted(NodeID(0)))
+}
+
+func TestInvalidResponseNotTimed(t *testing.T) {
+	connman := NewConnman()
+	connman.AddNode(NodeID(0))
+	p := NewProcessorWithConfig(connman, Config{})
+	for i := 0; i < 4; i++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{Hash(i), int64(i), true, true}))
+	}
+
+	// A truncated response from another network is refused without being
+	// timed or shrinking the node's polls
+	poll, ok := p.PollNode(NodeID(0))
+	assertTrue(t, ok && len(poll.GetInvs()) == 4)
+	resp := NewTruncatedResponse(poll.GetRound(), 0, []Vote{NewVote(VoteYes, poll.GetInvs()[0].TargetHash)})
+	assertFalse(t, p.RegisterVotes(NodeID(0), resp.WithNetwork(TestNet), &[]StatusUpdate{}))
+	_, ok = p.GetPeerLatency(NodeID(0))
+	assertFalse(t, ok)
+	assertTrue(t, p.responseInvLimit(NodeID(0), AvalancheMaxElementPoll) == AvalancheMaxElementPoll)
+
+	// The same response on our network is
+	poll, ok = p.PollNode(NodeID(0))
+	assertTrue(t, ok)
+	resp = NewTruncatedResponse(poll.GetRound(), 0, []Vote{NewVote(VoteYes, poll.GetInvs()[0].TargetHash)})
+	assertTrue(t, p.RegisterVotes(NodeID(0), resp, &[]StatusUpdate{}))
+	_, ok = p.GetPeerLatency(NodeID(0))
+	assertTrue(t, ok)
+	assertTrue(t, p.responseInvLimit(NodeID(0), AvalancheMaxElementPoll) == 1)
+}
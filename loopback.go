@@ -0,0 +1,79 @@
+package avalanche
+
+import "time"
+
+// LoopbackConfig sets how a loopback peer answers; see AddLoopbackPeer
+type LoopbackConfig struct {
+	// Latency is how long the peer takes to answer a poll, and Jitter is the
+	// most a response may randomly be later than that
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// DropRate is the chance, from 0 to 1, that a poll is never answered, so
+	// the query times out
+	DropRate float64
+
+	// Vote decides the peer's vote on each Inv. If it is nil the peer answers
+	// as HandlePoll would, with the Processor's own view.
+	Vote func(Inv) uint32
+}
+
+// polledResponse is a loopback peer's or a PollSender's response waiting to be
+// registered by the event loop
+type polledResponse struct {
+	nodeID NodeID
+	resp   Response
+}
+
+// AddLoopbackPeer adds a built-in node with the given ID that answers the
+// Processor's own polls after a synthetic delay, so a single node can
+// benchmark its processing pipeline without a network. Responses are
+// registered by the running Processor's event loop, and are dropped while it is
+// stopped. It returns false if the Connman refuses the node. It must be called
+// before the *Processor is started.
+func (p *Processor) AddLoopbackPeer(id NodeID, config LoopbackConfig) bool {
+	if !p.connman.AddNode(id) {
+		return false
+	}
+	p.loopbacks[id] = config
+	return true
+}
+
+// sendLoopbackPoll answers a poll sent to a loopback peer once its delay has
+// passed, if the Processor is still running
+func (p *Processor) sendLoopbackPoll(id NodeID, config LoopbackConfig, poll Poll) {
+	quit := p.quitCh
+	if quit == nil || p.rng.Float64() < config.DropRate {
+		return
+	}
+
+	resp := p.HandlePoll(id, poll)
+	if config.Vote != nil {
+		resp.Release()
+		invs := poll.GetInvs()
+		votes := make([]Vote, len(invs))
+		for i, inv := range invs {
+			votes[i] = NewVote(config.Vote(inv), inv.TargetHash)
+		}
+		resp = NewResponse(poll.GetRound(), 0, votes).WithNetwork(p.network())
+	}
+
+	delay := config.Latency
+	if config.Jitter > 0 {
+		delay += time.Duration(p.rng.Int63n(int64(config.Jitter) + 1))
+	}
+	time.AfterFunc(delay, func() {
+		select {
+		case p.responseCh <- polledResponse{id, resp}:
+		case <-quit:
+		}
+	})
+}
+
+// registerPolledResponse registers a loopback peer's or a PollSender's
+// response. Updates are only published to subscribers.
+func (p *Processor) registerPolledResponse(lr polledResponse) {
+	updates := []StatusUpdate{}
+	p.RegisterVotes(lr.nodeID, lr.resp, &updates)
+	lr.resp.Release()
+}
@@ -0,0 +1,54 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoopbackPeer(t *testing.T) {
+	newProcessor := func(lc LoopbackConfig) (*Processor, <-chan StatusUpdate) {
+		config := DefaultConfig
+		config.PollInterval = time.Millisecond
+		config.FinalizationScore = 4
+		p := NewProcessorWithConfig(NewConnman(), config)
+		assertTrue(t, p.AddLoopbackPeer(NodeID(1), lc))
+		assertFalse(t, p.AddLoopbackPeer(NodeID(1), lc))
+		return p, p.Subscribe()
+	}
+	waitFor := func(updates <-chan StatusUpdate, status Status) {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case u := <-updates:
+				if u.Status == status {
+					return
+				}
+			case <-timeout:
+				t.Fatal("Timed out waiting for", status)
+			}
+		}
+	}
+
+	// By default the peer agrees with our own view
+	p, updates := newProcessor(LoopbackConfig{Latency: time.Millisecond, Jitter: time.Millisecond})
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(65), 1, true, true}))
+	assertTrue(t, p.Start())
+	waitFor(updates, StatusFinalized)
+	assertTrue(t, p.Stop())
+
+	// The peer's votes can be set
+	p, updates = newProcessor(LoopbackConfig{Vote: func(Inv) uint32 { return VoteNo }})
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(65), 1, true, true}))
+	assertTrue(t, p.Start())
+	waitFor(updates, StatusInvalid)
+	assertTrue(t, p.Stop())
+
+	// Dropped polls are never answered
+	p, _ = newProcessor(LoopbackConfig{DropRate: 1})
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(65), 1, true, true}))
+	assertTrue(t, p.Start())
+	time.Sleep(20 * time.Millisecond)
+	assertTrue(t, p.Stop())
+	info := p.PeerInfo()
+	assertTrue(t, len(info) == 1 && info[0].PollsSent > 0 && info[0].ResponsesReceived == 0)
+}
@@ -0,0 +1,76 @@
+package avalanche
+
+// MempoolSource lists the targets currently in a node's mempool
+type MempoolSource interface {
+	List() ([]Target, error)
+}
+
+// AcceptancePolicy decides whether a Target should initially be considered
+// accepted, in place of the Target's own IsAccepted
+type AcceptancePolicy func(Target) bool
+
+// WarmStart adds every target listed by src for reconciliation so a restarted
+// node immediately takes part in ongoing voting. The initial acceptance of each
+// target is decided by policy, or as by AddTargetToReconcile if policy is nil.
+// It returns the number of targets added.
+func (p *Processor) WarmStart(src MempoolSource, policy AcceptancePolicy) (int, error) {
+	targets, err := src.List()
+	if err != nil {
+		return 0, &Error{"list mempool", err}
+	}
+
+	if policy == nil {
+		policy = p.isInitiallyAccepted
+	}
+
+	added := 0
+	for _, t := range targets {
+		if p.addTarget(t, policy(t), nil) {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// SyncMempool withdraws targets of the given type that are no longer in any of
+// the mempools listed by srcs, so they aren't voted on forever after being
+// evicted. A target is only withdrawn, with WithdrawEvicted, once it has been
+// missing from two syncs in a row, so a transaction that left the mempools for
+// a block that HandleBlock hasn't seen yet is resolved by inclusion instead.
+// Call it periodically. Nothing is withdrawn if any source fails. It returns
+// the number of targets withdrawn.
+func (p *Processor) SyncMempool(targetType string, srcs ...MempoolSource) (int, error) {
+	listed := map[Hash]struct{}{}
+	for _, src := range srcs {
+		targets, err := src.List()
+		if err != nil {
+			return 0, &Error{"list mempool", err}
+		}
+		for _, t := range targets {
+			listed[t.Hash()] = struct{}{}
+		}
+	}
+
+	missing := map[Hash]struct{}{}
+	withdrawn := 0
+	for h, t := range p.targets {
+		if t.Type() != targetType {
+			continue
+		}
+		if _, ok := listed[h]; ok {
+			continue
+		}
+
+		if _, ok := p.missing[h]; !ok {
+			missing[h] = struct{}{}
+			continue
+		}
+		if p.RemoveTarget(h, WithdrawEvicted) {
+			withdrawn++
+		}
+	}
+	p.missing = missing
+
+	return withdrawn, nil
+}
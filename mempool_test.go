@@ -0,0 +1,93 @@
+package avalanche
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubMempool struct {
+	targets []Target
+	err     error
+}
+
+func (m stubMempool) List() ([]Target, error) { return m.targets, m.err }
+
+func TestWarmStart(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		a       = &Block{Hash(1), 1, true, false}
+		b       = &Block{Hash(2), 1, true, true}
+		invalid = &Block{Hash(3), 1, false, true}
+		mempool = stubMempool{targets: []Target{a, b, invalid}}
+	)
+
+	// Our policy accepts targets regardless of what they think of themselves
+	policy := func(t Target) bool { return t.Hash() != b.Hash() }
+
+	added, err := p.WarmStart(mempool, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Fatal("Expected 2 targets to be added but got", added)
+	}
+	assertTrue(t, p.IsAccepted(a))
+	assertFalse(t, p.IsAccepted(b))
+	assertBlockPollCount(t, p, 2)
+
+	// Loading again adds nothing new
+	added, _ = p.WarmStart(mempool, nil)
+	if added != 0 {
+		t.Fatal("Expected no targets to be added but got", added)
+	}
+
+	// Errors from the source are returned
+	srcErr := errors.New("mempool unavailable")
+	if _, err = p.WarmStart(stubMempool{err: srcErr}, nil); err == nil || err.(*Error).Unwrap() != srcErr {
+		t.Fatal("Expected the error from the mempool source but got", err)
+	}
+}
+
+func TestSyncMempool(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		a       = &Block{Hash(1), 1, true, true}
+		b       = &Block{Hash(2), 1, true, true}
+		c       = &Block{Hash(3), 1, true, true}
+		primary = stubMempool{targets: []Target{a}}
+		backup  = stubMempool{targets: []Target{b}}
+		updates = p.Subscribe()
+	)
+	for _, block := range []*Block{a, b, c} {
+		assertTrue(t, p.AddTargetToReconcile(block))
+	}
+
+	// Targets of other types are left alone
+	withdrawn, err := p.SyncMempool("tx", primary)
+	assertTrue(t, err == nil && withdrawn == 0)
+
+	// A target in any mempool is kept, and one missing from all of them is
+	// withdrawn once it has been missing twice
+	withdrawn, err = p.SyncMempool("block", primary, backup)
+	assertTrue(t, err == nil && withdrawn == 0)
+	withdrawn, err = p.SyncMempool("block", primary, backup)
+	assertTrue(t, err == nil && withdrawn == 1)
+	u := <-updates
+	assertTrue(t, u.Hash == c.Hash() && u.Status == StatusWithdrawn && u.Reason == WithdrawEvicted)
+	assertBlockPollCount(t, p, 2)
+
+	// A target that comes back before the second sync is kept
+	withdrawn, _ = p.SyncMempool("block", primary)
+	assertTrue(t, withdrawn == 0)
+	withdrawn, _ = p.SyncMempool("block", primary, backup)
+	assertTrue(t, withdrawn == 0)
+	assertBlockPollCount(t, p, 2)
+
+	// Nothing is withdrawn when a mempool can't be listed
+	p.SyncMempool("block", primary)
+	_, err = p.SyncMempool("block", primary, stubMempool{err: errors.New("offline")})
+	if e, ok := err.(*Error); !ok || e.Op != "list mempool" {
+		t.Fatal("Expected a list error but got", err)
+	}
+	assertBlockPollCount(t, p, 2)
+}
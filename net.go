@@ -1,27 +1,115 @@
 package avalanche
 
 type node struct {
-	id NodeID
+	id   NodeID
+	addr string
 }
 
-func newNode(id NodeID) *node {
-	return &node{id: id}
+func newNode(id NodeID, addr string) *node {
+	return &node{id: id, addr: addr}
 }
 
+// Connman keeps track of the nodes available to query
 type Connman struct {
+	localID    NodeID
+	localAddrs map[string]struct{}
+
 	nodes map[NodeID]*node
+	addrs map[string]NodeID
+
+	// blocked are addresses that may not be added; see Block
+	blocked map[string]struct{}
 }
 
+// NewConnman creates a new *Connman
 func NewConnman() *Connman {
 	return &Connman{
-		nodes: map[NodeID]*node{},
+		localID:    NoNode,
+		localAddrs: map[string]struct{}{},
+
+		nodes:   map[NodeID]*node{},
+		addrs:   map[string]NodeID{},
+		blocked: map[string]struct{}{},
+	}
+}
+
+// SetLocalNode records our own identity and the addresses we advertise so we
+// never end up querying ourself. Any matching nodes already added are removed.
+func (c *Connman) SetLocalNode(id NodeID, addrs ...string) {
+	c.localID = id
+	c.localAddrs = make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		c.localAddrs[addr] = struct{}{}
+	}
+
+	for nodeID, n := range c.nodes {
+		if c.isLocal(nodeID, n.addr) {
+			c.removeNode(nodeID)
+		}
+	}
+}
+
+// AddNode adds a node to query. It returns false if the node is us or has
+// already been added.
+func (c *Connman) AddNode(id NodeID) bool {
+	return c.AddNodeWithAddr(id, "")
+}
+
+// AddNodeWithAddr adds a node reachable at the given address. It returns false
+// if the node is us, has already been added, if another node has already been
+// added with the same address, or if the address is blocked.
+func (c *Connman) AddNodeWithAddr(id NodeID, addr string) bool {
+	if c.isLocal(id, addr) {
+		return false
+	}
+
+	if _, ok := c.blocked[addr]; ok && addr != "" {
+		return false
+	}
+
+	if _, ok := c.nodes[id]; ok {
+		return false
+	}
+
+	if addr != "" {
+		if _, ok := c.addrs[addr]; ok {
+			return false
+		}
+		c.addrs[addr] = id
+	}
+
+	c.nodes[id] = newNode(id, addr)
+	return true
+}
+
+// RemoveNode removes a node so it is no longer queried. It returns false if
+// the node was not added.
+func (c *Connman) RemoveNode(id NodeID) bool {
+	if _, ok := c.nodes[id]; !ok {
+		return false
+	}
+	c.removeNode(id)
+	return true
+}
+
+// Block stops nodes at the address from being added until it is unblocked. A
+// node already added at the address is removed.
+func (c *Connman) Block(addr string) {
+	if addr == "" {
+		return
+	}
+	c.blocked[addr] = struct{}{}
+	if id, ok := c.addrs[addr]; ok {
+		c.removeNode(id)
 	}
 }
 
-func (c *Connman) AddNode(id NodeID) {
-	c.nodes[id] = newNode(id)
+// Unblock allows nodes at the address to be added again
+func (c *Connman) Unblock(addr string) {
+	delete(c.blocked, addr)
 }
 
+// NodesIDs returns the IDs of all nodes available to query
 func (c *Connman) NodesIDs() []NodeID {
 	nodeIDs := make([]NodeID, 0, len(c.nodes))
 	for nodeID := range c.nodes {
@@ -29,3 +117,25 @@ func (c *Connman) NodesIDs() []NodeID {
 	}
 	return nodeIDs
 }
+
+// isLocal returns whether the ID or address identifies us
+func (c *Connman) isLocal(id NodeID, addr string) bool {
+	if c.localID != NoNode && id == c.localID {
+		return true
+	}
+
+	_, ok := c.localAddrs[addr]
+	return addr != "" && ok
+}
+
+func (c *Connman) removeNode(id NodeID) {
+	n, ok := c.nodes[id]
+	if !ok {
+		return
+	}
+
+	delete(c.nodes, id)
+	if n.addr != "" {
+		delete(c.addrs, n.addr)
+	}
+}
@@ -0,0 +1,97 @@
+package avalanche
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConnmanSelfExclusion(t *testing.T) {
+	c := NewConnman()
+	assertTrue(t, c.AddNode(NodeID(0)))
+	assertTrue(t, c.AddNodeWithAddr(NodeID(1), "10.0.0.1:8333"))
+	assertTrue(t, c.AddNodeWithAddr(NodeID(2), "10.0.0.2:8333"))
+
+	// Duplicate IDs and addresses are not added
+	assertFalse(t, c.AddNode(NodeID(0)))
+	assertFalse(t, c.AddNodeWithAddr(NodeID(3), "10.0.0.1:8333"))
+	assertNodeCount(t, c, 3)
+
+	// Learning who we are removes ourself from the nodes
+	c.SetLocalNode(NodeID(0), "10.0.0.2:8333")
+	assertNodeCount(t, c, 1)
+
+	// And we can't be added back
+	assertFalse(t, c.AddNode(NodeID(0)))
+	assertFalse(t, c.AddNodeWithAddr(NodeID(4), "10.0.0.2:8333"))
+	assertTrue(t, c.AddNodeWithAddr(NodeID(2), "10.0.0.3:8333"))
+	assertNodeCount(t, c, 2)
+
+	// We never query ourself
+	p := NewProcessor(c)
+	assertTrue(t, p.getSuitableNodeToQuery() == NodeID(1))
+}
+
+func assertNodeCount(t *testing.T, c *Connman, count int) {
+	if n := len(c.NodesIDs()); n != count {
+		t.Fatal("Should have exactly", count, "nodes but have", n)
+	}
+}
+
+func TestPollPeerRotation(t *testing.T) {
+	var (
+		c      = NewConnman()
+		config = Config{MaxPollPeers: 2, PeerRotationInterval: time.Minute}
+		p      = NewProcessorWithConfig(c, config)
+		now    = time.Now()
+	)
+	p.rng = rand.New(rand.NewSource(1))
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	// Fewer nodes than the limit are all polled
+	assertTrue(t, c.AddNode(NodeID(0)))
+	assertTrue(t, len(p.GetPollPeers()) == 1)
+
+	// Only a subset of the rest is polled
+	for i := 1; i < 5; i++ {
+		assertTrue(t, c.AddNode(NodeID(i)))
+	}
+	peers := p.GetPollPeers()
+	assertTrue(t, len(peers) == 2)
+
+	// The slowest polled node is swapped for a random unpolled one
+	p.recordLatency(peers[0], time.Second)
+	p.recordLatency(peers[1], time.Millisecond)
+	p.rotatePollPeers()
+	rotated := p.GetPollPeers()
+	assertTrue(t, len(rotated) == 2)
+	assertFalse(t, containsNode(rotated, peers[0]))
+	assertTrue(t, containsNode(rotated, peers[1]))
+
+	// Not again until the interval has passed
+	p.rotatePollPeers()
+	assertTrue(t, reflect.DeepEqual(p.GetPollPeers(), rotated))
+	clock = stubClocker{now.Add(time.Minute)}
+	p.rotatePollPeers()
+	assertFalse(t, reflect.DeepEqual(p.GetPollPeers(), rotated))
+
+	// Nodes that leave the Connman are replaced
+	for _, id := range p.GetPollPeers() {
+		c.removeNode(id)
+	}
+	assertTrue(t, len(p.GetPollPeers()) == 2)
+	for _, id := range p.GetPollPeers() {
+		assertTrue(t, containsNode(c.NodesIDs(), id))
+	}
+}
+
+func containsNode(nodeIDs []NodeID, id NodeID) bool {
+	for _, nodeID := range nodeIDs {
+		if nodeID == id {
+			return true
+		}
+	}
+	return false
+}
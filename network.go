@@ -0,0 +1,56 @@
+package avalanche
+
+import "fmt"
+
+// Network identifies the network a node votes on by its magic bytes. Polls
+// and Responses carry it so nodes on different networks sharing
+// infrastructure refuse each other's votes. The values are those of the
+// Bitcoin Cash network messages read as a little endian uint32.
+type Network uint32
+
+const (
+	// MainNet is the main network. The zero Network is taken to be MainNet, so
+	// messages from nodes that predate networks are treated as mainnet ones.
+	MainNet Network = 0xe8f3e1e3
+
+	// TestNet is the public test network
+	TestNet Network = 0xf4f3e5f4
+
+	// RegTest is the regression test network
+	RegTest Network = 0xfabfb5da
+)
+
+// String returns the network's name, or its magic in hex if it isn't known
+func (n Network) String() string {
+	switch n.orMainNet() {
+	case MainNet:
+		return "mainnet"
+	case TestNet:
+		return "testnet"
+	case RegTest:
+		return "regtest"
+	}
+	return fmt.Sprintf("0x%08x", uint32(n))
+}
+
+// orMainNet returns MainNet for the zero Network and n otherwise
+func (n Network) orMainNet() Network {
+	if n == 0 {
+		return MainNet
+	}
+	return n
+}
+
+// wireMagic returns the magic to send for the network, which is left out for
+// mainnet
+func wireMagic(n Network) uint32 {
+	if n.orMainNet() == MainNet {
+		return 0
+	}
+	return uint32(n)
+}
+
+// network returns the network the Processor votes on
+func (p *Processor) network() Network {
+	return p.config.Network.orMainNet()
+}
@@ -0,0 +1,78 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	assertTrue(t, Network(0).String() == "mainnet" && MainNet.String() == "mainnet")
+	assertTrue(t, TestNet.String() == "testnet" && RegTest.String() == "regtest")
+	assertTrue(t, Network(1).String() == "0x00000001")
+
+	connman := NewConnman()
+	connman.AddNode(NodeID(0))
+	p := NewProcessorWithConfig(connman, Config{Network: TestNet})
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(1), 1, true, true}))
+
+	// Our polls are for our network
+	poll, ok := p.PollNode(NodeID(0))
+	assertTrue(t, ok && poll.GetNetwork() == TestNet)
+
+	// Polls from other networks are refused and get neutral votes
+	err := p.ValidatePoll(NewPoll(0, poll.GetInvs()))
+	if e, ok := err.(*PollError); !ok || e.Code != PollErrorWrongNetwork {
+		t.Fatal("Expected a wrong network error but got", err)
+	}
+	resp := p.HandlePoll(NodeID(1), NewPoll(0, poll.GetInvs()))
+	assertTrue(t, resp.GetNetwork() == TestNet && resp.GetVotes()[0].GetError() == VoteUnknown)
+	assertTrue(t, p.ValidatePoll(poll) == nil)
+	resp = p.HandlePoll(NodeID(1), poll)
+	assertTrue(t, resp.GetVotes()[0].GetError() == VoteYes)
+
+	// Responses from other networks count against the node
+	votes := []Vote{NewVote(VoteYes, Hash(1))}
+	err = p.CheckResponse(NodeID(0), NewResponse(poll.GetRound(), 0, votes))
+	if e, ok := err.(*ResponseError); !ok || e.Code != ResponseErrorWrongNetwork {
+		t.Fatal("Expected a wrong network error but got", err)
+	}
+	assertTrue(t, p.CheckResponse(NodeID(0), NewResponse(poll.GetRound(), 0, votes).WithNetwork(TestNet)) == nil)
+	updates := []StatusUpdate{}
+	assertFalse(t, p.RegisterVotes(NodeID(0), NewResponse(poll.GetRound(), 0, votes), &updates))
+	assertTrue(t, p.PeerInfo()[0].InvalidResponses == 1)
+}
+
+func TestNetworkEncoding(t *testing.T) {
+	invs := []Inv{{"block", Hash(1)}}
+
+	// The magic is left out for mainnet
+	data, err := json.Marshal(NewPoll(1, invs))
+	assertTrue(t, err == nil && !strings.Contains(string(data), "magic"))
+
+	for _, n := range []Network{MainNet, TestNet, RegTest} {
+		data, err = json.Marshal(NewPoll(1, invs).WithNetwork(n))
+		assertTrue(t, err == nil)
+		poll, err := DecodePoll(strings.NewReader(string(data)), true)
+		assertTrue(t, err == nil && poll.GetNetwork() == n)
+
+		data, err = NewPoll(1, invs).WithNetwork(n).MarshalBinary()
+		assertTrue(t, err == nil)
+		assertTrue(t, poll.UnmarshalBinary(data) == nil && poll.GetNetwork() == n)
+
+		resp := NewResponse(1, 0, []Vote{NewVote(VoteYes, Hash(1))}).WithNetwork(n)
+		data, err = json.Marshal(resp)
+		assertTrue(t, err == nil)
+		decoded, err := DecodeResponse(strings.NewReader(string(data)), true)
+		assertTrue(t, err == nil && decoded.GetNetwork() == n)
+
+		data, err = resp.MarshalBinary()
+		assertTrue(t, err == nil)
+		assertTrue(t, decoded.UnmarshalBinary(data) == nil && decoded.GetNetwork() == n)
+	}
+
+	// Compact polls keep the network through expansion
+	p := NewProcessorWithConfig(NewConnman(), Config{Network: RegTest})
+	expanded, missing := p.ExpandPoll(NewCompactPoll(NewPoll(1, invs).WithNetwork(RegTest), 7))
+	assertTrue(t, len(missing) == 1 && expanded.GetNetwork() == RegTest)
+}
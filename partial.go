@@ -0,0 +1,90 @@
+package avalanche
+
+// truncateResponse cuts votes down to Config.MaxResponseVotes, returning
+// whether any were cut. Votes are kept in Inv order so the querier can tell
+// which Invs went unanswered.
+func (p *Processor) truncateResponse(votes []Vote) ([]Vote, bool) {
+	max := p.config.MaxResponseVotes
+	if max <= 0 || len(votes) <= max {
+		return votes, false
+	}
+	return votes[:max], true
+}
+
+// recordResponseSize learns how many votes a node is willing to return from
+// its response to a query. A truncated response caps later polls to the node
+// at the number of votes it returned, and the unanswered Invs are asked again
+// in the next poll to it. A full response to a capped poll doubles the cap
+// until it is lifted.
+func (p *Processor) recordResponseSize(id NodeID, r RequestRecord, resp Response) {
+	invs, votes := r.GetInvs(), resp.GetVotes()
+
+	if resp.IsTruncated() && len(votes) < len(invs) {
+		limit := len(votes)
+		if limit < 1 {
+			limit = 1
+		}
+		p.responseLimits[id] = limit
+
+		remainder := p.remainders[id][:0]
+		for _, inv := range invs[len(votes):] {
+			remainder = append(remainder, inv.TargetHash)
+		}
+		p.remainders[id] = remainder
+		return
+	}
+
+	limit, ok := p.responseLimits[id]
+	if !ok || len(votes) < limit {
+		return
+	}
+	if limit *= 2; limit >= AvalancheMaxElementPoll {
+		delete(p.responseLimits, id)
+		return
+	}
+	p.responseLimits[id] = limit
+}
+
+// GetResponseLimit returns the most Invs a node has shown it will answer in
+// one response, and false if it has never truncated a response
+func (p *Processor) GetResponseLimit(id NodeID) (int, bool) {
+	limit, ok := p.responseLimits[id]
+	return limit, ok
+}
+
+// responseInvLimit lowers limit to the node's response limit, if it has one
+func (p *Processor) responseInvLimit(id NodeID, limit int) int {
+	if max, ok := p.responseLimits[id]; ok && max < limit {
+		return max
+	}
+	return limit
+}
+
+// appendRemainder appends Invs left unanswered by the node's last truncated
+// response to invs, up to a total of limit, skipping targets no longer being
+// polled or that the node doesn't track. Any that don't fit are kept for the
+// poll after.
+func (p *Processor) appendRemainder(id NodeID, invs []Inv, limit int) []Inv {
+	remainder := p.remainders[id]
+	policy, hasPolicy := p.peerPolicies[id]
+	for len(remainder) > 0 && len(invs) < limit {
+		h := remainder[0]
+		remainder = remainder[1:]
+
+		vr, ok := p.voteRecords[h]
+		if !ok || vr.hasFinalized() || !p.isWorthyPolling(p.targets[h]) || p.isOverSampled(h, id) {
+			continue
+		}
+		if hasPolicy && !policy.Tracks(p.targets[h]) {
+			continue
+		}
+		invs = append(invs, Inv{p.targets[h].Type(), h})
+	}
+
+	if len(remainder) == 0 {
+		delete(p.remainders, id)
+	} else {
+		p.remainders[id] = remainder
+	}
+	return invs
+}
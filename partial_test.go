@@ -0,0 +1,61 @@
+package avalanche
+
+import "testing"
+
+func TestPartialResponses(t *testing.T) {
+	var (
+		connman   = NewConnman()
+		p         = NewProcessor(connman)
+		responder = NewProcessorWithConfig(NewConnman(), Config{MaxResponseVotes: 2})
+		updates   = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	for h := Hash(1); h <= 5; h++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{h, 0, true, true}))
+	}
+
+	poll := func() Poll {
+		round := p.GetRound()
+		p.eventLoop()
+		return NewPoll(round, p.queries[queryKey{round, NodeID(0)}].GetInvs())
+	}
+	answer := func(poll Poll) Response {
+		resp := responder.HandlePoll(NodeID(1), poll)
+		assertTrue(t, p.RegisterVotes(NodeID(0), resp, &updates))
+		return resp
+	}
+
+	// An overloaded responder answers the first Invs and flags the rest
+	first := poll()
+	assertTrue(t, len(first.GetInvs()) == 5)
+	resp := answer(first)
+	assertTrue(t, resp.IsTruncated())
+	assertTrue(t, len(resp.GetVotes()) == 2)
+	for i, v := range resp.GetVotes() {
+		assertTrue(t, v.GetHash() == first.GetInvs()[i].TargetHash)
+	}
+
+	// The querier caps its polls to what the node answered and asks for the
+	// remainder first
+	limit, ok := p.GetResponseLimit(NodeID(0))
+	assertTrue(t, ok && limit == 2)
+	second := poll()
+	assertTrue(t, len(second.GetInvs()) == 2)
+	for i, inv := range second.GetInvs() {
+		assertTrue(t, inv.TargetHash == first.GetInvs()[i+2].TargetHash)
+	}
+
+	// Full answers raise the cap until it is lifted
+	assertFalse(t, answer(second).IsTruncated())
+	limit, _ = p.GetResponseLimit(NodeID(0))
+	assertTrue(t, limit == 4)
+	third := poll()
+	assertTrue(t, third.GetInvs()[0].TargetHash == first.GetInvs()[4].TargetHash)
+	assertTrue(t, len(third.GetInvs()) == 4)
+
+	seen := map[Hash]bool{}
+	for _, inv := range third.GetInvs() {
+		assertFalse(t, seen[inv.TargetHash])
+		seen[inv.TargetHash] = true
+	}
+}
@@ -0,0 +1,197 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+)
+
+// peerOverridesVersion is the version of the format written by
+// PeerOverrides.Save
+const peerOverridesVersion = 1
+
+// PeerOverrides are the peers an operator connected, disconnected or pinned by
+// hand. Peers are identified by address so the overrides outlive the NodeIDs
+// of a session; save them with SaveToFile and apply them after a restart with
+// Processor.ApplyPeerOverrides.
+type PeerOverrides struct {
+	// Connected are peers added with ConnectPeer
+	Connected []string `json:"connected,omitempty"`
+
+	// Disconnected are peers removed with DisconnectPeer, which stay blocked
+	// until connected by hand again
+	Disconnected []string `json:"disconnected,omitempty"`
+
+	// Pinned are peers that are always polled; see PinPeer
+	Pinned []string `json:"pinned,omitempty"`
+}
+
+// peerOverridesFile is the encoded form of PeerOverrides
+type peerOverridesFile struct {
+	Version int `json:"version"`
+	PeerOverrides
+}
+
+// Save writes the overrides to w as JSON
+func (o PeerOverrides) Save(w io.Writer) error {
+	return wrapError("write peer overrides", json.NewEncoder(w).Encode(peerOverridesFile{peerOverridesVersion, o}))
+}
+
+// SaveToFile writes the overrides to path, replacing it only once the new
+// overrides have been completely written
+func (o PeerOverrides) SaveToFile(path string) error {
+	return saveToFile(path, "write peer overrides", o.Save)
+}
+
+// LoadPeerOverrides reads overrides written by PeerOverrides.Save
+func LoadPeerOverrides(r io.Reader) (PeerOverrides, error) {
+	f := peerOverridesFile{}
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return PeerOverrides{}, &Error{"read peer overrides", err}
+	}
+	if f.Version != peerOverridesVersion {
+		return PeerOverrides{}, ErrPeerOverridesVersion
+	}
+	return f.PeerOverrides, nil
+}
+
+// LoadPeerOverridesFromFile reads overrides written by
+// PeerOverrides.SaveToFile
+func LoadPeerOverridesFromFile(path string) (PeerOverrides, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return PeerOverrides{}, &Error{"read peer overrides", err}
+	}
+	defer f.Close()
+
+	return LoadPeerOverrides(f)
+}
+
+// ConnectPeer adds a node by hand, unblocking its address if it was
+// disconnected by hand before. It returns false if the node could not be
+// added; see Connman.AddNodeWithAddr.
+func (p *Processor) ConnectPeer(id NodeID, addr string) bool {
+	p.connman.Unblock(addr)
+	if !p.connman.AddNodeWithAddr(id, addr) {
+		return false
+	}
+
+	if addr != "" {
+		p.manualPeers[addr] = struct{}{}
+	}
+	return true
+}
+
+// DisconnectPeer removes a node by hand. Its address is blocked so the node
+// isn't added back until it is connected by hand again, and it is unpinned. It
+// returns false if the node was not in the Connman.
+func (p *Processor) DisconnectPeer(id NodeID) bool {
+	n, ok := p.connman.nodes[id]
+	if !ok {
+		return false
+	}
+
+	p.UnpinPeer(id)
+	delete(p.pollPeers, id)
+	p.connman.RemoveNode(id)
+	if n.addr != "" {
+		delete(p.manualPeers, n.addr)
+		p.connman.Block(n.addr)
+	}
+	return true
+}
+
+// PinPeer makes a node always polled, whatever Config.MaxPollPeers, and never
+// rotated out. Nodes with an address stay pinned across reconnects. It
+// returns false if the node is not in the Connman.
+func (p *Processor) PinPeer(id NodeID) bool {
+	n, ok := p.connman.nodes[id]
+	if !ok {
+		return false
+	}
+
+	if n.addr != "" {
+		p.pinnedAddrs[n.addr] = struct{}{}
+	} else {
+		p.pinned[id] = struct{}{}
+	}
+	return true
+}
+
+// UnpinPeer returns a node to being sampled like any other. It returns false
+// if the node was not pinned.
+func (p *Processor) UnpinPeer(id NodeID) bool {
+	if !p.isPinned(id) {
+		return false
+	}
+
+	delete(p.pinned, id)
+	if n, ok := p.connman.nodes[id]; ok {
+		delete(p.pinnedAddrs, n.addr)
+	}
+	return true
+}
+
+// GetPinnedPeers returns the pinned nodes in the Connman
+func (p *Processor) GetPinnedPeers() []NodeID {
+	nodeIDs := []NodeID{}
+	for id := range p.connman.nodes {
+		if p.isPinned(id) {
+			nodeIDs = append(nodeIDs, id)
+		}
+	}
+	sort.Sort(nodesInRequestOrder(nodeIDs))
+	return nodeIDs
+}
+
+// GetPeerOverrides returns the overrides made by hand, for saving. Pins of
+// nodes without an address only last the session so are left out.
+func (p *Processor) GetPeerOverrides() PeerOverrides {
+	return PeerOverrides{
+		Connected:    sortedKeys(p.manualPeers),
+		Disconnected: sortedKeys(p.connman.blocked),
+		Pinned:       sortedKeys(p.pinnedAddrs),
+	}
+}
+
+// ApplyPeerOverrides restores saved overrides, connecting each peer connected
+// by hand with the NodeID given by nodeID. Pins apply to nodes at the pinned
+// addresses whenever they are in the Connman.
+func (p *Processor) ApplyPeerOverrides(o PeerOverrides, nodeID func(addr string) NodeID) {
+	for _, addr := range o.Disconnected {
+		p.connman.Block(addr)
+	}
+	for _, addr := range o.Connected {
+		if _, ok := p.connman.addrs[addr]; ok {
+			p.manualPeers[addr] = struct{}{}
+			continue
+		}
+		p.ConnectPeer(nodeID(addr), addr)
+	}
+	for _, addr := range o.Pinned {
+		p.pinnedAddrs[addr] = struct{}{}
+	}
+}
+
+// isPinned returns whether the node is pinned
+func (p *Processor) isPinned(id NodeID) bool {
+	if _, ok := p.pinned[id]; ok {
+		return true
+	}
+	n, ok := p.connman.nodes[id]
+	if !ok || n.addr == "" {
+		return false
+	}
+	_, ok = p.pinnedAddrs[n.addr]
+	return ok
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package avalanche
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPinnedPeers(t *testing.T) {
+	var (
+		c      = NewConnman()
+		config = Config{MaxPollPeers: 2, PeerRotationInterval: time.Minute}
+		p      = NewProcessorWithConfig(c, config)
+		now    = time.Now()
+	)
+	p.rng = rand.New(rand.NewSource(1))
+	clock = stubClocker{now}
+	defer func() { clock = realClocker{} }()
+
+	for i := 0; i < 5; i++ {
+		assertTrue(t, p.ConnectPeer(NodeID(i), "10.0.0."+strconv.Itoa(i)))
+	}
+	assertFalse(t, p.PinPeer(NodeID(9)))
+	assertTrue(t, p.PinPeer(NodeID(3)))
+	assertTrue(t, reflect.DeepEqual(p.GetPinnedPeers(), []NodeID{3}))
+
+	// A pinned node is always polled, even when it is the slowest
+	assertTrue(t, containsNode(p.GetPollPeers(), NodeID(3)))
+	p.recordLatency(NodeID(3), time.Hour)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Minute)
+		clock = stubClocker{now}
+		p.rotatePollPeers()
+		peers := p.GetPollPeers()
+		assertTrue(t, len(peers) == 2 && containsNode(peers, NodeID(3)))
+	}
+
+	// A disconnected node stays out until connected by hand
+	assertTrue(t, p.DisconnectPeer(NodeID(3)))
+	assertFalse(t, containsNode(p.GetPollPeers(), NodeID(3)))
+	assertFalse(t, c.AddNodeWithAddr(NodeID(3), "10.0.0.3"))
+	assertTrue(t, len(p.GetPinnedPeers()) == 0)
+	assertTrue(t, p.ConnectPeer(NodeID(3), "10.0.0.3"))
+	assertFalse(t, p.UnpinPeer(NodeID(3)))
+}
+
+func TestPeerOverridesPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overrides")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "overrides.json")
+
+	p := NewProcessor(NewConnman())
+	assertTrue(t, p.ConnectPeer(NodeID(1), "a"))
+	assertTrue(t, p.ConnectPeer(NodeID(2), "b"))
+	assertTrue(t, p.ConnectPeer(NodeID(3), "c"))
+	assertTrue(t, p.PinPeer(NodeID(1)))
+	assertTrue(t, p.DisconnectPeer(NodeID(2)))
+
+	expected := PeerOverrides{Connected: []string{"a", "c"}, Disconnected: []string{"b"}, Pinned: []string{"a"}}
+	if o := p.GetPeerOverrides(); !reflect.DeepEqual(o, expected) {
+		t.Fatal("Expected", expected, "but got", o)
+	}
+	if err = p.GetPeerOverrides().SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the overrides apply to the new session's NodeIDs
+	loaded, err := LoadPeerOverridesFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConnman()
+	restarted := NewProcessor(c)
+	restarted.ApplyPeerOverrides(loaded, func(addr string) NodeID { return NodeID(10 + addr[0] - 'a') })
+	nodeIDs := c.NodesIDs()
+	assertTrue(t, len(nodeIDs) == 2 && containsNode(nodeIDs, NodeID(10)) && containsNode(nodeIDs, NodeID(12)))
+	assertTrue(t, reflect.DeepEqual(restarted.GetPinnedPeers(), []NodeID{10}))
+	assertFalse(t, c.AddNodeWithAddr(NodeID(11), "b"))
+	if o := restarted.GetPeerOverrides(); !reflect.DeepEqual(o, expected) {
+		t.Fatal("Expected", expected, "but got", o)
+	}
+
+	_, err = LoadPeerOverridesFromFile(filepath.Join(dir, "missing"))
+	if e, ok := err.(*Error); !ok || e.Op != "read peer overrides" {
+		t.Fatal("Expected a read error but got", err)
+	}
+}
@@ -0,0 +1,207 @@
+package avalanche
+
+import (
+	"sort"
+	"time"
+)
+
+// PeerInfo describes our interactions with a node, in the spirit of bitcoind's
+// getpeerinfo
+type PeerInfo struct {
+	NodeID NodeID
+	Addr   string
+
+	// Connected is whether the node is in the Connman, and Polled whether it is
+	// among the nodes being polled
+	Connected bool
+	Polled    bool
+
+	// PollsSent are queries sent to the node and PollsReceived polls it sent us
+	PollsSent     int64
+	PollsReceived int64
+
+	// ResponsesReceived are responses that answered one of our queries
+	ResponsesReceived int64
+
+	// UnsolicitedResponses are responses that didn't match an outstanding
+	// query, and Timeouts are queries that expired unanswered
+	UnsolicitedResponses int64
+	Timeouts             int64
+
+	// InvalidResponses are responses to our queries whose votes didn't match
+	// the Invs asked about, such as padded or reordered ones. Each counts
+	// against the node's Reliability.
+	InvalidResponses int64
+
+	// VotesAgreed and VotesDisagreed count finalized targets the node's last
+	// vote agreed or disagreed with the outcome of
+	VotesAgreed    int64
+	VotesDisagreed int64
+
+	// VoteFlips counts the times the node changed its vote on a target, and
+	// VotesIgnored the votes ignored because it changed its vote on the
+	// target too often; see Config.MaxVoteFlips
+	VoteFlips    int64
+	VotesIgnored int64
+
+	// InvsSkipped counts the Invs left out of polls to the node because its
+	// FinalizedFilter said it had already finalized them; see
+	// SetPeerFinalizedFilter
+	InvsSkipped int64
+
+	// Reliability is the node's reliability score; see GetPeerReliability
+	Reliability float64
+
+	// Latency is the p95 response time and Demoted whether that got the node
+	// moved to the probe pool
+	Latency time.Duration
+	Demoted bool
+
+	// TimeOffset is how far the node's clock was ahead of ours when sampled;
+	// see RecordPeerTime
+	TimeOffset time.Duration
+
+	Bandwidth BandwidthStats
+}
+
+// peerStats are the counters behind PeerInfo
+type peerStats struct {
+	pollsSent            int64
+	pollsReceived        int64
+	responsesReceived    int64
+	unsolicitedResponses int64
+	invalidResponses     int64
+	timeouts             int64
+	votesAgreed          int64
+	votesDisagreed       int64
+	voteFlips            int64
+	votesIgnored         int64
+	invsSkipped          int64
+}
+
+// peerVote is a node's latest yes or no vote on a target and how many times
+// it has changed
+type peerVote struct {
+	yes   bool
+	flips int
+}
+
+// PeerInfo returns information on every node in the Connman, and every node we
+// have exchanged polls with, ordered by NodeID
+func (p *Processor) PeerInfo() []PeerInfo {
+	ids := map[NodeID]struct{}{}
+	for _, id := range p.connman.NodesIDs() {
+		ids[id] = struct{}{}
+	}
+	for id := range p.peerStats {
+		ids[id] = struct{}{}
+	}
+
+	polled := map[NodeID]struct{}{}
+	for _, id := range p.getPollPeers() {
+		polled[id] = struct{}{}
+	}
+
+	infos := make([]PeerInfo, 0, len(ids))
+	for id := range ids {
+		info := PeerInfo{NodeID: id, Bandwidth: p.GetPeerBandwidth(id), Demoted: p.IsDemoted(id)}
+		if n, ok := p.connman.nodes[id]; ok {
+			info.Connected = true
+			info.Addr = n.addr
+		}
+		_, info.Polled = polled[id]
+		info.Latency, _ = p.GetPeerLatency(id)
+		info.TimeOffset, _ = p.GetPeerTimeOffset(id)
+		info.Reliability = p.GetPeerReliability(id)
+
+		if s, ok := p.peerStats[id]; ok {
+			info.PollsSent = s.pollsSent
+			info.PollsReceived = s.pollsReceived
+			info.ResponsesReceived = s.responsesReceived
+			info.UnsolicitedResponses = s.unsolicitedResponses
+			info.InvalidResponses = s.invalidResponses
+			info.Timeouts = s.timeouts
+			info.VotesAgreed = s.votesAgreed
+			info.VotesDisagreed = s.votesDisagreed
+			info.VoteFlips = s.voteFlips
+			info.VotesIgnored = s.votesIgnored
+			info.InvsSkipped = s.invsSkipped
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].NodeID < infos[j].NodeID })
+	return infos
+}
+
+// GetPeerReliability scores how often a node's votes ended up on the winning
+// side of finalization, from 0 to 1. The score is smoothed so a node we know
+// nothing about starts at 0.5 and a few votes don't swing it to either end.
+// Invalid responses count as disagreements.
+func (p *Processor) GetPeerReliability(id NodeID) float64 {
+	agreed, disagreed := int64(0), int64(0)
+	if s, ok := p.peerStats[id]; ok {
+		agreed, disagreed = s.votesAgreed, s.votesDisagreed+s.invalidResponses
+	}
+	return float64(agreed+1) / float64(agreed+disagreed+2)
+}
+
+// stats returns the counters for a node, creating them if needed
+func (p *Processor) stats(id NodeID) *peerStats {
+	s, ok := p.peerStats[id]
+	if !ok {
+		s = &peerStats{}
+		p.peerStats[id] = s
+	}
+	return s
+}
+
+// recordPeerVote remembers a node's latest yes or no vote on a target so it
+// can be compared with the outcome, counting the times the node changes it.
+// It returns false if the vote should be ignored because the node has changed
+// its vote more than Config.MaxVoteFlips times. Neutral votes are not
+// remembered.
+func (p *Processor) recordPeerVote(id NodeID, h Hash, err uint32) bool {
+	if isUnknownVote(err) {
+		return true
+	}
+
+	votes, ok := p.peerVotes[h]
+	if !ok {
+		votes = map[NodeID]*peerVote{}
+		p.peerVotes[h] = votes
+	}
+
+	yes := err == VoteYes
+	v, ok := votes[id]
+	if !ok {
+		votes[id] = &peerVote{yes: yes}
+		return true
+	}
+
+	if v.yes != yes {
+		v.yes = yes
+		v.flips++
+		p.stats(id).voteFlips++
+	}
+
+	if p.config.MaxVoteFlips > 0 && v.flips > p.config.MaxVoteFlips {
+		p.stats(id).votesIgnored++
+		return false
+	}
+	return true
+}
+
+// tallyPeerVotes compares each node's latest vote on a finalized target with
+// its outcome
+func (p *Processor) tallyPeerVotes(h Hash, accepted bool) {
+	for id, v := range p.peerVotes[h] {
+		if v.yes == accepted {
+			p.stats(id).votesAgreed++
+		} else {
+			p.stats(id).votesDisagreed++
+		}
+	}
+	delete(p.peerVotes, h)
+}
@@ -0,0 +1,105 @@
+package avalanche
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerInfo(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollWindow: 2})
+		pindex  = blockForHash(Hash(65))
+		updates = []StatusUpdate{}
+	)
+	connman.AddNodeWithAddr(NodeID(0), "10.0.0.1:8333")
+	connman.AddNode(NodeID(1))
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+
+	// Node 0 is polled and answers yes; node 1 votes no unsolicited, which is
+	// refused, and then as if it had been polled
+	round := p.GetRound()
+	p.eventLoop()
+	p.eventLoop()
+	yes := NewResponse(round, 0, []Vote{NewVote(0, pindex.Hash())})
+	no := NewResponse(round, 0, []Vote{NewVote(1, pindex.Hash())})
+	assertTrue(t, p.RegisterVotes(NodeID(0), yes, &updates))
+	assertFalse(t, p.RegisterVotes(NodeID(1), no, &updates))
+	assertTrue(t, registerVotes(p, NodeID(1), no, &updates))
+	p.HandlePoll(NodeID(1), NewPoll(0, []Inv{{"block", pindex.Hash()}}))
+
+	// The other query times out
+	clock = stubClocker{time.Now().Add(2 * AvalancheRequestTimeout)}
+	defer func() { clock = realClocker{} }()
+	p.expireQueries()
+
+	// Node 0 keeps voting yes until the block finalizes
+	yes = NewResponse(-1, 0, yes.GetVotes())
+	assertFalse(t, p.RegisterVotes(NodeID(0), yes, &updates))
+	responses := int64(1)
+	for p.voteRecords[pindex.Hash()] != nil {
+		registerVotes(p, NodeID(0), yes, &updates)
+		responses++
+	}
+
+	infos := p.PeerInfo()
+	if len(infos) != 2 {
+		t.Fatal("Expected 2 peers but got", len(infos))
+	}
+
+	n0, n1 := infos[0], infos[1]
+	assertTrue(t, n0.NodeID == 0 && n0.Addr == "10.0.0.1:8333" && n0.Connected && n0.Polled)
+	assertTrue(t, n0.PollsSent == 2 && n0.ResponsesReceived == responses && n0.Timeouts == 1)
+	assertTrue(t, n0.UnsolicitedResponses > 0)
+	assertTrue(t, n0.VotesAgreed == 1 && n0.VotesDisagreed == 0)
+	assertTrue(t, n0.Bandwidth.BytesSent > 0)
+
+	assertTrue(t, n1.NodeID == 1 && n1.PollsSent == 0 && n1.PollsReceived == 1)
+	assertTrue(t, n1.UnsolicitedResponses == 1)
+	assertTrue(t, n1.VotesAgreed == 0 && n1.VotesDisagreed == 1)
+
+	// Reliability follows which side of finalization a node's votes were on
+	assertTrue(t, n0.Reliability == 2.0/3 && n1.Reliability == 1.0/3)
+	assertTrue(t, p.GetPeerReliability(NodeID(2)) == 0.5)
+}
+
+func TestVoteFlips(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{MaxVoteFlips: 2})
+		pindex  = blockForHash(Hash(65))
+		updates = []StatusUpdate{}
+	)
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+
+	vote := func(id NodeID, err uint32) {
+		registerVotes(p, id, NewResponse(0, 0, []Vote{NewVote(err, pindex.Hash())}), &updates)
+	}
+
+	// Node 1 may change its mind twice
+	vote(NodeID(1), VoteYes)
+	vote(NodeID(1), VoteNo)
+	vote(NodeID(1), VoteYes)
+	vote(NodeID(1), VoteUnknown)
+
+	// Then its votes on the target stop counting, whichever way they go
+	vr := *p.voteRecords[pindex.Hash()]
+	vote(NodeID(1), VoteNo)
+	vote(NodeID(1), VoteNo)
+	assertTrue(t, *p.voteRecords[pindex.Hash()] == vr)
+
+	// Other nodes' votes still count
+	vote(NodeID(2), VoteYes)
+	assertTrue(t, *p.voteRecords[pindex.Hash()] != vr)
+
+	info := p.PeerInfo()[0]
+	assertTrue(t, info.NodeID == NodeID(1) && info.VoteFlips == 3 && info.VotesIgnored == 2)
+
+	// Without a limit flips are counted but votes are never ignored
+	p = NewProcessor(NewConnman())
+	assertTrue(t, p.AddTargetToReconcile(pindex))
+	for i := 0; i < 10; i++ {
+		vote(NodeID(1), uint32(i%2))
+	}
+	info = p.PeerInfo()[0]
+	assertTrue(t, info.VoteFlips == 9 && info.VotesIgnored == 0)
+}
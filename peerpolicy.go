@@ -0,0 +1,103 @@
+package avalanche
+
+// PeerPolicy is what a node tells its peers in the handshake about the targets
+// it tracks, so they don't poll it about targets it would only vote
+// VoteUnknown on
+type PeerPolicy struct {
+	// MinFeeRate is the lowest fee rate, in satoshis per byte, of the
+	// transactions the node relays
+	MinFeeRate float64
+
+	// TargetTypes are the target types the node votes on. It votes on every
+	// type if it is empty.
+	TargetTypes []string
+
+	// MaxPollInvs is the most Invs the node answers in one poll, and
+	// MaxMessageSize the largest poll or response message it accepts, in
+	// bytes of ABC's wire encoding. Larger batches are split across several
+	// polls to the node. There is no limit if they are not positive.
+	MaxPollInvs    int
+	MaxMessageSize int
+}
+
+// feeRater is implemented by targets with a fee rate, such as *Tx
+type feeRater interface {
+	GetFeeRate() float64
+}
+
+// Tracks returns whether a node with the policy votes on the target
+func (pp PeerPolicy) Tracks(t Target) bool {
+	if len(pp.TargetTypes) > 0 && !containsString(pp.TargetTypes, t.Type()) {
+		return false
+	}
+
+	if fr, ok := t.(feeRater); ok && fr.GetFeeRate() < pp.MinFeeRate {
+		return false
+	}
+	return true
+}
+
+// maxInvs returns the most Invs a poll to a node with the policy may hold
+func (pp PeerPolicy) maxInvs() int {
+	limit := AvalancheMaxElementPoll
+	if pp.MaxPollInvs > 0 && pp.MaxPollInvs < limit {
+		limit = pp.MaxPollInvs
+	}
+	if pp.MaxMessageSize > 0 {
+		// Votes are as large as Invs, and responses have the larger overhead
+		n := (pp.MaxMessageSize - responseWireOverhead) / voteWireSize
+		if n < 0 {
+			n = 0
+		}
+		if n < limit {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// GetPolicy returns the policy to send peers in the handshake, from
+// Config.MinFeeRate, Config.TargetTypes and Config.MaxResponseVotes
+func (p *Processor) GetPolicy() PeerPolicy {
+	return PeerPolicy{
+		MinFeeRate:  p.config.MinFeeRate,
+		TargetTypes: append([]string{}, p.config.TargetTypes...),
+		MaxPollInvs: p.config.MaxResponseVotes,
+	}
+}
+
+// SetPeerPolicy records the policy a node sent in the handshake. The node is
+// then only polled about targets it tracks, in batches within its limits.
+// With an address book, its target types are remembered as its capabilities.
+func (p *Processor) SetPeerPolicy(id NodeID, policy PeerPolicy) {
+	policy.TargetTypes = append([]string{}, policy.TargetTypes...)
+	p.peerPolicies[id] = policy
+}
+
+// GetPeerPolicy returns the policy a node sent in the handshake, and false if
+// none has been recorded
+func (p *Processor) GetPeerPolicy(id NodeID) (PeerPolicy, bool) {
+	policy, ok := p.peerPolicies[id]
+	return policy, ok
+}
+
+// splitBatch cuts invs down to what a poll to the node may hold under its
+// policy, returning whether any were cut. The Invs cut are asked in the next
+// polls to the node, ahead of any others.
+func (p *Processor) splitBatch(id NodeID, invs []Inv) ([]Inv, bool) {
+	policy, ok := p.peerPolicies[id]
+	if !ok {
+		return invs, false
+	}
+	max := policy.maxInvs()
+	if len(invs) <= max {
+		return invs, false
+	}
+
+	rest := make([]Hash, 0, len(invs)-max+len(p.remainders[id]))
+	for _, inv := range invs[max:] {
+		rest = append(rest, inv.TargetHash)
+	}
+	p.remainders[id] = append(rest, p.remainders[id]...)
+	return invs[:max], true
+}
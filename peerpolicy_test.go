@@ -0,0 +1,127 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPeerPolicy(t *testing.T) {
+	connman := NewConnman()
+	p := NewProcessorWithConfig(connman, Config{MinFeeRate: 1, TargetTypes: []string{"tx", "block"}})
+	policy := p.GetPolicy()
+	assertTrue(t, policy.MinFeeRate == 1 && len(policy.TargetTypes) == 2)
+
+	provider := stubTargetProvider{
+		Hash(2): {Fee: 250, Size: 250, Valid: true, InMempool: true},
+		Hash(3): {Fee: 5000, Size: 250, Valid: true, InMempool: true},
+	}
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(1), 0, true, true}))
+	for h := Hash(2); h <= 3; h++ {
+		tx, err := NewTx(h, provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertTrue(t, p.AddTargetToReconcile(tx))
+	}
+
+	// Nodes are only polled about targets they track
+	p.SetPeerPolicy(NodeID(0), PeerPolicy{TargetTypes: []string{"tx"}})
+	p.SetPeerPolicy(NodeID(1), PeerPolicy{MinFeeRate: 10})
+	_, ok := p.GetPeerPolicy(NodeID(2))
+	assertFalse(t, ok)
+
+	for _, c := range []struct {
+		id       NodeID
+		expected []Hash
+	}{
+		{NodeID(0), []Hash{2, 3}},
+		{NodeID(1), []Hash{1, 3}},
+		{NodeID(2), []Hash{1, 2, 3}},
+	} {
+		invs := p.appendInvsForNextPoll(c.id, nil, AvalancheMaxElementPoll)
+		hashes := map[Hash]struct{}{}
+		for _, inv := range invs {
+			hashes[inv.TargetHash] = struct{}{}
+		}
+		if len(invs) != len(c.expected) {
+			t.Fatal("Expected node", c.id, "to be polled about", c.expected, "but got", invs)
+		}
+		for _, h := range c.expected {
+			if _, ok := hashes[h]; !ok {
+				t.Fatal("Expected node", c.id, "to be polled about", h, "but got", invs)
+			}
+		}
+	}
+
+	// The address book remembers the target types as capabilities
+	book := NewAddrBook()
+	p.SetAddrBook(book)
+	connman.AddNodeWithAddr(NodeID(0), "a")
+	p.UpdateAddrBook()
+	e, _ := book.Get("a")
+	assertTrue(t, len(e.Capabilities) == 1 && e.Capabilities[0] == "tx")
+}
+
+func TestPeerPolicyBatching(t *testing.T) {
+	connman := NewConnman()
+	connman.AddNode(NodeID(0))
+	p := NewProcessorWithConfig(connman, Config{PollWindow: 3, MaxResponseVotes: 8})
+	assertTrue(t, p.GetPolicy().MaxPollInvs == 8)
+	for i := 0; i < 10; i++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{Hash(i), int64(i), true, true}))
+	}
+
+	// A batch too large for the node is split across as many polls as its
+	// window allows
+	p.SetPeerPolicy(NodeID(0), PeerPolicy{MaxPollInvs: 4})
+	p.eventLoop()
+	assertTrue(t, len(p.queries) == 3)
+	asked := map[Hash]struct{}{}
+	for _, r := range p.queries {
+		assertTrue(t, len(r.GetInvs()) <= 4)
+		for _, inv := range r.GetInvs() {
+			asked[inv.TargetHash] = struct{}{}
+		}
+	}
+	assertTrue(t, len(asked) == 10)
+
+	// The message size limit caps polls too
+	p = NewProcessorWithConfig(NewConnman(), Config{})
+	for i := 0; i < 10; i++ {
+		assertTrue(t, p.AddTargetToReconcile(&Block{Hash(i), int64(i), true, true}))
+	}
+	p.SetPeerPolicy(NodeID(0), PeerPolicy{MaxPollInvs: 4, MaxMessageSize: responseWireOverhead + 2*voteWireSize})
+	poll, ok := p.PollNode(NodeID(0))
+	assertTrue(t, ok && len(poll.GetInvs()) == 2)
+	_, ok = p.PollNode(NodeID(0))
+	assertFalse(t, ok)
+	assertTrue(t, len(p.remainders[NodeID(0)]) == 8)
+}
+
+func TestPeerPolicyEncoding(t *testing.T) {
+	data, err := json.Marshal(PeerPolicy{MinFeeRate: 1.5, TargetTypes: []string{"tx"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"min_fee_rate":1.5,"target_types":["tx"]}`
+	if string(data) != expected {
+		t.Fatal("Expected", expected, "but got", string(data))
+	}
+
+	policy, err := DecodePeerPolicy(strings.NewReader(expected), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, policy.MinFeeRate == 1.5 && len(policy.TargetTypes) == 1 && policy.TargetTypes[0] == "tx")
+
+	// Limits are only sent when set
+	data, _ = json.Marshal(PeerPolicy{MaxPollInvs: 16, MaxMessageSize: 1024})
+	policy, err = DecodePeerPolicy(strings.NewReader(string(data)), true)
+	assertTrue(t, err == nil && policy.MaxPollInvs == 16 && policy.MaxMessageSize == 1024)
+
+	_, err = DecodePeerPolicy(strings.NewReader(`{"version":2}`), false)
+	if e, ok := err.(*Error); !ok || e.Op != "decode policy" || e.Err != ErrProtocolVersion {
+		t.Fatal("Expected a protocol version error but got", err)
+	}
+}
@@ -0,0 +1,148 @@
+package avalanche
+
+import (
+	"sort"
+	"time"
+)
+
+// GetPollPeers returns the nodes currently being polled. This is every node in
+// the Connman unless Config.MaxPollPeers limits it to a subset, which always
+// includes pinned nodes.
+func (p *Processor) GetPollPeers() []NodeID {
+	nodeIDs := p.getPollPeers()
+	sort.Sort(nodesInRequestOrder(nodeIDs))
+	return nodeIDs
+}
+
+// getPollPeers returns the nodes to poll, first dropping nodes that have left
+// the Connman, adding pinned nodes and topping the set up with random
+// candidates
+func (p *Processor) getPollPeers() []NodeID {
+	candidates := p.connman.NodesIDs()
+	if p.config.MaxPollPeers <= 0 {
+		return candidates
+	}
+
+	known := make(map[NodeID]struct{}, len(candidates))
+	for _, id := range candidates {
+		known[id] = struct{}{}
+		if p.isPinned(id) {
+			p.pollPeers[id] = struct{}{}
+		}
+	}
+	for id := range p.pollPeers {
+		if _, ok := known[id]; !ok {
+			delete(p.pollPeers, id)
+		}
+	}
+
+	for len(p.pollPeers) < p.config.MaxPollPeers {
+		id, ok := p.randomCandidate(candidates)
+		if !ok {
+			break
+		}
+		p.pollPeers[id] = struct{}{}
+	}
+
+	nodeIDs := make([]NodeID, 0, len(p.pollPeers))
+	for id := range p.pollPeers {
+		nodeIDs = append(nodeIDs, id)
+	}
+	return nodeIDs
+}
+
+// rotatePollPeers swaps the slowest polled node for a random candidate once
+// every PeerRotationInterval
+func (p *Processor) rotatePollPeers() {
+	if p.config.MaxPollPeers <= 0 || p.config.PeerRotationInterval <= 0 {
+		return
+	}
+
+	now := clock.Now()
+	if now.Before(p.nextRotation) {
+		return
+	}
+	p.nextRotation = now.Add(p.config.PeerRotationInterval)
+
+	candidates := p.connman.NodesIDs()
+	nodeIDs := p.getPollPeers()
+	if len(nodeIDs) < p.config.MaxPollPeers || len(candidates) <= len(nodeIDs) {
+		// There is no one to rotate in
+		return
+	}
+
+	worst := p.worstPollPeer(nodeIDs)
+	if worst == NoNode {
+		return
+	}
+
+	p.UpdateAddrBook()
+	replacement, ok := p.randomCandidate(candidates)
+	if !ok {
+		return
+	}
+
+	delete(p.pollPeers, worst)
+	p.pollPeers[replacement] = struct{}{}
+}
+
+// worstPollPeer returns the unpinned node with the highest p95 response time,
+// or NoNode if every node is pinned. Nodes that have not been timed yet are
+// given the benefit of the doubt, unless none have been.
+func (p *Processor) worstPollPeer(nodeIDs []NodeID) NodeID {
+	sort.Sort(nodesInRequestOrder(nodeIDs))
+
+	worst, worstLatency := NoNode, time.Duration(-1)
+	for _, id := range nodeIDs {
+		if p.isPinned(id) {
+			continue
+		}
+		if worst == NoNode {
+			worst = id
+		}
+		latency, ok := p.GetPeerLatency(id)
+		if ok && latency > worstLatency {
+			worst, worstLatency = id, latency
+		}
+	}
+	return worst
+}
+
+// randomCandidate returns a random node that is not being polled, preferring
+// those that would make the polled nodes more diverse; see
+// Config.MinPollSubnets. With an address book, nodes are weighted by their
+// historical reliability.
+func (p *Processor) randomCandidate(candidates []NodeID) (NodeID, bool) {
+	unpolled := make([]NodeID, 0, len(candidates))
+	for _, id := range candidates {
+		if _, ok := p.pollPeers[id]; !ok {
+			unpolled = append(unpolled, id)
+		}
+	}
+	if len(unpolled) == 0 {
+		return NoNode, false
+	}
+
+	unpolled = p.preferDiverse(unpolled)
+
+	// Sort first so the choice only depends on the random source
+	sort.Sort(nodesInRequestOrder(unpolled))
+	if p.addrBook == nil {
+		return unpolled[p.rng.Intn(len(unpolled))], true
+	}
+
+	weights := make([]float64, len(unpolled))
+	total := 0.0
+	for i, id := range unpolled {
+		weights[i] = p.addrBook.reliability(p.connman.nodes[id].addr)
+		total += weights[i]
+	}
+	pick := p.rng.Float64() * total
+	for i, w := range weights {
+		if pick < w {
+			return unpolled[i], true
+		}
+		pick -= w
+	}
+	return unpolled[len(unpolled)-1], true
+}
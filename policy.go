@@ -0,0 +1,22 @@
+package avalanche
+
+// SetAcceptancePolicy sets the policy deciding whether targets of the given
+// type are initially accepted when added, in place of the Target's own
+// IsAccepted. It lets a node's chain policy, such as a first-seen rule or a
+// preference for the most-work chain, decide its starting vote on blocks
+// without baking that into the Target. A nil policy restores the default.
+func (p *Processor) SetAcceptancePolicy(targetType string, policy AcceptancePolicy) {
+	if policy == nil {
+		delete(p.policies, targetType)
+		return
+	}
+	p.policies[targetType] = policy
+}
+
+// isInitiallyAccepted returns whether a target being added starts out accepted
+func (p *Processor) isInitiallyAccepted(t Target) bool {
+	if policy, ok := p.policies[t.Type()]; ok {
+		return policy(t)
+	}
+	return t.IsAccepted()
+}
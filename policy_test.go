@@ -0,0 +1,36 @@
+package avalanche
+
+import "testing"
+
+func TestAcceptancePolicy(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		heavy   = &Block{Hash(1), 10, true, false}
+		light   = &Block{Hash(2), 1, true, true}
+		mempool = stubMempool{targets: []Target{&Block{Hash(3), 10, true, false}}}
+	)
+
+	// A preference for the most work decides blocks' initial acceptance over
+	// what they say about themselves
+	p.SetAcceptancePolicy("block", func(t Target) bool { return t.(*Block).work >= 10 })
+	assertTrue(t, p.AddTargetToReconcile(heavy))
+	assertTrue(t, p.AddTargetToReconcileWithMetadata(light, Metadata{"k": "v"}))
+	assertTrue(t, p.IsAccepted(heavy))
+	assertFalse(t, p.IsAccepted(light))
+
+	// It also applies when warm starting without a policy of its own
+	added, err := p.WarmStart(mempool, nil)
+	assertTrue(t, err == nil && added == 1)
+	assertTrue(t, p.IsAccepted(mempool.targets[0]))
+
+	// Policies only apply to their own type, and can be removed
+	p = NewProcessor(NewConnman())
+	p.SetAcceptancePolicy("tx", func(Target) bool { return true })
+	assertTrue(t, p.AddTargetToReconcile(heavy))
+	assertFalse(t, p.IsAccepted(heavy))
+
+	p.SetAcceptancePolicy("block", func(Target) bool { return true })
+	p.SetAcceptancePolicy("block", nil)
+	assertTrue(t, p.AddTargetToReconcile(light))
+	assertTrue(t, p.IsAccepted(light))
+}
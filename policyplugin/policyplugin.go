@@ -0,0 +1,68 @@
+// Package policyplugin loads avalanche AcceptancePolicies from Go plugins, so
+// operators can change acceptance criteria such as fee floors without
+// recompiling. It is separate from the avalanche package so only binaries that
+// load plugins need the dynamic linking that plugins require.
+//
+// A plugin exports Symbol as either a func(avalanche.Target) bool or a variable
+// of type avalanche.AcceptancePolicy, and must be built against the same
+// version of the avalanche package:
+//
+//	package main
+//
+//	import avalanche "github.com/tyler-smith/go-avalanche"
+//
+//	func AcceptancePolicy(t avalanche.Target) bool { return t.Score() >= 1000 }
+//
+// built with go build -buildmode=plugin. Go plugins are only supported on some
+// platforms, and only in binaries built with cgo; elsewhere Load returns an
+// error.
+package policyplugin
+
+import (
+	"errors"
+	"plugin"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// Symbol is the symbol Load looks up in a plugin
+const Symbol = "AcceptancePolicy"
+
+// ErrInvalidSymbol is returned when a plugin's Symbol is nil or not an
+// AcceptancePolicy
+var ErrInvalidSymbol = errors.New("policyplugin: symbol " + Symbol + " is not an AcceptancePolicy")
+
+// Load loads the AcceptancePolicy exported by the plugin at path
+func Load(path string) (avalanche.AcceptancePolicy, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := p.Lookup(Symbol)
+	if err != nil {
+		return nil, err
+	}
+	return fromSymbol(sym)
+}
+
+// fromSymbol converts a plugin symbol into an AcceptancePolicy. Functions are
+// looked up as values and variables as pointers.
+func fromSymbol(sym plugin.Symbol) (avalanche.AcceptancePolicy, error) {
+	var policy avalanche.AcceptancePolicy
+	switch s := sym.(type) {
+	case func(avalanche.Target) bool:
+		policy = s
+	case *func(avalanche.Target) bool:
+		policy = *s
+	case *avalanche.AcceptancePolicy:
+		policy = *s
+	default:
+		return nil, ErrInvalidSymbol
+	}
+
+	if policy == nil {
+		return nil, ErrInvalidSymbol
+	}
+	return policy, nil
+}
@@ -0,0 +1,41 @@
+package policyplugin
+
+import (
+	"testing"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+type target avalanche.Hash
+
+func (t target) Hash() avalanche.Hash { return avalanche.Hash(t) }
+func (target) IsAccepted() bool       { return false }
+func (target) IsValid() bool          { return true }
+func (target) Type() string           { return "test" }
+func (target) Score() int64           { return 1 }
+
+func TestFromSymbol(t *testing.T) {
+	accept := func(avalanche.Target) bool { return true }
+	policy := avalanche.AcceptancePolicy(accept)
+	var nilPolicy avalanche.AcceptancePolicy
+
+	for _, sym := range []interface{}{accept, &accept, &policy} {
+		p, err := fromSymbol(sym)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p(target(1)) {
+			t.Fatal("Expected the policy to accept")
+		}
+	}
+
+	for _, sym := range []interface{}{&nilPolicy, 42, func() bool { return true }} {
+		if _, err := fromSymbol(sym); err != ErrInvalidSymbol {
+			t.Fatal("Expected ErrInvalidSymbol for", sym, "but got", err)
+		}
+	}
+
+	if _, err := Load("does-not-exist.so"); err == nil {
+		t.Fatal("Expected an error loading a missing plugin")
+	}
+}
@@ -0,0 +1,199 @@
+package avalanche
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Poll is a query for votes on a set of Targets
+type Poll struct {
+	round   int64
+	invs    []Inv
+	network Network
+}
+
+// NewPoll creates a new mainnet Poll for the given invs
+func NewPoll(round int64, invs []Inv) Poll {
+	return Poll{round, invs, MainNet}
+}
+
+// WithNetwork returns a copy of the Poll for the given network
+func (p Poll) WithNetwork(n Network) Poll {
+	p.network = n
+	return p
+}
+
+// GetNetwork returns the network the Poll is for
+func (p Poll) GetNetwork() Network {
+	return p.network.orMainNet()
+}
+
+// GetRound returns the round of the Poll
+func (p Poll) GetRound() int64 {
+	return p.round
+}
+
+// GetInvs returns the Invs being polled
+func (p Poll) GetInvs() []Inv {
+	return p.invs
+}
+
+// withInvsCopy returns a copy of the Poll with its own copy of the Invs
+func (p Poll) withInvsCopy() Poll {
+	p.invs = append([]Inv{}, p.invs...)
+	return p
+}
+
+// PollHandler answers Polls from other nodes
+type PollHandler interface {
+	HandlePoll(NodeID, Poll) Response
+}
+
+// PollHandlerFunc allows a plain function to be used as a PollHandler
+type PollHandlerFunc func(NodeID, Poll) Response
+
+// HandlePoll calls f(id, poll)
+func (f PollHandlerFunc) HandlePoll(id NodeID, poll Poll) Response {
+	return f(id, poll)
+}
+
+// LockedPollHandler returns a PollHandler that holds mu while calling h. It
+// allows a PollHandler that is not safe for concurrent use, such as a
+// *Processor, to be shared with the code that drives it.
+func LockedPollHandler(mu sync.Locker, h PollHandler) PollHandler {
+	return PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		mu.Lock()
+		defer mu.Unlock()
+		return h.HandlePoll(id, poll)
+	})
+}
+
+// PollErrorCode identifies why a Poll is invalid
+type PollErrorCode int
+
+const (
+	// PollErrorEmpty is used for a Poll with no Invs
+	PollErrorEmpty PollErrorCode = iota + 1
+
+	// PollErrorTooManyInvs is used for a Poll with more than
+	// AvalancheMaxElementPoll Invs
+	PollErrorTooManyInvs
+
+	// PollErrorMissingType is used for an Inv without a target type
+	PollErrorMissingType
+
+	// PollErrorUnknownType is used for an Inv whose target type is not one we
+	// vote on
+	PollErrorUnknownType
+
+	// PollErrorDuplicateInv is used for an Inv repeated within a Poll
+	PollErrorDuplicateInv
+
+	// PollErrorWrongNetwork is used for a Poll from another network
+	PollErrorWrongNetwork
+)
+
+// String returns the code's name as used in error payloads
+func (c PollErrorCode) String() string {
+	switch c {
+	case PollErrorEmpty:
+		return "empty"
+	case PollErrorTooManyInvs:
+		return "too_many_invs"
+	case PollErrorMissingType:
+		return "missing_type"
+	case PollErrorUnknownType:
+		return "unknown_type"
+	case PollErrorDuplicateInv:
+		return "duplicate_inv"
+	case PollErrorWrongNetwork:
+		return "wrong_network"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the code as its name
+func (c PollErrorCode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a code from its name, so a peer can read a PollError
+// it was sent. Names it doesn't know, such as codes added in later versions,
+// decode to zero.
+func (c *PollErrorCode) UnmarshalText(text []byte) error {
+	for code := PollErrorEmpty; code <= PollErrorWrongNetwork; code++ {
+		if code.String() == string(text) {
+			*c = code
+			return nil
+		}
+	}
+	*c = 0
+	return nil
+}
+
+// PollError describes why a Poll is invalid. It is meant to be sent back to
+// the peer, so it encodes to JSON as an object with a code, the index of the
+// offending Inv if there is one, and a message.
+type PollError struct {
+	Code    PollErrorCode `json:"code"`
+	Index   int           `json:"index"`
+	Message string        `json:"message"`
+}
+
+// Error implements error
+func (e *PollError) Error() string {
+	return "avalanche: invalid poll: " + e.Message
+}
+
+// Unwrap returns ErrInvalidPoll
+func (e *PollError) Unwrap() error {
+	return ErrInvalidPoll
+}
+
+// PollValidator checks inbound Polls before they are handled
+type PollValidator interface {
+	ValidatePoll(Poll) error
+}
+
+// ValidatePoll checks that a Poll is well formed. Each Inv must have a type,
+// one of types if any are given, and must not repeat an earlier Inv. The
+// returned error is a *PollError; Index is -1 if no single Inv is at fault.
+func ValidatePoll(poll Poll, types []string) error {
+	invs := poll.GetInvs()
+
+	switch {
+	case len(invs) == 0:
+		return &PollError{PollErrorEmpty, -1, "no invs"}
+	case len(invs) > AvalancheMaxElementPoll:
+		return &PollError{PollErrorTooManyInvs, -1,
+			fmt.Sprintf("%d invs is more than the limit of %d", len(invs), AvalancheMaxElementPoll)}
+	}
+
+	seen := make(map[Inv]struct{}, len(invs))
+	for i, inv := range invs {
+		if inv.TargetType == "" {
+			return &PollError{PollErrorMissingType, i, fmt.Sprintf("inv %d has no target type", i)}
+		}
+
+		if len(types) > 0 && !containsString(types, inv.TargetType) {
+			return &PollError{PollErrorUnknownType, i,
+				fmt.Sprintf("inv %d has unknown target type %q", i, inv.TargetType)}
+		}
+
+		if _, ok := seen[inv]; ok {
+			return &PollError{PollErrorDuplicateInv, i, fmt.Sprintf("inv %d is a duplicate", i)}
+		}
+		seen[inv] = struct{}{}
+	}
+
+	return nil
+}
+
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
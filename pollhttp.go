@@ -0,0 +1,214 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSONContentType is the media type of JSON encoded Polls and Responses
+	JSONContentType = "application/json"
+
+	// BinaryContentType is the media type of binary encoded Polls and
+	// Responses; see Poll.MarshalBinary
+	BinaryContentType = "application/x-avalanche"
+
+	// NodeIDHeader is the request header a PollHTTPHandler reads the polling
+	// node's NodeID from by default
+	NodeIDHeader = "X-Avalanche-Node-Id"
+
+	// maxPollBodySize is the largest Poll request body read. It fits
+	// AvalancheMaxElementPoll JSON encoded Invs with room to spare.
+	maxPollBodySize = 1 << 20
+)
+
+// PollHTTPHandler serves Polls POSTed to it through a *PollServer. Polls may be
+// sent in the legacy JSON encoding or the binary one, chosen by the request's
+// Content-Type, so nodes of mixed versions can poll the same listener during a
+// migration. The Response is encoded as the Accept header asks, and otherwise
+// in the encoding of the request.
+//
+// Malformed and invalid Polls are answered with 400 Bad Request and refused
+// ones with 503 Service Unavailable. A *PollError from the validator is sent
+// as JSON so the peer gets its Code and Index; it has no binary encoding, so
+// this is whatever the Accept header asks.
+type PollHTTPHandler struct {
+	server *PollServer
+
+	// NodeID returns the polling node's NodeID. By default it is read from
+	// the NodeIDHeader, and requests without one are rejected.
+	NodeID func(*http.Request) (NodeID, error)
+}
+
+// NewPollHTTPHandler creates a new *PollHTTPHandler serving Polls through the
+// given *PollServer
+func NewPollHTTPHandler(server *PollServer) *PollHTTPHandler {
+	return &PollHTTPHandler{server: server, NodeID: nodeIDFromHeader}
+}
+
+// ServeHTTP implements http.Handler
+func (h *PollHTTPHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reqType, ok := requestContentType(req)
+	if !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	respType, ok := negotiateContentType(req.Header.Get("Accept"), reqType)
+	if !ok {
+		http.Error(w, "no acceptable content type", http.StatusNotAcceptable)
+		return
+	}
+
+	id, err := h.NodeID(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	poll, err := decodePollBody(http.MaxBytesReader(w, req.Body, maxPollBodySize), reqType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respCh, err := h.server.SubmitPoll(id, poll)
+	switch {
+	case err == ErrPollRefused:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		writePollError(w, err)
+		return
+	}
+
+	var resp Response
+	select {
+	case resp, ok = <-respCh:
+		if !ok {
+			http.Error(w, "poll handler failed", http.StatusInternalServerError)
+			return
+		}
+	case <-req.Context().Done():
+		return
+	}
+
+	var body []byte
+	if respType == BinaryContentType {
+		body, err = resp.MarshalBinary()
+	} else {
+		body, err = json.Marshal(resp)
+	}
+	resp.Release()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", respType)
+	w.Header().Add("Vary", "Accept")
+	w.Write(body)
+}
+
+// writePollError answers a Poll refused by the validator with 400 Bad Request,
+// encoding the error as JSON if it is a *PollError
+func writePollError(w http.ResponseWriter, err error) {
+	pollErr, ok := err.(*PollError)
+	if !ok {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(pollErr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", JSONContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
+
+// nodeIDFromHeader reads a request's NodeID from the NodeIDHeader
+func nodeIDFromHeader(req *http.Request) (NodeID, error) {
+	id, err := strconv.ParseInt(req.Header.Get(NodeIDHeader), 10, 64)
+	if err != nil {
+		return NoNode, &Error{"read node id", err}
+	}
+	return NodeID(id), nil
+}
+
+// decodePollBody decodes a Poll in the given encoding, rejecting unknown JSON
+// fields
+func decodePollBody(body io.Reader, contentType string) (Poll, error) {
+	if contentType == JSONContentType {
+		return DecodePoll(body, true)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return Poll{}, &Error{"decode poll", err}
+	}
+	poll := Poll{}
+	return poll, wrapError("decode poll", poll.UnmarshalBinary(data))
+}
+
+// requestContentType returns the encoding of a request body. Requests without
+// a Content-Type are taken to be JSON, as sent by nodes predating the binary
+// encoding.
+func requestContentType(req *http.Request) (string, bool) {
+	ct := req.Header.Get("Content-Type")
+	if ct == "" {
+		return JSONContentType, true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil || (mt != JSONContentType && mt != BinaryContentType) {
+		return "", false
+	}
+	return mt, true
+}
+
+// negotiateContentType picks the encoding of the Response from an Accept
+// header, preferring the request's encoding when several are acceptable
+func negotiateContentType(accept string, reqType string) (string, bool) {
+	if accept == "" {
+		return reqType, true
+	}
+
+	wantJSON, wantBinary, wantAny := false, false, false
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mt {
+		case JSONContentType:
+			wantJSON = true
+		case BinaryContentType:
+			wantBinary = true
+		case "*/*", "application/*":
+			wantAny = true
+		}
+	}
+
+	switch {
+	case wantAny || (wantJSON && wantBinary):
+		return reqType, true
+	case wantJSON:
+		return JSONContentType, true
+	case wantBinary:
+		return BinaryContentType, true
+	}
+	return "", false
+}
@@ -0,0 +1,152 @@
+package avalanche
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	poll := NewPoll(7, []Inv{{"tx", Hash(1)}, {"block", Hash(-2)}, {"", Hash(3)}})
+	data, err := poll.MarshalBinary()
+	assertTrue(t, err == nil)
+	decoded := Poll{}
+	assertTrue(t, decoded.UnmarshalBinary(data) == nil)
+	assertTrue(t, reflect.DeepEqual(decoded, poll))
+
+	// Truncated and padded encodings are rejected
+	assertTrue(t, decoded.UnmarshalBinary(data[:len(data)-1]) == ErrInvalidBinary)
+	assertTrue(t, decoded.UnmarshalBinary(append(data, 0)) == ErrInvalidBinary)
+	data[0]++
+	assertTrue(t, decoded.UnmarshalBinary(data) == ErrProtocolVersion)
+
+	votes := []Vote{NewVote(VoteYes, Hash(1)), NewConflictVote(Hash(2), Hash(4)), NewVote(VoteUnknown, Hash(3))}
+	for _, resp := range []Response{NewResponse(7, 2, votes), NewTruncatedResponse(7, 0, votes[:1])} {
+		data, err = resp.MarshalBinary()
+		assertTrue(t, err == nil)
+		r := Response{}
+		assertTrue(t, r.UnmarshalBinary(data) == nil)
+		assertTrue(t, reflect.DeepEqual(r, resp))
+	}
+}
+
+func TestPollHTTPHandler(t *testing.T) {
+	p := NewProcessor(NewConnman())
+	assertTrue(t, p.AddTargetToReconcile(&Block{Hash(1), 1, true, true}))
+	server := NewPollServer(LockedPollHandler(&sync.Mutex{}, p), 1, 4)
+	server.SetValidator(p)
+	assertTrue(t, server.Start())
+	defer server.Stop()
+
+	ts := httptest.NewServer(NewPollHTTPHandler(server))
+	defer ts.Close()
+
+	poll := NewPoll(3, []Inv{{"block", Hash(1)}})
+	jsonPoll, _ := json.Marshal(poll)
+	binaryPoll, _ := poll.MarshalBinary()
+
+	post := func(body []byte, contentType, accept string, id string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if id != "" {
+			req.Header.Set(NodeIDHeader, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	decode := func(resp *http.Response) Response {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("Expected 200 but got", resp.StatusCode)
+		}
+		r := Response{}
+		var err error
+		switch resp.Header.Get("Content-Type") {
+		case JSONContentType:
+			r, err = DecodeResponse(resp.Body, true)
+		case BinaryContentType:
+			buf := bytes.Buffer{}
+			buf.ReadFrom(resp.Body)
+			err = r.UnmarshalBinary(buf.Bytes())
+		default:
+			t.Fatal("Unexpected content type", resp.Header.Get("Content-Type"))
+		}
+		if err != nil || r.GetRound() != 3 || len(r.GetVotes()) != 1 || r.GetVotes()[0].GetError() != VoteYes {
+			t.Fatal("Unexpected response", r, err)
+		}
+		return r
+	}
+
+	// Each encoding is answered in kind unless another is asked for
+	for _, c := range []struct {
+		body        []byte
+		contentType string
+		accept      string
+		want        string
+	}{
+		{jsonPoll, "", "", JSONContentType},
+		{jsonPoll, "application/json; charset=utf-8", "", JSONContentType},
+		{binaryPoll, BinaryContentType, "", BinaryContentType},
+		{binaryPoll, BinaryContentType, "*/*", BinaryContentType},
+		{jsonPoll, JSONContentType, BinaryContentType, BinaryContentType},
+		{binaryPoll, BinaryContentType, "application/json, " + BinaryContentType + ";q=0", JSONContentType},
+	} {
+		resp := post(c.body, c.contentType, c.accept, "1")
+		if ct := resp.Header.Get("Content-Type"); ct != c.want {
+			t.Fatal("Expected", c.want, "but got", ct)
+		}
+		decode(resp)
+	}
+
+	for _, c := range []struct {
+		body        []byte
+		contentType string
+		accept      string
+		id          string
+		want        int
+	}{
+		{jsonPoll, "text/plain", "", "1", http.StatusUnsupportedMediaType},
+		{jsonPoll, JSONContentType, "text/html", "1", http.StatusNotAcceptable},
+		{jsonPoll, JSONContentType, "", "", http.StatusBadRequest},
+		{binaryPoll, JSONContentType, "", "1", http.StatusBadRequest},
+		{jsonPoll, BinaryContentType, "", "1", http.StatusBadRequest},
+	} {
+		resp := post(c.body, c.contentType, c.accept, c.id)
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Fatal("Expected", c.want, "but got", resp.StatusCode)
+		}
+	}
+
+	// Invalid polls get the validator's error as JSON, whatever was accepted
+	emptyPoll, _ := NewPoll(4, []Inv{}).MarshalBinary()
+	resp := post(emptyPoll, BinaryContentType, BinaryContentType, "1")
+	pollErr := PollError{}
+	err := json.NewDecoder(resp.Body).Decode(&pollErr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || resp.Header.Get("Content-Type") != JSONContentType {
+		t.Fatal("Expected a 400 JSON response but got", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if err != nil || pollErr.Code != PollErrorEmpty || pollErr.Index != -1 || pollErr.Message != "no invs" {
+		t.Fatal("Unexpected poll error", pollErr, err)
+	}
+
+	server.Stop()
+	resp = post(jsonPoll, JSONContentType, "", "1")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("Expected 503 but got", resp.StatusCode)
+	}
+}
@@ -0,0 +1,324 @@
+package avalanche
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PollServerStats is a snapshot of a *PollServer's activity
+type PollServerStats struct {
+	// QueueDepth is the number of polls waiting for a worker
+	QueueDepth int
+
+	// InFlight is the number of polls currently being handled
+	InFlight int64
+
+	// Handled is the total number of polls answered
+	Handled int64
+
+	// Dropped is the total number of polls refused because the queue was full
+	// or the server was not running, or left queued when it stopped
+	Dropped int64
+
+	// Invalid is the total number of polls refused by the validator
+	Invalid int64
+
+	// Panics is the total number of polls whose handler panicked
+	Panics int64
+}
+
+// inboundPoll is a Poll waiting to be handled along with where to send the
+// Response
+type inboundPoll struct {
+	nodeID NodeID
+	poll   Poll
+	respCh chan Response
+}
+
+// PollServer answers inbound Polls using a bounded pool of workers. Polls that
+// arrive while every worker is busy wait in a bounded queue; once that is full
+// new Polls are refused rather than spawning more work.
+type PollServer struct {
+	// The counters are used atomically so they come first to be 64-bit
+	// aligned on 32-bit platforms
+	inFlight int64
+	handled  int64
+	dropped  int64
+	invalid  int64
+	panics   int64
+
+	handler   PollHandler
+	validator PollValidator
+	reporter  ErrorReporter
+	workers   int
+	queue     chan inboundPoll
+
+	// lifeMu serializes Start, Stop, and Shutdown so workers are never
+	// launched while earlier ones are still stopping. runMu guards isRunning
+	// and the queue against them without being held while they wait.
+	lifeMu    sync.Mutex
+	runMu     sync.Mutex
+	isRunning bool
+	quitCh    chan (struct{})
+	drainCh   chan (struct{})
+	wg        sync.WaitGroup
+}
+
+// NewPollServer creates a new *PollServer that answers Polls with handler using
+// the given number of workers and queue size. The handler must be safe for
+// concurrent use when workers is greater than one; see LockedPollHandler.
+func NewPollServer(handler PollHandler, workers int, queueSize int) *PollServer {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &PollServer{
+		handler: handler,
+		workers: workers,
+		queue:   make(chan inboundPoll, queueSize),
+	}
+}
+
+// SetValidator sets a PollValidator to check Polls before they are queued. It
+// must be called before the server is started.
+func (s *PollServer) SetValidator(v PollValidator) {
+	s.validator = v
+}
+
+// SetErrorReporter sets where panics in the handler are reported. It must be
+// called before the server is started.
+func (s *PollServer) SetErrorReporter(reporter ErrorReporter) {
+	s.reporter = reporter
+}
+
+// Start launches the workers
+func (s *PollServer) Start() bool {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if s.isRunning {
+		return false
+	}
+
+	s.isRunning = true
+	s.quitCh = make(chan (struct{}))
+	s.drainCh = make(chan (struct{}))
+
+	s.wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go s.work()
+	}
+
+	return true
+}
+
+// Stop waits for the workers to finish their current Polls and stops them.
+// Polls still in the queue are dropped and their Response channels closed.
+func (s *PollServer) Stop() bool {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+
+	if !s.stopAccepting() {
+		return false
+	}
+
+	close(s.quitCh)
+	s.wg.Wait()
+	s.dropQueued()
+
+	return true
+}
+
+// Shutdown stops accepting Polls and waits up to timeout for the workers to
+// answer those already queued before stopping them, so peers get their
+// Responses rather than timing out on a node that is going away. Polls still
+// queued after the timeout are dropped and their Response channels closed,
+// and a Poll being handled when it passes is still answered. It returns false
+// if the server was not running.
+func (s *PollServer) Shutdown(timeout time.Duration) bool {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+
+	if !s.stopAccepting() {
+		return false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	close(s.drainCh)
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	close(s.quitCh)
+	<-done
+	s.dropQueued()
+
+	return true
+}
+
+// stopAccepting marks the server as no longer running so new Polls are
+// refused. It returns false if the server was not running.
+func (s *PollServer) stopAccepting() bool {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if !s.isRunning {
+		return false
+	}
+	s.isRunning = false
+	return true
+}
+
+// dropQueued closes the Response channels of Polls left in the queue once the
+// workers have stopped, so callers are not left waiting on them
+func (s *PollServer) dropQueued() {
+	for {
+		select {
+		case req := <-s.queue:
+			atomic.AddInt64(&s.dropped, 1)
+			close(req.respCh)
+		default:
+			return
+		}
+	}
+}
+
+// Submit queues a Poll from the given node. The Response is delivered on the
+// returned channel, which is closed without one if the handler panics or the
+// server stops before the Poll is handled. It returns false if the Poll was
+// refused.
+func (s *PollServer) Submit(id NodeID, poll Poll) (<-chan Response, bool) {
+	respCh, err := s.SubmitPoll(id, poll)
+	return respCh, err == nil
+}
+
+// SubmitPoll is like Submit but says why a Poll was refused. The error is the
+// validator's if the Poll is invalid, so it can be returned to the peer, or
+// ErrPollRefused if the server is not running or its queue is full.
+func (s *PollServer) SubmitPoll(id NodeID, poll Poll) (<-chan Response, error) {
+	if s.validator != nil {
+		if err := s.validator.ValidatePoll(poll); err != nil {
+			atomic.AddInt64(&s.invalid, 1)
+			return nil, err
+		}
+	}
+
+	// The running check and the enqueue happen together so a Poll can't land
+	// in the queue after the server has stopped
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if !s.isRunning {
+		atomic.AddInt64(&s.dropped, 1)
+		return nil, ErrPollRefused
+	}
+
+	req := inboundPoll{id, poll, make(chan Response, 1)}
+	select {
+	case s.queue <- req:
+		return req.respCh, nil
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+		return nil, ErrPollRefused
+	}
+}
+
+// Serve queues a Poll from the given node and waits for its Response. It
+// returns false if the Poll was refused or the handler panicked.
+func (s *PollServer) Serve(id NodeID, poll Poll) (Response, bool) {
+	respCh, ok := s.Submit(id, poll)
+	if !ok {
+		return Response{}, false
+	}
+	resp, ok := <-respCh
+	return resp, ok
+}
+
+// Stats returns a snapshot of the server's activity
+func (s *PollServer) Stats() PollServerStats {
+	return PollServerStats{
+		QueueDepth: len(s.queue),
+		InFlight:   atomic.LoadInt64(&s.inFlight),
+		Handled:    atomic.LoadInt64(&s.handled),
+		Dropped:    atomic.LoadInt64(&s.dropped),
+		Invalid:    atomic.LoadInt64(&s.invalid),
+		Panics:     atomic.LoadInt64(&s.panics),
+	}
+}
+
+// work handles queued Polls until the server is stopped
+func (s *PollServer) work() {
+	defer s.wg.Done()
+
+	for {
+		// Stopping and draining take priority over queued Polls
+		select {
+		case <-s.quitCh:
+			return
+		case <-s.drainCh:
+			s.drain()
+			return
+		default:
+		}
+
+		select {
+		case <-s.quitCh:
+			return
+		case <-s.drainCh:
+			s.drain()
+			return
+		case req := <-s.queue:
+			s.handle(req)
+		}
+	}
+}
+
+// drain handles queued Polls until the queue is empty or the server is
+// stopped
+func (s *PollServer) drain() {
+	for {
+		select {
+		case <-s.quitCh:
+			return
+		default:
+		}
+
+		select {
+		case req := <-s.queue:
+			s.handle(req)
+		default:
+			return
+		}
+	}
+}
+
+// handle answers a Poll. A panic in the handler is recovered and reported so
+// the worker can go on to the next Poll, and the Response channel is closed to
+// release the caller.
+func (s *PollServer) handle(req inboundPoll) {
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+
+	defer func() {
+		if v := recover(); v != nil {
+			atomic.AddInt64(&s.panics, 1)
+			reportPanic(s.reporter, "handle poll", v, map[string]string{
+				"loop": "poll server",
+				"node": fmt.Sprint(req.nodeID),
+			})
+			close(req.respCh)
+		}
+	}()
+
+	req.respCh <- s.handler.HandlePoll(req.nodeID, req.poll)
+	atomic.AddInt64(&s.handled, 1)
+}
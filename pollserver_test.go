@@ -0,0 +1,275 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcessorHandlePoll(t *testing.T) {
+	var (
+		p        = NewProcessor(NewConnman())
+		accepted = blockForHash(Hash(65))
+		rejected = &Block{Hash(67), 1, true, false}
+		poll     = NewPoll(3, []Inv{{"block", accepted.Hash()}, {"block", rejected.Hash()}, {"block", Hash(68)}})
+	)
+	assertTrue(t, p.AddTargetToReconcile(accepted))
+	assertTrue(t, p.AddTargetToReconcile(rejected))
+
+	resp := p.HandlePoll(NodeID(1), poll)
+	if resp.GetRound() != 3 {
+		t.Fatal("Response should be for round 3 but got", resp.GetRound())
+	}
+
+	expected := []Vote{NewVote(0, Hash(65)), NewVote(1, Hash(67)), NewVote(negativeOne, Hash(68))}
+	assertVotes(t, resp, expected)
+
+	// Finalized targets are still answered for
+	delete(p.voteRecords, Hash(65))
+	p.recordFinalization(Hash(65), StatusFinalized)
+	p.recordFinalization(Hash(68), StatusInvalid)
+	expected = []Vote{NewVote(0, Hash(65)), NewVote(1, Hash(67)), NewVote(1, Hash(68))}
+	assertVotes(t, p.HandlePoll(NodeID(1), poll), expected)
+}
+
+func assertVotes(t *testing.T, resp Response, expected []Vote) {
+	votes := resp.GetVotes()
+	if len(votes) != len(expected) {
+		t.Fatal("Expected", len(expected), "votes but got", len(votes))
+	}
+	for i, v := range votes {
+		if v != expected[i] {
+			t.Fatal("Incorrect vote. Got", v, "but wanted:", expected[i])
+		}
+	}
+}
+
+func TestPollServer(t *testing.T) {
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		poll    = NewPoll(0, []Inv{{"block", Hash(65)}})
+	)
+
+	handler := PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		started <- struct{}{}
+		<-release
+		return NewResponse(poll.GetRound(), 0, []Vote{NewVote(0, Hash(65))})
+	})
+	s := NewPollServer(handler, 2, 1)
+
+	// Polls are refused until the server is running
+	_, ok := s.Submit(NodeID(0), poll)
+	assertFalse(t, ok)
+	assertTrue(t, s.Start())
+	assertFalse(t, s.Start())
+
+	// Occupy both workers and fill the queue
+	respChs := []<-chan Response{}
+	for i := 0; i < 3; i++ {
+		respCh, ok := s.Submit(NodeID(i), poll)
+		assertTrue(t, ok)
+		respChs = append(respChs, respCh)
+
+		if i < 2 {
+			<-started
+		}
+	}
+
+	stats := s.Stats()
+	if stats.InFlight != 2 || stats.QueueDepth != 1 {
+		t.Fatal("Expected 2 polls in flight and 1 queued but got", stats)
+	}
+
+	// Further polls are refused rather than spawning more work
+	_, ok = s.Submit(NodeID(3), poll)
+	assertFalse(t, ok)
+	if s.Stats().Dropped != 2 {
+		t.Fatal("Expected 2 dropped polls but got", s.Stats().Dropped)
+	}
+
+	// Everything queued gets answered
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-started
+	}()
+	close(release)
+	wg.Wait()
+
+	for _, respCh := range respChs {
+		if len((<-respCh).GetVotes()) != 1 {
+			t.Fatal("Expected a vote in the response")
+		}
+	}
+
+	assertTrue(t, s.Stop())
+	assertFalse(t, s.Stop())
+	if stats := s.Stats(); stats.Handled != 3 || stats.InFlight != 0 {
+		t.Fatal("Expected 3 handled polls but got", stats)
+	}
+}
+
+func TestValidatePoll(t *testing.T) {
+	config := DefaultConfig
+	config.TargetTypes = []string{"block"}
+	p := NewProcessorWithConfig(NewConnman(), config)
+
+	tooMany := make([]Inv, AvalancheMaxElementPoll+1)
+	for i := range tooMany {
+		tooMany[i] = Inv{"block", Hash(i)}
+	}
+
+	tests := []struct {
+		invs  []Inv
+		code  PollErrorCode
+		index int
+	}{
+		{[]Inv{}, PollErrorEmpty, -1},
+		{tooMany, PollErrorTooManyInvs, -1},
+		{[]Inv{{"block", Hash(65)}, {"", Hash(66)}}, PollErrorMissingType, 1},
+		{[]Inv{{"tx", Hash(65)}}, PollErrorUnknownType, 0},
+		{[]Inv{{"block", Hash(65)}, {"block", Hash(66)}, {"block", Hash(65)}}, PollErrorDuplicateInv, 2},
+	}
+	for _, test := range tests {
+		err, ok := p.ValidatePoll(NewPoll(0, test.invs)).(*PollError)
+		if !ok || err.Code != test.code || err.Index != test.index || err.Unwrap() != ErrInvalidPoll {
+			t.Fatal("Expected", test.code, "at", test.index, "but got", err)
+		}
+	}
+
+	assertTrue(t, p.ValidatePoll(NewPoll(0, []Inv{{"block", Hash(65)}})) == nil)
+
+	// Any type is accepted if none are configured
+	assertTrue(t, ValidatePoll(NewPoll(0, []Inv{{"tx", Hash(65)}}), nil) == nil)
+
+	payload, err := json.Marshal(p.ValidatePoll(NewPoll(0, []Inv{})))
+	if err != nil || string(payload) != `{"code":"empty","index":-1,"message":"no invs"}` {
+		t.Fatal("Unexpected error payload:", string(payload), err)
+	}
+
+	// The PollServer refuses invalid polls with the validator's error
+	s := NewPollServer(p, 1, 1)
+	s.SetValidator(p)
+	assertTrue(t, s.Start())
+	defer s.Stop()
+
+	_, err = s.SubmitPoll(NodeID(0), NewPoll(0, []Inv{{"tx", Hash(65)}}))
+	if pollErr, ok := err.(*PollError); !ok || pollErr.Code != PollErrorUnknownType {
+		t.Fatal("Expected an unknown type error but got", err)
+	}
+	if s.Stats().Invalid != 1 {
+		t.Fatal("Expected 1 invalid poll but got", s.Stats().Invalid)
+	}
+
+	resp, ok := s.Serve(NodeID(0), NewPoll(0, []Inv{{"block", Hash(65)}}))
+	assertTrue(t, ok)
+	assertTrue(t, len(resp.GetVotes()) == 1)
+}
+
+func TestPollServerShutdown(t *testing.T) {
+	var (
+		started = make(chan struct{}, 3)
+		release = make(chan struct{})
+		poll    = NewPoll(0, []Inv{{"block", Hash(65)}})
+	)
+
+	handler := PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		started <- struct{}{}
+		<-release
+		return NewResponse(poll.GetRound(), 0, []Vote{NewVote(0, Hash(65))})
+	})
+	s := NewPollServer(handler, 1, 2)
+	assertFalse(t, s.Shutdown(time.Second))
+	assertTrue(t, s.Start())
+
+	// One poll in flight and two queued are all answered while draining
+	respChs := []<-chan Response{}
+	for i := 0; i < 3; i++ {
+		respCh, ok := s.Submit(NodeID(i), poll)
+		assertTrue(t, ok)
+		respChs = append(respChs, respCh)
+		if i == 0 {
+			<-started
+		}
+	}
+	close(release)
+	assertTrue(t, s.Shutdown(time.Second))
+
+	for _, respCh := range respChs {
+		if len((<-respCh).GetVotes()) != 1 {
+			t.Fatal("Expected a vote in the response")
+		}
+	}
+	_, ok := s.Submit(NodeID(3), poll)
+	assertFalse(t, ok)
+
+	// Polls not answered within the timeout are dropped
+	release = make(chan struct{})
+	for len(started) > 0 {
+		<-started
+	}
+	assertTrue(t, s.Start())
+	for i := 0; i < 3; i++ {
+		_, ok := s.Submit(NodeID(i), poll)
+		assertTrue(t, ok)
+		if i == 0 {
+			<-started
+		}
+	}
+	go func(quitCh chan struct{}) {
+		<-quitCh
+		close(release)
+	}(s.quitCh)
+	assertTrue(t, s.Shutdown(10*time.Millisecond))
+	if stats := s.Stats(); stats.Handled != 4 || stats.QueueDepth != 0 || stats.Dropped != 3 {
+		t.Fatal("Expected 4 handled polls and 3 dropped but got", stats)
+	}
+}
+
+func TestPollServerStopRace(t *testing.T) {
+	poll := NewPoll(0, []Inv{{"block", Hash(65)}})
+	handler := PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		return NewResponse(poll.GetRound(), 0, []Vote{NewVote(0, Hash(65))})
+	})
+	s := NewPollServer(handler, 2, 16)
+
+	for i := 0; i < 20; i++ {
+		assertTrue(t, s.Start())
+
+		// Every accepted poll is either answered or has its channel closed,
+		// however its submission races with stopping the server
+		respChs := make(chan (<-chan Response), 4*64)
+		wg := sync.WaitGroup{}
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				for k := 0; k < 64; k++ {
+					if respCh, ok := s.Submit(NodeID(j), poll); ok {
+						respChs <- respCh
+					}
+				}
+			}(j)
+		}
+
+		if i%2 == 0 {
+			assertTrue(t, s.Stop())
+		} else {
+			assertTrue(t, s.Shutdown(time.Millisecond))
+		}
+		wg.Wait()
+		close(respChs)
+
+		for respCh := range respChs {
+			select {
+			case <-respCh:
+			case <-time.After(time.Second):
+				t.Fatal("Expected the response channel to be answered or closed")
+			}
+		}
+		assertTrue(t, s.Stats().QueueDepth == 0)
+	}
+}
@@ -0,0 +1,86 @@
+package avalanche
+
+import (
+	"sort"
+	"time"
+)
+
+// The presets are kept unexported so changing the exported copies can't change
+// what GetPreset returns
+var (
+	abcMainnetConfig = Config{
+		PollWindow:        1,
+		PollInterval:      AvalancheTimeStep,
+		PollDebounce:      AvalanchePollDebounce,
+		VoteWindow:        AvalancheVoteWindow,
+		VoteQuorum:        AvalancheVoteQuorum,
+		FinalizationScore: AvalancheFinalizationScore,
+	}
+
+	paperDefaultsConfig = Config{
+		PollWindow:        1,
+		PollInterval:      AvalancheTimeStep,
+		PollDebounce:      AvalanchePollDebounce,
+		VoteWindow:        AvalancheVoteWindow,
+		VoteQuorum:        7,
+		FinalizationScore: 150,
+	}
+
+	fastTestConfig = Config{
+		PollWindow:        4,
+		PollInterval:      time.Millisecond,
+		VoteWindow:        4,
+		VoteQuorum:        3,
+		FinalizationScore: 8,
+		GracePeriod:       time.Second,
+	}
+)
+
+var (
+	// ABCMainnetConfig matches Bitcoin ABC's avalanche parameters, so votes
+	// finalize at the same confidence as ABC nodes on the network. It is the
+	// safe choice for production, at the cost of 128 conclusive rounds per
+	// decision.
+	ABCMainnetConfig = abcMainnetConfig
+
+	// PaperDefaultsConfig follows the parameters evaluated in the Avalanche
+	// paper: its quorum of 80% rounded up to 7 of the 8 votes in the window,
+	// the same as ABC, and its finalization score of 150. It finalizes more
+	// slowly than ABC in exchange for a lower chance of finalizing conflicting
+	// decisions, and is useful for comparing results with the paper.
+	PaperDefaultsConfig = paperDefaultsConfig
+
+	// FastTestConfig finalizes after a handful of rounds polled every
+	// millisecond, with a short grace period and several queries in flight to
+	// each node. It is meant for tests and local development: a few colluding
+	// or unlucky votes are enough to finalize the wrong decision.
+	FastTestConfig = fastTestConfig
+)
+
+// presets are the Configs that can be selected by name
+var presets = map[string]Config{
+	"abc-mainnet":    abcMainnetConfig,
+	"paper-defaults": paperDefaultsConfig,
+	"fast-test":      fastTestConfig,
+}
+
+// GetPreset returns the Config preset with the given name, such as one read
+// from a config file. It returns ErrUnknownPreset for names not in
+// PresetNames.
+func GetPreset(name string) (Config, error) {
+	c, ok := presets[name]
+	if !ok {
+		return Config{}, ErrUnknownPreset
+	}
+	return c, nil
+}
+
+// PresetNames returns the names of the Config presets in alphabetical order
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,33 @@
+package avalanche
+
+import "testing"
+
+func TestPresets(t *testing.T) {
+	names := PresetNames()
+	assertTrue(t, len(names) == 3 && names[0] == "abc-mainnet")
+
+	for _, name := range names {
+		c, err := GetPreset(name)
+		assertTrue(t, err == nil)
+
+		// Every preset must be able to finalize a decision
+		params := c.voteParams()
+		assertTrue(t, int(params.quorum) <= countBits8(params.mask))
+		assertTrue(t, int(params.quorum) > countBits8(params.mask)/2)
+	}
+
+	c, err := GetPreset("abc-mainnet")
+	assertTrue(t, err == nil && c.voteParams() == DefaultConfig.voteParams())
+
+	// Presets are returned by value, and changing the exported ones doesn't
+	// change them either
+	c.FinalizationScore = 1
+	assertTrue(t, ABCMainnetConfig.FinalizationScore == AvalancheFinalizationScore)
+	defer func(saved Config) { PaperDefaultsConfig = saved }(PaperDefaultsConfig)
+	PaperDefaultsConfig.FinalizationScore = 1
+	c, _ = GetPreset("paper-defaults")
+	assertTrue(t, c.FinalizationScore == 150)
+
+	_, err = GetPreset("mainnet")
+	assertTrue(t, err == ErrUnknownPreset)
+}
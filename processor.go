@@ -1,8 +1,9 @@
 package avalanche
 
 import (
-	"fmt"
+	"math/rand"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -10,29 +11,205 @@ import (
 // Processor drives the Avalanche process by sending queries and handling
 // responses.
 type Processor struct {
-	connman *Connman
-
-	round       int64
-	targets     map[Hash]Target
-	voteRecords map[Hash]*VoteRecord
-	nodeIDs     map[NodeID]struct{}
-	queries     map[string]RequestRecord
+	connman  *Connman
+	config   Config
+	chain    ChainTipSource
+	journal  QueryJournal
+	reporter ErrorReporter
+	addrBook *AddrBook
+
+	// asnProvider finds the ASNs of nodes for Config.MinPollASNs
+	asnProvider ASNProvider
+
+	round         int64
+	targets       map[Hash]Target
+	voteRecords   map[Hash]*VoteRecord
+	metadata      map[Hash]Metadata
+	policies      map[string]AcceptancePolicy
+	hashers       map[string]Hasher
+	finalizations map[Hash]finalization
+	nodeIDs       map[NodeID]struct{}
+	queries       map[queryKey]RequestRecord
+	outstanding   map[NodeID]int
+	asked         map[Hash][]NodeID
+	latencies     map[NodeID]*peerLatency
+	pollPeers     map[NodeID]struct{}
+	bandwidth     map[NodeID]*bandwidthMeter
+	totalBW       *bandwidthMeter
+	peerStats     map[NodeID]*peerStats
+	peerVotes     map[Hash]map[NodeID]*peerVote
+
+	// responseLimits and remainders track nodes that truncate their
+	// responses; see recordResponseSize
+	responseLimits map[NodeID]int
+	remainders     map[NodeID][]Hash
+	graceful       map[Hash]gracePeriod
+	invalidSince   map[Hash]time.Time
+	nextRotation   time.Time
+	rng            *rand.Rand
+
+	// pollSeq counts the polls built so far and lastPolled records the last
+	// one each target was included in, or was added before; see pollPriority.
+	// pollCursor is the last target included in the previous poll and
+	// pendingScratch is reused while choosing targets to poll.
+	pollSeq        int64
+	lastPolled     map[Hash]int64
+	pollCursor     Hash
+	hasPollCursor  bool
+	pendingScratch []pollCandidate
+
+	// quorum is the adaptive vote quorum, or 0 if it has not been adapted;
+	// see adaptQuorum
+	quorum         uint8
+	nextAdaptation time.Time
+	quorumPeers    map[NodeID]struct{}
+	quorumHistory  []QuorumAdaptation
+
+	finalizationCallbacks map[string][]FinalizationCallback
+
+	// conflicts finds conflicting targets for HandlePoll, and
+	// reportedConflicts are those already passed to the conflictHandler
+	conflicts         ConflictSource
+	conflictHandler   ConflictHandler
+	reportedConflicts map[Hash]map[Hash]struct{}
+
+	// timeOffsets are how far each sampled peer's clock is ahead of ours, and
+	// clockSkewed whether ErrClockSkew has been reported for the current skew
+	timeOffsets map[NodeID]time.Duration
+	clockSkewed bool
+
+	// suspended is whether finalization is suspended for lack of peers, and
+	// held the targets that would have finalized since; see updateSuspension
+	suspended bool
+	held      map[Hash]struct{}
+
+	// recent caches the outcomes of finalized targets once they are pruned
+	// from finalizations; see Config.FinalizationCacheSize
+	recent *finalizationCache
+
+	// eclipse is the state of the eclipse attack checks; see checkEclipse
+	eclipse eclipseState
+
+	// missing are targets absent from the mempools at the last SyncMempool
+	missing map[Hash]struct{}
+
+	// pinned and pinnedAddrs are nodes pinned by hand, by ID for nodes
+	// without an address, and manualPeers are addresses connected by hand;
+	// see PinPeer and ConnectPeer
+	pinned      map[NodeID]struct{}
+	pinnedAddrs map[string]struct{}
+	manualPeers map[string]struct{}
+
+	// peerPolicies are the policies nodes sent in the handshake; see
+	// SetPeerPolicy
+	peerPolicies map[NodeID]PeerPolicy
+
+	// peerFilters are the FinalizedFilters nodes sent; see
+	// SetPeerFinalizedFilter
+	peerFilters map[NodeID]*FinalizedFilter
+
+	subscribersMu sync.Mutex
+	subscribers   []chan StatusUpdate
+	history       *updateRing
 
 	runMu     sync.Mutex
 	isRunning bool
 	quitCh    chan (struct{})
 	doneCh    chan (struct{})
+	triggerCh chan (struct{})
+
+	// loopbacks are the built-in peers answering our own polls, sender sends
+	// the polls for every other node, and responseCh carries their responses
+	// to the event loop
+	loopbacks  map[NodeID]LoopbackConfig
+	sender     PollSender
+	responseCh chan polledResponse
+
+	// validator checks the raw targets submitted with SubmitRawTarget.
+	// validating are those not yet applied, validated those whose validation
+	// has finished, and validationSem limits how many are validated at once.
+	validator     TargetValidator
+	validationMu  sync.Mutex
+	validating    map[Hash]struct{}
+	validated     []validation
+	validationSem chan struct{}
+
+	// coldStore holds the vote records spilled out of memory and resolve
+	// finds their targets again. cold is the accepted bit of each spilled
+	// record and coldOrder the order they were spilled in; see SetColdStore.
+	coldStore ColdStore
+	resolve   TargetResolver
+	cold      map[Hash]bool
+	coldOrder []Hash
+
+	// loopPanics counts the event loop's consecutive panics
+	loopPanics int
 }
 
-// NewProcessor creates a new *Processor
+// NewProcessor creates a new *Processor using DefaultConfig
 func NewProcessor(connman *Connman) *Processor {
+	return NewProcessorWithConfig(connman, DefaultConfig)
+}
+
+// NewProcessorWithConfig creates a new *Processor using the given Config
+func NewProcessorWithConfig(connman *Connman, config Config) *Processor {
+	historySize := config.UpdateHistorySize
+	if historySize <= 0 {
+		historySize = AvalancheUpdateHistorySize
+	}
+	validationConcurrency := config.ValidationConcurrency
+	if validationConcurrency <= 0 {
+		validationConcurrency = AvalancheValidationConcurrency
+	}
+
 	return &Processor{
-		voteRecords: map[Hash]*VoteRecord{},
-		targets:     map[Hash]Target{},
-		queries:     map[string]RequestRecord{},
-		nodeIDs:     map[NodeID]struct{}{},
+		voteRecords:    map[Hash]*VoteRecord{},
+		metadata:       map[Hash]Metadata{},
+		policies:       map[string]AcceptancePolicy{},
+		hashers:        map[string]Hasher{},
+		finalizations:  map[Hash]finalization{},
+		targets:        map[Hash]Target{},
+		queries:        map[queryKey]RequestRecord{},
+		outstanding:    map[NodeID]int{},
+		asked:          map[Hash][]NodeID{},
+		latencies:      map[NodeID]*peerLatency{},
+		pollPeers:      map[NodeID]struct{}{},
+		bandwidth:      map[NodeID]*bandwidthMeter{},
+		totalBW:        &bandwidthMeter{},
+		peerStats:      map[NodeID]*peerStats{},
+		peerVotes:      map[Hash]map[NodeID]*peerVote{},
+		graceful:       map[Hash]gracePeriod{},
+		invalidSince:   map[Hash]time.Time{},
+		lastPolled:     map[Hash]int64{},
+		responseLimits: map[NodeID]int{},
+		remainders:     map[NodeID][]Hash{},
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+		nodeIDs:        map[NodeID]struct{}{},
+		timeOffsets:    map[NodeID]time.Duration{},
+		peerPolicies:   map[NodeID]PeerPolicy{},
+		peerFilters:    map[NodeID]*FinalizedFilter{},
+		held:           map[Hash]struct{}{},
+		missing:        map[Hash]struct{}{},
+		pinned:         map[NodeID]struct{}{},
+		pinnedAddrs:    map[string]struct{}{},
+		manualPeers:    map[string]struct{}{},
 
 		connman: connman,
+		config:  config,
+		history: newUpdateRing(historySize),
+
+		triggerCh: make(chan (struct{}), 1),
+
+		loopbacks:  map[NodeID]LoopbackConfig{},
+		responseCh: make(chan polledResponse),
+
+		validating:    map[Hash]struct{}{},
+		cold:          map[Hash]bool{},
+		validationSem: make(chan struct{}, validationConcurrency),
+
+		finalizationCallbacks: map[string][]FinalizationCallback{},
+		reportedConflicts:     map[Hash]map[Hash]struct{}{},
+		recent:                newFinalizationCache(config.finalizationCacheSize()),
 	}
 }
 
@@ -41,78 +218,176 @@ func (p *Processor) GetRound() int64 {
 	return p.round
 }
 
-// AddTargetToReconcile begins the voting process for a given target
+// AddTargetToReconcile begins the voting process for a given target. Its
+// initial acceptance is decided by the policy for its type, if one is set with
+// SetAcceptancePolicy, or by the Target itself.
 func (p *Processor) AddTargetToReconcile(t Target) bool {
+	return p.addTarget(t, p.isInitiallyAccepted(t), nil)
+}
+
+// AddTargetToReconcileWithMetadata begins the voting process for a given
+// target, attaching md to every StatusUpdate for it
+func (p *Processor) AddTargetToReconcileWithMetadata(t Target, md Metadata) bool {
+	return p.addTarget(t, p.isInitiallyAccepted(t), md)
+}
+
+// addTarget begins the voting process for a given target with the given
+// initial acceptance and metadata
+func (p *Processor) addTarget(t Target, accepted bool, md Metadata) bool {
 	if !p.isWorthyPolling(t) {
 		return false
 	}
 
 	_, ok := p.voteRecords[t.Hash()]
-	if ok {
+	if _, cold := p.cold[t.Hash()]; ok || cold {
 		return false
 	}
+	p.recent.remove(t.Hash())
 
 	p.targets[t.Hash()] = t
-	p.voteRecords[t.Hash()] = NewVoteRecord(t.IsAccepted())
+	p.voteRecords[t.Hash()] = newVoteRecordWithParams(accepted, p.typeVoteParams(t.Type()))
+	p.lastPolled[t.Hash()] = p.pollSeq
+	if len(md) > 0 {
+		p.metadata[t.Hash()] = copyMetadata(md)
+	}
+	p.triggerPoll()
 	return true
 }
 
-// RegisterVotes processes responses to queries
-func (p *Processor) RegisterVotes(id NodeID, resp Response, updates *[]StatusUpdate) bool {
-	// Disabled while hacking on simulations
-	if false {
-		key := queryKey(resp.GetRound(), id)
+// CheckResponse returns the *ResponseError RegisterVotes would refuse a
+// Response from the node with, or nil if it answers an outstanding query
+func (p *Processor) CheckResponse(id NodeID, resp Response) error {
+	r, ok := p.queries[queryKey{resp.GetRound(), id}]
+	return checkResponse(r, ok, resp, p.network())
+}
 
-		r, ok := p.queries[key]
-		if !ok {
-			return false
-		}
+// penalizeResponse counts a malformed response against the node that sent it
+func (p *Processor) penalizeResponse(id NodeID, err error) {
+	p.stats(id).invalidResponses++
+	reportError(p.reporter, err, map[string]string{
+		"node": strconv.FormatInt(int64(id), 10),
+	})
+}
 
-		// Always delete the key if it's present
-		delete(p.queries, key)
+// triggerPoll asks the running event loop to poll soon rather than waiting for
+// the next tick
+func (p *Processor) triggerPoll() {
+	select {
+	case p.triggerCh <- struct{}{}:
+	default:
+	}
+}
 
-		if r.IsExpired() {
-			return false
-		}
+// RegisterVotes processes responses to queries. Responses that don't answer
+// an outstanding query are refused; see CheckResponse. Malformed responses
+// also count against the node's reliability and are sent to the
+// ErrorReporter. Use RegisterVotesWithResult to find out what became of each
+// vote.
+func (p *Processor) RegisterVotes(id NodeID, resp Response, updates *[]StatusUpdate) bool {
+	return p.applyVotes(id, resp, updates, nil)
+}
 
-		invs := r.GetInvs()
-		votes := resp.GetVotes()
+// applyVotes processes a response to a query, recording what became of it in
+// result unless that is nil
+func (p *Processor) applyVotes(id NodeID, resp Response, updates *[]StatusUpdate, result *RegisterResult) bool {
+	// Match the response to its query using the round as the request ID
+	key := queryKey{resp.GetRound(), id}
+	r, ok := p.queries[key]
+	p.meterReceived(id, len(resp.GetVotes()))
+	if ok {
+		// Always delete the query if it's present
+		p.removeQuery(key)
+		p.stats(id).responsesReceived++
+		defer r.release()
+	} else {
+		p.stats(id).unsolicitedResponses++
+	}
 
-		if len(votes) != len(invs) {
-			return false
+	if err := checkResponse(r, ok, resp, p.network()); err != nil {
+		malformed := err.(*ResponseError).isMalformed()
+		if malformed {
+			p.penalizeResponse(id, err)
 		}
-
-		for i, v := range votes {
-			if invs[i].TargetHash != v.GetHash() {
-				return false
-			}
+		if result != nil {
+			result.Err, result.Penalized = err, malformed
 		}
+		return false
 	}
 
-	votes := resp.GetVotes()
+	// Only responses that answer their query are timed and sized, so a
+	// misbehaving node can't skew its latency or poll size
+	p.recordLatency(id, clock.Now().Sub(r.sent))
+	p.recordResponseSize(id, r, resp)
 
-	for _, v := range votes {
+	for i, v := range resp.GetVotes() {
 		vr, ok := p.voteRecords[v.GetHash()]
 		if !ok {
-			// We are not voting on this anymore
+			// We are not voting on this anymore, but a late vote still tells
+			// us about the node
+			if result != nil {
+				result.setOutcome(i, p.ignoredOutcome(v.GetHash()))
+			}
+			p.registerLateVote(id, v.GetHash(), v.GetError())
 			continue
 		}
 
 		if !p.isWorthyPolling(p.targets[v.GetHash()]) {
+			result.setOutcome(i, VoteIgnoredInvalidTarget)
+			continue
+		}
+
+		p.reportConflict(id, v)
+
+		if !p.recordPeerVote(id, v.GetHash(), v.GetError()) {
+			// The node keeps changing its mind about this target
+			result.setOutcome(i, VotePenalized)
 			continue
 		}
+		result.setOutcome(i, VoteApplied)
+		p.countEclipseVote(v.GetError(), p.targets[v.GetHash()])
 
 		if !vr.regsiterVote(v.GetError()) {
+			// Signal decisions that are close to finalizing
+			if vr.isLikelyFinal() {
+				update := StatusUpdate{
+					Hash:        v.GetHash(),
+					Status:      vr.status(),
+					Metadata:    p.metadata[v.GetHash()],
+					LikelyFinal: true,
+					Confidence:  vr.confidenceFraction(),
+					Degraded:    p.IsDegraded(),
+				}
+				*updates = append(*updates, update)
+				p.publish(update)
+			}
+
 			// This vote did not provide any extra information
 			continue
 		}
 
+		if vr.hasFinalized() && p.suspended {
+			p.holdFinalization(v.GetHash(), vr, updates)
+			continue
+		}
+
 		// Add appropriate status
-		*updates = append(*updates, StatusUpdate{v.GetHash(), vr.status()})
+		update := StatusUpdate{
+			Hash:       v.GetHash(),
+			Status:     vr.status(),
+			Metadata:   p.metadata[v.GetHash()],
+			Confidence: vr.confidenceFraction(),
+			Degraded:   p.IsDegraded(),
+		}
+		*updates = append(*updates, update)
+		p.publish(update)
 
 		// When we finalize we want to remove our vote record
 		if vr.hasFinalized() {
-			delete(p.voteRecords, v.GetHash())
+			p.recordFinalization(v.GetHash(), update.Status)
+			p.beginGracePeriod(v.GetHash())
+			p.tallyPeerVotes(v.GetHash(), vr.isAccepted())
+			p.runFinalizationCallbacks(p.targets[v.GetHash()], update.Status)
+			p.forgetTarget(v.GetHash())
 		}
 	}
 
@@ -121,16 +396,133 @@ func (p *Processor) RegisterVotes(id NodeID, resp Response, updates *[]StatusUpd
 	return true
 }
 
+// Subscribe returns a channel that receives every StatusUpdate produced by the
+// *Processor from now on. Updates are dropped for subscribers that fall more
+// than AvalancheSubscriberBufferSize updates behind.
+func (p *Processor) Subscribe() <-chan StatusUpdate {
+	ch := make(chan StatusUpdate, AvalancheSubscriberBufferSize)
+
+	p.subscribersMu.Lock()
+	p.subscribers = append(p.subscribers, ch)
+	p.subscribersMu.Unlock()
+
+	return ch
+}
+
+// publish records the update for Cursors and sends it to all subscribers
+// without blocking
+func (p *Processor) publish(update StatusUpdate) {
+	p.history.append(update)
+
+	p.subscribersMu.Lock()
+	defer p.subscribersMu.Unlock()
+
+	for _, ch := range p.subscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
 // IsAccepted returns whether or not the Traget has been accepted by consensus
 func (p *Processor) IsAccepted(t Target) bool {
+	p.warmRecord(t.Hash())
 	if vr, ok := p.voteRecords[t.Hash()]; ok {
 		return vr.isAccepted()
 	}
 	return false
 }
 
+// GetMetadata returns the metadata attached to a Target that is still being
+// voted on
+func (p *Processor) GetMetadata(t Target) Metadata {
+	p.warmRecord(t.Hash())
+	return p.metadata[t.Hash()]
+}
+
+// copyMetadata copies md so callers can't change it after adding a target
+func copyMetadata(md Metadata) Metadata {
+	c := make(Metadata, len(md))
+	for k, v := range md {
+		c[k] = v
+	}
+	return c
+}
+
+// HandlePoll answers a Poll with our current view of each target, including
+// those we have finalized. Targets we don't know get a neutral vote, as does
+// every target in observer mode or of a Poll from another network. The votes
+// come from a pool; Release the Response once it has been sent.
+func (p *Processor) HandlePoll(id NodeID, poll Poll) Response {
+	p.stats(id).pollsReceived++
+
+	neutral := p.config.Observer || poll.GetNetwork() != p.network()
+	invs := poll.GetInvs()
+	buf := votesPool.Get().(*[]Vote)
+	votes := (*buf)[:0]
+	for _, inv := range invs {
+		if neutral {
+			votes = append(votes, NewVote(VoteUnknown, inv.TargetHash))
+		} else {
+			votes = append(votes, p.getConflictVote(inv.TargetHash))
+		}
+	}
+	*buf = votes
+
+	votes, truncated := p.truncateResponse(votes)
+	resp := NewResponse(poll.GetRound(), 0, votes).WithNetwork(p.network())
+	resp.truncated, resp.buf = truncated, buf
+	return resp
+}
+
+// ValidatePoll checks that an inbound Poll is from our network, well formed
+// and only asks about the target types in Config.TargetTypes. Observers refuse
+// every Poll with ErrObserver. It only reads the config, so unlike HandlePoll
+// it is safe to call concurrently.
+func (p *Processor) ValidatePoll(poll Poll) error {
+	if p.config.Observer {
+		return ErrObserver
+	}
+	if poll.GetNetwork() != p.network() {
+		return &PollError{PollErrorWrongNetwork, -1,
+			"poll is for " + poll.GetNetwork().String() + " rather than " + p.network().String()}
+	}
+	return ValidatePoll(poll, p.config.TargetTypes)
+}
+
+// getVote returns our vote for the target with the given hash
+func (p *Processor) getVote(h Hash) uint32 {
+	if vr, ok := p.voteRecords[h]; ok {
+		return yesOrNo(vr.isAccepted())
+	}
+
+	if accepted, ok := p.cold[h]; ok {
+		return yesOrNo(accepted)
+	}
+
+	if f, ok := p.finalizations[h]; ok {
+		return yesOrNo(f.accepted())
+	}
+
+	if accepted, ok := p.recent.get(h); ok {
+		return yesOrNo(accepted)
+	}
+
+	return VoteUnknown
+}
+
+// yesOrNo returns VoteYes if accepted and VoteNo otherwise
+func yesOrNo(accepted bool) uint32 {
+	if accepted {
+		return VoteYes
+	}
+	return VoteNo
+}
+
 // GetConfidence returns the confidence we have in the Target's acceptance
 func (p *Processor) GetConfidence(t Target) uint16 {
+	p.warmRecord(t.Hash())
 	vr, ok := p.voteRecords[t.Hash()]
 	if !ok {
 		panic("VoteRecord not found")
@@ -142,13 +534,47 @@ func (p *Processor) GetConfidence(t Target) uint16 {
 // GetInvsForNextPoll returns Invs for outstanding items that need to be
 // resolved by further queries
 func (p *Processor) GetInvsForNextPoll() []Inv {
-	invs := make([]Inv, 0, len(p.voteRecords))
+	return p.appendInvsForNextPoll(NoNode, make([]Inv, 0, len(p.voteRecords)), AvalancheMaxElementPoll)
+}
+
+// appendInvsForNextPoll appends the Invs for the next poll of a node to invs, up
+// to a total of limit, and returns the extended slice. Targets are chosen and
+// ordered by their polling priority; see pollPriority. Targets the poll would
+// over-sample, or the node doesn't track, are left out; see isOverSampled and
+// SetPeerPolicy.
+func (p *Processor) appendInvsForNextPoll(id NodeID, invs []Inv, limit int) []Inv {
+	room := limit - len(invs)
+	if room <= 0 {
+		return invs
+	}
+
+	// Targets already in invs aren't added again
+	var queued map[Hash]struct{}
+	if len(invs) > 0 {
+		queued = make(map[Hash]struct{}, len(invs))
+		for _, inv := range invs {
+			queued[inv.TargetHash] = struct{}{}
+		}
+	}
+
+	policy, hasPolicy := p.peerPolicies[id]
+
+	p.pollSeq++
+	pending := p.pendingScratch[:0]
 	for idx, r := range p.voteRecords {
 		if r.hasFinalized() {
 			// If this has finalized we can just skip.
 			continue
 		}
 
+		if _, ok := queued[idx]; ok {
+			continue
+		}
+
+		if p.isOverSampled(idx, id) {
+			continue
+		}
+
 		t := p.targets[idx]
 
 		// Obviously do not poll if the target is not worth polling
@@ -156,29 +582,60 @@ func (p *Processor) GetInvsForNextPoll() []Inv {
 			continue
 		}
 
+		if hasPolicy && !policy.Tracks(t) {
+			continue
+		}
+
+		if p.isFinalizedByPeer(id, idx, r) {
+			continue
+		}
+
 		// We don't have a decision, we need more votes.
-		invs = append(invs, Inv{t.Type(), idx})
+		pending = append(pending, p.newPollCandidate(idx, t))
 	}
+	p.pendingScratch = pending
 
-	// sortBlockInvsByWork(invs)
+	sort.Sort(byPollPriority(pending))
+	if len(pending) > room {
+		pending = pending[:room]
+	}
 
-	if len(invs) >= AvalancheMaxElementPoll {
-		invs = invs[:AvalancheMaxElementPoll]
+	for _, c := range pending {
+		invs = append(invs, Inv{p.targets[c.hash].Type(), c.hash})
+		p.lastPolled[c.hash] = p.pollSeq
+	}
+	if len(pending) > 0 {
+		p.pollCursor, p.hasPollCursor = pending[len(pending)-1].hash, true
 	}
 
 	return invs
 }
 
-// getSuitableNodeToQuery returns the best node to send the next query to
+// getSuitableNodeToQuery returns the best node to send the next query to. Nodes
+// with a full window of outstanding queries are not suitable. Demoted nodes are
+// only queried every SlowPeerProbeInterval rounds, or when no other node is
+// suitable.
 func (p *Processor) getSuitableNodeToQuery() NodeID {
-	nodeIDs := p.connman.NodesIDs()
+	nodeIDs := p.getPollPeers()
 
 	sort.Sort(nodesInRequestOrder(nodeIDs))
 
-	if len(nodeIDs) == 0 {
-		return NoNode
+	probe := p.round%int64(p.slowPeerProbeInterval()) == 0
+	slow := NoNode
+	for _, nodeID := range nodeIDs {
+		if p.outstanding[nodeID] >= p.config.pollWindow() {
+			continue
+		}
+
+		if p.IsDemoted(nodeID) != probe {
+			if slow == NoNode {
+				slow = nodeID
+			}
+			continue
+		}
+		return nodeID
 	}
-	return nodeIDs[0]
+	return slow
 }
 
 // isWorthyPolling determines whether or it's even worth polling about a Target
@@ -186,8 +643,8 @@ func (p *Processor) isWorthyPolling(t Target) bool {
 	return t.IsValid()
 }
 
-// start begins the poll/response cycle
-func (p *Processor) start() bool {
+// Start begins the poll/response cycle
+func (p *Processor) Start() bool {
 	p.runMu.Lock()
 	defer p.runMu.Unlock()
 
@@ -199,15 +656,34 @@ func (p *Processor) start() bool {
 	p.quitCh = make(chan (struct{}))
 	p.doneCh = make(chan (struct{}))
 
+	interval := p.config.PollInterval
+	if interval <= 0 {
+		interval = AvalancheTimeStep
+	}
+
 	go func() {
-		t := time.NewTicker(AvalancheTimeStep)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		// debounce is only set while an early poll is pending
+		var debounce <-chan time.Time
+
 		for {
 			select {
 			case <-p.quitCh:
 				close(p.doneCh)
 				return
 			case <-t.C:
-				p.eventLoop()
+				p.runEventLoop()
+			case <-p.triggerCh:
+				if debounce == nil {
+					debounce = time.After(p.config.PollDebounce)
+				}
+			case <-debounce:
+				debounce = nil
+				p.runEventLoop()
+			case lr := <-p.responseCh:
+				p.registerPolledResponse(lr)
 			}
 		}
 	}()
@@ -215,8 +691,8 @@ func (p *Processor) start() bool {
 	return true
 }
 
-// stop ends the poll/response cycle
-func (p *Processor) stop() bool {
+// Stop ends the poll/response cycle
+func (p *Processor) Stop() bool {
 	p.runMu.Lock()
 	defer p.runMu.Unlock()
 
@@ -233,16 +709,131 @@ func (p *Processor) stop() bool {
 
 // eventLoop performs a tick of processing
 func (p *Processor) eventLoop() {
-	invs := p.GetInvsForNextPoll()
-	if len(invs) == 0 {
+	p.applyValidations()
+	p.expireQueries()
+	p.collectGarbage()
+	p.tierRecords()
+	p.rotatePollPeers()
+	p.diversifyPollPeers()
+	p.checkEclipse()
+	p.adaptQuorum()
+	p.updateSuspension()
+
+	nodeID := p.getSuitableNodeToQuery()
+	if nodeID == NoNode {
 		return
 	}
 
-	nodeID := p.getSuitableNodeToQuery()
-	p.queries[queryKey(p.round, nodeID)] = NewRequestRecord(clock.Now().Unix(), invs)
+	// A batch too large for the node is split across polls, which are sent
+	// as far as its window allows
+	for {
+		poll, split, ok := p.query(nodeID)
+		if !ok {
+			return
+		}
+		if config, ok := p.loopbacks[nodeID]; ok {
+			p.sendLoopbackPoll(nodeID, config, poll.withInvsCopy())
+		} else if p.sender != nil {
+			p.sendPoll(nodeID, poll.withInvsCopy())
+		}
+		if !split || p.outstanding[nodeID] >= p.config.pollWindow() {
+			return
+		}
+	}
+}
+
+// PollNode builds the next Poll for the node and tracks it as an outstanding
+// query, so the node's Response to it is accepted by RegisterVotes. It is for
+// applications that choose which nodes to poll and send the Polls themselves.
+// It returns false if there is nothing to ask the node or it already has
+// PollWindow queries outstanding.
+func (p *Processor) PollNode(id NodeID) (Poll, bool) {
+	if p.outstanding[id] >= p.config.pollWindow() {
+		return Poll{}, false
+	}
+
+	poll, _, ok := p.query(id)
+	if !ok {
+		return Poll{}, false
+	}
+	return poll.withInvsCopy(), true
+}
+
+// query builds the next Poll for the node and tracks it as an outstanding
+// query. The Poll's Invs are only valid until the query is answered or expires.
+// It also returns whether Invs were split off for later polls because the
+// batch was too large for the node; see SetPeerPolicy.
+func (p *Processor) query(nodeID NodeID) (Poll, bool, bool) {
+	// Polls shrink to fit the bandwidth budget and what the node will answer
+	limit := p.responseInvLimit(nodeID, p.pollInvLimit(nodeID))
+	if limit == 0 {
+		return Poll{}, false, false
+	}
+
+	// Invs the node left unanswered last time go first
+	buf := invsPool.Get().(*[]Inv)
+	*buf = p.appendRemainder(nodeID, (*buf)[:0], limit)
+	*buf = p.appendInvsForNextPoll(nodeID, *buf, limit)
+	if len(*buf) == 0 {
+		invsPool.Put(buf)
+		return Poll{}, false, false
+	}
+
+	var split bool
+	*buf, split = p.splitBatch(nodeID, *buf)
+
+	now := clock.Now()
+	r := NewRequestRecord(now.Unix(), *buf)
+	r.buf = buf
+	r.sent = now
+
+	key := queryKey{p.round, nodeID}
+	if !p.journalQuery(key, r) {
+		r.release()
+		return Poll{}, false, false
+	}
+
+	p.meterSent(nodeID, len(*buf))
+	p.stats(nodeID).pollsSent++
+	p.addQuery(key, r)
+	p.round++
+	return NewPoll(key.round, *buf).WithNetwork(p.network()), split, true
+}
+
+// expireQueries removes queries that have gone unanswered for too long so the
+// nodes they were sent to can be queried again
+func (p *Processor) expireQueries() {
+	for key, r := range p.queries {
+		if r.IsExpired() {
+			p.removeQuery(key)
+			p.recordLatency(key.nodeID, AvalancheRequestTimeout)
+			p.stats(key.nodeID).timeouts++
+			r.release()
+		}
+	}
+}
+
+// addQuery tracks a query sent to a node until it is answered or expires
+func (p *Processor) addQuery(key queryKey, r RequestRecord) {
+	p.queries[key] = r
+	p.outstanding[key.nodeID]++
+	p.trackAsked(key.nodeID, r.GetInvs())
+}
+
+// removeQuery stops tracking the query and frees up space in its node's window
+func (p *Processor) removeQuery(key queryKey) {
+	p.untrackAsked(key.nodeID, p.queries[key].GetInvs())
+	delete(p.queries, key)
+	p.unjournalQuery(key)
+
+	p.outstanding[key.nodeID]--
+	if p.outstanding[key.nodeID] <= 0 {
+		delete(p.outstanding, key.nodeID)
+	}
 }
 
-// queryKey returns a string to use for map keys that reprents the given inputs
-func queryKey(round int64, nodeID NodeID) string {
-	return fmt.Sprintf("%d|%d", round, nodeID)
+// queryKey identifies a query by its round and the node it was sent to
+type queryKey struct {
+	round  int64
+	nodeID NodeID
 }
@@ -0,0 +1,304 @@
+// Package rebroadcast sends transactions avalanche finalizes to bitcoin nodes,
+// so they propagate through the base relay network as widely as possible
+// rather than only to nodes that took part in voting
+package rebroadcast
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+const (
+	// DefaultRate is the number of transactions sent per second if
+	// Config.Rate is not positive
+	DefaultRate = 10
+
+	// DefaultQueueSize is the number of transactions waiting to be sent if
+	// Config.QueueSize is not positive
+	DefaultQueueSize = 1024
+
+	// DefaultDedupeSize is the number of recently sent transactions remembered
+	// if Config.DedupeSize is not positive
+	DefaultDedupeSize = 10000
+)
+
+// Sender sends a raw transaction to a node
+type Sender interface {
+	SendRawTransaction(rawTx string) error
+}
+
+// RPCSender is a Sender calling sendrawtransaction on a node with bitcoind's
+// JSON-RPC interface
+type RPCSender struct {
+	Client *bitcoinrpc.Client
+}
+
+// SendRawTransaction implements Sender. Transactions the node already has in
+// its chain are not an error.
+func (s RPCSender) SendRawTransaction(rawTx string) error {
+	_, err := s.Client.Call("sendrawtransaction", rawTx)
+	if e, ok := err.(*bitcoinrpc.RPCError); ok && e.Code == bitcoinrpc.ErrCodeVerifyAlreadyInChain {
+		return nil
+	}
+	return err
+}
+
+// Config configures a Rebroadcaster
+type Config struct {
+	// Nodes are sent every finalized transaction
+	Nodes []Sender
+
+	// RawTx returns the hex serialization of a transaction target
+	RawTx func(avalanche.Target) (string, error)
+
+	// Rate is the most transactions sent per second
+	Rate float64
+
+	// QueueSize is the most transactions waiting to be sent. Transactions
+	// finalized while the queue is full are dropped.
+	QueueSize int
+
+	// DedupeSize is the number of recently queued transactions that are not
+	// queued again
+	DedupeSize int
+
+	// Reporter receives errors getting or sending transactions, if set
+	Reporter avalanche.ErrorReporter
+}
+
+// Stats are counts of a Rebroadcaster's activity
+type Stats struct {
+	// Queued is the total number of transactions queued to be sent
+	Queued int64
+
+	// Sent is the total number of transactions sent to at least one node
+	Sent int64
+
+	// Duplicates is the total number of transactions not queued because they
+	// were recently queued already
+	Duplicates int64
+
+	// Dropped is the total number of transactions not queued because the
+	// queue was full
+	Dropped int64
+
+	// Failed is the total number of failures getting or sending transactions,
+	// counting each node separately
+	Failed int64
+}
+
+// Rebroadcaster sends finalized transactions to the configured nodes. Its
+// Callback queues them without blocking the *Processor; they are sent at the
+// configured rate once the Rebroadcaster is started.
+type Rebroadcaster struct {
+	// The counters are used atomically so they come first to be 64-bit
+	// aligned on 32-bit platforms
+	queued     int64
+	sent       int64
+	duplicates int64
+	dropped    int64
+	failed     int64
+
+	config   Config
+	interval time.Duration
+	queue    chan avalanche.Target
+
+	// recent and recentOrder remember the last DedupeSize queued hashes,
+	// oldest first in recentOrder
+	recentMu    sync.Mutex
+	recent      map[avalanche.Hash]struct{}
+	recentOrder []avalanche.Hash
+
+	runMu     sync.Mutex
+	isRunning bool
+	quitCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a new *Rebroadcaster
+func New(config Config) *Rebroadcaster {
+	if config.Rate <= 0 {
+		config.Rate = DefaultRate
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.DedupeSize <= 0 {
+		config.DedupeSize = DefaultDedupeSize
+	}
+
+	return &Rebroadcaster{
+		config:   config,
+		interval: time.Duration(float64(time.Second) / config.Rate),
+		queue:    make(chan avalanche.Target, config.QueueSize),
+		recent:   make(map[avalanche.Hash]struct{}, config.DedupeSize),
+	}
+}
+
+// Callback returns a FinalizationCallback queueing finalized transactions.
+// Transactions with StatusIncluded are already in a block so they aren't sent.
+// Register it for transaction targets:
+//
+//	p.OnFinalized("tx", r.Callback())
+func (r *Rebroadcaster) Callback() avalanche.FinalizationCallback {
+	return func(t avalanche.Target, status avalanche.Status) error {
+		if status == avalanche.StatusFinalized {
+			r.Enqueue(t)
+		}
+		return nil
+	}
+}
+
+// Enqueue queues a transaction to be sent unless it was recently queued or the
+// queue is full. It returns whether the transaction was queued.
+func (r *Rebroadcaster) Enqueue(t avalanche.Target) bool {
+	if !r.remember(t.Hash()) {
+		atomic.AddInt64(&r.duplicates, 1)
+		return false
+	}
+
+	select {
+	case r.queue <- t:
+		atomic.AddInt64(&r.queued, 1)
+		return true
+	default:
+		// Forget it so it can be queued again once there's room
+		r.forget(t.Hash())
+		atomic.AddInt64(&r.dropped, 1)
+		return false
+	}
+}
+
+// Start launches the goroutine sending queued transactions
+func (r *Rebroadcaster) Start() bool {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.isRunning {
+		return false
+	}
+
+	r.isRunning = true
+	r.quitCh = make(chan struct{})
+
+	r.wg.Add(1)
+	go r.work()
+
+	return true
+}
+
+// Stop stops sending transactions. Queued transactions are kept until it is
+// started again.
+func (r *Rebroadcaster) Stop() bool {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if !r.isRunning {
+		return false
+	}
+
+	close(r.quitCh)
+	r.wg.Wait()
+
+	r.isRunning = false
+	return true
+}
+
+// Stats returns a snapshot of the Rebroadcaster's activity
+func (r *Rebroadcaster) Stats() Stats {
+	return Stats{
+		Queued:     atomic.LoadInt64(&r.queued),
+		Sent:       atomic.LoadInt64(&r.sent),
+		Duplicates: atomic.LoadInt64(&r.duplicates),
+		Dropped:    atomic.LoadInt64(&r.dropped),
+		Failed:     atomic.LoadInt64(&r.failed),
+	}
+}
+
+// work sends queued transactions no faster than the configured rate until the
+// Rebroadcaster is stopped
+func (r *Rebroadcaster) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.quitCh:
+			return
+		case t := <-r.queue:
+			r.send(t)
+		}
+
+		select {
+		case <-r.quitCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// send sends a transaction to every node
+func (r *Rebroadcaster) send(t avalanche.Target) {
+	tags := map[string]string{"hash": strconv.Itoa(int(t.Hash()))}
+
+	rawTx, err := r.config.RawTx(t)
+	if err != nil {
+		r.fail(&avalanche.Error{Op: "rebroadcast", Err: err}, tags)
+		return
+	}
+
+	sent := false
+	for _, node := range r.config.Nodes {
+		if err = node.SendRawTransaction(rawTx); err != nil {
+			r.fail(&avalanche.Error{Op: "rebroadcast", Err: err}, tags)
+			continue
+		}
+		sent = true
+	}
+	if sent {
+		atomic.AddInt64(&r.sent, 1)
+	}
+}
+
+func (r *Rebroadcaster) fail(err error, tags map[string]string) {
+	atomic.AddInt64(&r.failed, 1)
+	if r.config.Reporter != nil {
+		r.config.Reporter.ReportError(err, tags)
+	}
+}
+
+// remember records a hash as recently queued, evicting the oldest if there are
+// too many. It returns false if the hash was already recent.
+func (r *Rebroadcaster) remember(h avalanche.Hash) bool {
+	r.recentMu.Lock()
+	defer r.recentMu.Unlock()
+
+	if _, ok := r.recent[h]; ok {
+		return false
+	}
+	if len(r.recentOrder) >= r.config.DedupeSize {
+		delete(r.recent, r.recentOrder[0])
+		r.recentOrder = r.recentOrder[1:]
+	}
+	r.recent[h] = struct{}{}
+	r.recentOrder = append(r.recentOrder, h)
+	return true
+}
+
+// forget removes the most recently remembered hash
+func (r *Rebroadcaster) forget(h avalanche.Hash) {
+	r.recentMu.Lock()
+	defer r.recentMu.Unlock()
+
+	delete(r.recent, h)
+	if n := len(r.recentOrder); n > 0 && r.recentOrder[n-1] == h {
+		r.recentOrder = r.recentOrder[:n-1]
+	}
+}
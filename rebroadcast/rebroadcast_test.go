@@ -0,0 +1,145 @@
+package rebroadcast
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+type tx avalanche.Hash
+
+func (t tx) Hash() avalanche.Hash { return avalanche.Hash(t) }
+func (tx) IsAccepted() bool       { return true }
+func (tx) IsValid() bool          { return true }
+func (tx) Type() string           { return "tx" }
+func (tx) Score() int64           { return 1 }
+
+type recordingSender struct {
+	mu    sync.Mutex
+	sent  []string
+	times []time.Time
+	err   error
+}
+
+func (s *recordingSender) SendRawTransaction(rawTx string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, rawTx)
+	s.times = append(s.times, time.Now())
+	return s.err
+}
+
+func (s *recordingSender) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func rawTx(t avalanche.Target) (string, error) {
+	if t.Hash() == 0 {
+		return "", errors.New("unknown tx")
+	}
+	return strconv.Itoa(int(t.Hash())), nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRebroadcaster(t *testing.T) {
+	good, bad := &recordingSender{}, &recordingSender{err: errors.New("offline")}
+	reported := 0
+	r := New(Config{
+		Nodes:      []Sender{good, bad},
+		RawTx:      rawTx,
+		Rate:       50,
+		QueueSize:  3,
+		DedupeSize: 2,
+		Reporter: avalanche.ErrorReporterFunc(func(error, map[string]string) {
+			reported++
+		}),
+	})
+	cb := r.Callback()
+
+	// Only finalized transactions are queued, and only once
+	cb(tx(1), avalanche.StatusFinalized)
+	cb(tx(1), avalanche.StatusFinalized)
+	cb(tx(2), avalanche.StatusInvalid)
+	cb(tx(2), avalanche.StatusIncluded)
+	cb(tx(3), avalanche.StatusFinalized)
+
+	// The oldest hash is forgotten once there are more than DedupeSize
+	cb(tx(4), avalanche.StatusFinalized)
+	if r.Enqueue(tx(1)) {
+		t.Fatal("Expected the queue to be full")
+	}
+
+	// A dropped transaction is forgotten, so it is dropped again rather than
+	// deduplicated
+	if r.Enqueue(tx(1)) {
+		t.Fatal("Expected the queue to be full")
+	}
+
+	stats := r.Stats()
+	if stats.Queued != 3 || stats.Duplicates != 1 || stats.Dropped != 2 {
+		t.Fatal("Unexpected stats", stats)
+	}
+
+	if !r.Start() || r.Start() {
+		t.Fatal("Expected to start once")
+	}
+	waitFor(t, func() bool { return good.count() == 3 })
+	if !r.Stop() || r.Stop() {
+		t.Fatal("Expected to stop once")
+	}
+
+	// Every node is sent each transaction, at no more than the rate
+	for i, expected := range []string{"1", "3", "4"} {
+		if good.sent[i] != expected || bad.sent[i] != expected {
+			t.Fatal("Expected", expected, "but got", good.sent[i], bad.sent[i])
+		}
+	}
+	for i := 1; i < len(good.times); i++ {
+		if gap := good.times[i].Sub(good.times[i-1]); gap < 15*time.Millisecond {
+			t.Fatal("Expected sends to be rate limited but they were", gap, "apart")
+		}
+	}
+
+	// Failures to get or send a transaction are counted and reported
+	r.Enqueue(tx(0))
+	r.Start()
+	waitFor(t, func() bool { return r.Stats().Failed == 4 })
+	r.Stop()
+
+	stats = r.Stats()
+	if stats.Sent != 3 || reported != 4 {
+		t.Fatal("Unexpected stats", stats, "with", reported, "reported")
+	}
+}
+
+func TestRPCSender(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"result":null,"error":{"code":-27,"message":"transaction already in block chain"},"id":1}`))
+	}))
+	defer srv.Close()
+
+	// Transactions already in the chain have propagated
+	sender := RPCSender{bitcoinrpc.NewClient(bitcoinrpc.Config{URL: srv.URL})}
+	if err := sender.SendRawTransaction("00"); err != nil {
+		t.Fatal(err)
+	}
+}
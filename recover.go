@@ -0,0 +1,81 @@
+package avalanche
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrorReporter receives errors from background goroutines that have no caller
+// to return them to, such as the *Processor's event loop and a *PollServer's
+// workers. It matches the shape of error tracker clients such as Sentry's, so
+// one can be adapted with an ErrorReporterFunc. It must be safe for concurrent
+// use.
+type ErrorReporter interface {
+	// ReportError reports err along with tags describing where it happened
+	ReportError(err error, tags map[string]string)
+}
+
+// ErrorReporterFunc adapts a function to an ErrorReporter
+type ErrorReporterFunc func(err error, tags map[string]string)
+
+// ReportError calls f
+func (f ErrorReporterFunc) ReportError(err error, tags map[string]string) {
+	f(err, tags)
+}
+
+// PanicError is reported when a background goroutine recovers from a panic.
+// Value is what was passed to panic and Stack is where it happened.
+type PanicError struct {
+	Op    string
+	Value interface{}
+	Stack []byte
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("avalanche: %s: panic: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// reportError sends err to reporter if there is one
+func reportError(reporter ErrorReporter, err error, tags map[string]string) {
+	if reporter != nil {
+		reporter.ReportError(err, tags)
+	}
+}
+
+// reportPanic reports a value recovered from a panic during op. It must be
+// called with the result of recover from a deferred function.
+func reportPanic(reporter ErrorReporter, op string, v interface{}, tags map[string]string) {
+	reportError(reporter, &PanicError{op, v, debug.Stack()}, tags)
+}
+
+// SetErrorReporter sets where errors from the event loop are reported, such as
+// panics and queries that could not be journaled. It must be called before the
+// *Processor is started.
+func (p *Processor) SetErrorReporter(reporter ErrorReporter) {
+	p.reporter = reporter
+}
+
+// runEventLoop performs a tick of processing, recovering from and reporting a
+// panic so that one bad tick does not take down the node. The next tick tries
+// again.
+func (p *Processor) runEventLoop() {
+	defer func() {
+		if v := recover(); v != nil {
+			p.loopPanics++
+			reportPanic(p.reporter, "event loop", v, map[string]string{
+				"loop":        "event",
+				"consecutive": fmt.Sprint(p.loopPanics),
+			})
+		}
+	}()
+
+	p.eventLoop()
+	p.loopPanics = 0
+}
@@ -0,0 +1,131 @@
+package avalanche
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// panicTarget is a Target that panics once broken when the event loop checks
+// whether it is still worth polling
+type panicTarget struct {
+	hash   Hash
+	broken bool
+}
+
+func (t *panicTarget) Hash() Hash { return t.hash }
+
+func (*panicTarget) IsAccepted() bool { return true }
+
+func (t *panicTarget) IsValid() bool {
+	if t.broken {
+		panic("target is broken")
+	}
+	return true
+}
+
+func (*panicTarget) Type() string { return "block" }
+
+func (*panicTarget) Score() int64 { return 1 }
+
+// failingJournal is a QueryJournal that cannot record queries
+type failingJournal struct{}
+
+var errJournalFull = errors.New("journal full")
+
+func (failingJournal) Record(JournaledQuery) error { return errJournalFull }
+
+func (failingJournal) Remove(int64, NodeID) error { return nil }
+
+func (failingJournal) Load() ([]JournaledQuery, int64, error) { return nil, 0, nil }
+
+func TestEventLoopPanicRecovery(t *testing.T) {
+	config := DefaultConfig
+	config.PollInterval = time.Millisecond
+	p := NewProcessorWithConfig(NewConnman(), config)
+
+	type report struct {
+		err  error
+		tags map[string]string
+	}
+	reports := make(chan report, 16)
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		select {
+		case reports <- report{err, tags}:
+		default:
+		}
+	}))
+
+	// Every tick panics, and each is reported while the loop keeps running
+	target := &panicTarget{hash: Hash(1)}
+	assertTrue(t, p.AddTargetToReconcile(target))
+	target.broken = true
+	assertTrue(t, p.Start())
+	for i := 1; i <= 2; i++ {
+		r := <-reports
+		pe, ok := r.err.(*PanicError)
+		if !ok || pe.Op != "event loop" || pe.Value != "target is broken" || len(pe.Stack) == 0 {
+			t.Fatal("Expected a PanicError from the event loop but got", r.err)
+		}
+		if r.tags["consecutive"] != strconv.Itoa(i) {
+			t.Fatal("Expected", i, "consecutive panics but got", r.tags["consecutive"])
+		}
+	}
+	assertTrue(t, p.Stop())
+
+	// Panics with errors unwrap to them
+	pe := &PanicError{"event loop", errJournalFull, nil}
+	assertTrue(t, pe.Unwrap() == errJournalFull)
+}
+
+func TestJournalErrorReported(t *testing.T) {
+	connman := NewConnman()
+	connman.AddNode(NodeID(1))
+	p := NewProcessor(connman)
+	assertTrue(t, p.RecoverQueries(failingJournal{}) == nil)
+
+	var reported error
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		reported = err
+	}))
+
+	// The query is not made and the journal's error is reported
+	p.AddTargetToReconcile(blockForHash(Hash(65)))
+	p.eventLoop()
+	assertTrue(t, reported == errJournalFull)
+	assertTrue(t, len(p.queries) == 0)
+}
+
+func TestPollServerPanicRecovery(t *testing.T) {
+	calls := 0
+	handler := PollHandlerFunc(func(id NodeID, poll Poll) Response {
+		calls++
+		if calls == 1 {
+			panic("handler is broken")
+		}
+		return NewResponse(poll.GetRound(), 0, nil)
+	})
+
+	var reported error
+	s := NewPollServer(handler, 1, 1)
+	s.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		reported = err
+	}))
+	assertTrue(t, s.Start())
+	defer s.Stop()
+
+	// The panicking Poll fails and the worker goes on to answer the next
+	_, ok := s.Serve(NodeID(1), NewPoll(1, nil))
+	assertFalse(t, ok)
+	if pe, isPanic := reported.(*PanicError); !isPanic || pe.Op != "handle poll" {
+		t.Fatal("Expected a PanicError from the handler but got", reported)
+	}
+
+	resp, ok := s.Serve(NodeID(1), NewPoll(2, nil))
+	assertTrue(t, ok)
+	assertTrue(t, resp.GetRound() == 2)
+
+	stats := s.Stats()
+	assertTrue(t, stats.Panics == 1 && stats.Handled == 1 && stats.InFlight == 0)
+}
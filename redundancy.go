@@ -0,0 +1,56 @@
+package avalanche
+
+import "sort"
+
+// GetAskedPeers returns the nodes with outstanding queries about a target
+func (p *Processor) GetAskedPeers(h Hash) []NodeID {
+	nodeIDs := append([]NodeID{}, p.asked[h]...)
+	sort.Sort(nodesInRequestOrder(nodeIDs))
+	return nodeIDs
+}
+
+// isOverSampled returns whether polling a node about a target would leave more
+// queries about it outstanding than Config.PollRedundancy allows, or ask the
+// node twice at once. Without a limit a round can spend its polls on a few
+// targets, and a node's answers to the same question count twice towards the
+// quorum.
+func (p *Processor) isOverSampled(h Hash, id NodeID) bool {
+	if p.config.PollRedundancy <= 0 {
+		return false
+	}
+
+	asked := p.asked[h]
+	for _, askedID := range asked {
+		if askedID == id {
+			return true
+		}
+	}
+	return len(asked) >= p.config.PollRedundancy
+}
+
+// trackAsked records that a node has an outstanding query about the Invs.
+// Emptied lists are kept until their target is forgotten so polling the same
+// targets round after round doesn't allocate.
+func (p *Processor) trackAsked(id NodeID, invs []Inv) {
+	for _, inv := range invs {
+		if _, ok := p.voteRecords[inv.TargetHash]; ok {
+			p.asked[inv.TargetHash] = append(p.asked[inv.TargetHash], id)
+		}
+	}
+}
+
+// untrackAsked records that a query about the Invs is no longer outstanding
+func (p *Processor) untrackAsked(id NodeID, invs []Inv) {
+	for _, inv := range invs {
+		asked, ok := p.asked[inv.TargetHash]
+		if !ok {
+			continue
+		}
+		for i, askedID := range asked {
+			if askedID == id {
+				p.asked[inv.TargetHash] = append(asked[:i], asked[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package avalanche
+
+import "testing"
+
+func TestPollRedundancy(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollRedundancy: 2})
+		a       = &Block{Hash(1), 0, true, true}
+		b       = &Block{Hash(2), 0, true, true}
+		updates = []StatusUpdate{}
+	)
+	for id := NodeID(0); id < 3; id++ {
+		connman.AddNode(id)
+	}
+	assertTrue(t, p.AddTargetToReconcile(a))
+	assertTrue(t, p.AddTargetToReconcile(b))
+
+	// Only two nodes are asked about each target at once
+	p.eventLoop()
+	p.eventLoop()
+	p.eventLoop()
+	if len(p.queries) != 2 {
+		t.Fatal("Expected 2 outstanding queries but got", len(p.queries))
+	}
+	asked := p.GetAskedPeers(a.Hash())
+	assertTrue(t, len(asked) == 2 && asked[0] == NodeID(0) && asked[1] == NodeID(1))
+
+	// A target added since is not part of the query, so a response with a vote
+	// on it is refused, as is one with a repeated vote
+	c := &Block{Hash(3), 0, true, true}
+	assertTrue(t, p.AddTargetToReconcile(c))
+	padded := NewResponse(0, 0, []Vote{NewVote(VoteYes, a.Hash()), NewVote(VoteYes, b.Hash()), NewVote(VoteYes, c.Hash())})
+	assertTrue(t, p.CheckResponse(NodeID(0), padded).(*ResponseError).Code == ResponseErrorTooManyVotes)
+	repeated := NewResponse(0, 0, []Vote{NewVote(VoteYes, a.Hash()), NewVote(VoteYes, a.Hash())})
+	assertTrue(t, p.CheckResponse(NodeID(0), repeated).(*ResponseError).Code == ResponseErrorMismatch)
+
+	resp := NewResponse(0, 0, []Vote{NewVote(VoteYes, a.Hash()), NewVote(VoteYes, b.Hash())})
+	assertTrue(t, p.RegisterVotes(NodeID(0), resp, &updates))
+
+	once := newVoteRecordWithParams(true, p.voteParams())
+	once.regsiterVote(VoteYes)
+	assertTrue(t, *p.voteRecords[a.Hash()] == *once)
+	assertTrue(t, *p.voteRecords[b.Hash()] == *once)
+	assertTrue(t, *p.voteRecords[c.Hash()] == *newVoteRecordWithParams(true, p.voteParams()))
+
+	// Answering frees a slot for the target
+	asked = p.GetAskedPeers(a.Hash())
+	assertTrue(t, len(asked) == 1 && asked[0] == NodeID(1))
+	p.eventLoop()
+	asked = p.GetAskedPeers(a.Hash())
+	assertTrue(t, len(asked) == 2 && asked[0] == NodeID(0))
+}
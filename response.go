@@ -1,17 +1,59 @@
 package avalanche
 
-import "time"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// invsPool holds the Inv slices backing RequestRecords so each round can reuse
+// the slice from an earlier, completed query
+var invsPool = sync.Pool{
+	New: func() interface{} { return new([]Inv) },
+}
+
+// votesPool holds the Vote slices backing Responses from HandlePoll so each
+// answer can reuse the slice of one that has been released
+var votesPool = sync.Pool{
+	New: func() interface{} { return new([]Vote) },
+}
 
 // Response is a list of votes that respond to a Poll
 type Response struct {
 	round    int64
 	cooldown uint32
 	votes    []Vote
+
+	// truncated is set when only the Poll's first len(votes) Invs were
+	// answered
+	truncated bool
+
+	network Network
+
+	// buf is set when votes came from votesPool
+	buf *[]Vote
 }
 
-// NewResponse creates a new Response object with the given votes
+// NewResponse creates a new mainnet Response object with the given votes
 func NewResponse(round int64, cooldown uint32, votes []Vote) Response {
-	return Response{round, cooldown, votes}
+	return Response{round, cooldown, votes, false, MainNet, nil}
+}
+
+// NewTruncatedResponse creates a new mainnet Response that answers only the
+// first len(votes) Invs of a Poll, for a responder too busy to answer them all
+func NewTruncatedResponse(round int64, cooldown uint32, votes []Vote) Response {
+	return Response{round, cooldown, votes, true, MainNet, nil}
+}
+
+// WithNetwork returns a copy of the Response for the given network
+func (r Response) WithNetwork(n Network) Response {
+	r.network = n
+	return r
+}
+
+// GetNetwork returns the network the Response is from
+func (r Response) GetNetwork() Network {
+	return r.network.orMainNet()
 }
 
 // GetVotes returns the votes in the Response
@@ -24,15 +66,40 @@ func (r Response) GetRound() int64 {
 	return r.round
 }
 
+// IsTruncated returns whether the Response answers only some of the Poll's
+// Invs
+func (r Response) IsTruncated() bool {
+	return r.truncated
+}
+
+// Release returns the Response's votes to the pool if they came from one, as
+// those from HandlePoll do, so answering the next Poll doesn't allocate them.
+// It is for whoever sends the Response once it has been encoded or
+// registered; neither the Response nor any copy of it may be used afterwards.
+// Releasing a Response that didn't come from the pool does nothing.
+func (r Response) Release() {
+	if r.buf != nil {
+		*r.buf = r.votes[:0]
+		votesPool.Put(r.buf)
+	}
+}
+
 // RequestRecord is a poll request for more votes
 type RequestRecord struct {
 	timestamp int64
 	invs      []Inv
+
+	// buf is set when invs came from invsPool
+	buf *[]Inv
+
+	// sent is when the query was sent, for measuring the response time and
+	// expiring the query against the monotonic clock
+	sent time.Time
 }
 
 // NewRequestRecord creates a new RequestRecord
 func NewRequestRecord(timestamp int64, invs []Inv) RequestRecord {
-	return RequestRecord{timestamp, invs}
+	return RequestRecord{timestamp: timestamp, invs: invs}
 }
 
 // GetTimestamp returns the timestamp that the request was created
@@ -45,7 +112,125 @@ func (r RequestRecord) GetInvs() []Inv {
 	return r.invs
 }
 
-// IsExpired returns true if the request has expired
+// IsExpired returns true if the request has expired. Requests sent by a
+// *Processor are timed with the monotonic clock, so a jump of the wall clock,
+// such as an NTP correction, neither expires them early nor keeps them late.
 func (r RequestRecord) IsExpired() bool {
+	if !r.sent.IsZero() {
+		return clock.Now().Sub(r.sent) > AvalancheRequestTimeout
+	}
 	return time.Unix(r.timestamp, 0).Add(AvalancheRequestTimeout).Before(clock.Now())
 }
+
+// release returns the record's Invs to the pool if they came from it. The Invs
+// must not be used afterwards.
+func (r RequestRecord) release() {
+	if r.buf != nil {
+		invsPool.Put(r.buf)
+	}
+}
+
+// ResponseErrorCode identifies why a Response doesn't answer a query
+type ResponseErrorCode int
+
+const (
+	// ResponseErrorUnsolicited is used for a Response that matches no
+	// outstanding query, such as one whose query has already been answered
+	ResponseErrorUnsolicited ResponseErrorCode = iota + 1
+
+	// ResponseErrorExpired is used for a Response to a query that has expired
+	ResponseErrorExpired
+
+	// ResponseErrorTooManyVotes is used for a Response padded with more votes
+	// than its query had Invs
+	ResponseErrorTooManyVotes
+
+	// ResponseErrorTooFewVotes is used for a Response with fewer votes than
+	// its query had Invs that isn't truncated
+	ResponseErrorTooFewVotes
+
+	// ResponseErrorMismatch is used for a vote on another target than the Inv
+	// in the same position of the query, such as in a reordered Response
+	ResponseErrorMismatch
+
+	// ResponseErrorWrongNetwork is used for a Response from another network
+	ResponseErrorWrongNetwork
+)
+
+// String returns the code's name
+func (c ResponseErrorCode) String() string {
+	switch c {
+	case ResponseErrorUnsolicited:
+		return "unsolicited"
+	case ResponseErrorExpired:
+		return "expired"
+	case ResponseErrorTooManyVotes:
+		return "too_many_votes"
+	case ResponseErrorTooFewVotes:
+		return "too_few_votes"
+	case ResponseErrorMismatch:
+		return "mismatch"
+	case ResponseErrorWrongNetwork:
+		return "wrong_network"
+	}
+	return "unknown"
+}
+
+// ResponseError describes why a Response doesn't answer a query. Index is the
+// offending vote, or -1 if no single vote is at fault.
+type ResponseError struct {
+	Code    ResponseErrorCode
+	Index   int
+	Message string
+}
+
+// Error implements error
+func (e *ResponseError) Error() string {
+	return "avalanche: invalid response: " + e.Message
+}
+
+// Unwrap returns ErrInvalidResponse
+func (e *ResponseError) Unwrap() error {
+	return ErrInvalidResponse
+}
+
+// isMalformed returns whether the Response answered its query wrongly, rather
+// than simply arriving late
+func (e *ResponseError) isMalformed() bool {
+	return e.Code != ResponseErrorUnsolicited && e.Code != ResponseErrorExpired
+}
+
+// checkResponse checks that resp answers the query r, if there is one, on the
+// given network. Its votes must be on the query's Invs in the same order, and
+// there must be a vote for every Inv unless resp is truncated. The returned
+// error is a *ResponseError.
+func checkResponse(r RequestRecord, ok bool, resp Response, network Network) error {
+	if !ok {
+		return &ResponseError{ResponseErrorUnsolicited, -1, fmt.Sprintf("no query for round %d", resp.GetRound())}
+	}
+	if r.IsExpired() {
+		return &ResponseError{ResponseErrorExpired, -1, fmt.Sprintf("query for round %d expired", resp.GetRound())}
+	}
+	if resp.GetNetwork() != network {
+		return &ResponseError{ResponseErrorWrongNetwork, -1,
+			fmt.Sprintf("response is from %s rather than %s", resp.GetNetwork(), network)}
+	}
+
+	invs, votes := r.GetInvs(), resp.GetVotes()
+	switch {
+	case len(votes) > len(invs):
+		return &ResponseError{ResponseErrorTooManyVotes, len(invs),
+			fmt.Sprintf("%d votes for %d invs", len(votes), len(invs))}
+	case len(votes) < len(invs) && !resp.IsTruncated():
+		return &ResponseError{ResponseErrorTooFewVotes, -1,
+			fmt.Sprintf("%d votes for %d invs", len(votes), len(invs))}
+	}
+
+	for i, v := range votes {
+		if invs[i].TargetHash != v.GetHash() {
+			return &ResponseError{ResponseErrorMismatch, i,
+				fmt.Sprintf("vote %d is on %s rather than %s", i, v.GetHash().DisplayHex(), invs[i].TargetHash.DisplayHex())}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package avalanche
+
+// pollCandidate is a target that could be included in the next poll
+type pollCandidate struct {
+	hash     Hash
+	score    int64
+	priority int64
+
+	// wrapped is whether the hash is at or before the poll cursor, so ties
+	// are broken by taking turns through the backlog in hash order
+	wrapped bool
+}
+
+// pollPriority returns how urgently a target should be polled: its Score plus
+// the aging weight for each poll since it was last included. Without aging a
+// steady stream of high scoring targets would starve the rest whenever there
+// are more targets than fit in a poll.
+func (p *Processor) pollPriority(h Hash, t Target) int64 {
+	weight := p.config.AgingWeight
+	if weight <= 0 {
+		weight = AvalancheAgingWeight
+	}
+	return t.Score() + weight*(p.pollSeq-p.lastPolled[h])
+}
+
+// newPollCandidate returns the pollCandidate for a target
+func (p *Processor) newPollCandidate(h Hash, t Target) pollCandidate {
+	return pollCandidate{h, t.Score(), p.pollPriority(h, t), p.hasPollCursor && h <= p.pollCursor}
+}
+
+// byPollPriority sorts pollCandidates by descending priority, then by
+// descending score, then by hash starting after the poll cursor
+type byPollPriority []pollCandidate
+
+// Len implements the sort interface Len method for byPollPriority
+func (a byPollPriority) Len() int { return len(a) }
+
+// Swap implements the sort interface Swap method for byPollPriority
+func (a byPollPriority) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// Less implements the sort interface Less method for byPollPriority
+func (a byPollPriority) Less(i, j int) bool {
+	if a[i].priority != a[j].priority {
+		return a[i].priority > a[j].priority
+	}
+	if a[i].score != a[j].score {
+		return a[i].score > a[j].score
+	}
+	if a[i].wrapped != a[j].wrapped {
+		return !a[i].wrapped
+	}
+	return a[i].hash < a[j].hash
+}
@@ -0,0 +1,175 @@
+package avalanche
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// shortIDBytes is the length of a ShortID. It is a variable so tests can
+// shorten it to force collisions.
+var shortIDBytes = 6
+
+// ShortID is a salted digest of a Hash, shortened to 6 bytes like the short
+// transaction IDs of compact blocks. A querier picks a new random salt for
+// each CompactPoll so collisions can't be engineered ahead of time.
+type ShortID uint64
+
+// NewShortID returns the ShortID of h under salt
+func NewShortID(salt uint64, h Hash) ShortID {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:], salt)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(h))
+	sum := sha256.Sum256(buf[:])
+
+	var id [8]byte
+	copy(id[:], sum[:shortIDBytes])
+	return ShortID(binary.LittleEndian.Uint64(id[:]))
+}
+
+// CompactInv is an Inv in a CompactPoll. It holds either a ShortID or, when
+// Full is set, the full TargetHash.
+type CompactInv struct {
+	TargetType string
+	ShortID    ShortID
+	TargetHash Hash
+	Full       bool
+}
+
+// CompactPoll is a Poll with its Invs shortened to ShortIDs, for sending large
+// polls to nodes that already know most of the targets. Invs whose ShortIDs
+// collide within the poll are sent in full.
+type CompactPoll struct {
+	round   int64
+	salt    uint64
+	invs    []CompactInv
+	network Network
+}
+
+// NewCompactPoll shortens the Invs of poll using salt
+func NewCompactPoll(poll Poll, salt uint64) CompactPoll {
+	invs := poll.GetInvs()
+	ids := make([]ShortID, len(invs))
+	counts := make(map[ShortID]int, len(invs))
+	for i, inv := range invs {
+		ids[i] = NewShortID(salt, inv.TargetHash)
+		counts[ids[i]]++
+	}
+
+	compact := make([]CompactInv, len(invs))
+	for i, inv := range invs {
+		if counts[ids[i]] > 1 {
+			compact[i] = CompactInv{TargetType: inv.TargetType, TargetHash: inv.TargetHash, Full: true}
+			continue
+		}
+		compact[i] = CompactInv{TargetType: inv.TargetType, ShortID: ids[i]}
+	}
+	return CompactPoll{poll.GetRound(), salt, compact, poll.network}
+}
+
+// NewCompactPollFromInvs creates a CompactPoll from its parts, such as after
+// decoding it from the wire
+func NewCompactPollFromInvs(round int64, salt uint64, invs []CompactInv) CompactPoll {
+	return CompactPoll{round, salt, invs, MainNet}
+}
+
+// GetRound returns the round of the CompactPoll
+func (cp CompactPoll) GetRound() int64 {
+	return cp.round
+}
+
+// WithNetwork returns a copy of the CompactPoll for the given network
+func (cp CompactPoll) WithNetwork(n Network) CompactPoll {
+	cp.network = n
+	return cp
+}
+
+// GetNetwork returns the network the CompactPoll is for
+func (cp CompactPoll) GetNetwork() Network {
+	return cp.network.orMainNet()
+}
+
+// GetSalt returns the salt the ShortIDs were made with
+func (cp CompactPoll) GetSalt() uint64 {
+	return cp.salt
+}
+
+// GetInvs returns the CompactPoll's Invs
+func (cp CompactPoll) GetInvs() []CompactInv {
+	return cp.invs
+}
+
+// Fill returns a copy of the CompactPoll with the Invs at indexes replaced by
+// the full invs the querier sent for them
+func (cp CompactPoll) Fill(indexes []int, invs []Inv) CompactPoll {
+	filled := append([]CompactInv{}, cp.invs...)
+	for i, idx := range indexes {
+		if idx >= 0 && idx < len(filled) && i < len(invs) {
+			filled[idx] = CompactInv{TargetType: invs[i].TargetType, TargetHash: invs[i].TargetHash, Full: true}
+		}
+	}
+	return CompactPoll{cp.round, cp.salt, filled, cp.network}
+}
+
+// wireSize returns the encoded size of the CompactPoll: the poll overhead and
+// salt, a bitmap of which Invs are full, and the Invs themselves
+func (cp CompactPoll) wireSize() int {
+	size := pollWireOverhead + 8 + (len(cp.invs)+7)/8
+	for _, inv := range cp.invs {
+		if inv.Full {
+			size += invWireSize
+		} else {
+			size += 4 + shortIDBytes
+		}
+	}
+	return size
+}
+
+// ExpandPoll resolves the ShortIDs of a CompactPoll against the targets we are
+// voting on or have finalized. It returns the indexes of any Invs that could
+// not be resolved to exactly one target; the caller should ask the querier
+// for their full hashes, Fill them in, and expand again. The Poll is only
+// complete once no indexes are returned.
+func (p *Processor) ExpandPoll(cp CompactPoll) (Poll, []int) {
+	var known map[ShortID][]Hash
+	invs := make([]Inv, len(cp.invs))
+	missing := []int{}
+	for i, inv := range cp.invs {
+		if inv.Full {
+			invs[i] = Inv{inv.TargetType, inv.TargetHash}
+			continue
+		}
+
+		if known == nil {
+			known = p.shortIDs(cp.salt)
+		}
+
+		hashes := known[inv.ShortID]
+		if len(hashes) != 1 {
+			missing = append(missing, i)
+			continue
+		}
+		invs[i] = Inv{inv.TargetType, hashes[0]}
+	}
+	return NewPoll(cp.round, invs).WithNetwork(cp.network), missing
+}
+
+// shortIDs returns the hashes of every target we know indexed by ShortID
+func (p *Processor) shortIDs(salt uint64) map[ShortID][]Hash {
+	ids := make(map[ShortID][]Hash, len(p.voteRecords)+len(p.finalizations))
+	for h := range p.voteRecords {
+		id := NewShortID(salt, h)
+		ids[id] = append(ids[id], h)
+	}
+	for h := range p.cold {
+		id := NewShortID(salt, h)
+		ids[id] = append(ids[id], h)
+	}
+	for h := range p.finalizations {
+		if _, ok := p.voteRecords[h]; ok {
+			continue
+		}
+		id := NewShortID(salt, h)
+		ids[id] = append(ids[id], h)
+	}
+	return ids
+}
@@ -0,0 +1,78 @@
+package avalanche
+
+import "testing"
+
+func TestCompactPoll(t *testing.T) {
+	var (
+		querier   = NewProcessor(NewConnman())
+		responder = NewProcessor(NewConnman())
+		invs      = make([]Inv, 1000)
+	)
+	for i := range invs {
+		b := &Block{Hash(i), 0, true, true}
+		invs[i] = Inv{b.Type(), b.Hash()}
+		assertTrue(t, querier.AddTargetToReconcile(b))
+		if i%10 != 0 {
+			assertTrue(t, responder.AddTargetToReconcile(b))
+		}
+	}
+	poll := NewPoll(7, invs)
+
+	// Large polls shrink by about three quarters
+	cp := NewCompactPoll(poll, 42)
+	if size := cp.wireSize(); size*10 > pollWireSize(len(invs))*3 {
+		t.Fatal("Expected a compact poll under 30% of", pollWireSize(len(invs)), "bytes but got", size)
+	}
+
+	// The responder resolves the targets it knows and asks for the rest
+	expanded, missing := responder.ExpandPoll(cp)
+	assertTrue(t, expanded.GetRound() == 7)
+	if len(missing) != 100 {
+		t.Fatal("Expected 100 unresolved invs but got", len(missing))
+	}
+	full := make([]Inv, len(missing))
+	for i, idx := range missing {
+		full[i] = invs[idx]
+	}
+	expanded, missing = responder.ExpandPoll(cp.Fill(missing, full))
+	assertTrue(t, len(missing) == 0)
+	for i, inv := range expanded.GetInvs() {
+		assertTrue(t, inv == invs[i])
+	}
+}
+
+func TestCompactPollCollisions(t *testing.T) {
+	shortIDBytes = 1
+	defer func() { shortIDBytes = 6 }()
+
+	var (
+		p    = NewProcessor(NewConnman())
+		invs = make([]Inv, 64)
+	)
+	for i := 0; i < 256; i++ {
+		b := &Block{Hash(i), 0, true, true}
+		if i < len(invs) {
+			invs[i] = Inv{b.Type(), b.Hash()}
+		}
+		assertTrue(t, p.AddTargetToReconcile(b))
+	}
+
+	// Invs whose ShortIDs collide within the poll are sent in full
+	cp := NewCompactPoll(NewPoll(0, invs), 1)
+	full := 0
+	for i, inv := range cp.GetInvs() {
+		if inv.Full {
+			full++
+			assertTrue(t, inv.TargetHash == invs[i].TargetHash)
+		}
+	}
+	assertTrue(t, full > 0)
+
+	// Colliding with targets outside the poll leaves the Inv unresolved
+	// rather than guessing
+	_, missing := p.ExpandPoll(cp)
+	assertTrue(t, len(missing) > 0)
+	for _, idx := range missing {
+		assertFalse(t, cp.GetInvs()[idx].Full)
+	}
+}
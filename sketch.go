@@ -0,0 +1,182 @@
+package avalanche
+
+import "encoding/binary"
+
+const (
+	// sketchHashCount is the number of cells each hash is added to
+	sketchHashCount = 3
+
+	// sketchCellSize is the encoded size of a sketch cell
+	sketchCellSize = 4 + 8 + 8
+
+	// sketchCheckSeed separates the checksum of a key from its cell indexes
+	sketchCheckSeed = 0x9e3779b97f4a7c15
+
+	// sketchIndexSeed separates the cell indexes of each hash function
+	sketchIndexSeed = 0x632be59bd9b4e019
+)
+
+// TargetSketch is an invertible Bloom lookup table over target hashes. Two
+// peers each build a sketch of their targets and exchange them. Subtracting one
+// from the other leaves only the hashes that differ between the sets, which can
+// then be decoded. The size needed is proportional to the size of the
+// difference rather than the sets; see SketchSizeForDifference.
+type TargetSketch struct {
+	cells []sketchCell
+}
+
+type sketchCell struct {
+	count    int32
+	keySum   uint64
+	checkSum uint64
+}
+
+// NewTargetSketch creates an empty sketch with about the given number of cells
+func NewTargetSketch(size int) *TargetSketch {
+	// Round up so each hash function gets an equal share of the cells
+	if size < sketchHashCount {
+		size = sketchHashCount
+	}
+	size += (sketchHashCount - size%sketchHashCount) % sketchHashCount
+
+	return &TargetSketch{cells: make([]sketchCell, size)}
+}
+
+// SketchSizeForDifference returns a sketch size that can decode a difference of
+// the given number of hashes with high probability
+func SketchSizeForDifference(diff int) int {
+	return 2*diff + 2*sketchHashCount
+}
+
+// TargetSketch returns a sketch of size cells over the targets being
+// reconciled
+func (p *Processor) TargetSketch(size int) *TargetSketch {
+	s := NewTargetSketch(size)
+	for h := range p.voteRecords {
+		s.Insert(h)
+	}
+	return s
+}
+
+// Insert adds a hash to the sketch
+func (s *TargetSketch) Insert(h Hash) {
+	s.update(uint64(h), 1)
+}
+
+// Subtract returns a sketch of the hashes in s but not other, and in other but
+// not s. It returns false if the sketches are different sizes.
+func (s *TargetSketch) Subtract(other *TargetSketch) (*TargetSketch, bool) {
+	if len(s.cells) != len(other.cells) {
+		return nil, false
+	}
+
+	diff := &TargetSketch{cells: make([]sketchCell, len(s.cells))}
+	for i := range s.cells {
+		diff.cells[i] = sketchCell{
+			count:    s.cells[i].count - other.cells[i].count,
+			keySum:   s.cells[i].keySum ^ other.cells[i].keySum,
+			checkSum: s.cells[i].checkSum ^ other.cells[i].checkSum,
+		}
+	}
+	return diff, true
+}
+
+// Decode lists the hashes in a sketch made by Subtract. ours are the hashes
+// that were only in the sketch subtracted from, theirs only in the sketch that
+// was subtracted. It returns false if the difference was too large to decode
+// completely, in which case a larger sketch is needed.
+func (s *TargetSketch) Decode() (ours, theirs []Hash, ok bool) {
+	work := &TargetSketch{cells: append([]sketchCell{}, s.cells...)}
+
+	// Repeatedly peel off cells holding a single hash until none are left
+	for peeled := true; peeled; {
+		peeled = false
+		for i := range work.cells {
+			c := work.cells[i]
+			if (c.count != 1 && c.count != -1) || c.checkSum != sketchCheck(c.keySum) {
+				continue
+			}
+
+			if c.count == 1 {
+				ours = append(ours, Hash(c.keySum))
+			} else {
+				theirs = append(theirs, Hash(c.keySum))
+			}
+
+			work.update(c.keySum, -c.count)
+			peeled = true
+
+			// A well formed sketch can't hold more hashes than cells, so a
+			// malformed one from a peer can't keep us peeling forever
+			if len(ours)+len(theirs) > len(work.cells) {
+				return ours, theirs, false
+			}
+		}
+	}
+
+	for _, c := range work.cells {
+		if c != (sketchCell{}) {
+			return ours, theirs, false
+		}
+	}
+	return ours, theirs, true
+}
+
+// MarshalBinary encodes the sketch for sending to a peer
+func (s *TargetSketch) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, len(s.cells)*sketchCellSize)
+	for i, c := range s.cells {
+		b := buf[i*sketchCellSize:]
+		binary.LittleEndian.PutUint32(b, uint32(c.count))
+		binary.LittleEndian.PutUint64(b[4:], c.keySum)
+		binary.LittleEndian.PutUint64(b[12:], c.checkSum)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded with MarshalBinary
+func (s *TargetSketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || len(data)%(sketchCellSize*sketchHashCount) != 0 {
+		return ErrInvalidSketch
+	}
+
+	s.cells = make([]sketchCell, len(data)/sketchCellSize)
+	for i := range s.cells {
+		b := data[i*sketchCellSize:]
+		s.cells[i] = sketchCell{
+			count:    int32(binary.LittleEndian.Uint32(b)),
+			keySum:   binary.LittleEndian.Uint64(b[4:]),
+			checkSum: binary.LittleEndian.Uint64(b[12:]),
+		}
+	}
+	return nil
+}
+
+// update adds delta copies of key to the sketch. Each hash function has its own
+// partition of the cells so a key never lands in the same cell twice.
+func (s *TargetSketch) update(key uint64, delta int32) {
+	check := sketchCheck(key)
+	partition := uint64(len(s.cells) / sketchHashCount)
+
+	for i := uint64(0); i < sketchHashCount; i++ {
+		c := &s.cells[i*partition+mix64(key+(i+1)*sketchIndexSeed)%partition]
+		c.count += delta
+		c.keySum ^= key
+		c.checkSum ^= check
+	}
+}
+
+// sketchCheck returns the checksum used to tell cells holding a single key
+func sketchCheck(key uint64) uint64 {
+	return mix64(key ^ sketchCheckSeed)
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
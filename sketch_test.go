@@ -0,0 +1,65 @@
+package avalanche
+
+import "testing"
+
+func TestTargetSketch(t *testing.T) {
+	var (
+		ours   = NewProcessor(NewConnman())
+		theirs = NewProcessor(NewConnman())
+	)
+
+	// Share most targets, with a few unique to each side
+	for h := Hash(0); h < 1000; h++ {
+		ours.AddTargetToReconcile(&Block{h, 1, true, true})
+		theirs.AddTargetToReconcile(&Block{h, 1, true, true})
+	}
+	for h := Hash(1000); h < 1004; h++ {
+		ours.AddTargetToReconcile(&Block{h, 1, true, true})
+	}
+	for h := Hash(2000); h < 2003; h++ {
+		theirs.AddTargetToReconcile(&Block{h, 1, true, true})
+	}
+
+	size := SketchSizeForDifference(10)
+
+	// Send their sketch over the wire
+	encoded, err := theirs.TargetSketch(size).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirSketch := &TargetSketch{}
+	if err = theirSketch.UnmarshalBinary(encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, ok := ours.TargetSketch(size).Subtract(theirSketch)
+	assertTrue(t, ok)
+
+	onlyOurs, onlyTheirs, ok := diff.Decode()
+	assertTrue(t, ok)
+	assertHashes(t, onlyOurs, 1000, 1004)
+	assertHashes(t, onlyTheirs, 2000, 2003)
+
+	// A sketch too small for the difference fails to decode
+	diff, _ = ours.TargetSketch(3).Subtract(theirs.TargetSketch(3))
+	_, _, ok = diff.Decode()
+	assertFalse(t, ok)
+
+	// Sketches of different sizes can't be compared
+	_, ok = ours.TargetSketch(3).Subtract(theirs.TargetSketch(6))
+	assertFalse(t, ok)
+}
+
+// assertHashes checks hashes holds exactly [from, to) in any order
+func assertHashes(t *testing.T, hashes []Hash, from, to Hash) {
+	seen := map[Hash]bool{}
+	for _, h := range hashes {
+		if h < from || h >= to || seen[h] {
+			t.Fatal("Unexpected hash", h, "in", hashes)
+		}
+		seen[h] = true
+	}
+	if len(seen) != int(to-from) {
+		t.Fatal("Expected", to-from, "hashes but got", hashes)
+	}
+}
@@ -0,0 +1,210 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format written by Snapshot
+const snapshotVersion = 1
+
+// TargetResolver looks up the Target for a hash when restoring a snapshot. It
+// returns nil for targets that are no longer known.
+type TargetResolver func(Hash) Target
+
+// snapshot is the encoded state of a Processor
+type snapshot struct {
+	Version int            `json:"version"`
+	Params  snapshotParams `json:"params"`
+	Round   int64          `json:"round"`
+
+	Records       []snapshotRecord       `json:"records"`
+	Finalizations []snapshotFinalization `json:"finalizations"`
+}
+
+type snapshotParams struct {
+	VoteWindow        int `json:"vote_window"`
+	VoteQuorum        int `json:"vote_quorum"`
+	FinalizationScore int `json:"finalization_score"`
+
+	// Types are the parameters of target types with their own
+	Types map[string]snapshotParams `json:"types,omitempty"`
+}
+
+type snapshotRecord struct {
+	Hash       Hash     `json:"hash"`
+	Votes      uint8    `json:"votes"`
+	Consider   uint8    `json:"consider"`
+	Confidence uint16   `json:"confidence"`
+	Metadata   Metadata `json:"metadata,omitempty"`
+}
+
+type snapshotFinalization struct {
+	Hash   Hash   `json:"hash"`
+	Status Status `json:"status"`
+	Anchor Anchor `json:"anchor"`
+}
+
+// Snapshot writes the Processor's voting state to w: the vote records of
+// targets being reconciled, finalizations, and the current round. Targets
+// themselves are not included; Restore asks for them by hash.
+func (p *Processor) Snapshot(w io.Writer) error {
+	s := snapshot{
+		Version:       snapshotVersion,
+		Params:        p.config.snapshotParams(),
+		Round:         p.round,
+		Records:       make([]snapshotRecord, 0, len(p.voteRecords)),
+		Finalizations: make([]snapshotFinalization, 0, len(p.finalizations)),
+	}
+
+	for h, vr := range p.voteRecords {
+		s.Records = append(s.Records, snapshotRecord{h, vr.votes, vr.consider, vr.confidence, p.metadata[h]})
+	}
+	sort.Slice(s.Records, func(i, j int) bool { return s.Records[i].Hash < s.Records[j].Hash })
+
+	for h, f := range p.finalizations {
+		s.Finalizations = append(s.Finalizations, snapshotFinalization{h, f.status, f.anchor})
+	}
+	sort.Slice(s.Finalizations, func(i, j int) bool { return s.Finalizations[i].Hash < s.Finalizations[j].Hash })
+
+	return wrapError("write snapshot", json.NewEncoder(w).Encode(s))
+}
+
+// Restore loads a snapshot written by Snapshot, replacing the Processor's
+// voting state. The snapshot must have been taken with the same voting
+// parameters as the running config, or ErrSnapshotMismatch is returned and
+// nothing is changed. Records for targets that resolve returns nil for are
+// dropped. Everything kept about the replaced targets goes with them,
+// including records spilled to the ColdStore, which are deleted from it.
+func (p *Processor) Restore(r io.Reader, resolve TargetResolver) error {
+	s := snapshot{}
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return &Error{"read snapshot", err}
+	}
+
+	if s.Version != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	if !s.Params.equal(p.config.snapshotParams()) {
+		return ErrSnapshotMismatch
+	}
+
+	p.resetTargets()
+	for _, rec := range s.Records {
+		t := resolve(rec.Hash)
+		if t == nil {
+			continue
+		}
+
+		p.targets[rec.Hash] = t
+		p.voteRecords[rec.Hash] = &VoteRecord{votes: rec.Votes, consider: rec.Consider, confidence: rec.Confidence, params: p.typeVoteParams(t.Type())}
+		p.lastPolled[rec.Hash] = p.pollSeq
+		if len(rec.Metadata) > 0 {
+			p.metadata[rec.Hash] = rec.Metadata
+		}
+	}
+
+	p.finalizations = make(map[Hash]finalization, len(s.Finalizations))
+	p.recent = newFinalizationCache(p.config.finalizationCacheSize())
+	for _, f := range s.Finalizations {
+		p.finalizations[f.Hash] = finalization{f.Status, f.Anchor}
+	}
+
+	if s.Round > p.round {
+		p.round = s.Round
+	}
+	return nil
+}
+
+// SnapshotToFile writes a snapshot to path, replacing it only once the
+// snapshot is complete
+func (p *Processor) SnapshotToFile(path string) error {
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return &Error{"write snapshot", err}
+	}
+
+	err = p.Snapshot(f)
+	if err == nil {
+		err = wrapError("write snapshot", f.Sync())
+	}
+	if cerr := f.Close(); err == nil {
+		err = wrapError("write snapshot", cerr)
+	}
+	if err == nil {
+		err = wrapError("write snapshot", os.Rename(path+".tmp", path))
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+	}
+	return err
+}
+
+// RestoreFromFile restores a snapshot written by SnapshotToFile
+func (p *Processor) RestoreFromFile(path string, resolve TargetResolver) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return &Error{"read snapshot", err}
+	}
+	defer f.Close()
+
+	return p.Restore(f, resolve)
+}
+
+// snapshotParams returns the parameters a snapshot must match to be restored
+func (c Config) snapshotParams() snapshotParams {
+	sp := newSnapshotParams(c.voteParams())
+	for targetType := range c.Types {
+		if sp.Types == nil {
+			sp.Types = map[string]snapshotParams{}
+		}
+		sp.Types[targetType] = newSnapshotParams(c.typeVoteParams(targetType))
+	}
+	return sp
+}
+
+// newSnapshotParams returns the encoding of a VoteRecord's thresholds
+func newSnapshotParams(params voteParams) snapshotParams {
+	window := 0
+	for mask := params.mask; mask != 0; mask >>= 1 {
+		window++
+	}
+	return snapshotParams{window, int(params.quorum), int(params.finalizationScore), nil}
+}
+
+// equal returns whether both have the same parameters for every target type
+func (sp snapshotParams) equal(other snapshotParams) bool {
+	if sp.VoteWindow != other.VoteWindow || sp.VoteQuorum != other.VoteQuorum ||
+		sp.FinalizationScore != other.FinalizationScore || len(sp.Types) != len(other.Types) {
+		return false
+	}
+	for targetType, params := range sp.Types {
+		if o, ok := other.Types[targetType]; !ok || !params.equal(o) {
+			return false
+		}
+	}
+	return true
+}
+
+// resetTargets forgets every target being voted on, in memory or spilled
+func (p *Processor) resetTargets() {
+	for h := range p.cold {
+		p.coldStore.Delete(h)
+	}
+	p.cold = map[Hash]bool{}
+	p.coldOrder = nil
+
+	p.targets = map[Hash]Target{}
+	p.voteRecords = map[Hash]*VoteRecord{}
+	p.metadata = map[Hash]Metadata{}
+	p.lastPolled = map[Hash]int64{}
+	p.peerVotes = map[Hash]map[NodeID]*peerVote{}
+	p.invalidSince = map[Hash]time.Time{}
+	p.asked = map[Hash][]NodeID{}
+	p.reportedConflicts = map[Hash]map[Hash]struct{}{}
+	p.held = map[Hash]struct{}{}
+	p.missing = map[Hash]struct{}{}
+}
@@ -0,0 +1,108 @@
+package avalanche
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		a       = blockForHash(Hash(65))
+		b       = blockForHash(Hash(66))
+		updates = []StatusUpdate{}
+	)
+	assertTrue(t, p.AddTargetToReconcileWithMetadata(a, Metadata{"source": "test"}))
+	assertTrue(t, p.AddTargetToReconcile(b))
+
+	// Make some progress on a and finalize b
+	resp := NewResponse(0, 0, []Vote{NewVote(0, a.Hash())})
+	for i := 0; i < 10; i++ {
+		registerVotes(p, NodeID(0), resp, &updates)
+	}
+	resp = NewResponse(0, 0, []Vote{NewVote(1, b.Hash())})
+	for p.voteRecords[b.Hash()] != nil {
+		registerVotes(p, NodeID(0), resp, &updates)
+	}
+	p.round = 42
+
+	buf := &bytes.Buffer{}
+	assertTrue(t, p.Snapshot(buf) == nil)
+	snap := buf.Bytes()
+
+	restored := NewProcessor(NewConnman())
+	resolve := func(h Hash) Target {
+		if h == a.Hash() {
+			return a
+		}
+		return nil
+	}
+	assertTrue(t, restored.Restore(bytes.NewReader(snap), resolve) == nil)
+
+	assertTrue(t, restored.GetRound() == 42)
+	assertTrue(t, *restored.voteRecords[a.Hash()] == *p.voteRecords[a.Hash()])
+	assertTrue(t, restored.GetMetadata(a)["source"] == "test")
+	assertTrue(t, restored.finalizations[b.Hash()] == p.finalizations[b.Hash()])
+
+	// Snapshots taken with other voting parameters are refused
+	config := DefaultConfig
+	config.FinalizationScore = 64
+	other := NewProcessorWithConfig(NewConnman(), config)
+	assertTrue(t, other.Restore(bytes.NewReader(snap), resolve) == ErrSnapshotMismatch)
+	assertTrue(t, len(other.voteRecords) == 0)
+
+	// So are snapshots in other formats, and ones that can't be decoded
+	assertTrue(t, other.Restore(strings.NewReader(`{"version":99}`), resolve) == ErrSnapshotVersion)
+	_, ok := other.Restore(strings.NewReader("{"), resolve).(*Error)
+	assertTrue(t, ok)
+}
+
+// memColdStore is a ColdStore kept in a map
+type memColdStore map[Hash]ColdRecord
+
+func (s memColdStore) Put(rec ColdRecord) error { s[rec.Hash] = rec; return nil }
+
+func (s memColdStore) Get(h Hash) (ColdRecord, error) {
+	rec, ok := s[h]
+	if !ok {
+		return rec, os.ErrNotExist
+	}
+	return rec, nil
+}
+
+func (s memColdStore) Delete(h Hash) error { delete(s, h); return nil }
+
+func TestRestoreReplacesState(t *testing.T) {
+	var (
+		store  = memColdStore{}
+		blocks = map[Hash]Target{}
+		p      = NewProcessorWithConfig(NewConnman(), Config{MaxHotRecords: 1})
+	)
+	resolve := func(h Hash) Target { return blocks[h] }
+	p.SetColdStore(store, resolve)
+	for h := Hash(1); h <= 3; h++ {
+		blocks[h] = &Block{h, 1, true, true}
+		assertTrue(t, p.AddTargetToReconcile(blocks[h]))
+	}
+	p.eventLoop()
+	assertTrue(t, p.GetColdRecordCount() == 2 && len(store) == 2)
+	p.asked[Hash(3)] = []NodeID{NodeID(0)}
+	p.held[Hash(3)] = struct{}{}
+
+	src := NewProcessor(NewConnman())
+	blocks[Hash(4)] = &Block{Hash(4), 1, true, true}
+	assertTrue(t, src.AddTargetToReconcile(blocks[Hash(4)]))
+	buf := &bytes.Buffer{}
+	assertTrue(t, src.Snapshot(buf) == nil)
+
+	// Nothing about the replaced targets is left behind, spilled or not
+	assertTrue(t, p.Restore(buf, resolve) == nil)
+	assertTrue(t, p.GetColdRecordCount() == 0 && len(p.coldOrder) == 0 && len(store) == 0)
+	assertTrue(t, len(p.asked) == 0 && len(p.held) == 0)
+	for h := Hash(1); h <= 3; h++ {
+		assertTrue(t, p.getVote(h) == VoteUnknown)
+	}
+	assertTrue(t, len(p.voteRecords) == 1 && p.getVote(Hash(4)) == VoteYes)
+}
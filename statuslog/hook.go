@@ -0,0 +1,199 @@
+package statuslog
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"text/template"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// SignatureHeader is the header a webhook's HMAC-SHA256 signature is sent in,
+// as "sha256=" followed by the hex encoded signature of the body
+const SignatureHeader = "X-Avalanche-Signature"
+
+// WebhookConfig configures a webhook Sink
+type WebhookConfig struct {
+	// URL is where each Entry is POSTed
+	URL string
+
+	// Template renders the body from the Entry. The body is a JSON object with
+	// the hash, status, time and metadata if it is nil.
+	Template *template.Template
+
+	// ContentType is the body's content type, application/json if empty
+	ContentType string
+
+	// Secret signs the body with HMAC-SHA256 if it is not empty
+	Secret []byte
+
+	// Retries is how many more times a failed request is attempted. Requests
+	// fail on errors and non-2xx responses.
+	Retries int
+
+	// RetryBackoff is the wait before the first retry, doubling after each.
+	// One second is used if it is not positive.
+	RetryBackoff time.Duration
+
+	// Client sends the requests. http.DefaultClient is used if it is nil.
+	Client *http.Client
+}
+
+// webhookSink POSTs Entries to a URL
+type webhookSink struct {
+	config WebhookConfig
+}
+
+// NewWebhookSink returns a Sink that POSTs each Entry to a webhook. Log blocks
+// while retrying, so Forward falls behind a slow webhook rather than spawning
+// requests without bound.
+func NewWebhookSink(config WebhookConfig) Sink {
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return webhookSink{config}
+}
+
+// Log POSTs the Entry, retrying failures
+func (s webhookSink) Log(e Entry) error {
+	body, err := s.render(e)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil || attempt >= s.config.Retries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// render builds the request body for an Entry
+func (s webhookSink) render(e Entry) ([]byte, error) {
+	if s.config.Template == nil {
+		return json.Marshal(struct {
+			Hash        avalanche.Hash           `json:"hash"`
+			Status      string                   `json:"status"`
+			LikelyFinal bool                     `json:"likely_final,omitempty"`
+			Confidence  float64                  `json:"confidence"`
+			Reason      avalanche.WithdrawReason `json:"reason,omitempty"`
+			Degraded    bool                     `json:"degraded,omitempty"`
+			Time        time.Time                `json:"time"`
+			Metadata    avalanche.Metadata       `json:"metadata,omitempty"`
+		}{e.Hash, e.Status.String(), e.LikelyFinal, e.Confidence, e.Reason, e.Degraded, e.Time, e.Metadata})
+	}
+
+	buf := &bytes.Buffer{}
+	if err := s.config.Template.Execute(buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s webhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.config.ContentType)
+	if len(s.config.Secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.config.Secret, body))
+	}
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &WebhookStatusError{resp.StatusCode, resp.Status}
+	}
+	return nil
+}
+
+// WebhookStatusError is returned when a webhook responds with a status outside
+// the 2xx range, so callers can tell rejected deliveries from network errors
+type WebhookStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error implements error
+func (e *WebhookStatusError) Error() string {
+	return "statuslog: webhook returned " + e.Status
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body, as sent in SignatureHeader
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// execSink runs a command for each Entry
+type execSink struct {
+	name string
+	args []string
+}
+
+// NewExecSink returns a Sink that runs a command for each Entry. The Entry is
+// passed in the AVALANCHE_HASH, AVALANCHE_STATUS and AVALANCHE_TIME
+// environment variables. Log fails if the command exits unsuccessfully.
+func NewExecSink(name string, args ...string) Sink {
+	return execSink{name, args}
+}
+
+// Log runs the command and waits for it to exit
+func (s execSink) Log(e Entry) error {
+	cmd := exec.Command(s.name, s.args...)
+	cmd.Env = append(os.Environ(),
+		"AVALANCHE_HASH="+strconv.FormatInt(int64(e.Hash), 10),
+		"AVALANCHE_STATUS="+e.Status.String(),
+		"AVALANCHE_TIME="+e.Time.UTC().Format(time.RFC3339Nano),
+	)
+	return cmd.Run()
+}
+
+// statusFilterSink passes on Entries with certain statuses
+type statusFilterSink struct {
+	sink     Sink
+	statuses map[avalanche.Status]struct{}
+}
+
+// NewStatusFilterSink returns a Sink that only writes Entries with one of the
+// given statuses to sink, such as to fire hooks on finalization alone
+func NewStatusFilterSink(sink Sink, statuses ...avalanche.Status) Sink {
+	s := statusFilterSink{sink, make(map[avalanche.Status]struct{}, len(statuses))}
+	for _, status := range statuses {
+		s.statuses[status] = struct{}{}
+	}
+	return s
+}
+
+// Log writes the Entry if its status is wanted
+func (s statusFilterSink) Log(e Entry) error {
+	if _, ok := s.statuses[e.Status]; !ok {
+		return nil
+	}
+	return s.sink.Log(e)
+}
@@ -0,0 +1,83 @@
+package statuslog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"text/template"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		secret   = []byte("secret")
+		requests = 0
+		bodies   = make(chan string, 1)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != "sha256="+Sign(secret, body) {
+			t.Error("Incorrect signature:", r.Header.Get(SignatureHeader))
+		}
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(WebhookConfig{
+		URL:          server.URL,
+		Template:     template.Must(template.New("").Parse(`{{.Hash}} is {{.Status}} for {{index .Metadata "order"}}`)),
+		Secret:       secret,
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	})
+
+	e := Entry{Hash: 65, Status: avalanche.StatusFinalized, Metadata: avalanche.Metadata{"order": "1234"}}
+	if err := sink.Log(e); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-bodies; body != "65 is finalized for 1234" {
+		t.Fatal("Unexpected body:", body)
+	}
+
+	// Retries are given up on eventually
+	requests = 0
+	sink = NewWebhookSink(WebhookConfig{URL: server.URL, RetryBackoff: time.Millisecond})
+	err := sink.Log(e)
+	if statusErr, ok := err.(*WebhookStatusError); !ok || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("Expected the webhook to fail with its status but got", err)
+	}
+}
+
+func TestExecSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	sink := NewExecSink("sh", "-c", `test "$AVALANCHE_HASH" = 65 && test "$AVALANCHE_STATUS" = finalized`)
+	if err := sink.Log(Entry{Hash: 65, Status: avalanche.StatusFinalized}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Log(Entry{Hash: 66, Status: avalanche.StatusFinalized}); err == nil {
+		t.Fatal("Expected the command to fail")
+	}
+}
+
+func TestStatusFilterSink(t *testing.T) {
+	sink := NewStatusFilterSink(failingSink{}, avalanche.StatusFinalized)
+	if err := sink.Log(Entry{Status: avalanche.StatusAccepted}); err != nil {
+		t.Fatal("Expected accepted entries to be skipped")
+	}
+	if err := sink.Log(Entry{Status: avalanche.StatusFinalized}); err == nil {
+		t.Fatal("Expected finalized entries to be written")
+	}
+}
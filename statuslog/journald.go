@@ -0,0 +1,76 @@
+//go:build linux
+// +build linux
+
+package statuslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// JournaldSocket is the path of journald's native protocol socket
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// journaldSink writes Entries to journald using its native protocol so the
+// hash and status are stored as fields that can be matched on
+type journaldSink struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+// NewJournaldSink returns a Sink that writes to journald. Entries carry the
+// AVALANCHE_HASH, AVALANCHE_STATUS, and AVALANCHE_CONFIDENCE fields, plus
+// AVALANCHE_DEGRADED=1 while an eclipse is suspected, and the given identifier
+// as SYSLOG_IDENTIFIER.
+func NewJournaldSink(identifier string) (Sink, error) {
+	return newJournaldSink(JournaldSocket, identifier)
+}
+
+func newJournaldSink(path string, identifier string) (Sink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return journaldSink{conn, identifier}, nil
+}
+
+// Log writes the Entry as a single journal record
+func (s journaldSink) Log(e Entry) error {
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", e.Message())
+	writeJournalField(buf, "PRIORITY", strconv.Itoa(int(e.Severity())))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", s.identifier)
+	writeJournalField(buf, "AVALANCHE_HASH", strconv.FormatInt(int64(e.Hash), 10))
+	writeJournalField(buf, "AVALANCHE_STATUS", e.Status.String())
+	writeJournalField(buf, "AVALANCHE_CONFIDENCE", strconv.FormatFloat(e.Confidence, 'g', -1, 64))
+	if e.Degraded {
+		writeJournalField(buf, "AVALANCHE_DEGRADED", "1")
+	}
+
+	_, err := s.conn.Write(buf.Bytes())
+	return err
+}
+
+// writeJournalField writes a KEY=value line. Values containing new lines, such
+// as a withdrawal Reason or the identifier, use the protocol's binary form of
+// the key, a little-endian 64-bit length, and the value so they can't end the
+// field early and inject others.
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if strings.IndexByte(value, '\n') == -1 {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.WriteByte('\n')
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
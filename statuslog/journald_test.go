@@ -0,0 +1,75 @@
+//go:build linux
+// +build linux
+
+package statuslog
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+func TestJournaldSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statuslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Stand in for journald's socket
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := newJournaldSink(path, "avalanche")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Log(Entry{Hash: 65, Status: avalanche.StatusFinalized, Confidence: 1, Degraded: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := string(buf[:n])
+	for _, field := range []string{
+		"MESSAGE=target 65 is finalized\n",
+		"PRIORITY=5\n",
+		"SYSLOG_IDENTIFIER=avalanche\n",
+		"AVALANCHE_HASH=65\n",
+		"AVALANCHE_STATUS=finalized\n",
+		"AVALANCHE_CONFIDENCE=1\n",
+		"AVALANCHE_DEGRADED=1\n",
+	} {
+		if !strings.Contains(record, field) {
+			t.Fatalf("Record %q is missing field %q", record, field)
+		}
+	}
+
+	// Values with new lines use the binary form so they can't inject fields
+	reason := avalanche.WithdrawReason("evicted\nPRIORITY=0")
+	if err = sink.Log(Entry{Hash: 65, Status: avalanche.StatusWithdrawn, Reason: reason}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err = conn.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	message := "target 65 is withdrawn (" + string(reason) + ")"
+	expected := "MESSAGE\n" + string([]byte{byte(len(message)), 0, 0, 0, 0, 0, 0, 0}) + message + "\nPRIORITY=6\n"
+	if record := string(buf[:n]); !strings.HasPrefix(record, expected) {
+		t.Fatalf("Expected record %q to start with %q", record, expected)
+	}
+}
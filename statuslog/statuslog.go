@@ -0,0 +1,166 @@
+// Package statuslog routes StatusUpdates from an avalanche Processor to log
+// sinks such as syslog or journald, with the hash and status as structured
+// fields. Sinks can also fire webhooks or run commands, for example to act on
+// finalizations.
+package statuslog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+// Severity is how important an Entry is, using syslog's levels
+type Severity int
+
+const (
+	// SeverityWarning is used for targets finalized as invalid
+	SeverityWarning Severity = 4
+
+	// SeverityNotice is used for finalizations and rejections
+	SeverityNotice Severity = 5
+
+	// SeverityInfo is used for everything else
+	SeverityInfo Severity = 6
+)
+
+// Entry is a single status transition to be logged
+type Entry struct {
+	Time     time.Time
+	Hash     avalanche.Hash
+	Status   avalanche.Status
+	Metadata avalanche.Metadata
+
+	// LikelyFinal is set for the early signal that Status is likely to be
+	// finalized; see avalanche.StatusUpdate
+	LikelyFinal bool
+
+	// Confidence is how far Status is towards finalization, from 0 to 1
+	Confidence float64
+
+	// Reason is why the target was withdrawn, for avalanche.StatusWithdrawn
+	Reason avalanche.WithdrawReason
+
+	// Degraded is set while the Processor suspects an eclipse attack, so
+	// Status may not reflect the network's view; see avalanche.StatusUpdate
+	Degraded bool
+}
+
+// Severity returns the severity the Entry should be logged at
+func (e Entry) Severity() Severity {
+	if e.LikelyFinal {
+		return SeverityInfo
+	}
+
+	switch e.Status {
+	case avalanche.StatusInvalid:
+		return SeverityWarning
+	case avalanche.StatusFinalized, avalanche.StatusRejected:
+		return SeverityNotice
+	}
+	return SeverityInfo
+}
+
+// Message returns a human readable description of the Entry
+func (e Entry) Message() string {
+	if e.LikelyFinal {
+		return fmt.Sprintf("target %d is likely to finalize as %s", e.Hash, e.Status)
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("target %d is %s (%s)", e.Hash, e.Status, e.Reason)
+	}
+	return fmt.Sprintf("target %d is %s", e.Hash, e.Status)
+}
+
+// Sink writes Entries somewhere
+type Sink interface {
+	Log(Entry) error
+}
+
+// Forward logs each update received to sink until ctx is done or updates is
+// closed. Errors from the sink are passed to onError if it is not nil.
+func Forward(ctx context.Context, updates <-chan avalanche.StatusUpdate, sink Sink, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			err := sink.Log(Entry{
+				Time:        time.Now(),
+				Hash:        update.Hash,
+				Status:      update.Status,
+				Metadata:    update.Metadata,
+				LikelyFinal: update.LikelyFinal,
+				Confidence:  update.Confidence,
+				Reason:      update.Reason,
+				Degraded:    update.Degraded,
+			})
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// writerSink writes Entries as lines of key=value pairs
+type writerSink struct {
+	w io.Writer
+}
+
+// NewWriterSink returns a Sink writing each Entry to w as a line of key=value
+// pairs
+func NewWriterSink(w io.Writer) Sink {
+	return writerSink{w}
+}
+
+// Log writes the Entry as a line. Metadata follows the fixed keys, sorted by
+// key.
+func (s writerSink) Log(e Entry) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "time=%s hash=%d status=%s confidence=%g msg=%q",
+		e.Time.UTC().Format(time.RFC3339Nano), e.Hash, e.Status, e.Confidence, e.Message())
+	if e.Degraded {
+		buf.WriteString(" degraded=true")
+	}
+
+	keys := make([]string, 0, len(e.Metadata))
+	for k := range e.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(buf, " %s=%q", k, e.Metadata[k])
+	}
+	buf.WriteByte('\n')
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// multiSink writes each Entry to several Sinks
+type multiSink []Sink
+
+// NewMultiSink returns a Sink that writes each Entry to all of sinks. Every
+// sink is written to even if an earlier one fails; the first error is returned.
+func NewMultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+// Log writes the Entry to each Sink
+func (sinks multiSink) Log(e Entry) (err error) {
+	for _, s := range sinks {
+		if sErr := s.Log(e); sErr != nil && err == nil {
+			err = sErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package statuslog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+type failingSink struct{}
+
+func (failingSink) Log(Entry) error { return errors.New("sink failed") }
+
+func TestForward(t *testing.T) {
+	var (
+		buf     = &bytes.Buffer{}
+		updates = make(chan avalanche.StatusUpdate, 2)
+		errs    = []error{}
+	)
+	updates <- avalanche.StatusUpdate{Hash: 65, Status: avalanche.StatusFinalized, Confidence: 1}
+	updates <- avalanche.StatusUpdate{Hash: 66, Status: avalanche.StatusInvalid, Confidence: 0.25, Degraded: true,
+		Metadata: avalanche.Metadata{"source": "rpc", "client": "a b"}}
+	close(updates)
+
+	sink := NewMultiSink(NewWriterSink(buf), failingSink{})
+	Forward(context.Background(), updates, sink, func(err error) { errs = append(errs, err) })
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("Expected 2 lines but got", len(lines))
+	}
+	if !strings.Contains(lines[0], "hash=65 status=finalized confidence=1 ") || strings.Contains(lines[0], "degraded") {
+		t.Fatal("Unexpected line:", lines[0])
+	}
+	if !strings.Contains(lines[1], "hash=66 status=invalid confidence=0.25 ") || !strings.Contains(lines[1], " degraded=true") || !strings.HasSuffix(lines[1], ` client="a b" source="rpc"`) {
+		t.Fatal("Unexpected line:", lines[1])
+	}
+
+	// Every sink is written to and each failure is reported
+	if len(errs) != 2 {
+		t.Fatal("Expected 2 errors but got", len(errs))
+	}
+}
+
+func TestEntrySeverity(t *testing.T) {
+	expected := map[avalanche.Status]Severity{
+		avalanche.StatusInvalid:   SeverityWarning,
+		avalanche.StatusRejected:  SeverityNotice,
+		avalanche.StatusAccepted:  SeverityInfo,
+		avalanche.StatusFinalized: SeverityNotice,
+	}
+	for status, severity := range expected {
+		if s := (Entry{Status: status}).Severity(); s != severity {
+			t.Fatal("Incorrect severity for", status, "got", s, "but wanted:", severity)
+		}
+	}
+
+	// Early signals are informational whatever the status
+	e := Entry{Hash: 65, Status: avalanche.StatusRejected, LikelyFinal: true}
+	if e.Severity() != SeverityInfo || e.Message() != "target 65 is likely to finalize as rejected" {
+		t.Fatal("Unexpected likely final entry:", e.Severity(), e.Message())
+	}
+
+	// Withdrawals say why
+	e = Entry{Hash: 65, Status: avalanche.StatusWithdrawn, Reason: avalanche.WithdrawEvicted}
+	if e.Severity() != SeverityInfo || e.Message() != "target 65 is withdrawn (evicted)" {
+		t.Fatal("Unexpected withdrawn entry:", e.Severity(), e.Message())
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package statuslog
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// syslogSink writes Entries to the system log
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink returns a Sink that writes to the local syslog daemon with the
+// given tag. The structured fields are included in the message as key=value
+// pairs.
+func NewSyslogSink(tag string) (Sink, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return syslogSink{w}, nil
+}
+
+// Log writes the Entry at its severity
+func (s syslogSink) Log(e Entry) error {
+	msg := fmt.Sprintf("%s hash=%d status=%s", e.Message(), e.Hash, e.Status)
+
+	switch e.Severity() {
+	case SeverityWarning:
+		return s.w.Warning(msg)
+	case SeverityNotice:
+		return s.w.Notice(msg)
+	}
+	return s.w.Info(msg)
+}
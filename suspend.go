@@ -0,0 +1,67 @@
+package avalanche
+
+import (
+	"sort"
+	"strconv"
+)
+
+// IsSuspended returns whether finalization is suspended because fewer nodes
+// are being polled than the vote quorum; see Config.SuspendBelowQuorum
+func (p *Processor) IsSuspended() bool {
+	return p.suspended
+}
+
+// GetSuspendedTargets returns the targets held back from finalizing during the
+// current suspension
+func (p *Processor) GetSuspendedTargets() []Hash {
+	hashes := make([]Hash, 0, len(p.held))
+	for h := range p.held {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// updateSuspension suspends finalization while fewer nodes are being polled
+// than the vote quorum and lifts it once enough have returned. Losing the
+// quorum is reported once per suspension as ErrQuorumLost.
+func (p *Processor) updateSuspension() {
+	if !p.config.SuspendBelowQuorum {
+		return
+	}
+
+	peers, quorum := len(p.getPollPeers()), p.GetVoteQuorum()
+	suspended := peers < quorum
+	switch {
+	case suspended && !p.suspended:
+		reportError(p.reporter, &Error{"check quorum", ErrQuorumLost}, map[string]string{
+			"peers":  strconv.Itoa(peers),
+			"quorum": strconv.Itoa(quorum),
+		})
+	case !suspended && p.suspended:
+		p.held = map[Hash]struct{}{}
+	}
+	p.suspended = suspended
+}
+
+// holdFinalization takes back the vote that finalized a target while
+// suspended, so it finalizes on the first conclusive round after the quorum
+// returns instead. A StatusUpdate with StatusSuspended is sent the first time
+// each target is held.
+func (p *Processor) holdFinalization(h Hash, vr *VoteRecord, updates *[]StatusUpdate) {
+	vr.confidence -= 2
+	if _, ok := p.held[h]; ok {
+		return
+	}
+	p.held[h] = struct{}{}
+
+	update := StatusUpdate{
+		Hash:       h,
+		Status:     StatusSuspended,
+		Metadata:   p.metadata[h],
+		Confidence: vr.confidenceFraction(),
+		Degraded:   p.IsDegraded(),
+	}
+	*updates = append(*updates, update)
+	p.publish(update)
+}
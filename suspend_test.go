@@ -0,0 +1,59 @@
+package avalanche
+
+import "testing"
+
+func TestSuspendBelowQuorum(t *testing.T) {
+	var (
+		connman  = NewConnman()
+		p        = NewProcessorWithConfig(connman, Config{VoteQuorum: 3, FinalizationScore: 4, SuspendBelowQuorum: true})
+		block    = &Block{Hash(1), 0, true, true}
+		reported = []error{}
+	)
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, tags map[string]string) {
+		reported = append(reported, err)
+	}))
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcile(block))
+
+	// With fewer polled nodes than the quorum, finalization is suspended
+	p.eventLoop()
+	assertTrue(t, p.IsSuspended())
+	if len(reported) != 1 {
+		t.Fatal("Expected the lost quorum to be reported once but got", reported)
+	}
+	if e, ok := reported[0].(*Error); !ok || e.Err != ErrQuorumLost {
+		t.Fatal("Expected ErrQuorumLost but got", reported[0])
+	}
+
+	// Votes that would finalize the target mark it suspended instead, once
+	suspended := 0
+	for i := 0; i < 20; i++ {
+		updates := []StatusUpdate{}
+		registerVotes(p, NodeID(0), NewResponse(0, 0, []Vote{NewVote(VoteYes, block.Hash())}), &updates)
+		for _, u := range updates {
+			if u.Status == StatusFinalized {
+				t.Fatal("Expected no finalization while suspended")
+			}
+			if u.Status == StatusSuspended {
+				suspended++
+			}
+		}
+	}
+	assertTrue(t, suspended == 1)
+	assertTrue(t, p.IsAccepted(block))
+	held := p.GetSuspendedTargets()
+	assertTrue(t, len(held) == 1 && held[0] == block.Hash())
+
+	// Once enough nodes return the next conclusive round finalizes it
+	connman.AddNode(NodeID(1))
+	connman.AddNode(NodeID(2))
+	p.eventLoop()
+	assertFalse(t, p.IsSuspended())
+	assertTrue(t, len(p.GetSuspendedTargets()) == 0 && len(reported) == 1)
+
+	updates := []StatusUpdate{}
+	registerVotes(p, NodeID(1), NewResponse(1, 0, []Vote{NewVote(VoteYes, block.Hash())}), &updates)
+	if len(updates) != 1 || updates[0].Status != StatusFinalized {
+		t.Fatal("Expected the target to finalize but got", updates)
+	}
+}
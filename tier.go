@@ -0,0 +1,190 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ColdRecord is a vote record spilled out of memory, with what is needed to
+// load it back. Age is how many polls had passed since the target was last
+// polled, so it keeps its place in line when it returns.
+type ColdRecord struct {
+	Hash       Hash     `json:"hash"`
+	Type       string   `json:"type"`
+	Accepted   bool     `json:"accepted"`
+	Votes      uint8    `json:"votes"`
+	Consider   uint8    `json:"consider"`
+	Confidence uint16   `json:"confidence"`
+	Age        int64    `json:"age"`
+	Metadata   Metadata `json:"metadata,omitempty"`
+}
+
+// ColdStore holds the vote records spilled out of memory when there are more
+// than Config.MaxHotRecords; see SetColdStore
+type ColdStore interface {
+	Put(ColdRecord) error
+	Get(Hash) (ColdRecord, error)
+	Delete(Hash) error
+}
+
+// SetColdStore sets where vote records are spilled when more than
+// Config.MaxHotRecords targets are being voted on, so a huge backlog doesn't
+// have to fit in memory. The records polled least urgently are spilled each
+// tick, and loaded back as room frees up, oldest first. Their targets are
+// looked up again with resolve, as when restoring a snapshot.
+//
+// Spilled targets are still known: we vote on them from the accepted bit kept
+// in memory, and methods taking one of them, such as GetConfidence and
+// RemoveTarget, load it back first. Snapshots only cover records in memory.
+func (p *Processor) SetColdStore(store ColdStore, resolve TargetResolver) {
+	p.coldStore = store
+	p.resolve = resolve
+}
+
+// GetColdRecordCount returns how many vote records are spilled to the
+// ColdStore
+func (p *Processor) GetColdRecordCount() int {
+	return len(p.cold)
+}
+
+// tierRecords spills the least urgent vote records while there are more than
+// Config.MaxHotRecords in memory, or loads spilled ones back while there is
+// room
+func (p *Processor) tierRecords() {
+	max := p.config.MaxHotRecords
+	if p.coldStore == nil || max <= 0 {
+		return
+	}
+
+	for len(p.voteRecords) < max && len(p.coldOrder) > 0 {
+		// Records loaded back some other way are skipped, and a failed load
+		// is retried on the next tick
+		h := p.coldOrder[0]
+		if _, ok := p.cold[h]; ok && !p.warmRecord(h) {
+			if _, ok := p.cold[h]; ok {
+				return
+			}
+		}
+		p.coldOrder = p.coldOrder[1:]
+	}
+	if len(p.voteRecords) <= max {
+		return
+	}
+
+	// Records with outstanding queries stay, so their votes can be counted
+	candidates := []pollCandidate{}
+	for h, vr := range p.voteRecords {
+		if vr.hasFinalized() || len(p.asked[h]) > 0 {
+			continue
+		}
+		if _, ok := p.held[h]; ok {
+			continue
+		}
+		candidates = append(candidates, p.newPollCandidate(h, p.targets[h]))
+	}
+	sort.Sort(sort.Reverse(byPollPriority(candidates)))
+
+	for _, c := range candidates {
+		if len(p.voteRecords) <= max || !p.spillRecord(c.hash) {
+			return
+		}
+	}
+}
+
+// spillRecord moves a vote record to the ColdStore, returning false if it
+// could not be stored
+func (p *Processor) spillRecord(h Hash) bool {
+	vr, t := p.voteRecords[h], p.targets[h]
+	rec := ColdRecord{h, t.Type(), vr.isAccepted(), vr.votes, vr.consider, vr.confidence, p.pollSeq - p.lastPolled[h], p.metadata[h]}
+	if err := p.coldStore.Put(rec); err != nil {
+		reportError(p.reporter, &Error{"spill record", err}, map[string]string{"target": h.DisplayHex()})
+		return false
+	}
+
+	delete(p.voteRecords, h)
+	delete(p.targets, h)
+	delete(p.metadata, h)
+	delete(p.lastPolled, h)
+	p.cold[h] = rec.Accepted
+	p.coldOrder = append(p.coldOrder, h)
+	return true
+}
+
+// warmRecord loads a spilled vote record back into memory. It returns false
+// if the record could not be loaded; if its target no longer resolves, the
+// record is dropped.
+func (p *Processor) warmRecord(h Hash) bool {
+	if _, ok := p.cold[h]; !ok {
+		return false
+	}
+
+	rec, err := p.coldStore.Get(h)
+	if err != nil {
+		reportError(p.reporter, &Error{"load record", err}, map[string]string{"target": h.DisplayHex()})
+		return false
+	}
+	delete(p.cold, h)
+	p.coldStore.Delete(h)
+
+	t := p.resolve(h)
+	if t == nil {
+		return false
+	}
+	p.targets[h] = t
+	p.voteRecords[h] = &VoteRecord{votes: rec.Votes, consider: rec.Consider, confidence: rec.Confidence, params: p.typeVoteParams(t.Type())}
+	p.lastPolled[h] = p.pollSeq - rec.Age
+	if len(rec.Metadata) > 0 {
+		p.metadata[h] = rec.Metadata
+	}
+	return true
+}
+
+// FileColdStore is a ColdStore keeping each record in its own JSON file in a
+// directory
+type FileColdStore struct {
+	dir string
+}
+
+// OpenFileColdStore opens the store in dir, creating it if needed
+func OpenFileColdStore(dir string) (*FileColdStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, &Error{"open cold store", err}
+	}
+	return &FileColdStore{dir}, nil
+}
+
+// Put writes the record, replacing it only once it is complete
+func (s *FileColdStore) Put(rec ColdRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return &Error{"put record", err}
+	}
+
+	path := s.path(rec.Hash)
+	if err = ioutil.WriteFile(path+".tmp", data, 0600); err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	return wrapError("put record", err)
+}
+
+// Get reads a record
+func (s *FileColdStore) Get(h Hash) (ColdRecord, error) {
+	rec := ColdRecord{}
+	data, err := ioutil.ReadFile(s.path(h))
+	if err == nil {
+		err = json.Unmarshal(data, &rec)
+	}
+	return rec, wrapError("get record", err)
+}
+
+// Delete removes a record
+func (s *FileColdStore) Delete(h Hash) error {
+	return wrapError("delete record", os.Remove(s.path(h)))
+}
+
+func (s *FileColdStore) path(h Hash) string {
+	return filepath.Join(s.dir, h.DisplayHex()+".json")
+}
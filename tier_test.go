@@ -0,0 +1,62 @@
+package avalanche
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColdStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coldstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenFileColdStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := map[Hash]*Block{}
+	p := NewProcessorWithConfig(NewConnman(), Config{MaxHotRecords: 2})
+	p.SetColdStore(store, func(h Hash) Target {
+		if b, ok := blocks[h]; ok {
+			return b
+		}
+		return nil
+	})
+	for i := 1; i <= 5; i++ {
+		blocks[Hash(i)] = &Block{Hash(i), int64(i), true, i%2 == 1}
+		assertTrue(t, p.AddTargetToReconcileWithMetadata(blocks[Hash(i)], Metadata{"n": string(rune('0' + i))}))
+	}
+
+	// The records polled least urgently are spilled
+	p.eventLoop()
+	assertTrue(t, len(p.voteRecords) == 2 && p.GetColdRecordCount() == 3)
+	_, ok := p.voteRecords[Hash(5)]
+	assertTrue(t, ok)
+	_, ok = p.voteRecords[Hash(4)]
+	assertTrue(t, ok)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assertTrue(t, len(files) == 3)
+
+	// Spilled targets are still known
+	assertTrue(t, p.getVote(Hash(1)) == VoteYes && p.getVote(Hash(2)) == VoteNo)
+	assertFalse(t, p.AddTargetToReconcile(blocks[Hash(1)]))
+
+	// and load back when asked about
+	assertTrue(t, p.GetMetadata(blocks[Hash(3)])["n"] == "3")
+	assertTrue(t, p.GetColdRecordCount() == 2 && len(p.voteRecords) == 3)
+	assertTrue(t, p.RemoveTarget(Hash(2), WithdrawEvicted))
+	assertTrue(t, p.GetColdRecordCount() == 1)
+
+	// Records return as room frees up, unless their target is gone
+	delete(blocks, Hash(1))
+	assertTrue(t, p.RemoveTarget(Hash(3), WithdrawEvicted))
+	assertTrue(t, p.RemoveTarget(Hash(4), WithdrawEvicted))
+	p.eventLoop()
+	assertTrue(t, p.GetColdRecordCount() == 0 && len(p.voteRecords) == 1)
+	files, _ = filepath.Glob(filepath.Join(dir, "*.json"))
+	assertTrue(t, len(files) == 0)
+}
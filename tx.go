@@ -0,0 +1,92 @@
+package avalanche
+
+// TxInfo is what a node knows about a transaction
+type TxInfo struct {
+	// Fee is the transaction's fee in satoshis
+	Fee int64
+
+	// Size is the transaction's serialized size in bytes
+	Size int64
+
+	// Valid is whether the transaction can still be mined, e.g. it hasn't been
+	// double spent
+	Valid bool
+
+	// InMempool is whether the node accepted the transaction into its mempool
+	InMempool bool
+}
+
+// TargetProvider looks up targets on the node that owns them
+type TargetProvider interface {
+	TxInfo(Hash) (TxInfo, error)
+}
+
+// Tx is a transaction Target whose Score is its fee rate, so invs for the
+// transactions miners most want are polled first
+type Tx struct {
+	hash     Hash
+	provider TargetProvider
+	info     TxInfo
+}
+
+// NewTx creates a new *Tx with its info fetched from provider
+func NewTx(h Hash, provider TargetProvider) (*Tx, error) {
+	tx := &Tx{hash: h, provider: provider}
+	if err := tx.Refresh(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Refresh fetches the transaction's info again, such as after a block that
+// could have double spent it
+func (tx *Tx) Refresh() error {
+	info, err := tx.provider.TxInfo(tx.hash)
+	if err != nil {
+		return &Error{"fetch tx", err}
+	}
+	tx.info = info
+	return nil
+}
+
+// Hash returns the Tx's id
+func (tx *Tx) Hash() Hash {
+	return tx.hash
+}
+
+// Type returns the Target type; in this case a tx
+func (tx *Tx) Type() string {
+	return "tx"
+}
+
+// Score returns the fee rate in thousandths of a satoshi per byte, so that
+// fractional fee rates still order transactions
+func (tx *Tx) Score() int64 {
+	if tx.info.Size <= 0 {
+		return 0
+	}
+	return tx.info.Fee * 1000 / tx.info.Size
+}
+
+// IsAccepted returns whether the node accepted the Tx into its mempool
+func (tx *Tx) IsAccepted() bool {
+	return tx.info.InMempool
+}
+
+// IsValid returns whether the Tx was valid when last fetched
+func (tx *Tx) IsValid() bool {
+	return tx.info.Valid
+}
+
+// GetFeeRate returns the fee rate in satoshis per byte
+func (tx *Tx) GetFeeRate() float64 {
+	if tx.info.Size <= 0 {
+		return 0
+	}
+	return float64(tx.info.Fee) / float64(tx.info.Size)
+}
+
+// GetSize returns the serialized size in bytes
+func (tx *Tx) GetSize() int64 {
+	return tx.info.Size
+}
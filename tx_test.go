@@ -0,0 +1,63 @@
+package avalanche
+
+import (
+	"errors"
+	"testing"
+)
+
+type stubTargetProvider map[Hash]TxInfo
+
+func (p stubTargetProvider) TxInfo(h Hash) (TxInfo, error) {
+	info, ok := p[h]
+	if !ok {
+		return TxInfo{}, errors.New("tx not found")
+	}
+	return info, nil
+}
+
+func TestTx(t *testing.T) {
+	provider := stubTargetProvider{
+		Hash(1): {Fee: 226, Size: 226, Valid: true, InMempool: true},
+		Hash(2): {Fee: 5000, Size: 250, Valid: true, InMempool: true},
+		Hash(3): {Fee: 300, Size: 200, Valid: true, InMempool: false},
+	}
+
+	p := NewProcessor(NewConnman())
+	txs := []*Tx{}
+	for h := Hash(1); h <= 3; h++ {
+		tx, err := NewTx(h, provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+		assertTrue(t, p.AddTargetToReconcile(tx))
+	}
+
+	assertTrue(t, txs[1].GetFeeRate() == 20 && txs[1].GetSize() == 250)
+	assertTrue(t, txs[0].Score() == 1000 && txs[2].Score() == 1500)
+	assertTrue(t, p.IsAccepted(txs[0]))
+	assertFalse(t, p.IsAccepted(txs[2]))
+
+	// Invs are polled in order of fee rate
+	invs := p.GetInvsForNextPoll()
+	expected := []Hash{Hash(2), Hash(3), Hash(1)}
+	if len(invs) != len(expected) {
+		t.Fatal("Expected", len(expected), "invs but got", invs)
+	}
+	for i, h := range expected {
+		if invs[i].TargetHash != h || invs[i].TargetType != "tx" {
+			t.Fatal("Expected", h, "at", i, "but got", invs)
+		}
+	}
+
+	// Refreshing picks up a double spend
+	provider[Hash(1)] = TxInfo{Fee: 226, Size: 226}
+	assertTrue(t, txs[0].Refresh() == nil)
+	assertFalse(t, txs[0].IsValid())
+
+	// Lookup errors are wrapped
+	_, err := NewTx(Hash(4), provider)
+	if e, ok := err.(*Error); !ok || e.Op != "fetch tx" {
+		t.Fatal("Expected a fetch error but got", err)
+	}
+}
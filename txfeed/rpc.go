@@ -0,0 +1,112 @@
+package txfeed
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+// DefaultPollInterval is how often an RPCSource checks the mempool if its
+// interval is not positive
+const DefaultPollInterval = time.Second
+
+// RPCSource is a Source polling getrawmempool on a node with bitcoind's
+// JSON-RPC interface, such as bitcoind or BCHD. Every transaction in the
+// mempool is announced on subscribing, then each new one as it appears. A
+// failed call counts as a disconnect.
+type RPCSource struct {
+	client   *bitcoinrpc.Client
+	interval time.Duration
+
+	mu     sync.Mutex
+	quitCh chan struct{}
+}
+
+// NewRPCSource creates a new *RPCSource checking the mempool every interval
+func NewRPCSource(client *bitcoinrpc.Client, interval time.Duration) *RPCSource {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &RPCSource{client: client, interval: interval}
+}
+
+// Subscribe implements Source
+func (s *RPCSource) Subscribe() (<-chan avalanche.Hash, error) {
+	txids, err := s.mempool()
+	if err != nil {
+		return nil, err
+	}
+
+	quitCh := make(chan struct{})
+	s.mu.Lock()
+	if s.quitCh != nil {
+		close(s.quitCh)
+	}
+	s.quitCh = quitCh
+	s.mu.Unlock()
+
+	hashes := make(chan avalanche.Hash)
+	go func() {
+		defer close(hashes)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		seen := map[string]struct{}{}
+		for {
+			next := make(map[string]struct{}, len(txids))
+			for _, txid := range txids {
+				next[txid] = struct{}{}
+				if _, ok := seen[txid]; ok {
+					continue
+				}
+				h, err := avalanche.HashFromDisplayHex(txid)
+				if err != nil {
+					continue
+				}
+				select {
+				case <-quitCh:
+					return
+				case hashes <- h:
+				}
+			}
+			seen = next
+
+			select {
+			case <-quitCh:
+				return
+			case <-ticker.C:
+			}
+			if txids, err = s.mempool(); err != nil {
+				return
+			}
+		}
+	}()
+
+	return hashes, nil
+}
+
+// Close implements Source
+func (s *RPCSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.quitCh != nil {
+		close(s.quitCh)
+		s.quitCh = nil
+	}
+	return nil
+}
+
+// mempool returns the txids in the node's mempool
+func (s *RPCSource) mempool() ([]string, error) {
+	raw, err := s.client.Call("getrawmempool")
+	if err != nil {
+		return nil, err
+	}
+	txids := []string{}
+	return txids, json.Unmarshal(raw, &txids)
+}
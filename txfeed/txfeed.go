@@ -0,0 +1,244 @@
+// Package txfeed follows the transactions announced by a list of bitcoin
+// nodes, failing over to the next when one disconnects, so that losing a
+// single upstream doesn't blind the voter
+package txfeed
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+)
+
+const (
+	// DefaultRetryInterval is how long to wait after every source has failed
+	// to connect if Config.RetryInterval is not positive
+	DefaultRetryInterval = 5 * time.Second
+
+	// DefaultDedupeSize is the number of recently seen transactions remembered
+	// if Config.DedupeSize is not positive
+	DefaultDedupeSize = 100000
+)
+
+// ErrDisconnected is reported when the active source's connection is lost
+var ErrDisconnected = errors.New("txfeed: source disconnected")
+
+// Source is a connection to a node announcing new transactions
+type Source interface {
+	// Subscribe connects and returns a channel of the hashes of new
+	// transactions. The channel is closed when the connection is lost.
+	Subscribe() (<-chan avalanche.Hash, error)
+
+	// Close ends the subscription, closing its channel
+	Close() error
+}
+
+// Config configures a Feed
+type Config struct {
+	// Sources are connected to one at a time, in order, moving to the next
+	// when the active one fails
+	Sources []Source
+
+	// Handler is called with each new transaction, once however many sources
+	// announce it
+	Handler func(avalanche.Hash)
+
+	// RetryInterval is how long to wait after every source has failed to
+	// connect before trying them again
+	RetryInterval time.Duration
+
+	// DedupeSize is the number of recently handled transactions that are not
+	// handled again when announced by another source
+	DedupeSize int
+
+	// Reporter receives connection errors and disconnects, if set
+	Reporter avalanche.ErrorReporter
+}
+
+// Stats are counts of a Feed's activity
+type Stats struct {
+	// Active is the index of the connected source, or -1 if none is
+	Active int
+
+	// Handled is the total number of transactions passed to the Handler
+	Handled int64
+
+	// Duplicates is the total number of announcements of transactions that
+	// were recently handled already
+	Duplicates int64
+
+	// Failovers is the total number of times the active source was lost
+	Failovers int64
+
+	// Failed is the total number of failed attempts to connect to a source
+	Failed int64
+}
+
+// Feed passes the transactions announced by the active source to a Handler
+type Feed struct {
+	// The counters are used atomically so they come first to be 64-bit
+	// aligned on 32-bit platforms
+	handled    int64
+	duplicates int64
+	failovers  int64
+	failed     int64
+	active     int32
+
+	config Config
+
+	// recent and recentOrder remember the last DedupeSize handled hashes,
+	// oldest first in recentOrder. They are only used by the worker.
+	recent      map[avalanche.Hash]struct{}
+	recentOrder []avalanche.Hash
+
+	runMu     sync.Mutex
+	isRunning bool
+	quitCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a new *Feed
+func New(config Config) *Feed {
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if config.DedupeSize <= 0 {
+		config.DedupeSize = DefaultDedupeSize
+	}
+
+	return &Feed{
+		config: config,
+		recent: make(map[avalanche.Hash]struct{}, config.DedupeSize),
+		active: -1,
+	}
+}
+
+// Start connects to the first available source and launches the goroutine
+// following it
+func (f *Feed) Start() bool {
+	f.runMu.Lock()
+	defer f.runMu.Unlock()
+
+	if f.isRunning || len(f.config.Sources) == 0 {
+		return false
+	}
+
+	f.isRunning = true
+	f.quitCh = make(chan struct{})
+
+	f.wg.Add(1)
+	go f.work()
+
+	return true
+}
+
+// Stop closes the active source and stops following it
+func (f *Feed) Stop() bool {
+	f.runMu.Lock()
+	defer f.runMu.Unlock()
+
+	if !f.isRunning {
+		return false
+	}
+
+	close(f.quitCh)
+	f.wg.Wait()
+
+	f.isRunning = false
+	return true
+}
+
+// Stats returns a snapshot of the Feed's activity
+func (f *Feed) Stats() Stats {
+	return Stats{
+		Active:     int(atomic.LoadInt32(&f.active)),
+		Handled:    atomic.LoadInt64(&f.handled),
+		Duplicates: atomic.LoadInt64(&f.duplicates),
+		Failovers:  atomic.LoadInt64(&f.failovers),
+		Failed:     atomic.LoadInt64(&f.failed),
+	}
+}
+
+// work follows one source at a time until the Feed is stopped, moving on to
+// the next whenever the active one fails to connect or disconnects
+func (f *Feed) work() {
+	defer f.wg.Done()
+
+	sources := f.config.Sources
+	for i, failures := 0, 0; ; i = (i + 1) % len(sources) {
+		// Back off once every source has been tried in vain
+		if failures > 0 && failures%len(sources) == 0 {
+			select {
+			case <-f.quitCh:
+				return
+			case <-time.After(f.config.RetryInterval):
+			}
+		}
+
+		tags := map[string]string{"source": strconv.Itoa(i)}
+		hashes, err := sources[i].Subscribe()
+		if err != nil {
+			failures++
+			atomic.AddInt64(&f.failed, 1)
+			f.report(&avalanche.Error{Op: "subscribe", Err: err}, tags)
+			continue
+		}
+		failures = 0
+
+		atomic.StoreInt32(&f.active, int32(i))
+		if !f.follow(sources[i], hashes) {
+			atomic.StoreInt32(&f.active, -1)
+			return
+		}
+		atomic.StoreInt32(&f.active, -1)
+
+		atomic.AddInt64(&f.failovers, 1)
+		f.report(ErrDisconnected, tags)
+	}
+}
+
+// follow handles the hashes announced by a source until it disconnects or the
+// Feed is stopped, in which case it closes the source and returns false
+func (f *Feed) follow(src Source, hashes <-chan avalanche.Hash) bool {
+	for {
+		select {
+		case <-f.quitCh:
+			src.Close()
+			return false
+		case h, ok := <-hashes:
+			if !ok {
+				return true
+			}
+			if !f.remember(h) {
+				atomic.AddInt64(&f.duplicates, 1)
+				continue
+			}
+			atomic.AddInt64(&f.handled, 1)
+			f.config.Handler(h)
+		}
+	}
+}
+
+func (f *Feed) report(err error, tags map[string]string) {
+	if f.config.Reporter != nil {
+		f.config.Reporter.ReportError(err, tags)
+	}
+}
+
+// remember records a hash as recently handled, evicting the oldest if there
+// are too many. It returns false if the hash was already recent.
+func (f *Feed) remember(h avalanche.Hash) bool {
+	if _, ok := f.recent[h]; ok {
+		return false
+	}
+	if len(f.recentOrder) >= f.config.DedupeSize {
+		delete(f.recent, f.recentOrder[0])
+		f.recentOrder = f.recentOrder[1:]
+	}
+	f.recent[h] = struct{}{}
+	f.recentOrder = append(f.recentOrder, h)
+	return true
+}
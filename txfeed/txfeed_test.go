@@ -0,0 +1,165 @@
+package txfeed
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	avalanche "github.com/tyler-smith/go-avalanche"
+	"github.com/tyler-smith/go-avalanche/bitcoinrpc"
+)
+
+// chanSource is a Source announcing whatever is sent on its channel
+type chanSource struct {
+	mu     sync.Mutex
+	ch     chan avalanche.Hash
+	err    error
+	closed bool
+}
+
+func (s *chanSource) Subscribe() (<-chan avalanche.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.ch, nil
+}
+
+func (s *chanSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	hashes []avalanche.Hash
+	errs   []error
+}
+
+func (r *recorder) handle(h avalanche.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hashes = append(r.hashes, h)
+}
+
+func (r *recorder) ReportError(err error, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.hashes)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	var (
+		down    = &chanSource{err: errors.New("connection refused")}
+		primary = &chanSource{ch: make(chan avalanche.Hash)}
+		backup  = &chanSource{ch: make(chan avalanche.Hash)}
+		r       = &recorder{}
+	)
+	f := New(Config{Sources: []Source{down, primary, backup}, Handler: r.handle, Reporter: r, RetryInterval: time.Millisecond})
+	if !f.Start() || f.Start() {
+		t.Fatal("Expected to start once")
+	}
+
+	// Sources that fail to connect are skipped
+	primary.ch <- 1
+	primary.ch <- 2
+	waitFor(t, func() bool { return r.count() == 2 })
+	if stats := f.Stats(); stats.Active != 1 || stats.Failed != 1 {
+		t.Fatal("Expected the second source to be active but got", stats)
+	}
+
+	// Losing the active source fails over to the next, and transactions both
+	// announced are only handled once
+	close(primary.ch)
+	backup.ch <- 2
+	backup.ch <- 3
+	waitFor(t, func() bool { return f.Stats().Duplicates == 1 && r.count() == 3 })
+	if stats := f.Stats(); stats.Active != 2 || stats.Failovers != 1 || stats.Handled != 3 {
+		t.Fatal("Expected to fail over to the third source but got", stats)
+	}
+
+	if !f.Stop() || f.Stop() {
+		t.Fatal("Expected to stop once")
+	}
+	if !backup.closed || f.Stats().Active != -1 {
+		t.Fatal("Expected the active source to be closed")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errs) != 2 || r.errs[1] != ErrDisconnected {
+		t.Fatal("Expected a connection error and a disconnect but got", r.errs)
+	}
+	if e, ok := r.errs[0].(*avalanche.Error); !ok || e.Op != "subscribe" {
+		t.Fatal("Expected a subscribe error but got", r.errs[0])
+	}
+}
+
+func TestRPCSource(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		mempool = []string{avalanche.Hash(1).DisplayHex()}
+		fail    = false
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": mempool, "error": nil, "id": 1})
+	}))
+	defer srv.Close()
+
+	s := NewRPCSource(bitcoinrpc.NewClient(bitcoinrpc.Config{URL: srv.URL}), time.Millisecond)
+	hashes, err := s.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mempool is announced first, then each new transaction once
+	if h := <-hashes; h != avalanche.Hash(1) {
+		t.Fatal("Expected hash 1 but got", h)
+	}
+	mu.Lock()
+	mempool = append(mempool, avalanche.Hash(2).DisplayHex())
+	mu.Unlock()
+	if h := <-hashes; h != avalanche.Hash(2) {
+		t.Fatal("Expected hash 2 but got", h)
+	}
+
+	// A failed call is a disconnect
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	for range hashes {
+		t.Fatal("Expected no more hashes")
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
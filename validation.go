@@ -0,0 +1,128 @@
+package avalanche
+
+import "runtime/debug"
+
+// TargetValidator checks a raw target fetched from a peer, such as running a
+// transaction's scripts and the node's relay policy, and returns the Target to
+// reconcile. Its IsAccepted and IsValid decide our vote on it. It is called
+// from up to Config.ValidationConcurrency goroutines at once, so it must be
+// safe for concurrent use.
+type TargetValidator interface {
+	ValidateTarget(targetType string, h Hash, raw []byte) (Target, error)
+}
+
+// TargetValidatorFunc adapts a function to a TargetValidator
+type TargetValidatorFunc func(targetType string, h Hash, raw []byte) (Target, error)
+
+// ValidateTarget calls f
+func (f TargetValidatorFunc) ValidateTarget(targetType string, h Hash, raw []byte) (Target, error) {
+	return f(targetType, h, raw)
+}
+
+// validation is the outcome of validating a raw target, waiting to be applied
+// by the event loop
+type validation struct {
+	targetType string
+	hash       Hash
+	target     Target
+	err        error
+}
+
+// SetTargetValidator sets the TargetValidator that raw targets passed to
+// SubmitRawTarget are run through. It must be called before the *Processor is
+// started.
+func (p *Processor) SetTargetValidator(v TargetValidator) {
+	p.validator = v
+}
+
+// SubmitRawTarget queues the serialized bytes of a target fetched from a peer,
+// such as a transaction it announced, to be validated in the background. Until
+// the TargetValidator is done we vote VoteUnknown on the target. The Target it
+// returns is then added for reconciliation by the event loop, so our vote
+// switches to yes or no. Validation errors are sent to the ErrorReporter and
+// leave the vote unknown.
+//
+// The bytes must hash to h; see VerifyTargetBytes. Targets that are already
+// known, recently finalized or being validated are ignored. ErrValidationBusy is returned if
+// Config.MaxPendingValidations targets are already waiting.
+func (p *Processor) SubmitRawTarget(targetType string, h Hash, raw []byte) error {
+	if p.validator == nil {
+		return ErrNoValidator
+	}
+	if err := p.VerifyTargetBytes(targetType, h, raw); err != nil {
+		return err
+	}
+	if _, ok := p.voteRecords[h]; ok || p.isRecentlyFinalized(h) {
+		return nil
+	}
+
+	p.validationMu.Lock()
+	defer p.validationMu.Unlock()
+	if _, ok := p.validating[h]; ok {
+		return nil
+	}
+	if max := p.config.MaxPendingValidations; max > 0 && len(p.validating) >= max {
+		return ErrValidationBusy
+	}
+	p.validating[h] = struct{}{}
+
+	go p.validate(p.validator, targetType, h, append([]byte{}, raw...))
+	return nil
+}
+
+// GetPendingValidations returns how many raw targets are waiting for or
+// undergoing validation
+func (p *Processor) GetPendingValidations() int {
+	p.validationMu.Lock()
+	defer p.validationMu.Unlock()
+	return len(p.validating)
+}
+
+// validate runs a raw target through v once a slot is free, and queues the
+// outcome for the event loop. A panic in v is recovered and treated as a
+// validation error.
+func (p *Processor) validate(v TargetValidator, targetType string, h Hash, raw []byte) {
+	p.validationSem <- struct{}{}
+	result := validation{targetType: targetType, hash: h}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result.err = &PanicError{"validate " + targetType, r, debug.Stack()}
+			}
+		}()
+		result.target, result.err = v.ValidateTarget(targetType, h, raw)
+	}()
+	<-p.validationSem
+
+	p.validationMu.Lock()
+	p.validated = append(p.validated, result)
+	p.validationMu.Unlock()
+	p.triggerPoll()
+}
+
+// applyValidations adds the targets whose validation has finished, so they
+// are voted on and polled
+func (p *Processor) applyValidations() {
+	p.validationMu.Lock()
+	validated := p.validated
+	p.validated = nil
+	for _, v := range validated {
+		delete(p.validating, v.hash)
+	}
+	p.validationMu.Unlock()
+
+	for _, v := range validated {
+		err := v.err
+		if err == nil && (v.target == nil || v.target.Hash() != v.hash) {
+			err = ErrHashMismatch
+		}
+		if err != nil {
+			if _, ok := err.(*PanicError); !ok {
+				err = &Error{"validate " + v.targetType, err}
+			}
+			reportError(p.reporter, err, map[string]string{"target": v.hash.DisplayHex()})
+			continue
+		}
+		p.AddTargetToReconcile(v.target)
+	}
+}
@@ -0,0 +1,95 @@
+package avalanche
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubmitRawTarget(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		mu      sync.Mutex
+		running int
+		most    int
+	)
+	validator := TargetValidatorFunc(func(targetType string, h Hash, raw []byte) (Target, error) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		switch string(raw) {
+		case "bad":
+			return nil, errors.New("script failed")
+		case "panic":
+			panic("boom")
+		}
+		return &Block{h, 1, true, string(raw) == "yes"}, nil
+	})
+
+	reported := make(chan error, 4)
+	p := NewProcessorWithConfig(NewConnman(), Config{ValidationConcurrency: 2, MaxPendingValidations: 4})
+	p.SetErrorReporter(ErrorReporterFunc(func(err error, _ map[string]string) { reported <- err }))
+	raw := func(s string) (Hash, []byte) {
+		sum := DoubleSHA256.Sum([]byte(s))
+		h, _ := HashFromWireBytes(sum[:])
+		return h, []byte(s)
+	}
+
+	yes, yesRaw := raw("yes")
+	if err := p.SubmitRawTarget("tx", yes, yesRaw); err != ErrNoValidator {
+		t.Fatal("Expected ErrNoValidator but got", err)
+	}
+	p.SetTargetValidator(validator)
+
+	// The bytes must match the hash
+	if err := p.SubmitRawTarget("tx", yes, []byte("no")); err == nil {
+		t.Fatal("Expected an error for mismatched bytes")
+	}
+
+	hashes := []Hash{}
+	for _, s := range []string{"yes", "no", "bad", "panic"} {
+		h, b := raw(s)
+		assertTrue(t, p.SubmitRawTarget("tx", h, b) == nil)
+		hashes = append(hashes, h)
+	}
+	assertTrue(t, p.SubmitRawTarget("tx", yes, yesRaw) == nil)
+	assertTrue(t, p.GetPendingValidations() == 4)
+	other, otherRaw := raw("other")
+	assertTrue(t, p.SubmitRawTarget("tx", other, otherRaw) == ErrValidationBusy)
+
+	// Votes stay unknown until validation is done
+	assertTrue(t, p.getVote(yes) == VoteUnknown)
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for p.GetPendingValidations() > 0 && time.Now().Before(deadline) {
+		p.eventLoop()
+		time.Sleep(time.Millisecond)
+	}
+	assertTrue(t, p.GetPendingValidations() == 0)
+	assertTrue(t, most <= 2)
+
+	assertTrue(t, p.getVote(hashes[0]) == VoteYes)
+	assertTrue(t, p.getVote(hashes[1]) == VoteNo)
+	assertTrue(t, p.getVote(hashes[2]) == VoteUnknown)
+	assertTrue(t, p.getVote(hashes[3]) == VoteUnknown)
+
+	for i := 0; i < 2; i++ {
+		switch err := (<-reported).(type) {
+		case *Error:
+			assertTrue(t, err.Op == "validate tx")
+		case *PanicError:
+			assertTrue(t, err.Value == "boom")
+		default:
+			t.Fatal("Unexpected error", err)
+		}
+	}
+}
@@ -1,14 +1,44 @@
 package avalanche
 
+// Vote values sent in a Response. They follow ABC, where the value is an
+// error code read as a signed integer: zero is yes, positive is no, and
+// negative means the voter has no opinion.
+const (
+	// VoteYes is a vote to accept the target
+	VoteYes uint32 = 0
+
+	// VoteNo is a vote to reject the target
+	VoteNo uint32 = 1
+
+	// VoteConflict is a vote to reject the target because it conflicts with
+	// one the voter has accepted, which is named in the Vote; see
+	// Vote.GetConflict
+	VoteConflict uint32 = 2
+
+	// VoteUnknown means the voter doesn't know the target yet. It is left out
+	// of the consider mask, so it counts neither for nor against a quorum.
+	VoteUnknown = ^uint32(0)
+)
+
 // Vote represents a single vote for a target
 type Vote struct {
 	err  uint32 // this is called "error" in abc for some reason
 	hash Hash
+
+	// conflict is the accepted target the voted on one conflicts with, for
+	// VoteConflict
+	conflict Hash
 }
 
 // NewVote creates a new Vote for the given hash
 func NewVote(err uint32, hash Hash) Vote {
-	return Vote{err, hash}
+	return Vote{err, hash, 0}
+}
+
+// NewConflictVote creates a new VoteConflict Vote rejecting hash because it
+// conflicts with the accepted target conflict
+func NewConflictVote(hash Hash, conflict Hash) Vote {
+	return Vote{VoteConflict, hash, conflict}
 }
 
 // GetHash returns the target hash
@@ -21,17 +51,64 @@ func (v Vote) GetError() uint32 {
 	return v.err
 }
 
+// GetConflict returns the target the voter accepted instead, and false unless
+// the vote is VoteConflict
+func (v Vote) GetConflict() (Hash, bool) {
+	return v.conflict, v.err == VoteConflict
+}
+
+// IsUnknown returns whether the voter didn't know the target; see VoteUnknown
+func (v Vote) IsUnknown() bool {
+	return isUnknownVote(v.err)
+}
+
+// isUnknownVote returns whether the vote is negative when read as a signed
+// integer, which includes VoteUnknown
+func isUnknownVote(err uint32) bool {
+	return int32(err) < 0
+}
+
 // VoteRecord keeps track of a series of votes for a target
 type VoteRecord struct {
 	votes      uint8
 	consider   uint8
 	confidence uint16
+	params     voteParams
+
+	// likelyFinal is set when the last vote raised the confidence to the
+	// likely final score
+	likelyFinal bool
+}
+
+// voteParams are the thresholds a VoteRecord uses to reach a decision
+type voteParams struct {
+	// mask selects the votes within the window
+	mask uint8
+
+	quorum            uint8
+	finalizationScore uint16
+
+	// likelyFinalScore is the confidence at which a decision is likely to
+	// finalize, or 0 if that isn't signalled
+	likelyFinalScore uint16
+}
+
+// defaultVoteParams are the thresholds used by ABC
+var defaultVoteParams = voteParams{
+	mask:              0xff,
+	quorum:            AvalancheVoteQuorum,
+	finalizationScore: AvalancheFinalizationScore,
 }
 
 // NewVoteRecord instantiates a new base record for voting on a target
 // `accepted` indicates whether or not the initial state should be acceptance
 func NewVoteRecord(accepted bool) *VoteRecord {
-	return &VoteRecord{confidence: boolToUint16(accepted)}
+	return newVoteRecordWithParams(accepted, defaultVoteParams)
+}
+
+// newVoteRecordWithParams instantiates a new record using the given thresholds
+func newVoteRecordWithParams(accepted bool, params voteParams) *VoteRecord {
+	return &VoteRecord{confidence: boolToUint16(accepted), params: params}
 }
 
 // isAccepted returns whether or not the voted state is acceptance or not
@@ -46,26 +123,45 @@ func (vr VoteRecord) getConfidence() uint16 {
 
 // hasFinalized returns whether or not the record has finalized a state
 func (vr VoteRecord) hasFinalized() bool {
-	return vr.getConfidence() >= AvalancheFinalizationScore
+	return vr.getConfidence() >= vr.params.finalizationScore
+}
+
+// isLikelyFinal returns whether the last vote raised the confidence to the
+// likely final score. Inconclusive votes leave the confidence where it is
+// without setting it again, so it is set once per decision.
+func (vr VoteRecord) isLikelyFinal() bool {
+	return vr.likelyFinal
+}
+
+// confidenceFraction returns how far the decision is towards finalization,
+// from 0 to 1
+func (vr VoteRecord) confidenceFraction() float64 {
+	if vr.hasFinalized() {
+		return 1
+	}
+	return float64(vr.getConfidence()) / float64(vr.params.finalizationScore)
 }
 
 // regsiterVote adds a new vote for an item and update confidence accordingly.
 // Returns true if the acceptance or finalization state changed.
 func (vr *VoteRecord) regsiterVote(err uint32) bool {
-	vr.votes = (vr.votes << 1) | boolToUint8(err == 0)
-	vr.consider = (vr.consider << 1) | boolToUint8(int32(err) >= 0)
+	vr.likelyFinal = false
+	vr.votes = (vr.votes << 1) | boolToUint8(err == VoteYes)
+	vr.consider = (vr.consider << 1) | boolToUint8(!isUnknownVote(err))
 
-	yes := countBits8(vr.votes&vr.consider&0xff) > 6
+	quorum := int(vr.params.quorum)
+	yes := countBits8(vr.votes&vr.consider&vr.params.mask) >= quorum
 
 	// The round is inconclusive
-	if !yes && countBits8((-vr.votes-1)&vr.consider&0xff) <= 6 {
+	if !yes && countBits8((-vr.votes-1)&vr.consider&vr.params.mask) < quorum {
 		return false
 	}
 
 	// Vote is conclusive and agrees with our current state
 	if vr.isAccepted() == yes {
 		vr.confidence += 2
-		return vr.getConfidence() == AvalancheFinalizationScore
+		vr.likelyFinal = vr.params.likelyFinalScore > 0 && vr.getConfidence() == vr.params.likelyFinalScore
+		return vr.getConfidence() == vr.params.finalizationScore
 	}
 
 	// Vote is conclusive but does not agree with our current state
@@ -0,0 +1,87 @@
+package avalanche
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// ABCVoteRecordSize is the size of a VoteRecord in the byte layout Bitcoin ABC
+// uses: the votes and consider bytes followed by the little endian confidence
+const ABCVoteRecordSize = 4
+
+// abcRecordEntrySize is the size of an exported record with its hash
+const abcRecordEntrySize = HashSize + ABCVoteRecordSize
+
+// MarshalABC returns the record in ABC's byte layout
+func (vr *VoteRecord) MarshalABC() [ABCVoteRecordSize]byte {
+	b := [ABCVoteRecordSize]byte{vr.votes, vr.consider}
+	binary.LittleEndian.PutUint16(b[2:], vr.confidence)
+	return b
+}
+
+// UnmarshalABC sets the record's votes, consider bits and confidence from
+// ABC's byte layout. Its thresholds are left as they are.
+func (vr *VoteRecord) UnmarshalABC(b []byte) error {
+	if len(b) != ABCVoteRecordSize {
+		return ErrInvalidVoteRecord
+	}
+	vr.votes, vr.consider = b[0], b[1]
+	vr.confidence = binary.LittleEndian.Uint16(b[2:])
+	return nil
+}
+
+// ExportVoteRecords writes the record of every target being voted on, in hash
+// order, as its hash in wire byte order followed by the record in ABC's byte
+// layout
+func (p *Processor) ExportVoteRecords(w io.Writer) error {
+	hashes := make([]Hash, 0, len(p.voteRecords))
+	for h := range p.voteRecords {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	bw := bufio.NewWriter(w)
+	for _, h := range hashes {
+		hash, rec := h.WireBytes(), p.voteRecords[h].MarshalABC()
+		bw.Write(hash[:])
+		bw.Write(rec[:])
+	}
+	return wrapError("export vote records", bw.Flush())
+}
+
+// ImportVoteRecords reads records written by ExportVoteRecords, or by another
+// implementation in the same layout, and votes on the targets resolve returns
+// starting from them. Records replace those of targets already being voted on,
+// and records resolve returns nil for are skipped. The records take the
+// Processor's thresholds for their targets' types. It returns the number of
+// records imported.
+func (p *Processor) ImportVoteRecords(r io.Reader, resolve TargetResolver) (int, error) {
+	br := bufio.NewReader(r)
+	buf := [abcRecordEntrySize]byte{}
+
+	imported := 0
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err == io.EOF {
+			return imported, nil
+		} else if err != nil {
+			return imported, &Error{"import vote records", err}
+		}
+
+		h, _ := HashFromWireBytes(buf[:HashSize])
+		t := resolve(h)
+		if t == nil {
+			continue
+		}
+
+		vr := &VoteRecord{params: p.typeVoteParams(t.Type())}
+		vr.UnmarshalABC(buf[HashSize:])
+		if _, ok := p.voteRecords[h]; !ok {
+			p.lastPolled[h] = p.pollSeq
+		}
+		p.targets[h] = t
+		p.voteRecords[h] = vr
+		imported++
+	}
+}
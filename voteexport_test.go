@@ -0,0 +1,57 @@
+package avalanche
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestVoteRecordABCLayout(t *testing.T) {
+	vr := &VoteRecord{votes: 0x7f, consider: 0xfe, confidence: 0x0103}
+	b := vr.MarshalABC()
+	assertTrue(t, b == [ABCVoteRecordSize]byte{0x7f, 0xfe, 0x03, 0x01})
+
+	decoded := &VoteRecord{params: defaultVoteParams}
+	assertTrue(t, decoded.UnmarshalABC(b[:]) == nil)
+	assertTrue(t, decoded.votes == vr.votes && decoded.consider == vr.consider && decoded.confidence == vr.confidence)
+	assertTrue(t, decoded.UnmarshalABC(b[:3]) == ErrInvalidVoteRecord)
+}
+
+func TestExportImportVoteRecords(t *testing.T) {
+	var (
+		p       = NewProcessor(NewConnman())
+		a       = blockForHash(Hash(65))
+		b       = blockForHash(Hash(66))
+		updates = []StatusUpdate{}
+	)
+	assertTrue(t, p.AddTargetToReconcile(a))
+	assertTrue(t, p.AddTargetToReconcile(b))
+	resp := NewResponse(0, 0, []Vote{NewVote(0, a.Hash()), NewVote(1, b.Hash())})
+	for i := 0; i < 10; i++ {
+		registerVotes(p, NodeID(0), resp, &updates)
+	}
+
+	buf := &bytes.Buffer{}
+	assertTrue(t, p.ExportVoteRecords(buf) == nil)
+	assertTrue(t, buf.Len() == 2*abcRecordEntrySize)
+	exported := buf.Bytes()
+
+	// Only resolved targets are imported, with the records they had
+	imported := NewProcessor(NewConnman())
+	n, err := imported.ImportVoteRecords(bytes.NewReader(exported), func(h Hash) Target {
+		if h == a.Hash() {
+			return a
+		}
+		return nil
+	})
+	assertTrue(t, err == nil && n == 1)
+	assertTrue(t, *imported.voteRecords[a.Hash()] == *p.voteRecords[a.Hash()])
+	assertTrue(t, imported.voteRecords[b.Hash()] == nil)
+	assertTrue(t, imported.IsAccepted(a) && imported.GetConfidence(a) == p.GetConfidence(a))
+
+	// A cut off record is an error
+	_, err = imported.ImportVoteRecords(bytes.NewReader(exported[:abcRecordEntrySize+1]), func(h Hash) Target { return a })
+	if e, ok := err.(*Error); !ok || e.Err != io.ErrUnexpectedEOF {
+		t.Fatal("Expected an unexpected EOF but got", err)
+	}
+}
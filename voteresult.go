@@ -0,0 +1,99 @@
+package avalanche
+
+// VoteOutcome is what RegisterVotesWithResult did with a single vote
+type VoteOutcome int
+
+const (
+	// VoteApplied is used for a vote registered on its target's record,
+	// including neutral votes
+	VoteApplied VoteOutcome = iota + 1
+
+	// VoteIgnoredUnknownTarget is used for a vote on a target we aren't
+	// voting on and haven't decided
+	VoteIgnoredUnknownTarget
+
+	// VoteIgnoredStale is used for a vote on a target that has already been
+	// decided. It is still compared with the outcome during the grace period.
+	VoteIgnoredStale
+
+	// VoteIgnoredInvalidTarget is used for a vote on a target that is no
+	// longer worth polling
+	VoteIgnoredInvalidTarget
+
+	// VotePenalized is used for a vote ignored because the node changed its
+	// vote on the target more than Config.MaxVoteFlips times. It is counted in
+	// the node's PeerInfo.VotesIgnored.
+	VotePenalized
+)
+
+// String returns the outcome's name
+func (o VoteOutcome) String() string {
+	switch o {
+	case VoteApplied:
+		return "applied"
+	case VoteIgnoredUnknownTarget:
+		return "unknown_target"
+	case VoteIgnoredStale:
+		return "stale"
+	case VoteIgnoredInvalidTarget:
+		return "invalid_target"
+	case VotePenalized:
+		return "penalized"
+	}
+	return "unknown"
+}
+
+// RegisterResult describes what RegisterVotesWithResult did with a Response
+type RegisterResult struct {
+	// Err is the *ResponseError the Response was refused with, or nil if its
+	// votes were processed
+	Err error
+
+	// Penalized is whether the Response as a whole counted against the node
+	Penalized bool
+
+	// Outcomes are what became of each vote, in the order of the Response.
+	// It is empty if the Response was refused.
+	Outcomes []VoteOutcome
+}
+
+// Count returns the number of votes with the given outcome
+func (r RegisterResult) Count(o VoteOutcome) int {
+	n := 0
+	for _, outcome := range r.Outcomes {
+		if outcome == o {
+			n++
+		}
+	}
+	return n
+}
+
+// RegisterVotesWithResult processes a response to a query like RegisterVotes,
+// and describes which votes were applied, which were ignored and why, and what
+// the node was penalized for
+func (p *Processor) RegisterVotesWithResult(id NodeID, resp Response, updates *[]StatusUpdate) RegisterResult {
+	result := RegisterResult{Outcomes: make([]VoteOutcome, len(resp.GetVotes()))}
+	if !p.applyVotes(id, resp, updates, &result) {
+		result.Outcomes = nil
+	}
+	return result
+}
+
+// setOutcome records the outcome of the ith vote, if the result is wanted
+func (r *RegisterResult) setOutcome(i int, o VoteOutcome) {
+	if r != nil {
+		r.Outcomes[i] = o
+	}
+}
+
+// ignoredOutcome returns why a vote on a target without a vote record is
+// ignored
+func (p *Processor) ignoredOutcome(h Hash) VoteOutcome {
+	if _, ok := p.finalizations[h]; ok {
+		return VoteIgnoredStale
+	}
+	if _, ok := p.graceful[h]; ok {
+		return VoteIgnoredStale
+	}
+	return VoteIgnoredUnknownTarget
+}
@@ -0,0 +1,55 @@
+package avalanche
+
+import "testing"
+
+func TestRegisterVotesWithResult(t *testing.T) {
+	var (
+		p       = NewProcessorWithConfig(NewConnman(), Config{MaxVoteFlips: 1})
+		a       = &Block{Hash(1), 4, true, true}
+		b       = &Block{Hash(2), 3, true, true}
+		c       = &Block{Hash(3), 2, true, true}
+		d       = &Block{Hash(4), 1, true, true}
+		updates = []StatusUpdate{}
+	)
+	for _, block := range []*Block{a, b, c, d} {
+		assertTrue(t, p.AddTargetToReconcile(block))
+	}
+
+	respond := func(errs ...uint32) RegisterResult {
+		poll, ok := p.PollNode(NodeID(0))
+		assertTrue(t, ok && len(poll.GetInvs()) == len(errs))
+		votes := make([]Vote, len(errs))
+		for i, inv := range poll.GetInvs() {
+			votes[i] = NewVote(errs[i], inv.TargetHash)
+		}
+		return p.RegisterVotesWithResult(NodeID(0), NewResponse(poll.GetRound(), 0, votes), &updates)
+	}
+
+	// Votes on targets that became invalid, were withdrawn or were decided
+	// after the poll was sent are ignored
+	poll, _ := p.PollNode(NodeID(0))
+	b.valid = false
+	p.RemoveTarget(c.Hash(), WithdrawEvicted)
+	p.recordFinalization(d.Hash(), StatusFinalized)
+	p.forgetTarget(d.Hash())
+	votes := []Vote{NewVote(VoteYes, a.Hash()), NewVote(VoteYes, b.Hash()), NewVote(VoteYes, c.Hash()), NewVote(VoteYes, d.Hash())}
+	result := p.RegisterVotesWithResult(NodeID(0), NewResponse(poll.GetRound(), 0, votes), &updates)
+	assertTrue(t, result.Err == nil && !result.Penalized && len(result.Outcomes) == 4)
+	assertTrue(t, result.Outcomes[0] == VoteApplied && result.Outcomes[1] == VoteIgnoredInvalidTarget)
+	assertTrue(t, result.Outcomes[2] == VoteIgnoredUnknownTarget && result.Outcomes[3] == VoteIgnoredStale)
+	assertTrue(t, result.Count(VoteApplied) == 1)
+
+	// A node that keeps changing its vote is penalized
+	assertTrue(t, respond(VoteNo).Outcomes[0] == VoteApplied)
+	assertTrue(t, respond(VoteYes).Outcomes[0] == VotePenalized)
+
+	// Refused responses have no outcomes, and only malformed ones are
+	// penalized
+	result = p.RegisterVotesWithResult(NodeID(0), NewResponse(99, 0, votes), &updates)
+	assertTrue(t, result.Err.(*ResponseError).Code == ResponseErrorUnsolicited && !result.Penalized)
+	assertTrue(t, result.Outcomes == nil)
+
+	poll, _ = p.PollNode(NodeID(0))
+	result = p.RegisterVotesWithResult(NodeID(0), NewResponse(poll.GetRound(), 0, votes), &updates)
+	assertTrue(t, result.Err.(*ResponseError).Code == ResponseErrorTooManyVotes && result.Penalized)
+}
@@ -0,0 +1,248 @@
+package avalanche
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ProtocolVersion is the version of the JSON encoding of Polls, Responses,
+// AddrMessages and PeerPolicies. Decoding a message with any other version fails with
+// ErrProtocolVersion, so nodes that don't speak the same protocol find out
+// rather than exchanging messages they misread.
+const ProtocolVersion = 1
+
+// wirePoll is the JSON encoding of a Poll. Magic is left out for mainnet so
+// nodes that predate networks can still read it.
+type wirePoll struct {
+	Version int    `json:"version"`
+	Magic   uint32 `json:"magic,omitempty"`
+	Round   int64  `json:"round"`
+	Invs    []Inv  `json:"invs"`
+}
+
+// wireVote is the JSON encoding of a Vote. Conflict is only set for
+// VoteConflict.
+type wireVote struct {
+	Error    uint32 `json:"error"`
+	Hash     Hash   `json:"hash"`
+	Conflict *Hash  `json:"conflict,omitempty"`
+}
+
+// wireResponse is the JSON encoding of a Response. Magic is left out for
+// mainnet, as for wirePoll.
+type wireResponse struct {
+	Version   int        `json:"version"`
+	Magic     uint32     `json:"magic,omitempty"`
+	Round     int64      `json:"round"`
+	Cooldown  uint32     `json:"cooldown"`
+	Votes     []wireVote `json:"votes"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// wireNetAddr is the JSON encoding of a NetAddr. Timestamps are in seconds.
+type wireNetAddr struct {
+	Addr      string      `json:"addr"`
+	Timestamp int64       `json:"time"`
+	Services  ServiceFlag `json:"services"`
+}
+
+// wireAddrMessage is the JSON encoding of an AddrMessage
+type wireAddrMessage struct {
+	Version int           `json:"version"`
+	Addrs   []wireNetAddr `json:"addrs"`
+}
+
+// wirePeerPolicy is the JSON encoding of a PeerPolicy
+type wirePeerPolicy struct {
+	Version        int      `json:"version"`
+	MinFeeRate     float64  `json:"min_fee_rate"`
+	TargetTypes    []string `json:"target_types,omitempty"`
+	MaxPollInvs    int      `json:"max_poll_invs,omitempty"`
+	MaxMessageSize int      `json:"max_message_size,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (p Poll) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wirePoll{ProtocolVersion, wireMagic(p.network), p.round, p.invs})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are ignored; use
+// DecodePoll to reject them.
+func (p *Poll) UnmarshalJSON(data []byte) (err error) {
+	*p, err = decodePoll(bytes.NewReader(data), false)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (v Vote) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newWireVote(v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (v *Vote) UnmarshalJSON(data []byte) error {
+	w := wireVote{}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*v = w.vote()
+	return nil
+}
+
+// newWireVote returns the JSON encoding of a Vote
+func newWireVote(v Vote) wireVote {
+	w := wireVote{Error: v.err, Hash: v.hash}
+	if conflict, ok := v.GetConflict(); ok {
+		w.Conflict = &conflict
+	}
+	return w
+}
+
+// vote returns the Vote a wireVote encodes
+func (w wireVote) vote() Vote {
+	v := Vote{w.Error, w.Hash, 0}
+	if w.Conflict != nil {
+		v.conflict = *w.Conflict
+	}
+	return v
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Response) MarshalJSON() ([]byte, error) {
+	w := wireResponse{ProtocolVersion, wireMagic(r.network), r.round, r.cooldown, make([]wireVote, len(r.votes)), r.truncated}
+	for i, v := range r.votes {
+		w.Votes[i] = newWireVote(v)
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are ignored; use
+// DecodeResponse to reject them.
+func (r *Response) UnmarshalJSON(data []byte) (err error) {
+	*r, err = decodeResponse(bytes.NewReader(data), false)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (m AddrMessage) MarshalJSON() ([]byte, error) {
+	w := wireAddrMessage{ProtocolVersion, make([]wireNetAddr, len(m.addrs))}
+	for i, a := range m.addrs {
+		w.Addrs[i] = wireNetAddr{a.Addr, a.Timestamp.Unix(), a.Services}
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are ignored; use
+// DecodeAddrMessage to reject them.
+func (m *AddrMessage) UnmarshalJSON(data []byte) (err error) {
+	*m, err = decodeAddrMessage(bytes.NewReader(data), false)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler
+func (pp PeerPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wirePeerPolicy{ProtocolVersion, pp.MinFeeRate, pp.TargetTypes, pp.MaxPollInvs, pp.MaxMessageSize})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are ignored; use
+// DecodePeerPolicy to reject them.
+func (pp *PeerPolicy) UnmarshalJSON(data []byte) (err error) {
+	*pp, err = decodePeerPolicy(bytes.NewReader(data), false)
+	return err
+}
+
+// DecodePoll reads a JSON encoded Poll from rd, such as the body of a request
+// from a peer. In strict mode fields this version doesn't know are an error,
+// so that peers running other versions fail loudly.
+func DecodePoll(rd io.Reader, strict bool) (Poll, error) {
+	p, err := decodePoll(rd, strict)
+	return p, wrapError("decode poll", err)
+}
+
+// DecodeResponse reads a JSON encoded Response from rd. In strict mode fields
+// this version doesn't know are an error.
+func DecodeResponse(rd io.Reader, strict bool) (Response, error) {
+	r, err := decodeResponse(rd, strict)
+	return r, wrapError("decode response", err)
+}
+
+// DecodeAddrMessage reads a JSON encoded AddrMessage from rd. In strict mode
+// fields this version doesn't know are an error.
+func DecodeAddrMessage(rd io.Reader, strict bool) (AddrMessage, error) {
+	m, err := decodeAddrMessage(rd, strict)
+	return m, wrapError("decode addr", err)
+}
+
+// DecodePeerPolicy reads a JSON encoded PeerPolicy from rd, as sent in the
+// handshake. In strict mode fields this version doesn't know are an error.
+func DecodePeerPolicy(rd io.Reader, strict bool) (PeerPolicy, error) {
+	pp, err := decodePeerPolicy(rd, strict)
+	return pp, wrapError("decode policy", err)
+}
+
+func decodePoll(rd io.Reader, strict bool) (Poll, error) {
+	w := wirePoll{}
+	if err := decodeWire(rd, strict, &w); err != nil {
+		return Poll{}, err
+	}
+	if w.Version != ProtocolVersion {
+		return Poll{}, ErrProtocolVersion
+	}
+	return Poll{w.Round, w.Invs, Network(w.Magic).orMainNet()}, nil
+}
+
+func decodeResponse(rd io.Reader, strict bool) (Response, error) {
+	w := wireResponse{}
+	if err := decodeWire(rd, strict, &w); err != nil {
+		return Response{}, err
+	}
+	if w.Version != ProtocolVersion {
+		return Response{}, ErrProtocolVersion
+	}
+
+	votes := make([]Vote, len(w.Votes))
+	for i, v := range w.Votes {
+		votes[i] = v.vote()
+	}
+	return Response{w.Round, w.Cooldown, votes, w.Truncated, Network(w.Magic).orMainNet(), nil}, nil
+}
+
+func decodeAddrMessage(rd io.Reader, strict bool) (AddrMessage, error) {
+	w := wireAddrMessage{}
+	if err := decodeWire(rd, strict, &w); err != nil {
+		return AddrMessage{}, err
+	}
+	if w.Version != ProtocolVersion {
+		return AddrMessage{}, ErrProtocolVersion
+	}
+	if len(w.Addrs) > AvalancheMaxAddrs {
+		return AddrMessage{}, ErrTooManyAddrs
+	}
+
+	addrs := make([]NetAddr, len(w.Addrs))
+	for i, a := range w.Addrs {
+		addrs[i] = NetAddr{a.Addr, time.Unix(a.Timestamp, 0), a.Services}
+	}
+	return AddrMessage{addrs}, nil
+}
+
+func decodePeerPolicy(rd io.Reader, strict bool) (PeerPolicy, error) {
+	w := wirePeerPolicy{}
+	if err := decodeWire(rd, strict, &w); err != nil {
+		return PeerPolicy{}, err
+	}
+	if w.Version != ProtocolVersion {
+		return PeerPolicy{}, ErrProtocolVersion
+	}
+	return PeerPolicy{w.MinFeeRate, w.TargetTypes, w.MaxPollInvs, w.MaxMessageSize}, nil
+}
+
+// decodeWire decodes a single JSON value from rd into v
+func decodeWire(rd io.Reader, strict bool, v interface{}) error {
+	dec := json.NewDecoder(rd)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
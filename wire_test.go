@@ -0,0 +1,77 @@
+package avalanche
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWireEncoding(t *testing.T) {
+	poll := NewPoll(7, []Inv{{"block", Hash(65)}, {"tx", Hash(66)}})
+	data, err := json.Marshal(poll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"round":7,"invs":[{"type":"block","hash":65},{"type":"tx","hash":66}]}`
+	if string(data) != expected {
+		t.Fatal("Expected", expected, "but got", string(data))
+	}
+
+	decodedPoll := Poll{}
+	if err = json.Unmarshal(data, &decodedPoll); err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, decodedPoll.GetRound() == 7)
+	assertTrue(t, len(decodedPoll.GetInvs()) == 2 && decodedPoll.GetInvs()[1] == poll.GetInvs()[1])
+
+	resp := NewTruncatedResponse(7, 2, []Vote{NewVote(VoteYes, Hash(65)), NewVote(VoteUnknown, Hash(66))})
+	data, err = json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"version":1,"round":7,"cooldown":2,"votes":[{"error":0,"hash":65},{"error":4294967295,"hash":66}],"truncated":true}`
+	if string(data) != expected {
+		t.Fatal("Expected", expected, "but got", string(data))
+	}
+
+	decodedResp := Response{}
+	if err = json.Unmarshal(data, &decodedResp); err != nil {
+		t.Fatal(err)
+	}
+	assertTrue(t, decodedResp.GetRound() == 7 && decodedResp.cooldown == 2 && decodedResp.IsTruncated())
+	assertVotes(t, decodedResp, resp.GetVotes())
+
+	// Votes encode on their own too
+	data, err = json.Marshal(NewVote(VoteNo, Hash(67)))
+	assertTrue(t, err == nil && string(data) == `{"error":1,"hash":67}`)
+}
+
+func TestWireStrictDecoding(t *testing.T) {
+	// Unknown fields, including those nested in Invs and Votes, are only
+	// rejected in strict mode
+	for _, body := range []string{
+		`{"version":1,"round":1,"invs":[],"extra":true}`,
+		`{"version":1,"round":1,"invs":[{"type":"block","hash":65,"extra":true}]}`,
+	} {
+		_, err := DecodePoll(strings.NewReader(body), false)
+		assertTrue(t, err == nil)
+		_, err = DecodePoll(strings.NewReader(body), true)
+		assertTrue(t, err != nil)
+	}
+
+	body := `{"version":1,"round":1,"votes":[{"error":0,"hash":65,"extra":true}]}`
+	_, err := DecodeResponse(strings.NewReader(body), false)
+	assertTrue(t, err == nil)
+	_, err = DecodeResponse(strings.NewReader(body), true)
+	assertTrue(t, err != nil)
+
+	// Messages from other versions, or without one, are refused
+	for _, body := range []string{`{"round":1,"votes":[]}`, `{"version":2,"round":1,"votes":[]}`} {
+		_, err = DecodeResponse(strings.NewReader(body), false)
+		e, ok := err.(*Error)
+		if !ok || e.Op != "decode response" || e.Err != ErrProtocolVersion {
+			t.Fatal("Expected a protocol version error but got", err)
+		}
+	}
+	assertTrue(t, json.Unmarshal([]byte(`{"round":1,"invs":[]}`), &Poll{}) == ErrProtocolVersion)
+}
@@ -0,0 +1,213 @@
+package avalanche
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The binary encoding of Polls and Responses is a compact alternative to the
+// JSON one for peers that negotiate it; see PollHTTPHandler. Integers are
+// little endian and hashes are HashSize bytes in wire byte order. Both start
+// with the ProtocolVersion, the Network magic and the round:
+//
+//	Poll:     version u32, magic u32, round i64, count u32, count * Inv
+//	Inv:      type length u8, type, hash
+//	Response: version u32, magic u32, round i64, cooldown u32, flags u8,
+//	          count u32, count * Vote
+//	Vote:     error u32, hash, conflicting hash for VoteConflict only
+//
+// The only Response flag is bit 0, set when the Response is truncated.
+
+// responseFlagTruncated marks a truncated Response in the binary encoding
+const responseFlagTruncated = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (p Poll) MarshalBinary() ([]byte, error) {
+	size := 20
+	for _, inv := range p.invs {
+		if len(inv.TargetType) > math.MaxUint8 {
+			return nil, ErrInvalidBinary
+		}
+		size += 1 + len(inv.TargetType) + HashSize
+	}
+
+	w := binaryWriter{make([]byte, 0, size)}
+	w.uint32(ProtocolVersion)
+	w.uint32(uint32(p.GetNetwork()))
+	w.uint64(uint64(p.round))
+	w.uint32(uint32(len(p.invs)))
+	for _, inv := range p.invs {
+		w.buf = append(w.buf, byte(len(inv.TargetType)))
+		w.buf = append(w.buf, inv.TargetType...)
+		w.hash(inv.TargetHash)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Polls with more than
+// AvalancheMaxElementPoll Invs are rejected before anything is allocated for
+// them.
+func (p *Poll) UnmarshalBinary(data []byte) error {
+	r := binaryReader{buf: data}
+	if !r.version() {
+		return r.err
+	}
+	network := Network(r.uint32()).orMainNet()
+	round := int64(r.uint64())
+	n := r.count(AvalancheMaxElementPoll)
+
+	invs := make([]Inv, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		typ := string(r.bytes(int(r.uint8())))
+		invs = append(invs, Inv{typ, r.hash()})
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	*p = Poll{round, invs, network}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (r Response) MarshalBinary() ([]byte, error) {
+	w := binaryWriter{make([]byte, 0, 25+len(r.votes)*(4+HashSize))}
+	w.uint32(ProtocolVersion)
+	w.uint32(uint32(r.GetNetwork()))
+	w.uint64(uint64(r.round))
+	w.uint32(r.cooldown)
+	if r.truncated {
+		w.buf = append(w.buf, responseFlagTruncated)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+	w.uint32(uint32(len(r.votes)))
+	for _, v := range r.votes {
+		w.uint32(v.err)
+		w.hash(v.hash)
+		if v.err == VoteConflict {
+			w.hash(v.conflict)
+		}
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (r *Response) UnmarshalBinary(data []byte) error {
+	br := binaryReader{buf: data}
+	if !br.version() {
+		return br.err
+	}
+	network := Network(br.uint32()).orMainNet()
+	round := int64(br.uint64())
+	cooldown := br.uint32()
+	flags := br.uint8()
+	n := br.count(AvalancheMaxElementPoll)
+
+	votes := make([]Vote, 0, n)
+	for i := 0; i < n && br.err == nil; i++ {
+		v := Vote{err: br.uint32(), hash: br.hash()}
+		if v.err == VoteConflict {
+			v.conflict = br.hash()
+		}
+		votes = append(votes, v)
+	}
+	if err := br.done(); err != nil {
+		return err
+	}
+
+	*r = Response{round, cooldown, votes, flags&responseFlagTruncated != 0, network, nil}
+	return nil
+}
+
+// binaryWriter appends the fields of the binary encoding to buf
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *binaryWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *binaryWriter) hash(h Hash) {
+	b := h.WireBytes()
+	w.buf = append(w.buf, b[:]...)
+}
+
+// binaryReader reads the fields of the binary encoding from buf. Once a read
+// runs past the end, err is set and every later read returns zero.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+// bytes returns the next n bytes
+func (r *binaryReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.buf) {
+		r.err = ErrInvalidBinary
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *binaryReader) uint8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binaryReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *binaryReader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *binaryReader) hash() Hash {
+	h, _ := HashFromWireBytes(r.bytes(HashSize))
+	return h
+}
+
+// version reads the ProtocolVersion, returning false if it isn't ours
+func (r *binaryReader) version() bool {
+	if v := r.uint32(); r.err == nil && v != ProtocolVersion {
+		r.err = ErrProtocolVersion
+	}
+	return r.err == nil
+}
+
+// count reads the number of elements that follow, which may be at most max
+func (r *binaryReader) count(max int) int {
+	n := r.uint32()
+	if n > uint32(max) {
+		r.err = ErrInvalidBinary
+		return 0
+	}
+	return int(n)
+}
+
+// done returns the first error, or ErrInvalidBinary if bytes are left over
+func (r *binaryReader) done() error {
+	if r.err == nil && len(r.buf) > 0 {
+		r.err = ErrInvalidBinary
+	}
+	return r.err
+}
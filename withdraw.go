@@ -0,0 +1,38 @@
+package avalanche
+
+// WithdrawReason says why a target was withdrawn with RemoveTarget
+type WithdrawReason string
+
+const (
+	// WithdrawEvicted is for a transaction evicted from the mempool, such as
+	// for a low fee or for expiring
+	WithdrawEvicted WithdrawReason = "evicted"
+
+	// WithdrawReplaced is for a target replaced by another, such as a
+	// transaction replaced by one spending the same outputs
+	WithdrawReplaced WithdrawReason = "replaced"
+)
+
+// RemoveTarget withdraws a target that is no longer worth deciding on before it
+// finalizes, publishing a StatusUpdate with StatusWithdrawn and the reason. It
+// stops being polled, and late votes on it are ignored. No decision is
+// recorded, so it can be added again. It returns false if the target is not
+// being voted on.
+func (p *Processor) RemoveTarget(h Hash, reason WithdrawReason) bool {
+	p.warmRecord(h)
+	vr, ok := p.voteRecords[h]
+	if !ok {
+		return false
+	}
+
+	p.publish(StatusUpdate{
+		Hash:       h,
+		Status:     StatusWithdrawn,
+		Metadata:   p.metadata[h],
+		Confidence: vr.confidenceFraction(),
+		Reason:     reason,
+		Degraded:   p.IsDegraded(),
+	})
+	p.forgetTarget(h)
+	return true
+}
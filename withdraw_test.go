@@ -0,0 +1,44 @@
+package avalanche
+
+import "testing"
+
+func TestRemoveTarget(t *testing.T) {
+	var (
+		connman = NewConnman()
+		p       = NewProcessorWithConfig(connman, Config{PollRedundancy: 1})
+		sub     = p.Subscribe()
+		tx      = &Block{Hash(1), 0, true, true}
+		other   = &Block{Hash(2), 0, true, true}
+		updates = []StatusUpdate{}
+	)
+	connman.AddNode(NodeID(0))
+	assertTrue(t, p.AddTargetToReconcileWithMetadata(tx, Metadata{"source": "mempool"}))
+	assertTrue(t, p.AddTargetToReconcile(other))
+	p.eventLoop()
+	assertTrue(t, len(p.GetAskedPeers(tx.Hash())) == 1)
+
+	assertTrue(t, p.RemoveTarget(tx.Hash(), WithdrawEvicted))
+	assertFalse(t, p.RemoveTarget(tx.Hash(), WithdrawEvicted))
+
+	update := <-sub
+	assertTrue(t, update.Hash == tx.Hash() && update.Status == StatusWithdrawn && update.Reason == WithdrawEvicted)
+	assertTrue(t, update.Metadata["source"] == "mempool")
+
+	// It's no longer tracked or polled, and no decision is recorded
+	assertTrue(t, len(p.GetAskedPeers(tx.Hash())) == 0)
+	_, finalized := p.finalizations[tx.Hash()]
+	assertFalse(t, finalized)
+	invs := p.GetInvsForNextPoll()
+	assertTrue(t, len(invs) == 0)
+
+	// Late votes on it are ignored while the rest of the response counts
+	resp := NewResponse(0, 0, []Vote{NewVote(VoteYes, tx.Hash()), NewVote(VoteYes, other.Hash())})
+	assertTrue(t, registerVotes(p, NodeID(0), resp, &updates))
+	assertTrue(t, p.voteRecords[tx.Hash()] == nil)
+	assertTrue(t, p.GetConfidence(other) == 0)
+	invs = p.GetInvsForNextPoll()
+	assertTrue(t, len(invs) == 1 && invs[0].TargetHash == other.Hash())
+
+	// It can be added again
+	assertTrue(t, p.AddTargetToReconcile(tx))
+}